@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/hay-kot/cronprom/internal/web"
+)
+
+// FlagsConn are the connection settings used by commands that talk to the server
+type FlagsConn struct {
+	CAFile     string
+	CertFile   string
+	KeyFile    string
+	UnixSocket string // Connect to the server over this socket, the host of the URL is ignored
+	HTTP2      bool   // Negotiate HTTP/2 with TLS servers, otherwise only HTTP/1.1 is used
+}
+
+// newHTTPClient creates the HTTP client used to communicate with the server,
+// presenting a client certificate when one is configured. The client keeps idle
+// connections open, so commands sending several requests reuse a connection
+// instead of paying for a new handshake each time.
+func newHTTPClient(flags FlagsConn) (*http.Client, error) {
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = 8
+	transport.IdleConnTimeout = 5 * time.Minute
+	client.Transport = transport
+
+	if !flags.HTTP2 {
+		var protocols http.Protocols
+		protocols.SetHTTP1(true)
+		transport.Protocols = &protocols
+		transport.ForceAttemptHTTP2 = false
+	}
+
+	if flags.UnixSocket != "" {
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", flags.UnixSocket)
+		}
+	}
+
+	if flags.CAFile == "" && flags.CertFile == "" && flags.KeyFile == "" {
+		return client, nil
+	}
+
+	tlsCfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if flags.CAFile != "" {
+		pool, err := web.LoadCertPool(flags.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	if (flags.CertFile == "") != (flags.KeyFile == "") {
+		return nil, errors.New("client certificate and key must be provided together")
+	}
+
+	if flags.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(flags.CertFile, flags.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	transport.TLSClientConfig = tlsCfg
+
+	return client, nil
+}
@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+)
+
+// FlagsConfig selects the configuration of the commands that load it
+type FlagsConfig struct {
+	Path     string   // File, directory, or URL
+	Format   string   // Format of the file, defaults to the format of its extension
+	Headers  []string // Headers of config fetches from a URL
+	Template bool     // Render the config files as Go templates
+	Values   string   // Values file of the templates
+	Strict   bool     // Reject unknown fields
+	AgeKey   string   // Age key file decrypting sops encrypted files
+
+	// Settings replacing those of the config files, only set by serve
+	Overrides config.Overrides
+}
+
+// loadConfig loads the configuration from a file, directory, or URL. The remote
+// source is returned for configurations loaded from a URL and nil otherwise.
+func loadConfig(ctx context.Context, flags FlagsConfig) (*config.Config, *config.RemoteConfig, error) {
+	opts := config.LoadOptions{
+		Template:   flags.Template,
+		Values:     flags.Values,
+		Strict:     flags.Strict,
+		AgeKeyFile: flags.AgeKey,
+		Overrides:  flags.Overrides,
+	}
+	if flags.Format != "" {
+		var err error
+		opts.Format, err = config.ParseConfigFormat(flags.Format)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if !config.IsRemoteConfig(flags.Path) {
+		cfg, err := config.LoadConfigWith(flags.Path, opts)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error loading configuration: %w", err)
+		}
+		return cfg, nil, nil
+	}
+
+	remote, err := config.NewRemoteConfig(flags.Path, opts, flags.Headers)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading configuration: %w", err)
+	}
+
+	cfg, err := remote.Load(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading configuration: %w", err)
+	}
+	return cfg, remote, nil
+}
@@ -0,0 +1,188 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/hay-kot/cronprom/internal/services/collector"
+	"github.com/mattn/go-isatty"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type FlagsConfigInit struct {
+	Output    string // File to write, - for stdout
+	Namespace string
+	Address   string
+	Force     bool // Overwrite an existing file
+}
+
+// starterConfig is the config written by config init, with one example metric of
+// every type
+var starterConfig = template.Must(template.New("config").Parse(`# cronprom configuration, see the example config in the repository for all settings
+web:
+  address: {{ printf "%q" .Address }}
+
+global:
+  namespace: {{ printf "%q" .Namespace }}
+  refresh_interval: "30s"
+
+# Bearer tokens accepted by the push API, the push API is unauthenticated without
+# tokens
+# auth:
+#   tokens:
+#     - "change-me"
+
+metrics:
+  # Set to the time of the last successful run
+  - name: "job_last_success_timestamp"
+    description: "Unix time of the last successful job run"
+    type: "gauge"
+    labels: ["job_name"]
+    # Report jobs that didn't succeed for more than a day
+    expected_interval: 25h
+
+  # Incremented by every failed run
+  - name: "job_failures_total"
+    description: "Number of failed job runs"
+    type: "counter"
+    labels: ["job_name"]
+
+  # Observes the duration of every run
+  - name: "job_duration"
+    description: "Duration of job runs"
+    unit: "seconds"
+    type: "histogram"
+    labels: ["job_name"]
+    buckets: [1, 5, 10, 30, 60, 300, 600, 1800, 3600]
+
+  # Observes the number of processed records of every run
+  - name: "job_records_processed"
+    description: "Records processed per job run"
+    type: "summary"
+    labels: ["job_name"]
+    objectives: {0.5: 0.05, 0.9: 0.01, 0.99: 0.001}
+
+  # A push replaces the values of the info labels, e.g., the version a job ran with
+  - name: "job"
+    description: "Details of the latest job run"
+    type: "info"
+    labels: ["job_name"]
+    info_labels: ["version"]
+
+  # A push sets the state in the label named after the metric,
+  # e.g., {"job_state": "running", "job_name": "backup"}
+  - name: "job_state"
+    description: "State of the job"
+    type: "enum"
+    labels: ["job_name"]
+    states: ["idle", "running", "failed"]
+`))
+
+// ConfigInit writes a starter configuration. Settings without a flag are asked for
+// when stdin is a terminal, otherwise their defaults are used. The written
+// configuration is validated first.
+func ConfigInit(flags FlagsConfigInit) error {
+	if isatty.IsTerminal(os.Stdin.Fd()) {
+		in := bufio.NewReader(os.Stdin)
+
+		var err error
+		for _, p := range []struct {
+			value  *string
+			prompt string
+		}{
+			{&flags.Namespace, "Namespace of the metrics"},
+			{&flags.Address, "Address to listen on"},
+			{&flags.Output, "File to write"},
+		} {
+			*p.value, err = prompt(in, p.prompt, *p.value)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := starterConfig.Execute(&buf, flags); err != nil {
+		return err
+	}
+
+	if err := checkConfig(buf.Bytes()); err != nil {
+		return err
+	}
+
+	if flags.Output == "-" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+
+	mode := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if flags.Force {
+		mode = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+
+	f, err := os.OpenFile(flags.Output, mode, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", flags.Output)
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "wrote %s, start the server with: cronprom serve --config-path %s\n", flags.Output, flags.Output)
+	return nil
+}
+
+// checkConfig loads the rendered configuration to catch invalid settings before it
+// is written
+func checkConfig(data []byte) error {
+	dir, err := os.MkdirTemp("", "cronprom-init")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	path := filepath.Join(dir, "config.yml")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig(path)
+	if err == nil {
+		_, err = collector.NewMetricCollector(cfg, prometheus.NewRegistry())
+	}
+	if err != nil {
+		return fmt.Errorf("invalid settings: %w", err)
+	}
+	return nil
+}
+
+// prompt asks for a value on stderr, an empty answer keeps the default
+func prompt(in *bufio.Reader, question, value string) (string, error) {
+	fmt.Fprintf(os.Stderr, "%s [%s]: ", question, value)
+
+	answer, err := in.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	if answer = strings.TrimSpace(answer); answer != "" {
+		return answer, nil
+	}
+	return value, nil
+}
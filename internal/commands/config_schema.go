@@ -0,0 +1,15 @@
+package commands
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+)
+
+// ConfigSchema prints the JSON Schema of the configuration file
+func ConfigSchema() error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(config.JSONSchema())
+}
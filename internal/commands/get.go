@@ -0,0 +1,359 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+type FlagsGet struct {
+	URL       string
+	Name      string
+	Labels    []string
+	Quantiles []float64
+	Buckets   []float64
+	Output    string
+	Username  string
+	Password  string
+	Conn      FlagsConn
+}
+
+// Sample is a single value read back from the exposition endpoint.
+type Sample struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels"`
+	Value  float64           `json:"value"`
+}
+
+// MarshalJSON encodes NaN and infinite values, like the quantiles of an empty
+// histogram, as null, JSON numbers can't represent them
+func (s Sample) MarshalJSON() ([]byte, error) {
+	type sample Sample
+
+	var value *float64
+	if !math.IsNaN(s.Value) && !math.IsInf(s.Value, 0) {
+		value = &s.Value
+	}
+
+	return json.Marshal(struct {
+		sample
+		Value *float64 `json:"value"`
+	}{sample(s), value})
+}
+
+// Get fetches the current state of a metric from the /metrics endpoint and prints
+// the value of each matching series. For histograms and summaries the requested
+// quantiles and bucket counts are printed instead.
+func Get(ctx context.Context, flags FlagsGet) error {
+	switch flags.Output {
+	case "", OutputText, OutputJSON:
+	default:
+		return fmt.Errorf("invalid output format: %s (expected text or json)", flags.Output)
+	}
+
+	for _, q := range flags.Quantiles {
+		if q < 0 || q > 1 {
+			return fmt.Errorf("invalid quantile: %v (expected a value between 0 and 1)", q)
+		}
+	}
+
+	match := make(map[string]string, len(flags.Labels))
+	for _, label := range flags.Labels {
+		key, val, ok := parseLabel(label)
+		if !ok {
+			return fmt.Errorf("invalid label format: %s (expected key=value)", label)
+		}
+		match[key] = val
+	}
+
+	client, err := newHTTPClient(flags.Conn)
+	if err != nil {
+		return err
+	}
+
+	families, err := fetchMetricFamilies(ctx, client, flags.URL, flags.Username, flags.Password)
+	if err != nil {
+		return err
+	}
+
+	family, err := findMetricFamily(families, flags.Name)
+	if err != nil {
+		return err
+	}
+
+	samples := []Sample{}
+	for _, m := range family.GetMetric() {
+		labels := labelMap(m)
+		if !matchLabels(labels, match) {
+			continue
+		}
+
+		s, err := familySamples(family, m, labels, flags.Quantiles, flags.Buckets)
+		if err != nil {
+			return err
+		}
+		samples = append(samples, s...)
+	}
+
+	if len(samples) == 0 {
+		return fmt.Errorf("no series of metric '%s' match the given labels", family.GetName())
+	}
+
+	if flags.Output == OutputJSON {
+		return json.NewEncoder(os.Stdout).Encode(samples)
+	}
+
+	for _, s := range samples {
+		fmt.Println(formatSample(s))
+	}
+
+	return nil
+}
+
+// fetchMetricFamilies scrapes the given URL and parses the text exposition format
+func fetchMetricFamilies(ctx context.Context, client *http.Client, url, username, password string) (map[string]*dto.MetricFamily, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", string(expfmt.NewFormat(expfmt.TypeTextPlain)))
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse metrics: %w", err)
+	}
+
+	return families, nil
+}
+
+// findMetricFamily looks up a metric family by its fully qualified name, falling
+// back to a unique match on the configured (un-namespaced) name.
+func findMetricFamily(families map[string]*dto.MetricFamily, name string) (*dto.MetricFamily, error) {
+	if family, ok := families[name]; ok {
+		return family, nil
+	}
+
+	var found *dto.MetricFamily
+	for fqName, family := range families {
+		if !strings.HasSuffix(fqName, "_"+name) {
+			continue
+		}
+
+		if found != nil {
+			return nil, fmt.Errorf("metric name '%s' is ambiguous, use the fully qualified name", name)
+		}
+		found = family
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf("metric '%s' not found", name)
+	}
+
+	return found, nil
+}
+
+func labelMap(m *dto.Metric) map[string]string {
+	labels := make(map[string]string, len(m.GetLabel()))
+	for _, lp := range m.GetLabel() {
+		labels[lp.GetName()] = lp.GetValue()
+	}
+	return labels
+}
+
+func matchLabels(labels, match map[string]string) bool {
+	for k, v := range match {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// familySamples converts a single series into the samples requested by the user
+func familySamples(family *dto.MetricFamily, m *dto.Metric, labels map[string]string, quantiles, buckets []float64) ([]Sample, error) {
+	name := family.GetName()
+
+	withLabel := func(key string, value float64) map[string]string {
+		l := make(map[string]string, len(labels)+1)
+		for k, v := range labels {
+			l[k] = v
+		}
+		l[key] = strconv.FormatFloat(value, 'g', -1, 64)
+		return l
+	}
+
+	switch family.GetType() {
+	case dto.MetricType_GAUGE:
+		return []Sample{{Name: name, Labels: labels, Value: m.GetGauge().GetValue()}}, nil
+	case dto.MetricType_COUNTER:
+		return []Sample{{Name: name, Labels: labels, Value: m.GetCounter().GetValue()}}, nil
+	case dto.MetricType_UNTYPED:
+		return []Sample{{Name: name, Labels: labels, Value: m.GetUntyped().GetValue()}}, nil
+	case dto.MetricType_SUMMARY:
+		if len(buckets) > 0 {
+			return nil, fmt.Errorf("metric '%s' is a summary and has no buckets", name)
+		}
+
+		summary := m.GetSummary()
+		if len(quantiles) == 0 {
+			return []Sample{
+				{Name: name + "_sum", Labels: labels, Value: summary.GetSampleSum()},
+				{Name: name + "_count", Labels: labels, Value: float64(summary.GetSampleCount())},
+			}, nil
+		}
+
+		samples := make([]Sample, 0, len(quantiles))
+		for _, q := range quantiles {
+			idx := slices.IndexFunc(summary.GetQuantile(), func(sq *dto.Quantile) bool {
+				return sq.GetQuantile() == q
+			})
+			if idx == -1 {
+				return nil, fmt.Errorf("quantile %v is not an objective of summary '%s'", q, name)
+			}
+
+			samples = append(samples, Sample{
+				Name:   name,
+				Labels: withLabel("quantile", q),
+				Value:  summary.GetQuantile()[idx].GetValue(),
+			})
+		}
+		return samples, nil
+	case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+		histogram := m.GetHistogram()
+		if len(quantiles) == 0 && len(buckets) == 0 {
+			return []Sample{
+				{Name: name + "_sum", Labels: labels, Value: histogram.GetSampleSum()},
+				{Name: name + "_count", Labels: labels, Value: float64(histogram.GetSampleCount())},
+			}, nil
+		}
+
+		samples := make([]Sample, 0, len(quantiles)+len(buckets))
+		for _, q := range quantiles {
+			samples = append(samples, Sample{
+				Name:   name,
+				Labels: withLabel("quantile", q),
+				Value:  histogramQuantile(q, histogram),
+			})
+		}
+
+		for _, le := range buckets {
+			idx := slices.IndexFunc(histogram.GetBucket(), func(b *dto.Bucket) bool {
+				return b.GetUpperBound() == le
+			})
+			if idx == -1 && !math.IsInf(le, 1) {
+				return nil, fmt.Errorf("bucket %v is not defined for histogram '%s'", le, name)
+			}
+
+			count := histogram.GetSampleCount()
+			if idx != -1 {
+				count = histogram.GetBucket()[idx].GetCumulativeCount()
+			}
+
+			samples = append(samples, Sample{
+				Name:   name + "_bucket",
+				Labels: withLabel("le", le),
+				Value:  float64(count),
+			})
+		}
+		return samples, nil
+	default:
+		return nil, fmt.Errorf("unsupported metric type: %s", family.GetType())
+	}
+}
+
+// histogramQuantile estimates a quantile from the cumulative buckets of a histogram
+// using linear interpolation within the bucket, matching PromQL's histogram_quantile.
+func histogramQuantile(q float64, h *dto.Histogram) float64 {
+	count := float64(h.GetSampleCount())
+	if count == 0 {
+		return math.NaN()
+	}
+
+	buckets := slices.Clone(h.GetBucket())
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].GetUpperBound() < buckets[j].GetUpperBound()
+	})
+
+	rank := q * count
+
+	var lower, prevCount float64
+	for i, b := range buckets {
+		upper := b.GetUpperBound()
+		cumulative := float64(b.GetCumulativeCount())
+
+		if cumulative >= rank {
+			if math.IsInf(upper, 1) {
+				return lower
+			}
+
+			if i == 0 && upper <= 0 {
+				return upper
+			}
+
+			if cumulative == prevCount {
+				return upper
+			}
+
+			return lower + (upper-lower)*(rank-prevCount)/(cumulative-prevCount)
+		}
+
+		lower = upper
+		prevCount = cumulative
+	}
+
+	// The quantile falls into the implicit +Inf bucket
+	if len(buckets) == 0 {
+		return math.NaN()
+	}
+	return lower
+}
+
+func formatSample(s Sample) string {
+	keys := make([]string, 0, len(s.Labels))
+	for k := range s.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(s.Name)
+	if len(keys) > 0 {
+		b.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, "%s=%q", k, s.Labels[k])
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(strconv.FormatFloat(s.Value, 'g', -1, 64))
+
+	return b.String()
+}
@@ -1,39 +1,117 @@
 package commands
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"net"
 	"net/http"
+	"os"
 	"time"
 
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/hay-kot/cronprom/internal/services/collector"
+	"github.com/hay-kot/cronprom/internal/services/udp"
 	"github.com/hay-kot/cronprom/internal/web"
 	"github.com/rs/zerolog/log"
 )
 
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
 type FlagsPush struct {
-	URL    string   `json:"url"`
-	Name   string   `json:"name"`
-	Type   string   `json:"type"`
-	Labels []string `json:"labels"`
-	Value  float64  `json:"value"`
+	URL      string    `json:"url"`
+	UDP      string    `json:"udp,omitempty"` // Address of the UDP listener, replaces the URL
+	Name     string    `json:"name"`
+	Type     string    `json:"type"`
+	Labels   []string  `json:"labels"`
+	Value    float64   `json:"value"`
+	ValueSet bool      `json:"-"` // Whether a value was given, the value defaults to 0
+	Op       string    `json:"op,omitempty"`
+	TraceID  string    `json:"trace_id,omitempty"`
+	Output   string    `json:"output"`
+	Token    string    `json:"-"`
+	Conn     FlagsConn `json:"-"`
+
+	// KeepAlive sends the updates read from stdin over one client instead of the
+	// update of the flags
+	KeepAlive bool `json:"-"`
+}
+
+// PushResult is the outcome of a push, printed to stdout when the json output
+// format is selected.
+type PushResult struct {
+	Status     string  `json:"status"`
+	StatusCode int     `json:"status_code,omitempty"`
+	RequestID  string  `json:"request_id,omitempty"` // ID of the request in the server logs
+	Latency    float64 `json:"latency_seconds"`
+	Attempts   int     `json:"attempts"`
+	Response   string  `json:"response,omitempty"`
+	Error      string  `json:"error,omitempty"`
 }
 
 func Push(ctx context.Context, flags FlagsPush) error {
-	if !isValidMetricType(flags.Type) {
-		return fmt.Errorf("invalid metric type: %s", flags.Type)
+	switch flags.Output {
+	case "", OutputText, OutputJSON:
+	default:
+		return fmt.Errorf("invalid output format: %s (expected text or json)", flags.Output)
 	}
 
-	// Parse labels
-	labels := make(map[string]string)
-
-	for _, label := range flags.Labels {
-		key, val, ok := parseLabel(label)
-		if !ok {
-			return fmt.Errorf("invalid label format: %s (expected key=value)", label)
+	if flags.KeepAlive {
+		if flags.URL == "" {
+			return errors.New("a URL is required")
 		}
-		labels[key] = val
+		return pushKeepAlive(ctx, flags, os.Stdin)
+	}
+
+	result, err := push(ctx, flags)
+	if flags.Output != OutputJSON {
+		return err
+	}
+
+	if err != nil {
+		result.Status = "error"
+		result.Error = err.Error()
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	if encErr := enc.Encode(result); encErr != nil {
+		return fmt.Errorf("failed to write result: %w", encErr)
+	}
+
+	return err
+}
+
+func push(ctx context.Context, flags FlagsPush) (PushResult, error) {
+	if flags.Name == "" {
+		return PushResult{}, errors.New("a metric name is required")
+	}
+
+	if err := checkUpdate(flags.Type, flags.Op); err != nil {
+		return PushResult{}, err
+	}
+
+	op, err := collector.ParseGaugeOp(flags.Op)
+	if err != nil {
+		return PushResult{}, err
+	}
+
+	// Info and enum metrics only carry labels
+	needsValue := flags.Type != "info" && flags.Type != "enum" && (flags.Type != "gauge" || op.UsesValue())
+	if !flags.ValueSet && needsValue {
+		return PushResult{}, fmt.Errorf("a value is required for %s updates", flags.Type)
+	}
+
+	labels, err := parseLabels(flags.Labels)
+	if err != nil {
+		return PushResult{}, err
 	}
 
 	// Create metric update
@@ -42,14 +120,172 @@ func Push(ctx context.Context, flags FlagsPush) error {
 		Type:   flags.Type,
 		Value:  flags.Value,
 		Labels: labels,
+		Op:     flags.Op,
+	}
+
+	if flags.TraceID != "" {
+		update.Exemplar = &web.Exemplar{TraceID: flags.TraceID}
+	}
+
+	if flags.UDP != "" {
+		result, err := sendUDP(flags.UDP, update)
+		if err == nil && flags.Output != OutputJSON {
+			log.Info().
+				Str("metric", update.Name).
+				Str("addr", flags.UDP).
+				Msg("metric update sent over UDP")
+		}
+		return result, err
+	}
+
+	if flags.URL == "" {
+		return PushResult{}, errors.New("a URL is required")
 	}
 
 	// Send request
-	httpClient := &http.Client{
-		Timeout: 10 * time.Second,
+	httpClient, err := newHTTPClient(flags.Conn)
+	if err != nil {
+		return PushResult{}, err
+	}
+
+	result, err := sendMetricUpdate(ctx, httpClient, flags.URL, flags.Token, update)
+	if err != nil {
+		return result, err
+	}
+
+	if flags.Output != OutputJSON {
+		log.Info().
+			Str("metric", update.Name).
+			Str("type", update.Type).
+			Float64("value", update.Value).
+			Msg("metric update sent successfully")
+	}
+
+	return result, nil
+}
+
+// pushKeepAlive sends the updates read from in, one JSON MetricUpdate per line,
+// through a single client so that consecutive pushes reuse its connection. The
+// name, type, and labels of the flags are defaults for the updates. Failed updates
+// are reported and skipped, an error is returned once in is closed if any failed.
+func pushKeepAlive(ctx context.Context, flags FlagsPush, in io.Reader) error {
+	defaults, err := parseLabels(flags.Labels)
+	if err != nil {
+		return err
+	}
+
+	httpClient, err := newHTTPClient(flags.Conn)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+
+	var sent, failed int
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		sent++
+
+		result, err := pushLine(ctx, httpClient, flags, defaults, line)
+		if err != nil {
+			failed++
+			result.Status = "error"
+			result.Error = err.Error()
+		}
+
+		if flags.Output == OutputJSON {
+			if encErr := enc.Encode(result); encErr != nil {
+				return fmt.Errorf("failed to write result: %w", encErr)
+			}
+			continue
+		}
+
+		if err != nil {
+			log.Error().Err(err).Msg("failed to send metric update")
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read updates: %w", err)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d metric updates failed", failed, sent)
+	}
+	return nil
+}
+
+// pushLine sends a single update of the keepalive mode
+func pushLine(ctx context.Context, client *http.Client, flags FlagsPush, defaults map[string]string, line []byte) (PushResult, error) {
+	var update web.MetricUpdate
+	if err := json.Unmarshal(line, &update); err != nil {
+		return PushResult{}, fmt.Errorf("invalid update: %w", err)
+	}
+
+	if update.Name == "" {
+		update.Name = flags.Name
+	}
+	if update.Type == "" {
+		update.Type = flags.Type
+	}
+	if update.Name == "" {
+		return PushResult{}, errors.New("a metric name is required")
+	}
+	if err := checkUpdate(update.Type, update.Op); err != nil {
+		return PushResult{}, err
 	}
 
-	return sendMetricUpdate(ctx, httpClient, flags.URL, update)
+	if len(defaults) > 0 {
+		labels := maps.Clone(defaults)
+		maps.Copy(labels, update.Labels)
+		update.Labels = labels
+	}
+
+	result, err := sendMetricUpdate(ctx, client, flags.URL, flags.Token, update)
+	if err != nil {
+		return result, err
+	}
+
+	if flags.Output != OutputJSON {
+		log.Info().
+			Str("metric", update.Name).
+			Str("type", update.Type).
+			Float64("value", update.Value).
+			Msg("metric update sent successfully")
+	}
+
+	return result, nil
+}
+
+// checkUpdate validates the type and gauge operation of an update
+func checkUpdate(metricType, op string) error {
+	if !isValidMetricType(metricType) {
+		return fmt.Errorf("invalid metric type: %s", metricType)
+	}
+
+	if op != "" && metricType != "gauge" {
+		return fmt.Errorf("op is only supported for gauges, not %s", metricType)
+	}
+
+	return nil
+}
+
+// parseLabels parses labels in the format "key=value"
+func parseLabels(flags []string) (map[string]string, error) {
+	labels := make(map[string]string, len(flags))
+	for _, label := range flags {
+		key, val, ok := parseLabel(label)
+		if !ok {
+			return nil, fmt.Errorf("invalid label format: %s (expected key=value)", label)
+		}
+		labels[key] = val
+	}
+	return labels, nil
 }
 
 // isValidMetricType checks if the provided metric type is valid
@@ -59,6 +295,8 @@ func isValidMetricType(metricType string) bool {
 		"counter":   true,
 		"histogram": true,
 		"summary":   true,
+		"info":      true,
+		"enum":      true,
 	}
 	return validTypes[metricType]
 }
@@ -74,20 +312,25 @@ func parseLabel(label string) (string, string, bool) {
 }
 
 // sendMetricUpdate sends the metric update to the API
-func sendMetricUpdate(ctx context.Context, client *http.Client, url string, update web.MetricUpdate) error {
+func sendMetricUpdate(ctx context.Context, client *http.Client, url, token string, update web.MetricUpdate) (PushResult, error) {
+	result := PushResult{}
+
 	// Marshal the update to JSON
 	payload, err := json.Marshal(update)
 	if err != nil {
-		return fmt.Errorf("failed to marshal update: %w", err)
+		return result, fmt.Errorf("failed to marshal update: %w", err)
 	}
 
 	// Create request
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(payload))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return result, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
 
 	// Send request
 	log.Debug().
@@ -98,22 +341,71 @@ func sendMetricUpdate(ctx context.Context, client *http.Client, url string, upda
 		Interface("labels", update.Labels).
 		Msg("sending metric update")
 
+	start := time.Now()
+	result.Attempts++
+
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		result.Latency = time.Since(start).Seconds()
+		return result, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	result.Latency = time.Since(start).Seconds()
+	result.StatusCode = resp.StatusCode
+	result.Response = string(bytes.TrimSpace(body))
+	result.RequestID = resp.Header.Get("X-Request-ID")
+
 	// Check response
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		if result.RequestID != "" {
+			return result, fmt.Errorf("unexpected status code: %d (request id %s)", resp.StatusCode, result.RequestID)
+		}
+		return result, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	log.Info().
-		Str("metric", update.Name).
-		Str("type", update.Type).
-		Float64("value", update.Value).
-		Msg("metric update sent successfully")
+	result.Status = "success"
+	return result, nil
+}
 
-	return nil
+// sendUDP sends the update as a single packet of the UDP push protocol. Delivery
+// isn't confirmed, a successful result only means the packet was sent.
+func sendUDP(addr string, update web.MetricUpdate) (PushResult, error) {
+	result := PushResult{}
+
+	if update.Op != "" && update.Op != string(collector.GaugeOpSet) {
+		return result, fmt.Errorf("op %s is not supported over UDP", update.Op)
+	}
+	if update.Exemplar != nil {
+		return result, errors.New("exemplars are not supported over UDP")
+	}
+
+	packet, err := udp.Encode(udp.Packet{
+		Name:   update.Name,
+		Type:   config.MetricType(update.Type),
+		Value:  update.Value,
+		Labels: update.Labels,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	start := time.Now()
+	result.Attempts++
+
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return result, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write(packet); err != nil {
+		result.Latency = time.Since(start).Seconds()
+		return result, fmt.Errorf("failed to send packet: %w", err)
+	}
+
+	result.Latency = time.Since(start).Seconds()
+	result.Status = "sent"
+	return result, nil
 }
@@ -0,0 +1,175 @@
+package commands
+
+import (
+	"context"
+	"slices"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/hay-kot/cronprom/internal/services/collector"
+	"github.com/hay-kot/cronprom/internal/web"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	configReloadSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "cronprom_config_last_reload_successful",
+		Help: "Whether the last attempt to load the configuration was successful",
+	})
+	configReloadTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "cronprom_config_last_reload_success_timestamp_seconds",
+		Help: "Unix time of the last successful configuration load",
+	})
+)
+
+// configLoaded records a successful load of the configuration
+func configLoaded(health *web.Health, cfg *config.Config) {
+	health.ConfigLoaded(cfg.Hash())
+	configReloadSuccess.Set(1)
+	configReloadTimestamp.SetToCurrentTime()
+}
+
+// reloadableSections are the config sections applied on reload, changes of other
+// sections take effect after a restart
+var reloadableSections = []string{"global", "defaults", "jobs"}
+
+// configReloader applies reloaded configurations to the running server
+type configReloader struct {
+	flags   FlagsConfig
+	health  *web.Health
+	coll    *collector.MetricCollector
+	active  *config.Config
+	watcher *config.Watcher // Nil unless global.watch_config is set
+	changes chan struct{}   // Signaled by the watcher when the config files changed
+}
+
+// reload loads the configuration again and applies it, the active configuration is
+// kept if it fails to load
+func (r *configReloader) reload(ctx context.Context) {
+	log.Info().Str("path", r.flags.Path).Msg("reloading config")
+
+	next, _, err := loadConfig(ctx, r.flags)
+	if err != nil {
+		configReloadFailed(err)
+		return
+	}
+
+	r.apply(next)
+}
+
+// reloadChanged reloads the configuration after the watcher noticed a change of its
+// files, like reload, but changes that leave the configuration as it was, like a
+// touched file, are ignored
+func (r *configReloader) reloadChanged(ctx context.Context) {
+	next, _, err := loadConfig(ctx, r.flags)
+	if err != nil {
+		configReloadFailed(err)
+		return
+	}
+	if next.Hash() == r.active.Hash() {
+		log.Debug().Str("path", r.flags.Path).Msg("config files unchanged")
+		return
+	}
+
+	log.Info().Str("path", r.flags.Path).Msg("config files changed, reloading config")
+	r.apply(next)
+}
+
+// watch starts or stops watching the config files as global.watch_config of the
+// active configuration is set, and watches the files of the active configuration
+func (r *configReloader) watch() {
+	if !r.active.Global.WatchConfig || config.IsRemoteConfig(r.flags.Path) {
+		if r.watcher != nil {
+			_ = r.watcher.Close()
+			r.watcher = nil
+			log.Info().Str("path", r.flags.Path).Msg("stopped watching config files")
+		}
+		return
+	}
+
+	if r.watcher == nil {
+		watcher, err := config.NewWatcher(r.flags.Path)
+		if err != nil {
+			log.Error().Err(err).Msg("error watching config files")
+			return
+		}
+		r.watcher = watcher
+
+		go watcher.Run(func() {
+			select {
+			case r.changes <- struct{}{}:
+			default:
+				// A reload is pending already
+			}
+		})
+		log.Info().Str("path", r.flags.Path).Msg("watching config files")
+	}
+
+	if err := r.watcher.Watch(r.active.Files()); err != nil {
+		log.Error().Err(err).Msg("error watching config files")
+	}
+}
+
+// close stops watching the config files
+func (r *configReloader) close() {
+	if r.watcher != nil {
+		_ = r.watcher.Close()
+	}
+}
+
+// apply reconciles the collector with a loaded configuration. If it fails the
+// collector is rolled back and the active configuration is kept, so the next reload
+// is compared against the configuration that is actually applied.
+func (r *configReloader) apply(next *config.Config) {
+	diff := config.DiffConfig(r.active, next)
+	logConfigDiff(diff, next.Hash())
+
+	for _, field := range next.UnusedFields() {
+		log.Warn().Str("field", field).Msg("unknown config field ignored")
+	}
+
+	if err := r.coll.Reconcile(next); err != nil {
+		configReloadFailed(err)
+		if rerr := r.coll.Reconcile(r.active); rerr != nil {
+			log.Error().Err(rerr).Msg("error rolling back to the active config")
+		}
+		return
+	}
+	r.active = next
+	r.watch()
+	configLoaded(r.health, next)
+
+	for _, section := range diff.Sections {
+		if !slices.Contains(reloadableSections, section) {
+			log.Warn().Str("section", section).Msg("config section changed, restart to apply it")
+		}
+	}
+}
+
+// configReloadFailed records a failed reload
+func configReloadFailed(err error) {
+	configReloadSuccess.Set(0)
+	log.Error().Err(err).Msg("error reloading config")
+}
+
+// logConfigDiff logs the changes of a reloaded configuration
+func logConfigDiff(diff config.Diff, hash string) {
+	if diff.Empty() {
+		log.Info().Str("hash", hash).Msg("config unchanged")
+		return
+	}
+
+	changed := zerolog.Dict()
+	for _, name := range diff.ChangedMetrics() {
+		changed.Strs(name, diff.Changed[name])
+	}
+
+	log.Info().
+		Str("hash", hash).
+		Strs("metrics_added", diff.Added).
+		Strs("metrics_removed", diff.Removed).
+		Dict("metrics_changed", changed).
+		Strs("sections_changed", diff.Sections).
+		Msg("config changed")
+}
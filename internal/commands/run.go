@@ -0,0 +1,298 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hay-kot/cronprom/internal/procgroup"
+	"github.com/hay-kot/cronprom/internal/services/collector"
+	"github.com/hay-kot/cronprom/internal/web"
+	"github.com/rs/zerolog/log"
+)
+
+// ExitTimeout is the exit code of run when the command was killed at its timeout,
+// the same as timeout(1)
+const ExitTimeout = 124
+
+// ExitSkipped is the exit code of run when another run held the lock of the job,
+// EX_TEMPFAIL of sysexits.h
+const ExitSkipped = 75
+
+// killGrace is how long the command has to exit after SIGTERM at its timeout,
+// its process group is killed afterwards
+const killGrace = 10 * time.Second
+
+type FlagsRun struct {
+	URL      string        // Base URL of the server, e.g., http://localhost:8080
+	Token    string        // Bearer token of a key allowed to push to the metrics of the job
+	Job      string        // Name of the job the run is reported for
+	Timeout  time.Duration // Kills the command after this long, 0 lets it run until it exits
+	Lock     bool          // Skip the run while another run of the job holds its lock
+	LockWait time.Duration // Wait this long for the lock before skipping the run
+	LockFile string        // File locked when the server can't be reached
+	Output   int           // Bytes of the end of the output sent with the finish, 0 disables the capture
+	Command  []string      // Command and its arguments
+	Conn     FlagsConn
+}
+
+// Run runs a command as a run of a job, reporting its start and finish to the
+// server. The command runs even if the server can't be reached. It returns the exit
+// code of the command, ExitTimeout if the command was killed at its timeout, or
+// ExitSkipped if the run was skipped because another run held the lock.
+func Run(ctx context.Context, flags FlagsRun) (int, error) {
+	if flags.Job == "" {
+		return 0, errors.New("a job name is required")
+	}
+	if len(flags.Command) == 0 {
+		return 0, errors.New("a command is required")
+	}
+	if flags.Timeout < 0 || flags.LockWait < 0 {
+		return 0, errors.New("timeout and lock wait cannot be negative")
+	}
+
+	client, err := newHTTPClient(flags.Conn)
+	if err != nil {
+		return 0, err
+	}
+
+	// The run ID is chosen here so the finish matches the run even if the start
+	// didn't reach the server
+	runID := newRunID()
+	if flags.Lock {
+		unlock, err := lockJob(ctx, client, flags, runID)
+		if errors.Is(err, errLocked) {
+			log.Warn().Str("job", flags.Job).Msg("another run holds the lock of the job, skipping run")
+			skip := web.JobFinishRequest{RunID: runID, Status: collector.RunSkipped}
+			if err := jobRequest(ctx, client, flags, http.MethodPost, "finish", skip, nil); err != nil {
+				log.Warn().Err(err).Str("job", flags.Job).Msg("failed to report the skipped run")
+			}
+			return ExitSkipped, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		defer unlock()
+	}
+
+	if err := jobRequest(ctx, client, flags, http.MethodPost, "start", web.JobStartRequest{RunID: runID}, nil); err != nil {
+		log.Warn().Err(err).Str("job", flags.Job).Msg("failed to report the start of the run")
+	}
+
+	var output *outputTail
+	if flags.Output > 0 {
+		output = &outputTail{size: flags.Output}
+	}
+
+	exitCode, status, runErr := runCommand(ctx, flags, output)
+
+	finish := web.JobFinishRequest{RunID: runID, Status: status}
+	if output != nil {
+		finish.Output = output.String()
+	}
+	if exitCode >= 0 {
+		finish.ExitCode = &exitCode
+	}
+
+	var run collector.JobRun
+	if err := jobRequest(ctx, client, flags, http.MethodPost, "finish", finish, &run); err != nil {
+		log.Warn().Err(err).Str("job", flags.Job).Msg("failed to report the finish of the run")
+	}
+
+	event := log.Debug()
+	if status != collector.RunSuccess {
+		event = log.Warn()
+	}
+	event.
+		Str("job", flags.Job).
+		Str("run_id", runID).
+		Str("result", status).
+		Int("exit_code", exitCode).
+		Interface("duration_seconds", run.Duration).
+		Msg("run finished")
+
+	switch {
+	case runErr != nil:
+		return 0, runErr
+	case status == collector.RunTimeout:
+		return ExitTimeout, nil
+	case exitCode < 0:
+		// Killed by a signal
+		return 1, nil
+	default:
+		return exitCode, nil
+	}
+}
+
+// runCommand runs the command in its own process group with the standard streams
+// of cronprom, the output is also written to the tail unless it is nil. At the
+// timeout the group gets SIGTERM and is killed if the command doesn't exit within
+// killGrace. Interrupts of cronprom are forwarded to the group. The exit code is
+// -1 when the command didn't start or was killed by a signal.
+func runCommand(ctx context.Context, flags FlagsRun, output *outputTail) (int, string, error) {
+	if flags.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, flags.Timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, flags.Command[0], flags.Command[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if output != nil {
+		cmd.Stdout = io.MultiWriter(os.Stdout, output)
+		cmd.Stderr = io.MultiWriter(os.Stderr, output)
+	}
+	cmd.WaitDelay = killGrace
+	cmd.Cancel = func() error {
+		return procgroup.Signal(cmd.Process, syscall.SIGTERM)
+	}
+	procgroup.Set(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return -1, collector.RunFailure, fmt.Errorf("failed to start command: %w", err)
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-sigCh:
+				_ = procgroup.Signal(cmd.Process, sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err := cmd.Wait()
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		// Children left behind by the command would keep running past the timeout
+		_ = procgroup.Signal(cmd.Process, syscall.SIGKILL)
+		log.Warn().Str("job", flags.Job).Dur("timeout", flags.Timeout).Msg("command timed out, killed its process group")
+		return -1, collector.RunTimeout, nil
+	}
+
+	exitCode := cmd.ProcessState.ExitCode()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return exitCode, collector.RunFailure, err
+	}
+
+	status := collector.RunSuccess
+	if exitCode != 0 {
+		status = collector.RunFailure
+	}
+	return exitCode, status, nil
+}
+
+// outputTail keeps the last bytes of the output of the command, the command writes
+// stdout and stderr to it concurrently
+type outputTail struct {
+	mu   sync.Mutex
+	size int
+	buf  []byte
+}
+
+func (t *outputTail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.size {
+		t.buf = t.buf[len(t.buf)-t.size:]
+	}
+	return len(p), nil
+}
+
+func (t *outputTail) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.buf)
+}
+
+// jobAPIError is an error response of the jobs API
+type jobAPIError struct {
+	status int
+	body   string
+}
+
+func (e *jobAPIError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d: %s", e.status, e.body)
+}
+
+// jobRequest sends a request to an endpoint of the job and decodes the response
+// into out, which may be nil. Error responses are returned as a *jobAPIError.
+func jobRequest(ctx context.Context, client *http.Client, flags FlagsRun, method, action string, payload, out any) error {
+	if flags.URL == "" {
+		return errors.New("a URL is required")
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	endpoint := strings.TrimSuffix(flags.URL, "/") + "/api/v1/jobs/" + url.PathEscape(flags.Job) + "/" + action
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if flags.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+flags.Token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &jobAPIError{status: resp.StatusCode, body: string(bytes.TrimSpace(respBody))}
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// newRunID generates a random 64 bit run ID
+func newRunID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
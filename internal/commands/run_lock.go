@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hay-kot/cronprom/internal/web"
+	"github.com/rs/zerolog/log"
+)
+
+// lockTTL is the lease of the lock of the server, the wrapper renews it at a
+// third of the lease while the command runs
+const lockTTL = time.Minute
+
+// lockPollInterval is how often a run waiting for the lock of its job tries again
+const lockPollInterval = 5 * time.Second
+
+// errLocked is returned when another run holds the lock of the job
+var errLocked = errors.New("job is locked by another run")
+
+// lockJob locks the job for the run, waiting up to the lock wait for another run
+// to release it. The lock is a lease of the server, renewed while the command
+// runs. If the server can't be reached or fails with a server error, the lock
+// falls back to a file lock on this host. It returns the function releasing
+// the lock, or errLocked once the wait is over.
+func lockJob(ctx context.Context, client *http.Client, flags FlagsRun, holder string) (func(), error) {
+	deadline := time.Now().Add(flags.LockWait)
+	for {
+		unlock, err := tryLock(ctx, client, flags, holder)
+		if !errors.Is(err, errLocked) || !time.Now().Before(deadline) {
+			return unlock, err
+		}
+
+		log.Debug().Str("job", flags.Job).Msg("job is locked, waiting for the lock")
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(min(lockPollInterval, time.Until(deadline))):
+		}
+	}
+}
+
+// tryLock acquires the lock of the server once, or the file lock if the server
+// can't be reached or failed with a server error
+func tryLock(ctx context.Context, client *http.Client, flags FlagsRun, holder string) (func(), error) {
+	req := web.JobLockRequest{Holder: holder, TTL: lockTTL.Seconds()}
+
+	err := jobRequest(ctx, client, flags, http.MethodPost, "lock", req, nil)
+	if err == nil {
+		return renewLock(ctx, client, flags, req), nil
+	}
+
+	// The file lock is only for a server that can't be reached, a server refusing
+	// the lock won't grant it on the next run either
+	var apiErr *jobAPIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.status == http.StatusConflict:
+			return nil, errLocked
+		case apiErr.status < http.StatusInternalServerError:
+			return nil, fmt.Errorf("error locking the job: %w", err)
+		}
+	}
+
+	path := flags.LockFile
+	if path == "" {
+		// Job names are free text, the escaping keeps the file in the temp directory
+		path = filepath.Join(os.TempDir(), "cronprom-"+url.PathEscape(flags.Job)+".lock")
+	}
+	log.Warn().Err(err).Str("job", flags.Job).Str("file", path).Msg("failed to lock the job on the server, locking a file instead")
+
+	return lockFile(path)
+}
+
+// renewLock renews the lock of the server until the returned function is called,
+// which releases the lock
+func renewLock(ctx context.Context, client *http.Client, flags FlagsRun, req web.JobLockRequest) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(lockTTL / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := jobRequest(ctx, client, flags, http.MethodPost, "lock", req, nil); err != nil && ctx.Err() == nil {
+					log.Warn().Err(err).Str("job", flags.Job).Msg("failed to renew the lock of the job")
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+
+		// The context of the run may be canceled already
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		if err := jobRequest(ctx, client, flags, http.MethodDelete, "lock", req, nil); err != nil {
+			log.Warn().Err(err).Str("job", flags.Job).Msg("failed to release the lock of the job")
+		}
+	}
+}
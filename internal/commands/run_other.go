@@ -0,0 +1,10 @@
+//go:build !unix
+
+package commands
+
+import "errors"
+
+// lockFile fails, file locks are only supported on unix
+func lockFile(string) (func(), error) {
+	return nil, errors.New("file locks are not supported on this platform")
+}
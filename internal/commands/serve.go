@@ -4,30 +4,61 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/hay-kot/cronprom/internal/data/config"
 	"github.com/hay-kot/cronprom/internal/services/collector"
+	"github.com/hay-kot/cronprom/internal/services/execcollector"
+	"github.com/hay-kot/cronprom/internal/services/graphite"
+	"github.com/hay-kot/cronprom/internal/services/notifier"
+	"github.com/hay-kot/cronprom/internal/services/remotewrite"
+	"github.com/hay-kot/cronprom/internal/services/scheduler"
+	"github.com/hay-kot/cronprom/internal/services/textfile"
+	"github.com/hay-kot/cronprom/internal/services/udp"
 	"github.com/hay-kot/cronprom/internal/web"
+	"github.com/hay-kot/cronprom/pkg/validate"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
 )
 
 type FlagsServe struct {
-	ConfigFile string
-	Version    string
-	Commit     string
-	Date       string
+	Config        FlagsConfig
+	ConfigRefresh time.Duration // How often a config from a URL is fetched again
+	Version       string
+	Commit        string
+	Date          string
+
+	// Settings overriding the config, empty settings are taken from the config
+	Address         string
+	InternalAddress string
+	Namespace       string
+	OverlayFile     string
+	StateFile       string
 }
 
 func Serve(ctx context.Context, flags FlagsServe) error {
-	cfg, err := config.LoadConfig(flags.ConfigFile)
+	flags.Config.Overrides = config.Overrides{
+		Address:         flags.Address,
+		InternalAddress: flags.InternalAddress,
+		Namespace:       flags.Namespace,
+		OverlayFile:     flags.OverlayFile,
+		StateFile:       flags.StateFile,
+	}
+
+	cfg, remote, err := loadConfig(ctx, flags.Config)
 	if err != nil {
-		return fmt.Errorf("error loading configuration: %w", err)
+		return err
+	}
+
+	for _, field := range cfg.UnusedFields() {
+		log.Warn().Str("field", field).Msg("unknown config field ignored")
 	}
 
 	registry := prometheus.NewRegistry()
@@ -37,37 +68,393 @@ func Serve(ctx context.Context, flags FlagsServe) error {
 		return fmt.Errorf("error initializing metric collector: %w", err)
 	}
 
-	metricHandler := web.NewMetricHandler(coll)
+	validators := validate.Chain(validate.Registered())
+	for _, v := range cfg.Validators {
+		validators = append(validators, &validate.Exec{
+			Name:    v.Name,
+			Command: v.Command,
+			Args:    v.Args,
+			Timeout: v.Timeout,
+		})
+	}
 
-	registry.MustRegister(buildInfo)
+	var overlay *config.Overlay
+	if cfg.Global.OverlayFile != "" {
+		overlay, err = config.OpenOverlay(cfg.Global.OverlayFile)
+		if err != nil {
+			return fmt.Errorf("error opening overlay file: %w", err)
+		}
+	}
+
+	if cfg.Global.StateFile != "" {
+		if err := coll.LoadState(cfg.Global.StateFile); err != nil {
+			return err
+		}
+	}
+
+	metricHandler := web.NewMetricHandler(coll, validators, overlay)
+	if cfg.Web.Queue.Enabled() {
+		metricHandler.EnableQueue(cfg.Web.Queue)
+	}
+
+	registry.MustRegister(buildInfo, configReloadSuccess, configReloadTimestamp)
+	web.RegisterMetrics(registry)
+	remotewrite.RegisterMetrics(registry)
+	notifier.RegisterMetrics(registry)
+	execcollector.RegisterMetrics(registry)
 
 	buildInfo.WithLabelValues(flags.Version, flags.Commit, flags.Date).Set(1)
 
 	// Set up HTTP routes
-	http.HandleFunc("/api/v1/push", metricHandler.PushHandler)
-	http.Handle("/metrics", promhttp.HandlerFor(coll.GetRegistry(), promhttp.HandlerOpts{}))
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte(`{"status":"ok"}`))
-	})
+	keys, err := cfg.Auth.LoadKeys()
+	if err != nil {
+		return fmt.Errorf("error loading auth tokens: %w", err)
+	}
+
+	if len(keys) == 0 {
+		log.Warn().Msg("no auth tokens configured, the push API is unauthenticated")
+	}
+
+	creds := web.Credentials{PushKeys: keys}
+	if cfg.Web.MetricsAuth.Enabled() {
+		creds.MetricsUsername = cfg.Web.MetricsAuth.Username
+		creds.MetricsPassword, err = cfg.Web.MetricsAuth.LoadPassword()
+		if err != nil {
+			return fmt.Errorf("error loading metrics auth password: %w", err)
+		}
+	}
+
+	health := web.NewHealth(flags.Version, flags.Commit, func() int { return len(coll.Metrics()) })
+	configLoaded(health, cfg)
+	if overlay != nil {
+		health.AddCheck("overlay", func(context.Context) error { return overlay.Check() })
+	}
+
+	router, internalRouter := web.Routes(cfg.Web, creds, metricHandler, health, web.MetricsHandler(cfg.Web, coll.Gatherer()))
+
+	server := newHTTPServer(cfg.Web, router)
+	server.RegisterOnShutdown(metricHandler.CloseStreams)
+
+	if cfg.Web.TLS.Enabled() {
+		server.TLSConfig, err = web.TLSConfig(cfg.Web.TLS)
+		if err != nil {
+			return fmt.Errorf("error configuring TLS: %w", err)
+		}
+	}
+
+	// Everything that can fail is set up before the first server starts, a failure
+	// after it goes through the shutdown below
+	var exporters []*remotewrite.Exporter
+	for _, rw := range cfg.RemoteWrite {
+		exporter, err := remotewrite.New(rw, coll.Gatherer())
+		if err != nil {
+			return err
+		}
+		exporters = append(exporters, exporter)
+	}
+
+	var notifiers []*notifier.Notifier
+	for _, n := range cfg.Notifiers {
+		if err := n.LoadSecrets(); err != nil {
+			return fmt.Errorf("notifier '%s': %w", n.Name, err)
+		}
+		notifiers = append(notifiers, notifier.New(n, coll))
+	}
+
+	var sched *scheduler.Scheduler
+	if len(cfg.Schedules) > 0 {
+		sched, err = scheduler.New(cfg.Schedules, coll)
+		if err != nil {
+			return err
+		}
+	}
+
+	var grpcServer *grpc.Server
+	if cfg.GRPC.Enabled() {
+		grpcServer, err = web.NewGRPCServer(cfg.Web, keys, metricHandler)
+		if err != nil {
+			return fmt.Errorf("error configuring gRPC server: %w", err)
+		}
+	}
+
+	// The internal listener serves plain HTTP, it is meant to be bound to a private
+	// interface only reachable by Prometheus and operators
+	var internalServer *http.Server
+	if internalRouter != router {
+		internalCfg := cfg.Web
+		internalCfg.Address = cfg.Web.InternalAddress
+
+		internalServer = newHTTPServer(internalCfg, internalRouter)
+		internalServer.RegisterOnShutdown(metricHandler.CloseStreams)
+	}
+
+	// Bind the listeners, the bound ones are closed if a later one fails
+	ln, err := web.Listen(cfg.Web)
+	if err != nil {
+		return fmt.Errorf("failed to start HTTP server: %w", err)
+	}
+
+	var internalLn net.Listener
+	if internalServer != nil {
+		internalCfg := cfg.Web
+		internalCfg.Address = cfg.Web.InternalAddress
+
+		internalLn, err = web.Listen(internalCfg)
+		if err != nil {
+			_ = ln.Close()
+			return fmt.Errorf("failed to start internal HTTP server: %w", err)
+		}
+	}
+
+	var grpcLn net.Listener
+	if grpcServer != nil {
+		grpcLn, err = net.Listen("tcp", cfg.GRPC.Address)
+		if err != nil {
+			_ = ln.Close()
+			if internalLn != nil {
+				_ = internalLn.Close()
+			}
+			return fmt.Errorf("failed to start gRPC server: %w", err)
+		}
+	}
+
+	// Every server and listener sends at most one error, none of them blocks
+	errCh := make(chan error, 5)
 
 	// Start HTTP server
 	go func() {
-		log.Info().Str("addr", cfg.Web.Address).Msg("starting HTTP server")
-		if err := http.ListenAndServe(cfg.Web.Address, nil); err != nil {
-			if errors.Is(err, http.ErrServerClosed) {
-				return
-			}
-			log.Fatal().Err(err).Msg("failed to start HTTP server")
+		log.Info().Str("addr", cfg.Web.Address).Bool("tls", cfg.Web.TLS.Enabled()).Msg("starting HTTP server")
+
+		var err error
+		if cfg.Web.TLS.Enabled() {
+			// Certificates are already loaded into the TLS config
+			err = server.ServeTLS(ln, "", "")
+		} else {
+			err = server.Serve(ln)
+		}
+
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- fmt.Errorf("failed to start HTTP server: %w", err)
 		}
 	}()
 
-	// Wait for termination signal
+	if internalServer != nil {
+		go func() {
+			log.Info().Str("addr", internalServer.Addr).Msg("starting internal HTTP server")
+			if err := internalServer.Serve(internalLn); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errCh <- fmt.Errorf("failed to start internal HTTP server: %w", err)
+			}
+		}()
+	}
+
+	// Start ingestion listeners, they stop when Serve returns. The Graphite and UDP
+	// listeners are waited for before the final checkpoint.
+	listenCtx, stopListeners := context.WithCancel(ctx)
+	defer stopListeners()
+
+	var listeners sync.WaitGroup
+
+	go coll.RunExpiry(listenCtx)
+	go metricHandler.RunQueue(listenCtx)
+
+	if cfg.Global.StateFile != "" {
+		go coll.RunCheckpoint(listenCtx, cfg.Global.StateFile, cfg.Global.StateInterval)
+	}
+
+	if cfg.Graphite.Enabled() {
+		if len(keys) > 0 && len(cfg.Web.AllowedNetworks()) == 0 {
+			log.Warn().Msg("the Graphite listener doesn't authenticate lines, restrict it with web.allow_cidrs")
+		}
+
+		listeners.Add(1)
+		go func() {
+			defer listeners.Done()
+			if err := graphite.New(cfg.Graphite, cfg.Web.AllowedNetworks(), coll, metricHandler).ListenAndServe(listenCtx); err != nil {
+				errCh <- err
+			}
+		}()
+	}
+
+	if cfg.UDP.Enabled() {
+		if len(keys) > 0 && len(cfg.Web.AllowedNetworks()) == 0 {
+			log.Warn().Msg("the UDP listener doesn't authenticate packets, restrict it with web.allow_cidrs")
+		}
+
+		listeners.Add(1)
+		go func() {
+			defer listeners.Done()
+			if err := udp.New(cfg.UDP, cfg.Web.AllowedNetworks(), metricHandler).ListenAndServe(listenCtx); err != nil {
+				errCh <- err
+			}
+		}()
+	}
+
+	for _, exporter := range exporters {
+		go exporter.Run(listenCtx)
+	}
+
+	for _, n := range notifiers {
+		go n.Run(listenCtx)
+	}
+
+	if sched != nil {
+		go sched.Run(listenCtx)
+	}
+
+	if cfg.Textfile.Enabled() {
+		coll.AddGatherer(textfile.New(cfg.Textfile.Directory))
+	}
+
+	for _, e := range cfg.ExecCollectors {
+		ec := execcollector.New(e)
+		coll.AddGatherer(ec)
+		go ec.Run(listenCtx)
+	}
+
+	reloader := &configReloader{
+		flags:   flags.Config,
+		health:  health,
+		coll:    coll,
+		active:  cfg,
+		changes: make(chan struct{}, 1),
+	}
+	if remote != nil && cfg.Global.WatchConfig {
+		log.Warn().Msg("global.watch_config has no effect on a remote config, use --config-refresh")
+	}
+	reloader.watch()
+	defer reloader.close()
+
+	// Changed remote configs are applied by the signal loop below
+	remoteChanges := make(chan *config.Config)
+	if remote != nil && flags.ConfigRefresh > 0 {
+		go remote.Poll(listenCtx, flags.ConfigRefresh, func(changed *config.Config) {
+			select {
+			case remoteChanges <- changed:
+			case <-listenCtx.Done():
+			}
+		})
+	}
+
+	if grpcServer != nil {
+		go func() {
+			log.Info().Str("addr", cfg.GRPC.Address).Msg("starting gRPC server")
+			if err := grpcServer.Serve(grpcLn); err != nil {
+				errCh <- fmt.Errorf("gRPC server: %w", err)
+			}
+		}()
+	}
+
+	// The configuration is loaded and the listeners are bound
+	health.SetReady(true)
+
+	// Reload the config on SIGHUP and wait for a termination signal
 	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-	sig := <-sigCh
-	log.Info().Msgf("Received signal %v, shutting down", sig)
-	return nil
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigCh)
+
+	// A failing server stops serve like a signal, the state is still saved
+	var serveErr error
+
+wait:
+	for {
+		select {
+		case serveErr = <-errCh:
+			log.Error().Err(serveErr).Msg("server failed, shutting down")
+			break wait
+		case <-ctx.Done():
+			log.Info().Msg("context canceled, shutting down")
+			break wait
+		case <-reloader.changes:
+			reloader.reloadChanged(ctx)
+		case changed := <-remoteChanges:
+			log.Info().Str("url", flags.Config.Path).Msg("remote config changed")
+			reloader.apply(changed)
+		case sig := <-sigCh:
+			if sig == syscall.SIGHUP {
+				reloader.reload(ctx)
+				continue
+			}
+			log.Info().Msgf("Received signal %v, shutting down", sig)
+			break wait
+		}
+	}
+
+	// Give load balancers time to notice the failing readiness probe
+	health.SetReady(false)
+	if serveErr == nil && cfg.Web.ShutdownDelay > 0 {
+		log.Info().Dur("delay", cfg.Web.ShutdownDelay).Msg("draining before shutdown")
+		time.Sleep(cfg.Web.ShutdownDelay)
+	}
+
+	// Stop every ingress and wait for in-flight pushes to complete, updates applied
+	// after the final checkpoint would be lost on restart. A shutdown timeout of 0
+	// waits for them without a deadline.
+	shutdownCtx, cancel := context.WithCancel(context.Background())
+	if cfg.Web.ShutdownTimeout > 0 {
+		shutdownCtx, cancel = context.WithTimeout(context.Background(), cfg.Web.ShutdownTimeout)
+	}
+	defer cancel()
+
+	errs := []error{serveErr}
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to gracefully shutdown HTTP server: %w", err))
+	}
+
+	if internalServer != nil {
+		if err := internalServer.Shutdown(shutdownCtx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to gracefully shutdown internal HTTP server: %w", err))
+		}
+	}
+
+	if grpcServer != nil {
+		stopGRPC(shutdownCtx, grpcServer)
+	}
+
+	stopListeners()
+	listeners.Wait()
+
+	log.Info().Msg("servers stopped")
+
+	// Apply the updates queued by the last pushes
+	metricHandler.FlushQueue()
+
+	// In-flight pushes are complete, checkpoint the final values
+	if cfg.Global.StateFile != "" {
+		if err := coll.SaveState(cfg.Global.StateFile); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// stopGRPC stops the gRPC server once in-flight calls complete, calls still
+// running when the context is done are canceled
+func stopGRPC(ctx context.Context, server *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		server.Stop()
+		<-stopped
+	}
+}
+
+// newHTTPServer creates an HTTP server with the timeouts of the web configuration
+func newHTTPServer(cfg config.Web, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              cfg.Address,
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
 }
 
 // buildInfo mostly exists to ensure the /metrics doesn't 404 when you start the application
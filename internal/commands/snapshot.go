@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+type FlagsSnapshot struct {
+	URL   string // Base URL of the server, e.g., http://localhost:8080
+	Token string
+	File  string // Snapshot file, - for stdout or stdin
+	Conn  FlagsConn
+}
+
+// SnapshotSave downloads the state of all series from the admin API and writes it
+// to the snapshot file
+func SnapshotSave(ctx context.Context, flags FlagsSnapshot) error {
+	body, err := snapshotRequest(ctx, flags, "/api/v1/admin/snapshot", nil)
+	if err != nil {
+		return err
+	}
+
+	if flags.File == "" || flags.File == "-" {
+		_, err := os.Stdout.Write(body)
+		return err
+	}
+
+	if err := os.WriteFile(flags.File, body, 0o600); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	log.Info().Str("file", flags.File).Msg("snapshot saved")
+	return nil
+}
+
+// SnapshotRestore uploads a snapshot file to the admin API
+func SnapshotRestore(ctx context.Context, flags FlagsSnapshot) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	if flags.File == "" || flags.File == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(flags.File)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	body, err := snapshotRequest(ctx, flags, "/api/v1/admin/restore", data)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(bytes.TrimSpace(body)))
+	return nil
+}
+
+// snapshotRequest sends a POST request to an admin endpoint and returns the body
+// of a successful response
+func snapshotRequest(ctx context.Context, flags FlagsSnapshot, path string, payload []byte) ([]byte, error) {
+	client, err := newHTTPClient(flags.Conn)
+	if err != nil {
+		return nil, err
+	}
+	// Snapshots of large collectors take longer than a single push
+	client.Timeout = time.Minute
+
+	url := strings.TrimSuffix(flags.URL, "/") + path
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if flags.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+flags.Token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+
+	return body, nil
+}
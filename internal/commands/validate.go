@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/hay-kot/cronprom/internal/services/collector"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Validate loads and validates the configuration like serve does and prints a
+// summary. Unknown fields are reported as warnings, metrics that can't be
+// registered together fail the validation.
+func Validate(ctx context.Context, flags FlagsConfig) error {
+	cfg, _, err := loadConfig(ctx, flags)
+	if err != nil {
+		return err
+	}
+
+	byType := make(map[string]int)
+	for _, m := range cfg.Metrics {
+		byType[m.Type.String()]++
+	}
+
+	counts := make([]string, 0, len(byType))
+	for _, t := range slices.Sorted(maps.Keys(byType)) {
+		counts = append(counts, fmt.Sprintf("%d %s", byType[t], t))
+	}
+
+	fmt.Printf("config:  %s\n", flags.Path)
+	fmt.Printf("hash:    %s\n", cfg.Hash())
+	fmt.Printf("metrics: %d", len(cfg.Metrics))
+	if len(counts) > 0 {
+		fmt.Printf(" (%s)", strings.Join(counts, ", "))
+	}
+	fmt.Println()
+	if len(cfg.Jobs) > 0 {
+		fmt.Printf("jobs:    %d\n", len(cfg.Jobs))
+	}
+	if len(cfg.Schedules) > 0 {
+		fmt.Printf("schedules: %d\n", len(cfg.Schedules))
+	}
+
+	for _, field := range cfg.UnusedFields() {
+		fmt.Printf("warning: unknown field ignored, %s\n", field)
+	}
+
+	var problems int
+	duplicates := cfg.DuplicateNames()
+	for _, name := range slices.Sorted(maps.Keys(duplicates)) {
+		fmt.Printf("error: metrics %s are all exposed as '%s'\n", strings.Join(duplicates[name], ", "), name)
+		problems++
+	}
+
+	// Registering the metrics catches conflicts of companion metrics too
+	if len(duplicates) == 0 {
+		if _, err := collector.NewMetricCollector(cfg, prometheus.NewRegistry()); err != nil {
+			fmt.Printf("error: %s\n", err)
+			problems++
+		}
+	}
+
+	if problems > 0 {
+		return fmt.Errorf("configuration is invalid, found %d problem(s)", problems)
+	}
+
+	fmt.Println("configuration is valid")
+	return nil
+}
@@ -3,28 +3,493 @@ package config
 
 import (
 	"fmt"
-	"os"
+	"io/fs"
+	"net/netip"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/rs/zerolog"
 	"gopkg.in/yaml.v3"
 )
 
 // Config represents the root configuration structure
 type Config struct {
-	Global  GlobalConfig   `yaml:"global"`
-	Metrics []MetricConfig `yaml:"metrics"`
-	Web     Web            `yaml:"web"`
+	Global      GlobalConfig      `yaml:"global"`
+	Metrics     []MetricConfig    `yaml:"metrics"`
+	Defaults    MetricDefaults    `yaml:"defaults"` // Applied to the metrics of the config files
+	Web         Web               `yaml:"web"`
+	Validators  []ValidatorConfig `yaml:"validators"`
+	Auth        Auth              `yaml:"auth"`
+	Graphite    Graphite          `yaml:"graphite"`
+	UDP         UDP               `yaml:"udp"`
+	GRPC        GRPC              `yaml:"grpc"`
+	Textfile    Textfile          `yaml:"textfile"`
+	RemoteWrite []RemoteWrite     `yaml:"remote_write"`
+	Notifiers   []Notifier        `yaml:"notifiers"`
+	Jobs        []Job             `yaml:"jobs"`      // Metrics grouped by cron job
+	Schedules   []Schedule        `yaml:"schedules"` // Commands run by serve on a cron schedule
+
+	// ExecCollectors expose the output of commands as metrics
+	ExecCollectors []ExecCollector `yaml:"exec_collectors"`
+
+	// MetricTemplates expand into one metric per instance
+	MetricTemplates []MetricTemplate `yaml:"metric_templates"`
+
+	hash   string   // SHA-256 of the config files
+	unused []string // Keys that don't match a field
+	files  []string // Files read, empty for a remote config
+}
+
+// Hash returns the SHA-256 of the config file the configuration was loaded from
+func (c *Config) Hash() string {
+	return c.hash
+}
+
+// Files returns the config files the configuration was loaded from, including the
+// included files, or nothing for a remote config
+func (c *Config) Files() []string {
+	return c.files
+}
+
+// UnusedFields returns the keys of the config files that don't match a field and
+// were ignored, likely typos, like "line 12: metrics[1].descripton"
+func (c *Config) UnusedFields() []string {
+	return c.unused
 }
 
 type Web struct {
-	Address string `yaml:"address"`
+	Address            string        `yaml:"address"`             // host:port, or unix:///path/to.sock for a unix domain socket
+	InternalAddress    string        `yaml:"internal_address"`    // Serves the exposition, health, and admin endpoints separately from the push API
+	SocketMode         string        `yaml:"socket_mode"`         // Octal permissions of the unix socket, e.g., 0660
+	SocketUser         string        `yaml:"socket_user"`         // Owner of the unix socket, name or uid
+	SocketGroup        string        `yaml:"socket_group"`        // Group of the unix socket, name or gid
+	ShutdownTimeout    time.Duration `yaml:"shutdown_timeout"`    // Time to wait for in-flight requests on shutdown, 0 waits without a deadline
+	ShutdownDelay      time.Duration `yaml:"shutdown_delay"`      // Time /readyz reports 503 before the listeners are closed on shutdown
+	ReadTimeout        time.Duration `yaml:"read_timeout"`        // Maximum duration for reading an entire request
+	ReadHeaderTimeout  time.Duration `yaml:"read_header_timeout"` // Maximum duration for reading request headers
+	WriteTimeout       time.Duration `yaml:"write_timeout"`       // Maximum duration before timing out writes of a response
+	IdleTimeout        time.Duration `yaml:"idle_timeout"`        // Maximum time to wait for the next request on keep-alive connections
+	MaxBodySize        int64         `yaml:"max_body_size"`       // Maximum size of a push request body in bytes, 0 disables the limit
+	TLS                TLS           `yaml:"tls"`
+	MetricsAuth        MetricsAuth   `yaml:"metrics_auth"`
+	MetricsCompression []string      `yaml:"metrics_compression"` // Encodings offered on /metrics: gzip, zstd, identity
+
+	// Backpressure and error handling of /metrics, for registries scraped by
+	// several Prometheus servers
+	MetricsMaxRequestsInFlight int                  `yaml:"metrics_max_requests_in_flight"` // Concurrent scrapes, further scrapes get 503, 0 is unlimited
+	MetricsTimeout             time.Duration        `yaml:"metrics_timeout"`                // Scrapes taking longer get 503, 0 disables the timeout
+	MetricsErrorHandling       MetricsErrorHandling `yaml:"metrics_error_handling"`         // http_error (default), continue, or panic
+	MetricsOpenMetrics         *bool                `yaml:"metrics_open_metrics"`           // Negotiate the OpenMetrics format, defaults to true
+
+	CORS           CORS        `yaml:"cors"`
+	SourceLabel    SourceLabel `yaml:"source_label"`
+	Queue          Queue       `yaml:"queue"`
+	AccessLog      bool        `yaml:"access_log"`       // Log every request at access_log_level instead of debug
+	AccessLogLevel string      `yaml:"access_log_level"` // Level of the access log, defaults to info
+	PProf          bool        `yaml:"pprof"`            // Serve the runtime profiles at /debug/pprof/ on the internal listener
+	SwaggerUI      bool        `yaml:"swagger_ui"`       // Serve a Swagger UI for the OpenAPI document at /api/v1/docs
+	AllowCIDRs     []string    `yaml:"allow_cidrs"`      // Networks allowed to push, empty allows all
+	TrustedProxies []string    `yaml:"trusted_proxies"`  // Proxies whose X-Forwarded-For header is trusted
+
+	allowNets   []netip.Prefix // Used internally after parsing
+	trustedNets []netip.Prefix // Used internally after parsing
+	socketMode  fs.FileMode    // Used internally after parsing
+	accessLevel zerolog.Level  // Used internally after parsing
+}
+
+// UnixSocket returns the path of the unix domain socket to listen on, if the
+// address uses the unix:// scheme
+func (w *Web) UnixSocket() (string, bool) {
+	return strings.CutPrefix(w.Address, "unix://")
+}
+
+// SocketFileMode returns the parsed permissions of the unix socket, 0 keeps the
+// permissions set by the umask
+func (w *Web) SocketFileMode() fs.FileMode {
+	return w.socketMode
+}
+
+// RequestLogLevel returns the level requests are logged at
+func (w *Web) RequestLogLevel() zerolog.Level {
+	return w.accessLevel
+}
+
+// AllowedNetworks returns the parsed networks allowed to push
+func (w *Web) AllowedNetworks() []netip.Prefix {
+	return w.allowNets
+}
+
+// TrustedProxyNetworks returns the parsed networks of trusted proxies
+func (w *Web) TrustedProxyNetworks() []netip.Prefix {
+	return w.trustedNets
+}
+
+// parsePrefixes parses a list of CIDRs, plain IP addresses are treated as a single host
+func parsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, v := range values {
+		if addr, err := netip.ParseAddr(v); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR '%s': %w", v, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// GRPC configures the gRPC push API. It shares the auth, allowed networks, and TLS
+// settings of the web server.
+type GRPC struct {
+	Address string `yaml:"address"` // Address to listen on, empty disables the gRPC server
+}
+
+// Enabled reports whether the gRPC server should be started
+func (g *GRPC) Enabled() bool {
+	return g.Address != ""
+}
+
+// MetricsAuth contains the basic auth credentials protecting the /metrics endpoint
+type MetricsAuth struct {
+	Username     string `yaml:"username"`
+	PasswordFile string `yaml:"password_file"`
+}
+
+// Enabled reports whether basic auth is required to scrape /metrics
+func (m *MetricsAuth) Enabled() bool {
+	return m.Username != ""
+}
+
+// LoadPassword reads the password from the password file
+func (m *MetricsAuth) LoadPassword() (string, error) {
+	password, err := readSecret(m.PasswordFile)
+	if err != nil {
+		return "", fmt.Errorf("error reading password file: %w", err)
+	}
+	return password, nil
+}
+
+// Validate checks if the web configuration is valid
+func (w *Web) Validate() error {
+	if w.ShutdownTimeout < 0 {
+		return fmt.Errorf("web shutdown timeout cannot be negative")
+	}
+
+	if w.ShutdownDelay < 0 {
+		return fmt.Errorf("web shutdown delay cannot be negative")
+	}
+
+	if w.ReadTimeout < 0 || w.ReadHeaderTimeout < 0 || w.WriteTimeout < 0 || w.IdleTimeout < 0 {
+		return fmt.Errorf("web timeouts cannot be negative")
+	}
+
+	if w.MaxBodySize < 0 {
+		return fmt.Errorf("web max body size cannot be negative")
+	}
+
+	if w.MetricsMaxRequestsInFlight < 0 || w.MetricsTimeout < 0 {
+		return fmt.Errorf("web metrics_max_requests_in_flight and metrics_timeout cannot be negative")
+	}
+
+	if w.MetricsErrorHandling == "" {
+		w.MetricsErrorHandling = MetricsErrorHandlingHttpError
+	}
+
+	if !w.MetricsErrorHandling.IsValid() {
+		return fmt.Errorf("unknown web metrics_error_handling '%s', expected http_error, continue, or panic", w.MetricsErrorHandling)
+	}
+
+	if (w.MetricsAuth.Username == "") != (w.MetricsAuth.PasswordFile == "") {
+		return fmt.Errorf("web metrics_auth username and password_file must be set together")
+	}
+
+	if path, ok := w.UnixSocket(); ok {
+		if path == "" {
+			return fmt.Errorf("web address 'unix://' must include the socket path")
+		}
+
+		if w.SocketMode != "" {
+			mode, err := strconv.ParseUint(w.SocketMode, 8, 32)
+			if err != nil || mode > 0o777 {
+				return fmt.Errorf("invalid web socket_mode '%s'", w.SocketMode)
+			}
+			w.socketMode = fs.FileMode(mode)
+		}
+	} else if w.SocketMode != "" || w.SocketUser != "" || w.SocketGroup != "" {
+		return fmt.Errorf("web socket settings require a unix:// address")
+	}
+
+	var err error
+	if w.allowNets, err = parsePrefixes(w.AllowCIDRs); err != nil {
+		return fmt.Errorf("web allow_cidrs: %w", err)
+	}
+
+	if w.trustedNets, err = parsePrefixes(w.TrustedProxies); err != nil {
+		return fmt.Errorf("web trusted_proxies: %w", err)
+	}
+
+	w.accessLevel = zerolog.DebugLevel
+	if w.AccessLog {
+		if w.AccessLogLevel == "" {
+			w.AccessLogLevel = "info"
+		}
+
+		level, err := zerolog.ParseLevel(w.AccessLogLevel)
+		if err != nil || level == zerolog.NoLevel || level == zerolog.Disabled {
+			return fmt.Errorf("invalid web access_log_level '%s'", w.AccessLogLevel)
+		}
+		w.accessLevel = level
+	}
+
+	for _, c := range w.MetricsCompression {
+		switch c {
+		case "gzip", "zstd", "identity":
+		default:
+			return fmt.Errorf("invalid web metrics_compression '%s', expected gzip, zstd, or identity", c)
+		}
+	}
+
+	if err := w.CORS.Validate(); err != nil {
+		return err
+	}
+
+	if err := w.SourceLabel.Validate(); err != nil {
+		return err
+	}
+
+	if err := w.Queue.Validate(); err != nil {
+		return err
+	}
+
+	return w.TLS.Validate()
+}
+
+// QueueOverflow represents how pushes are handled once the ingestion queue is
+// full. Reject answers them with 503 Service Unavailable, drop_oldest discards the
+// oldest pending update to make room.
+// ENUM(reject, drop_oldest)
+type QueueOverflow string
+
+// Queue configures the ingestion queue. Queued pushes are validated and answered
+// with 202 Accepted right away, then applied in the background. Pending gauge sets,
+// info and enum updates of the same series are coalesced into the last update and
+// pending counter increments are summed.
+type Queue struct {
+	Capacity      int           `yaml:"capacity"`       // Maximum number of pending updates, 0 disables the queue
+	FlushInterval time.Duration `yaml:"flush_interval"` // How often pending updates are applied, defaults to 1s
+	Overflow      QueueOverflow `yaml:"overflow"`       // reject (default) or drop_oldest
+	Async         *bool         `yaml:"async"`          // Queue pushes that don't set the async parameter, defaults to true
+}
+
+// Enabled reports whether the ingestion queue is available
+func (q *Queue) Enabled() bool {
+	return q.Capacity > 0
+}
+
+// AsyncDefault reports whether pushes that don't ask for a mode are queued
+func (q *Queue) AsyncDefault() bool {
+	return q.Enabled() && (q.Async == nil || *q.Async)
+}
+
+// Validate checks the queue configuration and sets its defaults
+func (q *Queue) Validate() error {
+	if q.Capacity < 0 {
+		return fmt.Errorf("web queue capacity must not be negative")
+	}
+
+	if q.FlushInterval < 0 {
+		return fmt.Errorf("web queue flush_interval must not be negative")
+	}
+
+	if q.FlushInterval == 0 {
+		q.FlushInterval = time.Second
+	}
+
+	if q.Overflow == "" {
+		q.Overflow = QueueOverflowReject
+	}
+
+	if !q.Overflow.IsValid() {
+		return fmt.Errorf("unknown web queue overflow '%s', expected reject or drop_oldest", q.Overflow)
+	}
+
+	return nil
+}
+
+// MetricsErrorHandling represents how /metrics handles errors gathering the
+// metrics. HTTP error responds with 500, continue serves the metrics that were
+// gathered, and panic panics.
+// ENUM(http_error, continue, panic)
+type MetricsErrorHandling string
+
+// OpenMetrics reports whether /metrics negotiates the OpenMetrics format
+func (w *Web) OpenMetrics() bool {
+	return w.MetricsOpenMetrics == nil || *w.MetricsOpenMetrics
+}
+
+// LabelSource represents where the value of the source label is taken from: the
+// client IP, a request header, or the name of the API key
+// ENUM(ip, header, key)
+type LabelSource string
+
+// SourceLabel sets a label identifying the pusher on every update of metrics that
+// define the label, overriding the value sent by the pusher
+type SourceLabel struct {
+	Name   string      `yaml:"name"`   // Label to set, e.g., instance or pushed_by
+	From   LabelSource `yaml:"from"`   // ip, header, or key
+	Header string      `yaml:"header"` // Header carrying the value when from is header
+}
+
+// Enabled reports whether pushes are labeled with their source
+func (s *SourceLabel) Enabled() bool {
+	return s.Name != ""
+}
+
+// Validate checks the source label configuration
+func (s *SourceLabel) Validate() error {
+	if !s.Enabled() {
+		if s.From != "" || s.Header != "" {
+			return fmt.Errorf("web source_label requires a name")
+		}
+		return nil
+	}
+
+	if !model.LabelName(s.Name).IsValidLegacy() || strings.HasPrefix(s.Name, "__") {
+		return fmt.Errorf("web source_label name '%s' is not a valid label name", s.Name)
+	}
+
+	if !s.From.IsValid() {
+		return fmt.Errorf("unknown web source_label from '%s', expected ip, header, or key", s.From)
+	}
+
+	if (s.From == LabelSourceHeader) != (s.Header != "") {
+		return fmt.Errorf("web source_label header must be set if and only if from is header")
+	}
+
+	return nil
+}
+
+// CORS contains the cross-origin settings for browser based clients
+type CORS struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins"`   // Origins allowed to call the API, "*" allows any origin
+	AllowedMethods   []string      `yaml:"allowed_methods"`   // Defaults to GET, POST, PUT, and DELETE
+	AllowedHeaders   []string      `yaml:"allowed_headers"`   // Defaults to Authorization and Content-Type
+	AllowCredentials bool          `yaml:"allow_credentials"` // Allow cookies and basic auth credentials
+	MaxAge           time.Duration `yaml:"max_age"`           // How long browsers may cache preflight responses
+}
+
+// Enabled reports whether CORS headers should be sent
+func (c *CORS) Enabled() bool {
+	return len(c.AllowedOrigins) > 0
+}
+
+// Validate checks the CORS configuration and applies defaults
+func (c *CORS) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+
+	if c.AllowCredentials && slices.Contains(c.AllowedOrigins, "*") {
+		return fmt.Errorf("web cors allow_credentials cannot be used with the '*' origin")
+	}
+
+	if c.MaxAge < 0 {
+		return fmt.Errorf("web cors max_age cannot be negative")
+	}
+
+	if len(c.AllowedMethods) == 0 {
+		c.AllowedMethods = []string{"GET", "POST", "PUT", "DELETE"}
+	}
+
+	if len(c.AllowedHeaders) == 0 {
+		c.AllowedHeaders = []string{"Authorization", "Content-Type"}
+	}
+
+	for i, m := range c.AllowedMethods {
+		c.AllowedMethods[i] = strings.ToUpper(m)
+	}
+
+	return nil
+}
+
+// ClientAuthType represents the client certificate policy of the TLS listener
+// ENUM(none, request, require)
+type ClientAuthType string
+
+// TLS contains the TLS settings of the HTTP server
+type TLS struct {
+	CertFile     string         `yaml:"cert_file"`
+	KeyFile      string         `yaml:"key_file"`
+	ClientCAFile string         `yaml:"client_ca_file"` // CA bundle used to verify client certificates
+	ClientAuth   ClientAuthType `yaml:"client_auth"`    // none, request (verify if given), or require
+}
+
+// Enabled reports whether the server should serve TLS
+func (t *TLS) Enabled() bool {
+	return t.CertFile != ""
+}
+
+// Validate checks if the TLS configuration is valid
+func (t *TLS) Validate() error {
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return fmt.Errorf("web tls cert_file and key_file must be set together")
+	}
+
+	if t.ClientAuth == "" {
+		t.ClientAuth = ClientAuthTypeNone
+		if t.ClientCAFile != "" {
+			t.ClientAuth = ClientAuthTypeRequire
+		}
+	}
+
+	if !t.ClientAuth.IsValid() {
+		return fmt.Errorf("unknown web tls client_auth '%s'", t.ClientAuth)
+	}
+
+	if !t.Enabled() && (t.ClientCAFile != "" || t.ClientAuth != ClientAuthTypeNone) {
+		return fmt.Errorf("web tls client authentication requires cert_file and key_file")
+	}
+
+	if t.ClientAuth != ClientAuthTypeNone && t.ClientCAFile == "" {
+		return fmt.Errorf("web tls client_auth '%s' requires client_ca_file", t.ClientAuth)
+	}
+
+	return nil
+}
+
+// ValidatorConfig defines an external command run to validate every push
+type ValidatorConfig struct {
+	Name    string        `yaml:"name"`
+	Command string        `yaml:"command"`
+	Args    []string      `yaml:"args"`
+	Timeout time.Duration `yaml:"timeout"`
 }
 
 // GlobalConfig contains global settings
 type GlobalConfig struct {
-	Namespace       string        `yaml:"namespace"`
-	RefreshInterval string        `yaml:"refresh_interval"`
-	parsedInterval  time.Duration // Used internally after parsing
+	Namespace           string            `yaml:"namespace"`
+	RefreshInterval     string            `yaml:"refresh_interval"`
+	OverlayFile         string            `yaml:"overlay_file"`          // File persisting metrics created through the admin API
+	AllowDynamicMetrics bool              `yaml:"allow_dynamic_metrics"` // Create unknown metrics on their first push from the type, labels, and help of the update
+	TTL                 time.Duration     `yaml:"ttl"`                   // Remove series that weren't pushed for this long, 0 keeps them forever
+	TrackLastPush       bool              `yaml:"track_last_push"`       // Expose <name>_last_push_timestamp_seconds for every metric
+	StateFile           string            `yaml:"state_file"`            // File the series values are checkpointed to and restored from on startup
+	StateInterval       time.Duration     `yaml:"state_interval"`        // How often the state file is written, defaults to 1m
+	LabelPolicy         LabelPolicy       `yaml:"label_policy"`          // How pushes with missing or extra labels are handled, defaults to lenient
+	MissingLabels       MissingLabels     `yaml:"missing_labels"`        // How lenient metrics handle missing labels, defaults to fill
+	LabelFiller         *string           `yaml:"label_filler"`          // Value of filled missing labels, defaults to <missing>
+	ExternalLabels      map[string]string `yaml:"external_labels"`       // Labels added to every exposed series, e.g., region or cluster
+	WatchConfig         bool              `yaml:"watch_config"`          // Reload the configuration when its files change
+	parsedInterval      time.Duration     // Used internally after parsing
 }
 
 // ParsedRefreshInterval returns the parsed refresh interval
@@ -42,18 +507,217 @@ func (g *GlobalConfig) ParsedRefreshInterval() (time.Duration, error) {
 }
 
 // MetricType represents the type of metric
-// ENUM(gauge, counter, histogram, summary)
+// ENUM(gauge, counter, histogram, summary, info, enum)
 type MetricType string
 
+// LabelPolicy represents how pushes whose labels don't match the configured labels
+// are handled. Lenient fills missing labels and drops extra labels, strict rejects
+// the push.
+// ENUM(lenient, strict)
+type LabelPolicy string
+
+// MissingLabels represents how pushes without some of the configured labels are
+// handled by metrics with the lenient label policy. Fill sets the missing labels to
+// the label filler, reject rejects the push, and drop silently discards the sample.
+// ENUM(fill, reject, drop)
+type MissingLabels string
+
+// SeriesLimitAction represents how updates that would create a series beyond the
+// max_series of a metric are handled. Reject rejects the update, overflow applies
+// it to the overflow series whose labels all have the value OverflowLabelValue.
+// ENUM(reject, overflow)
+type SeriesLimitAction string
+
+// Eviction represents how a metric makes room for new series once it reached its
+// max_series. LRU deletes the series that wasn't pushed for the longest time.
+// ENUM(lru)
+type Eviction string
+
+// OverflowLabelValue is the value of all labels of the overflow series
+const OverflowLabelValue = "overflow"
+
+// DefaultLabelFiller is the value of missing labels unless configured otherwise
+const DefaultLabelFiller = "<missing>"
+
+// Objectives maps the quantiles of a summary to their allowed absolute error
+type Objectives map[float64]float64
+
+// UnmarshalYAML decodes objectives, accepting quoted quantiles as produced by JSON
+func (o *Objectives) UnmarshalYAML(value *yaml.Node) error {
+	var raw map[string]float64
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+
+	objectives := make(Objectives, len(raw))
+	for k, v := range raw {
+		q, err := strconv.ParseFloat(k, 64)
+		if err != nil {
+			return fmt.Errorf("invalid objective quantile '%s': %w", k, err)
+		}
+		objectives[q] = v
+	}
+
+	*o = objectives
+	return nil
+}
+
+// Buckets are the upper bounds of the buckets of a histogram
+type Buckets []float64
+
+// BucketGenerator generates evenly spaced buckets instead of listing every bound
+type BucketGenerator struct {
+	Type   string  `yaml:"type"`   // linear or exponential
+	Start  float64 `yaml:"start"`  // Upper bound of the first bucket
+	Width  float64 `yaml:"width"`  // Distance between the bounds of linear buckets
+	Factor float64 `yaml:"factor"` // Growth of the bounds of exponential buckets
+	Count  int     `yaml:"count"`  // Number of buckets
+}
+
+// Buckets returns the generated bucket bounds
+func (g BucketGenerator) Buckets() (Buckets, error) {
+	if g.Count < 1 {
+		return nil, fmt.Errorf("bucket count must be at least 1")
+	}
+
+	switch g.Type {
+	case "linear":
+		if g.Width <= 0 {
+			return nil, fmt.Errorf("linear bucket width must be positive")
+		}
+		return prometheus.LinearBuckets(g.Start, g.Width, g.Count), nil
+	case "exponential":
+		if g.Start <= 0 {
+			return nil, fmt.Errorf("exponential bucket start must be positive")
+		}
+		if g.Factor <= 1 {
+			return nil, fmt.Errorf("exponential bucket factor must be greater than 1")
+		}
+		return prometheus.ExponentialBuckets(g.Start, g.Factor, g.Count), nil
+	default:
+		return nil, fmt.Errorf("unknown bucket generator type '%s', expected linear or exponential", g.Type)
+	}
+}
+
+// UnmarshalYAML decodes buckets from a list of bounds or a bucket generator
+func (b *Buckets) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		var bounds []float64
+		if err := value.Decode(&bounds); err != nil {
+			return err
+		}
+		*b = bounds
+		return nil
+	}
+
+	var gen BucketGenerator
+	if err := value.Decode(&gen); err != nil {
+		return err
+	}
+
+	buckets, err := gen.Buckets()
+	if err != nil {
+		return fmt.Errorf("line %d: %w", value.Line, err)
+	}
+
+	*b = buckets
+	return nil
+}
+
 // MetricConfig represents a single metric configuration
 type MetricConfig struct {
-	Name         string              `yaml:"name"`
-	Description  string              `yaml:"description"`
-	Type         MetricType          `yaml:"type"`
-	Labels       []string            `yaml:"labels"`
-	DefaultValue float64             `yaml:"default_value,omitempty"`
-	Buckets      []float64           `yaml:"buckets,omitempty"`    // For histogram
-	Objectives   map[float64]float64 `yaml:"objectives,omitempty"` // For summary
+	Name         string     `yaml:"name"`
+	Namespace    *string    `yaml:"namespace,omitempty"` // Overrides the global namespace, an empty namespace opts out
+	Subsystem    string     `yaml:"subsystem,omitempty"` // Fully qualified name becomes <namespace>_<subsystem>_<name>
+	Description  string     `yaml:"description"`
+	Unit         string     `yaml:"unit,omitempty"` // Base unit appended to the exposed name, see Units
+	Type         MetricType `yaml:"type"`
+	Labels       []string   `yaml:"labels"`
+	InfoLabels   []string   `yaml:"info_labels,omitempty"` // For info, labels carrying the pushed values
+	States       []string   `yaml:"states,omitempty"`      // For enum, the states a series can be in
+	DefaultValue float64    `yaml:"default_value,omitempty"`
+	Buckets      Buckets    `yaml:"buckets,omitempty"`    // For histogram, a list of bounds or a generator
+	Objectives   Objectives `yaml:"objectives,omitempty"` // For summary
+
+	// MaxAge is how long observations of a summary count towards its quantiles,
+	// defaults to 10m. Raise it when scrapes may be further apart than the pushes.
+	MaxAge time.Duration `yaml:"max_age,omitempty"`
+
+	// AgeBuckets is the number of buckets the max age of a summary is split into,
+	// defaults to 5
+	AgeBuckets uint32 `yaml:"age_buckets,omitempty"`
+
+	// ExpectedDuration is the expected_duration of the job of a standard duration
+	// metric, the durations of finished runs are compared against it. It is set
+	// from the job and can't be configured on metrics.
+	ExpectedDuration time.Duration `yaml:"-"`
+
+	// TTL removes series that weren't pushed for this long, overriding the global TTL
+	TTL time.Duration `yaml:"ttl,omitempty"`
+
+	// ExpectedInterval is the longest time between two pushes of a series, series
+	// that miss it are reported by <name>_overdue
+	ExpectedInterval time.Duration `yaml:"expected_interval,omitempty"`
+
+	// TrackLastPush exposes <name>_last_push_timestamp_seconds with the time of the
+	// latest update of every series
+	TrackLastPush bool `yaml:"track_last_push,omitempty"`
+
+	// LabelPolicy overrides the global label policy
+	LabelPolicy LabelPolicy `yaml:"label_policy,omitempty"`
+
+	// MissingLabels overrides how the global policy handles missing labels
+	MissingLabels MissingLabels `yaml:"missing_labels,omitempty"`
+
+	// LabelFiller overrides the global value of filled missing labels
+	LabelFiller *string `yaml:"label_filler,omitempty"`
+
+	// LabelDefaults are the values of labels omitted by a push, they take precedence
+	// over the label policy and filler
+	LabelDefaults map[string]string `yaml:"label_defaults,omitempty"`
+
+	// MaxSeries limits the number of series of the metric, 0 is unlimited. Updates
+	// creating further series are handled according to MaxSeriesAction.
+	MaxSeries int `yaml:"max_series,omitempty"`
+
+	// MaxSeriesAction is how updates beyond MaxSeries are handled, defaults to reject
+	MaxSeriesAction SeriesLimitAction `yaml:"max_series_action,omitempty"`
+
+	// Eviction deletes existing series to make room for new series beyond MaxSeries
+	// instead of applying MaxSeriesAction
+	Eviction Eviction `yaml:"eviction,omitempty"`
+
+	// Job is the name of the job the metric belongs to, set for the metrics of a job
+	Job string `yaml:"job,omitempty"`
+}
+
+// Units are the base units a metric may declare, following the Prometheus naming
+// conventions
+var Units = []string{"seconds", "bytes", "ratio", "meters", "grams", "joules", "volts", "amperes", "celsius"}
+
+// ExposedName returns the name of the metric without namespace and subsystem, with
+// the unit appended unless the name already ends with it. For counters the unit is
+// placed before the _total suffix, info metrics end with _info.
+func (m MetricConfig) ExposedName() string {
+	if m.Type == MetricTypeInfo && !strings.HasSuffix(m.Name, "_info") {
+		return m.Name + "_info"
+	}
+
+	if m.Unit == "" {
+		return m.Name
+	}
+
+	name, total := m.Name, ""
+	if m.Type == MetricTypeCounter {
+		if trimmed, ok := strings.CutSuffix(name, "_total"); ok {
+			name, total = trimmed, "_total"
+		}
+	}
+
+	if !strings.HasSuffix(name, "_"+m.Unit) {
+		name += "_" + m.Unit
+	}
+	return name + total
 }
 
 // Validate checks if the metric configuration is valid
@@ -62,6 +726,10 @@ func (m *MetricConfig) Validate() error {
 		return fmt.Errorf("metric name cannot be empty")
 	}
 
+	if m.Unit != "" && !slices.Contains(Units, m.Unit) {
+		return fmt.Errorf("metric '%s' has unknown unit '%s', expected one of %s", m.Name, m.Unit, strings.Join(Units, ", "))
+	}
+
 	switch m.Type {
 	case MetricTypeGauge, MetricTypeCounter:
 		// No specific validation needed
@@ -73,26 +741,219 @@ func (m *MetricConfig) Validate() error {
 		if len(m.Objectives) == 0 {
 			return fmt.Errorf("summary metric '%s' must define objectives", m.Name)
 		}
+	case MetricTypeInfo:
+		if len(m.InfoLabels) == 0 {
+			return fmt.Errorf("info metric '%s' must define info_labels", m.Name)
+		}
+		for _, label := range m.InfoLabels {
+			if slices.Contains(m.Labels, label) {
+				return fmt.Errorf("info metric '%s' defines '%s' in both labels and info_labels", m.Name, label)
+			}
+		}
+		if m.Unit != "" {
+			return fmt.Errorf("info metric '%s' cannot define unit", m.Name)
+		}
+	case MetricTypeEnum:
+		if len(m.States) == 0 {
+			return fmt.Errorf("enum metric '%s' must define states", m.Name)
+		}
+		for i, state := range m.States {
+			if state == "" {
+				return fmt.Errorf("enum metric '%s' has an empty state", m.Name)
+			}
+			if slices.Contains(m.States[:i], state) {
+				return fmt.Errorf("enum metric '%s' defines state '%s' more than once", m.Name, state)
+			}
+		}
+		// The state is exposed in a label named after the metric
+		if strings.Contains(m.Name, ":") || slices.Contains(m.Labels, m.Name) {
+			return fmt.Errorf("enum metric '%s' name must be a valid label name that isn't one of its labels", m.Name)
+		}
+		if m.Unit != "" {
+			return fmt.Errorf("enum metric '%s' cannot define unit", m.Name)
+		}
 	default:
 		return fmt.Errorf("unknown metric type '%s' for metric '%s'", m.Type, m.Name)
 	}
 
+	if len(m.InfoLabels) > 0 && m.Type != MetricTypeInfo {
+		return fmt.Errorf("%s metric '%s' cannot define info_labels", m.Type, m.Name)
+	}
+
+	if len(m.States) > 0 && m.Type != MetricTypeEnum {
+		return fmt.Errorf("%s metric '%s' cannot define states", m.Type, m.Name)
+	}
+
+	if m.MaxAge < 0 {
+		return fmt.Errorf("metric '%s' max_age cannot be negative", m.Name)
+	}
+
+	if (m.MaxAge > 0 || m.AgeBuckets > 0) && m.Type != MetricTypeSummary {
+		return fmt.Errorf("%s metric '%s' cannot define max_age or age_buckets", m.Type, m.Name)
+	}
+
+	if m.TTL < 0 {
+		return fmt.Errorf("metric '%s' ttl cannot be negative", m.Name)
+	}
+
+	if m.ExpectedInterval < 0 {
+		return fmt.Errorf("metric '%s' expected_interval cannot be negative", m.Name)
+	}
+
+	if err := m.ValidateTTL(0); err != nil {
+		return err
+	}
+
+	if m.LabelPolicy != "" && !m.LabelPolicy.IsValid() {
+		return fmt.Errorf("metric '%s' has unknown label_policy '%s'", m.Name, m.LabelPolicy)
+	}
+
+	if m.MissingLabels != "" && !m.MissingLabels.IsValid() {
+		return fmt.Errorf("metric '%s' has unknown missing_labels '%s'", m.Name, m.MissingLabels)
+	}
+
+	for label := range m.LabelDefaults {
+		if !slices.Contains(m.Labels, label) {
+			return fmt.Errorf("metric '%s' defines a default for unknown label '%s'", m.Name, label)
+		}
+	}
+
+	if m.MaxSeries < 0 {
+		return fmt.Errorf("metric '%s' max_series cannot be negative", m.Name)
+	}
+
+	if m.MaxSeriesAction != "" {
+		if !m.MaxSeriesAction.IsValid() {
+			return fmt.Errorf("metric '%s' has unknown max_series_action '%s'", m.Name, m.MaxSeriesAction)
+		}
+		if m.MaxSeries == 0 {
+			return fmt.Errorf("metric '%s' max_series_action requires max_series", m.Name)
+		}
+		if m.MaxSeriesAction == SeriesLimitActionOverflow && len(m.Labels) == 0 {
+			return fmt.Errorf("metric '%s' without labels cannot overflow", m.Name)
+		}
+	}
+
+	if m.Eviction != "" {
+		if !m.Eviction.IsValid() {
+			return fmt.Errorf("metric '%s' has unknown eviction '%s'", m.Name, m.Eviction)
+		}
+		if m.MaxSeries == 0 {
+			return fmt.Errorf("metric '%s' eviction requires max_series", m.Name)
+		}
+		if m.MaxSeriesAction != "" {
+			return fmt.Errorf("metric '%s' cannot define both eviction and max_series_action", m.Name)
+		}
+	}
+
 	return nil
 }
 
-// LoadConfig loads the configuration from a YAML file
-func LoadConfig(filename string) (*Config, error) {
-	data, err := os.ReadFile(filename)
+// ConfigFormat represents the format of a config file, files without a format are
+// read in the format of their extension
+// ENUM(yaml, json, toml)
+type ConfigFormat string
+
+// LoadOptions control how the configuration files are read
+type LoadOptions struct {
+	// Format of the file at the config path regardless of its extension. The files
+	// of a directory and included files are always read in the format of their
+	// extension.
+	Format ConfigFormat
+
+	// Template renders every config file as a Go template before it is parsed, with
+	// the environment as .Env and the contents of the values file as .Values
+	Template bool
+
+	// Values is a YAML file with the values of templates, optional
+	Values string
+
+	// Strict rejects keys that don't match a field, e.g., a misspelled descripton,
+	// instead of ignoring them
+	Strict bool
+
+	// AgeKeyFile holds the age identities decrypting files encrypted by sops, the
+	// SOPS_AGE_KEY environment variable and the default key file of sops are read
+	// as well
+	AgeKeyFile string
+
+	// Overrides replace settings of the config files
+	Overrides Overrides
+}
+
+// LoadConfig loads the configuration from a YAML, JSON, or TOML file, or from all
+// config files of a directory, expanding the environment variable references in
+// their values. The files of a directory and the files matched by the include
+// patterns of a file are merged in order, each section may be set by one file only
+// and the metrics of all files are appended.
+func LoadConfig(path string) (*Config, error) {
+	return LoadConfigWith(path, LoadOptions{})
+}
+
+// LoadConfigWith loads the configuration like LoadConfig with the given options
+func LoadConfigWith(path string, opts LoadOptions) (*Config, error) {
+	doc, hash, files, err := readConfig(path, opts)
 	if err != nil {
-		return nil, fmt.Errorf("error reading config file: %w", err)
+		return nil, err
+	}
+
+	config, err := decodeConfig(doc, hash, opts)
+	if err != nil {
+		return nil, err
 	}
 
+	config.files = files
+	return config, nil
+}
+
+// decodeConfig decodes the merged configuration files over the defaults, applies the
+// overrides of the options, merges the overlay, and validates the result
+func decodeConfig(doc *yaml.Node, hash string, opts LoadOptions) (*Config, error) {
 	config := Config{
-		Web: Web{Address: ":8080"},
+		Web: Web{
+			Address:           ":8080",
+			ShutdownTimeout:   30 * time.Second,
+			ReadTimeout:       10 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			MaxBodySize:       1 << 20,
+		},
 	}
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	if err := doc.Decode(&config); err != nil {
 		return nil, fmt.Errorf("error parsing config file: %w", err)
 	}
+	config.hash = hash
+	config.unused = unusedFields(doc, reflect.TypeOf(config), "")
+	if opts.Strict && len(config.unused) > 0 {
+		return nil, fmt.Errorf("error parsing config file: unknown fields: %s", strings.Join(config.unused, ", "))
+	}
+	opts.Overrides.apply(&config)
+
+	if err := config.expandMetricTemplates(); err != nil {
+		return nil, err
+	}
+
+	if err := config.expandJobs(); err != nil {
+		return nil, err
+	}
+
+	if err := config.expandSchedules(); err != nil {
+		return nil, err
+	}
+
+	for i := range config.Metrics {
+		config.Defaults.apply(&config.Metrics[i])
+	}
+
+	// Merge metrics created at runtime
+	if config.Global.OverlayFile != "" {
+		overlay, err := readOverlay(config.Global.OverlayFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Metrics = append(config.Metrics, overlay...)
+	}
 
 	// Validate the configuration
 	if err := config.Validate(); err != nil {
@@ -102,6 +963,25 @@ func LoadConfig(filename string) (*Config, error) {
 	return &config, nil
 }
 
+// ValidateTTL checks that series don't expire before they are overdue, which would
+// keep <name>_overdue from ever reporting them. The global TTL applies to metrics
+// without a TTL of their own.
+func (m MetricConfig) ValidateTTL(globalTTL time.Duration) error {
+	if m.ExpectedInterval == 0 {
+		return nil
+	}
+
+	if m.TTL > 0 && m.TTL <= m.ExpectedInterval {
+		return fmt.Errorf("metric '%s' ttl %s must be longer than its expected_interval %s, its series would expire before they are overdue", m.Name, m.TTL, m.ExpectedInterval)
+	}
+
+	if m.TTL == 0 && globalTTL > 0 && globalTTL <= m.ExpectedInterval {
+		return fmt.Errorf("global ttl %s must be longer than the expected_interval %s of metric '%s', its series would expire before they are overdue", globalTTL, m.ExpectedInterval, m.Name)
+	}
+
+	return nil
+}
+
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
 	// Validate global settings
@@ -113,6 +993,106 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	if c.Global.TTL < 0 {
+		return fmt.Errorf("global ttl cannot be negative")
+	}
+
+	if c.Global.StateInterval < 0 {
+		return fmt.Errorf("global state_interval cannot be negative")
+	}
+	if c.Global.StateInterval == 0 {
+		c.Global.StateInterval = time.Minute
+	}
+
+	if c.Global.LabelPolicy == "" {
+		c.Global.LabelPolicy = LabelPolicyLenient
+	}
+	if !c.Global.LabelPolicy.IsValid() {
+		return fmt.Errorf("unknown global label_policy '%s'", c.Global.LabelPolicy)
+	}
+
+	if c.Global.MissingLabels == "" {
+		c.Global.MissingLabels = MissingLabelsFill
+	}
+	if !c.Global.MissingLabels.IsValid() {
+		return fmt.Errorf("unknown global missing_labels '%s'", c.Global.MissingLabels)
+	}
+
+	for name := range c.Global.ExternalLabels {
+		if !model.LabelName(name).IsValidLegacy() || strings.HasPrefix(name, "__") {
+			return fmt.Errorf("global external label '%s' is not a valid label name", name)
+		}
+	}
+
+	if c.Global.LabelFiller == nil {
+		filler := DefaultLabelFiller
+		c.Global.LabelFiller = &filler
+	}
+
+	// Validate web settings
+	if err := c.Web.Validate(); err != nil {
+		return err
+	}
+
+	// Validate auth settings
+	if err := c.Auth.Validate(); err != nil {
+		return err
+	}
+
+	// Validate listeners
+	if err := c.Graphite.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.UDP.Validate(); err != nil {
+		return err
+	}
+
+	for i := range c.RemoteWrite {
+		if err := c.RemoteWrite[i].Validate(); err != nil {
+			return err
+		}
+	}
+
+	for i := range c.Notifiers {
+		if err := c.Notifiers[i].Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate jobs
+	if err := c.validateJobs(); err != nil {
+		return err
+	}
+
+	// Validate schedules
+	if err := c.validateSchedules(); err != nil {
+		return err
+	}
+
+	execNames := make(map[string]bool, len(c.ExecCollectors))
+	for i := range c.ExecCollectors {
+		e := &c.ExecCollectors[i]
+		if err := e.Validate(); err != nil {
+			return err
+		}
+		if execNames[e.Name] {
+			return fmt.Errorf("duplicate exec collector name: %s", e.Name)
+		}
+		execNames[e.Name] = true
+	}
+
+	// Validate validators
+	for i, v := range c.Validators {
+		if v.Command == "" {
+			return fmt.Errorf("validator %d must define a command", i)
+		}
+
+		if v.Name == "" {
+			c.Validators[i].Name = v.Command
+		}
+	}
+
 	// Validate metrics
 	metricNames := make(map[string]bool)
 	for i, metric := range c.Metrics {
@@ -120,6 +1100,10 @@ func (c *Config) Validate() error {
 			return err
 		}
 
+		if err := metric.ValidateTTL(c.Global.TTL); err != nil {
+			return err
+		}
+
 		// Check for duplicate metric names
 		if metricNames[metric.Name] {
 			return fmt.Errorf("duplicate metric name: %s", metric.Name)
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// Auth contains the authentication settings of the push API. When no tokens or
+// keys are configured the push API is unauthenticated.
+type Auth struct {
+	Tokens     []string `yaml:"tokens"`      // Unrestricted tokens
+	TokenFiles []string `yaml:"token_files"` // Files containing one unrestricted token per line
+	Keys       []APIKey `yaml:"keys"`        // Named keys, optionally scoped to a set of metrics
+}
+
+// APIKey is a named token that may be restricted to a set of metrics
+type APIKey struct {
+	Name      string   `yaml:"name"`
+	Token     string   `yaml:"token"`
+	TokenFile string   `yaml:"token_file"`
+	Metrics   []string `yaml:"metrics"` // Metric names or globs the key may push to, empty allows all
+	Admin     bool     `yaml:"admin"`   // Allows access to the admin API
+}
+
+// Allows reports whether the key may push to the metric
+func (k *APIKey) Allows(metric string) bool {
+	if len(k.Metrics) == 0 {
+		return true
+	}
+
+	for _, pattern := range k.Metrics {
+		if ok, _ := path.Match(pattern, metric); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Validate checks if the auth configuration is valid
+func (a *Auth) Validate() error {
+	for _, token := range a.Tokens {
+		if strings.TrimSpace(token) == "" {
+			return fmt.Errorf("auth tokens cannot be empty")
+		}
+	}
+
+	names := make(map[string]bool, len(a.Keys))
+	for i, key := range a.Keys {
+		if key.Name == "" {
+			return fmt.Errorf("auth key %d must define a name", i)
+		}
+
+		if names[key.Name] {
+			return fmt.Errorf("duplicate auth key name: %s", key.Name)
+		}
+		names[key.Name] = true
+
+		if (key.Token == "") == (key.TokenFile == "") {
+			return fmt.Errorf("auth key '%s' must define exactly one of token or token_file", key.Name)
+		}
+
+		for _, pattern := range key.Metrics {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("auth key '%s' has invalid metric pattern '%s': %w", key.Name, pattern, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// LoadKeys resolves all tokens into API keys, reading token files from disk.
+// Unrestricted tokens are returned as unnamed keys that allow every metric.
+func (a *Auth) LoadKeys() ([]APIKey, error) {
+	keys := make([]APIKey, 0, len(a.Tokens)+len(a.Keys))
+
+	for _, token := range a.Tokens {
+		keys = append(keys, APIKey{Token: token})
+	}
+
+	for _, file := range a.TokenFiles {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading token file: %w", err)
+		}
+
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			keys = append(keys, APIKey{Token: line})
+		}
+	}
+
+	for _, key := range a.Keys {
+		if key.TokenFile != "" {
+			data, err := os.ReadFile(key.TokenFile)
+			if err != nil {
+				return nil, fmt.Errorf("error reading token file for key '%s': %w", key.Name, err)
+			}
+
+			key.Token = strings.TrimSpace(string(data))
+			if key.Token == "" {
+				return nil, fmt.Errorf("token file for key '%s' is empty", key.Name)
+			}
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
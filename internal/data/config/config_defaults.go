@@ -0,0 +1,41 @@
+package config
+
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+// MetricDefaults are settings applied to every metric of the config files that
+// doesn't set them itself
+type MetricDefaults struct {
+	Labels           []string      `yaml:"labels"`            // Labels of metrics without labels, labels: [] opts out
+	Buckets          Buckets       `yaml:"buckets"`           // Buckets of histograms
+	Objectives       Objectives    `yaml:"objectives"`        // Objectives of summaries
+	TTL              time.Duration `yaml:"ttl"`               // Takes precedence over the global TTL
+	ExpectedInterval time.Duration `yaml:"expected_interval"` // Longest time between two pushes of a series
+}
+
+// apply sets the defaults on a metric that doesn't set them itself
+func (d MetricDefaults) apply(m *MetricConfig) {
+	// A nil slice is an unset list, an explicit empty list is kept
+	if m.Labels == nil {
+		m.Labels = slices.Clone(d.Labels)
+	}
+
+	if m.Type == MetricTypeHistogram && len(m.Buckets) == 0 {
+		m.Buckets = slices.Clone(d.Buckets)
+	}
+
+	if m.Type == MetricTypeSummary && len(m.Objectives) == 0 {
+		m.Objectives = maps.Clone(d.Objectives)
+	}
+
+	if m.TTL == 0 {
+		m.TTL = d.TTL
+	}
+
+	if m.ExpectedInterval == 0 {
+		m.ExpectedInterval = d.ExpectedInterval
+	}
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// Diff describes the changes between two configurations
+type Diff struct {
+	Added    []string            // Names of added metrics
+	Removed  []string            // Names of removed metrics
+	Changed  map[string][]string // Names of changed metrics to the fields that changed
+	Sections []string            // Other sections that changed, e.g., web or auth
+}
+
+// Empty reports whether the configurations are the same
+func (d Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0 && len(d.Sections) == 0
+}
+
+// DiffConfig compares a configuration with the next one. Metrics are compared by
+// name and field, all other sections as a whole.
+func DiffConfig(prev, next *Config) Diff {
+	diff := Diff{Changed: make(map[string][]string)}
+
+	oldMetrics := make(map[string]MetricConfig, len(prev.Metrics))
+	for _, m := range prev.Metrics {
+		oldMetrics[m.Name] = m
+	}
+
+	newNames := make(map[string]bool, len(next.Metrics))
+	for _, m := range next.Metrics {
+		newNames[m.Name] = true
+
+		old, ok := oldMetrics[m.Name]
+		if !ok {
+			diff.Added = append(diff.Added, m.Name)
+			continue
+		}
+
+		if fields := changedFields(reflect.ValueOf(old), reflect.ValueOf(m)); len(fields) > 0 {
+			diff.Changed[m.Name] = fields
+		}
+	}
+
+	for _, m := range prev.Metrics {
+		if !newNames[m.Name] {
+			diff.Removed = append(diff.Removed, m.Name)
+		}
+	}
+
+	// Changes of jobs and metric templates show up in their metrics
+	skip := []string{"metrics", "metric_templates"}
+	for _, name := range changedFields(reflect.ValueOf(*prev), reflect.ValueOf(*next)) {
+		if !slices.Contains(skip, name) {
+			diff.Sections = append(diff.Sections, name)
+		}
+	}
+
+	return diff
+}
+
+// changedFields returns the YAML keys of the fields of two structs that differ, in
+// lexical order
+func changedFields(a, b reflect.Value) []string {
+	var changed []string
+	for name, field := range yamlFields(a.Type()) {
+		if !reflect.DeepEqual(a.FieldByIndex(field.Index).Interface(), b.FieldByIndex(field.Index).Interface()) {
+			changed = append(changed, name)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
+// ChangedMetrics returns the names of the changed metrics in lexical order
+func (d Diff) ChangedMetrics() []string {
+	return slices.Sorted(maps.Keys(d.Changed))
+}
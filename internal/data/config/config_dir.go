@@ -0,0 +1,353 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configExtensions are the formats of config files by their extension
+var configExtensions = map[string]ConfigFormat{
+	".yaml": ConfigFormatYaml,
+	".yml":  ConfigFormatYaml,
+	".json": ConfigFormatJson,
+	".toml": ConfigFormatToml,
+}
+
+// configFiles returns the configuration files at path, the file itself or the
+// *.yaml, *.yml, *.json, and *.toml files of a directory in lexical order
+func configFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config directory: %w", err)
+	}
+
+	// Entries are sorted by name
+	var files []string
+	for _, entry := range entries {
+		if _, ok := configExtensions[filepath.Ext(entry.Name())]; ok && !entry.IsDir() {
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no *.yaml, *.yml, *.json, or *.toml config files in directory '%s'", path)
+	}
+
+	return files, nil
+}
+
+// readConfig reads the configuration files at path and merges them into a single
+// mapping, expanding the environment variable references of every file. Each
+// section may be set by one file only, except the metrics and the list sections,
+// which are appended in file order. It also returns a hash of the files and the
+// names of the files read, including the included files.
+func readConfig(path string, opts LoadOptions) (*yaml.Node, string, []string, error) {
+	files, err := configFiles(path)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	l, err := newConfigLoader(opts)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, file := range files {
+		fileFormat := opts.Format
+		if file != path {
+			fileFormat = ""
+		}
+
+		if err := l.load(file, fileFormat); err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	names := make([]string, len(l.files))
+	for i, f := range l.files {
+		names[i] = f.name
+	}
+
+	return l.result(), l.hash(), names, nil
+}
+
+// listSections are the sections besides the metrics whose lists are appended
+var listSections = []string{"jobs", "metric_templates"}
+
+// configLoader merges configuration files and the files they include
+type configLoader struct {
+	merged      *yaml.Node
+	metrics     *yaml.Node
+	lists       map[string]*yaml.Node // Appended list sections by name
+	sections    map[string]string     // Section name to the file that set it
+	metricFiles map[string]string     // Metric name to the file that defined it
+	loaded      map[string]bool
+	files       []loadedFile
+	template    *configTemplate // Nil unless the files are templates
+	ageKeyFile  string          // Key file decrypting sops encrypted files
+	identities  []age.Identity  // Read on the first encrypted file
+}
+
+func newConfigLoader(opts LoadOptions) (*configLoader, error) {
+	l := &configLoader{
+		merged:      &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"},
+		metrics:     &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"},
+		lists:       make(map[string]*yaml.Node),
+		sections:    make(map[string]string),
+		metricFiles: make(map[string]string),
+		loaded:      make(map[string]bool),
+		ageKeyFile:  opts.AgeKeyFile,
+	}
+
+	if opts.Template {
+		var err error
+		l.template, err = newConfigTemplate(opts.Values)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return l, nil
+}
+
+// result returns the merged configuration
+func (l *configLoader) result() *yaml.Node {
+	if len(l.metrics.Content) > 0 {
+		l.merged.Content = append(l.merged.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "metrics"}, l.metrics)
+	}
+	for _, name := range listSections {
+		if list, ok := l.lists[name]; ok {
+			l.merged.Content = append(l.merged.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}, list)
+		}
+	}
+	return l.merged
+}
+
+// loadedFile is the content of a loaded configuration file
+type loadedFile struct {
+	name string
+	data []byte
+}
+
+// hash returns the SHA-256 of the loaded files, of the content alone for a single
+// file so it matches the checksum of the file
+func (l *configLoader) hash() string {
+	h := sha256.New()
+	for _, f := range l.files {
+		if len(l.files) > 1 {
+			h.Write([]byte(f.name))
+		}
+		h.Write(f.data)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// load merges a configuration file and the files it includes. Files without a
+// format are read in the format of their extension.
+func (l *configLoader) load(file string, format ConfigFormat) error {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	if l.loaded[abs] {
+		return fmt.Errorf("config file '%s' is loaded more than once, check the includes", file)
+	}
+	l.loaded[abs] = true
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+
+	if format == "" {
+		format = configExtensions[filepath.Ext(file)]
+	}
+
+	includes, err := l.merge(file, data, format)
+	if err != nil {
+		return err
+	}
+
+	// Include paths are relative to the including file
+	for _, pattern := range includes {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(file), pattern)
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("config file '%s': invalid include '%s': %w", file, pattern, err)
+		}
+		if len(matches) == 0 && !strings.ContainsAny(pattern, `*?[`) {
+			return fmt.Errorf("config file '%s': included file '%s' does not exist", file, pattern)
+		}
+
+		for _, match := range matches {
+			if err := l.load(match, ""); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// merge merges the content of a configuration file, returning its include patterns
+func (l *configLoader) merge(file string, data []byte, format ConfigFormat) ([]string, error) {
+	if l.template != nil {
+		var err error
+		data, err = l.template.render(file, data)
+		if err != nil {
+			return nil, fmt.Errorf("config file '%s': %w", file, err)
+		}
+	}
+	l.files = append(l.files, loadedFile{name: file, data: data})
+
+	doc, err := parseConfigFile(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing config file '%s': %w", file, err)
+	}
+	if len(doc.Content) == 0 {
+		// Empty file
+		return nil, nil
+	}
+
+	if err := l.decrypt(doc.Content[0]); err != nil {
+		return nil, fmt.Errorf("error decrypting config file '%s': %w", file, err)
+	}
+
+	if err := expandEnv(&doc); err != nil {
+		return nil, fmt.Errorf("error expanding config file '%s': %w", file, err)
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config file '%s' must be a mapping", file)
+	}
+
+	var includes []string
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+
+		switch key.Value {
+		case "include":
+			if err := value.Decode(&includes); err != nil {
+				return nil, fmt.Errorf("config file '%s' line %d: include must be a list of paths: %w", file, value.Line, err)
+			}
+		case "metrics":
+			if err := l.addMetrics(file, value); err != nil {
+				return nil, err
+			}
+		default:
+			if slices.Contains(listSections, key.Value) {
+				if err := l.appendList(file, key.Value, value); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			if prev, ok := l.sections[key.Value]; ok {
+				return nil, fmt.Errorf("section '%s' is set in both '%s' and '%s'", key.Value, prev, file)
+			}
+			l.sections[key.Value] = file
+			l.merged.Content = append(l.merged.Content, key, value)
+		}
+	}
+
+	return includes, nil
+}
+
+// appendList appends the items of a list section of a file
+func (l *configLoader) appendList(file, section string, items *yaml.Node) error {
+	if items.Kind != yaml.SequenceNode {
+		return fmt.Errorf("config file '%s' line %d: %s must be a list", file, items.Line, section)
+	}
+
+	list, ok := l.lists[section]
+	if !ok {
+		list = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		l.lists[section] = list
+	}
+	list.Content = append(list.Content, items.Content...)
+	return nil
+}
+
+// addMetrics appends the metrics of a file, metric names must be unique across files
+func (l *configLoader) addMetrics(file string, metrics *yaml.Node) error {
+	if metrics.Kind != yaml.SequenceNode {
+		return fmt.Errorf("config file '%s' line %d: metrics must be a list", file, metrics.Line)
+	}
+
+	for _, metric := range metrics.Content {
+		name := mappingValue(metric, "name")
+		if prev, ok := l.metricFiles[name]; ok && name != "" {
+			return fmt.Errorf("metric '%s' is defined in both '%s' and '%s'", name, prev, file)
+		}
+		l.metricFiles[name] = file
+	}
+
+	l.metrics.Content = append(l.metrics.Content, metrics.Content...)
+	return nil
+}
+
+// parseConfigFile parses a config file into a YAML document. JSON is a subset of
+// YAML and parsed as is, TOML is converted. Files of an unknown format are parsed
+// as YAML.
+func parseConfigFile(data []byte, format ConfigFormat) (yaml.Node, error) {
+	var doc yaml.Node
+
+	if format != ConfigFormatToml {
+		err := yaml.Unmarshal(data, &doc)
+		return doc, err
+	}
+
+	var values map[string]any
+	if err := toml.Unmarshal(data, &values); err != nil {
+		return doc, err
+	}
+	if len(values) == 0 {
+		// Empty file, like an empty YAML document
+		return doc, nil
+	}
+
+	var root yaml.Node
+	if err := root.Encode(values); err != nil {
+		return doc, err
+	}
+
+	doc.Kind, doc.Content = yaml.DocumentNode, []*yaml.Node{&root}
+	return doc, nil
+}
+
+// mappingValue returns the scalar value of a key of a mapping node, or an empty
+// string if the node isn't a mapping or the key isn't set
+func mappingValue(node *yaml.Node, key string) string {
+	if node.Kind != yaml.MappingNode {
+		return ""
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1].Value
+		}
+	}
+	return ""
+}
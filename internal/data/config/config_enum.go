@@ -11,6 +11,182 @@ import (
 	"fmt"
 )
 
+const (
+	// ClientAuthTypeNone is a ClientAuthType of type none.
+	ClientAuthTypeNone ClientAuthType = "none"
+	// ClientAuthTypeRequest is a ClientAuthType of type request.
+	ClientAuthTypeRequest ClientAuthType = "request"
+	// ClientAuthTypeRequire is a ClientAuthType of type require.
+	ClientAuthTypeRequire ClientAuthType = "require"
+)
+
+var ErrInvalidClientAuthType = errors.New("not a valid ClientAuthType")
+
+// String implements the Stringer interface.
+func (x ClientAuthType) String() string {
+	return string(x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x ClientAuthType) IsValid() bool {
+	_, err := ParseClientAuthType(string(x))
+	return err == nil
+}
+
+var _ClientAuthTypeValue = map[string]ClientAuthType{
+	"none":    ClientAuthTypeNone,
+	"request": ClientAuthTypeRequest,
+	"require": ClientAuthTypeRequire,
+}
+
+// ParseClientAuthType attempts to convert a string to a ClientAuthType.
+func ParseClientAuthType(name string) (ClientAuthType, error) {
+	if x, ok := _ClientAuthTypeValue[name]; ok {
+		return x, nil
+	}
+	return ClientAuthType(""), fmt.Errorf("%s is %w", name, ErrInvalidClientAuthType)
+}
+
+const (
+	// ConfigFormatYaml is a ConfigFormat of type yaml.
+	ConfigFormatYaml ConfigFormat = "yaml"
+	// ConfigFormatJson is a ConfigFormat of type json.
+	ConfigFormatJson ConfigFormat = "json"
+	// ConfigFormatToml is a ConfigFormat of type toml.
+	ConfigFormatToml ConfigFormat = "toml"
+)
+
+var ErrInvalidConfigFormat = errors.New("not a valid ConfigFormat")
+
+// String implements the Stringer interface.
+func (x ConfigFormat) String() string {
+	return string(x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x ConfigFormat) IsValid() bool {
+	_, err := ParseConfigFormat(string(x))
+	return err == nil
+}
+
+var _ConfigFormatValue = map[string]ConfigFormat{
+	"yaml": ConfigFormatYaml,
+	"json": ConfigFormatJson,
+	"toml": ConfigFormatToml,
+}
+
+// ParseConfigFormat attempts to convert a string to a ConfigFormat.
+func ParseConfigFormat(name string) (ConfigFormat, error) {
+	if x, ok := _ConfigFormatValue[name]; ok {
+		return x, nil
+	}
+	return ConfigFormat(""), fmt.Errorf("%s is %w", name, ErrInvalidConfigFormat)
+}
+
+const (
+	// EvictionLru is a Eviction of type lru.
+	EvictionLru Eviction = "lru"
+)
+
+var ErrInvalidEviction = errors.New("not a valid Eviction")
+
+// String implements the Stringer interface.
+func (x Eviction) String() string {
+	return string(x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x Eviction) IsValid() bool {
+	_, err := ParseEviction(string(x))
+	return err == nil
+}
+
+var _EvictionValue = map[string]Eviction{
+	"lru": EvictionLru,
+}
+
+// ParseEviction attempts to convert a string to a Eviction.
+func ParseEviction(name string) (Eviction, error) {
+	if x, ok := _EvictionValue[name]; ok {
+		return x, nil
+	}
+	return Eviction(""), fmt.Errorf("%s is %w", name, ErrInvalidEviction)
+}
+
+const (
+	// LabelPolicyLenient is a LabelPolicy of type lenient.
+	LabelPolicyLenient LabelPolicy = "lenient"
+	// LabelPolicyStrict is a LabelPolicy of type strict.
+	LabelPolicyStrict LabelPolicy = "strict"
+)
+
+var ErrInvalidLabelPolicy = errors.New("not a valid LabelPolicy")
+
+// String implements the Stringer interface.
+func (x LabelPolicy) String() string {
+	return string(x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x LabelPolicy) IsValid() bool {
+	_, err := ParseLabelPolicy(string(x))
+	return err == nil
+}
+
+var _LabelPolicyValue = map[string]LabelPolicy{
+	"lenient": LabelPolicyLenient,
+	"strict":  LabelPolicyStrict,
+}
+
+// ParseLabelPolicy attempts to convert a string to a LabelPolicy.
+func ParseLabelPolicy(name string) (LabelPolicy, error) {
+	if x, ok := _LabelPolicyValue[name]; ok {
+		return x, nil
+	}
+	return LabelPolicy(""), fmt.Errorf("%s is %w", name, ErrInvalidLabelPolicy)
+}
+
+const (
+	// LabelSourceIp is a LabelSource of type ip.
+	LabelSourceIp LabelSource = "ip"
+	// LabelSourceHeader is a LabelSource of type header.
+	LabelSourceHeader LabelSource = "header"
+	// LabelSourceKey is a LabelSource of type key.
+	LabelSourceKey LabelSource = "key"
+)
+
+var ErrInvalidLabelSource = errors.New("not a valid LabelSource")
+
+// String implements the Stringer interface.
+func (x LabelSource) String() string {
+	return string(x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x LabelSource) IsValid() bool {
+	_, err := ParseLabelSource(string(x))
+	return err == nil
+}
+
+var _LabelSourceValue = map[string]LabelSource{
+	"ip":     LabelSourceIp,
+	"header": LabelSourceHeader,
+	"key":    LabelSourceKey,
+}
+
+// ParseLabelSource attempts to convert a string to a LabelSource.
+func ParseLabelSource(name string) (LabelSource, error) {
+	if x, ok := _LabelSourceValue[name]; ok {
+		return x, nil
+	}
+	return LabelSource(""), fmt.Errorf("%s is %w", name, ErrInvalidLabelSource)
+}
+
 const (
 	// MetricTypeGauge is a MetricType of type gauge.
 	MetricTypeGauge MetricType = "gauge"
@@ -20,6 +196,10 @@ const (
 	MetricTypeHistogram MetricType = "histogram"
 	// MetricTypeSummary is a MetricType of type summary.
 	MetricTypeSummary MetricType = "summary"
+	// MetricTypeInfo is a MetricType of type info.
+	MetricTypeInfo MetricType = "info"
+	// MetricTypeEnum is a MetricType of type enum.
+	MetricTypeEnum MetricType = "enum"
 )
 
 var ErrInvalidMetricType = errors.New("not a valid MetricType")
@@ -41,6 +221,8 @@ var _MetricTypeValue = map[string]MetricType{
 	"counter":   MetricTypeCounter,
 	"histogram": MetricTypeHistogram,
 	"summary":   MetricTypeSummary,
+	"info":      MetricTypeInfo,
+	"enum":      MetricTypeEnum,
 }
 
 // ParseMetricType attempts to convert a string to a MetricType.
@@ -50,3 +232,145 @@ func ParseMetricType(name string) (MetricType, error) {
 	}
 	return MetricType(""), fmt.Errorf("%s is %w", name, ErrInvalidMetricType)
 }
+
+const (
+	// MetricsErrorHandlingHttpError is a MetricsErrorHandling of type http_error.
+	MetricsErrorHandlingHttpError MetricsErrorHandling = "http_error"
+	// MetricsErrorHandlingContinue is a MetricsErrorHandling of type continue.
+	MetricsErrorHandlingContinue MetricsErrorHandling = "continue"
+	// MetricsErrorHandlingPanic is a MetricsErrorHandling of type panic.
+	MetricsErrorHandlingPanic MetricsErrorHandling = "panic"
+)
+
+var ErrInvalidMetricsErrorHandling = errors.New("not a valid MetricsErrorHandling")
+
+// String implements the Stringer interface.
+func (x MetricsErrorHandling) String() string {
+	return string(x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x MetricsErrorHandling) IsValid() bool {
+	_, err := ParseMetricsErrorHandling(string(x))
+	return err == nil
+}
+
+var _MetricsErrorHandlingValue = map[string]MetricsErrorHandling{
+	"http_error": MetricsErrorHandlingHttpError,
+	"continue":   MetricsErrorHandlingContinue,
+	"panic":      MetricsErrorHandlingPanic,
+}
+
+// ParseMetricsErrorHandling attempts to convert a string to a MetricsErrorHandling.
+func ParseMetricsErrorHandling(name string) (MetricsErrorHandling, error) {
+	if x, ok := _MetricsErrorHandlingValue[name]; ok {
+		return x, nil
+	}
+	return MetricsErrorHandling(""), fmt.Errorf("%s is %w", name, ErrInvalidMetricsErrorHandling)
+}
+
+const (
+	// MissingLabelsFill is a MissingLabels of type fill.
+	MissingLabelsFill MissingLabels = "fill"
+	// MissingLabelsReject is a MissingLabels of type reject.
+	MissingLabelsReject MissingLabels = "reject"
+	// MissingLabelsDrop is a MissingLabels of type drop.
+	MissingLabelsDrop MissingLabels = "drop"
+)
+
+var ErrInvalidMissingLabels = errors.New("not a valid MissingLabels")
+
+// String implements the Stringer interface.
+func (x MissingLabels) String() string {
+	return string(x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x MissingLabels) IsValid() bool {
+	_, err := ParseMissingLabels(string(x))
+	return err == nil
+}
+
+var _MissingLabelsValue = map[string]MissingLabels{
+	"fill":   MissingLabelsFill,
+	"reject": MissingLabelsReject,
+	"drop":   MissingLabelsDrop,
+}
+
+// ParseMissingLabels attempts to convert a string to a MissingLabels.
+func ParseMissingLabels(name string) (MissingLabels, error) {
+	if x, ok := _MissingLabelsValue[name]; ok {
+		return x, nil
+	}
+	return MissingLabels(""), fmt.Errorf("%s is %w", name, ErrInvalidMissingLabels)
+}
+
+const (
+	// QueueOverflowReject is a QueueOverflow of type reject.
+	QueueOverflowReject QueueOverflow = "reject"
+	// QueueOverflowDropOldest is a QueueOverflow of type drop_oldest.
+	QueueOverflowDropOldest QueueOverflow = "drop_oldest"
+)
+
+var ErrInvalidQueueOverflow = errors.New("not a valid QueueOverflow")
+
+// String implements the Stringer interface.
+func (x QueueOverflow) String() string {
+	return string(x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x QueueOverflow) IsValid() bool {
+	_, err := ParseQueueOverflow(string(x))
+	return err == nil
+}
+
+var _QueueOverflowValue = map[string]QueueOverflow{
+	"reject":      QueueOverflowReject,
+	"drop_oldest": QueueOverflowDropOldest,
+}
+
+// ParseQueueOverflow attempts to convert a string to a QueueOverflow.
+func ParseQueueOverflow(name string) (QueueOverflow, error) {
+	if x, ok := _QueueOverflowValue[name]; ok {
+		return x, nil
+	}
+	return QueueOverflow(""), fmt.Errorf("%s is %w", name, ErrInvalidQueueOverflow)
+}
+
+const (
+	// SeriesLimitActionReject is a SeriesLimitAction of type reject.
+	SeriesLimitActionReject SeriesLimitAction = "reject"
+	// SeriesLimitActionOverflow is a SeriesLimitAction of type overflow.
+	SeriesLimitActionOverflow SeriesLimitAction = "overflow"
+)
+
+var ErrInvalidSeriesLimitAction = errors.New("not a valid SeriesLimitAction")
+
+// String implements the Stringer interface.
+func (x SeriesLimitAction) String() string {
+	return string(x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x SeriesLimitAction) IsValid() bool {
+	_, err := ParseSeriesLimitAction(string(x))
+	return err == nil
+}
+
+var _SeriesLimitActionValue = map[string]SeriesLimitAction{
+	"reject":   SeriesLimitActionReject,
+	"overflow": SeriesLimitActionOverflow,
+}
+
+// ParseSeriesLimitAction attempts to convert a string to a SeriesLimitAction.
+func ParseSeriesLimitAction(name string) (SeriesLimitAction, error) {
+	if x, ok := _SeriesLimitActionValue[name]; ok {
+		return x, nil
+	}
+	return SeriesLimitAction(""), fmt.Errorf("%s is %w", name, ErrInvalidSeriesLimitAction)
+}
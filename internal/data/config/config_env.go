@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envRef matches ${VAR} and ${VAR:-default} references and the $${ escape. Other
+// ${...} sequences, like the ${1} of graphite templates, aren't references.
+var envRef = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// expandEnv replaces the environment variable references in the scalar values of
+// a parsed configuration, comments are left alone. ${VAR:-default} uses the default
+// if VAR is unset or empty, ${VAR} must be set, and $${ is a literal ${.
+func expandEnv(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode && strings.Contains(node.Value, "${") {
+		value, err := expandEnvString(node.Value)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+
+		node.Value = value
+		if node.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle) == 0 {
+			// Resolve the type of plain values again, ${PORT} may become a number
+			node.Tag = ""
+		}
+	}
+
+	for _, child := range node.Content {
+		if err := expandEnv(child); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// expandEnvString replaces the environment variable references in a string
+func expandEnvString(s string) (string, error) {
+	var (
+		b    strings.Builder
+		last int
+	)
+
+	for _, match := range envRef.FindAllStringSubmatchIndex(s, -1) {
+		b.WriteString(s[last:match[0]])
+		last = match[1]
+
+		if match[2] < 0 {
+			b.WriteString("${")
+			continue
+		}
+
+		name := s[match[2]:match[3]]
+		value, ok := os.LookupEnv(name)
+		switch {
+		case match[4] >= 0 && value == "":
+			value = s[match[6]:match[7]]
+		case !ok:
+			return "", fmt.Errorf("environment variable '%s' is not set, use ${%s:-default} for a default", name, name)
+		}
+
+		b.WriteString(value)
+	}
+
+	b.WriteString(s[last:])
+	return b.String(), nil
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// ExecCollector is a command run on an interval whose output is exposed as
+// metrics, in the Prometheus text format or as "name value" lines. The series of
+// the last successful run are exposed until the next run.
+type ExecCollector struct {
+	Name     string            `yaml:"name"`
+	Command  string            `yaml:"command"`  // Run without a shell, use sh -c for pipes and redirects
+	Args     []string          `yaml:"args"`     // Arguments of the command
+	Env      map[string]string `yaml:"env"`      // Added to the environment of serve
+	Interval time.Duration     `yaml:"interval"` // Defaults to 1m
+	Timeout  time.Duration     `yaml:"timeout"`  // Defaults to the interval
+	Labels   map[string]string `yaml:"labels"`   // Added to every series the command outputs
+}
+
+// Validate checks the exec collector and sets the defaults of the interval and the
+// timeout
+func (e *ExecCollector) Validate() error {
+	if e.Name == "" {
+		return fmt.Errorf("exec collector '%s' must have a name", e.Command)
+	}
+	if e.Command == "" {
+		return fmt.Errorf("exec collector '%s' must define a command", e.Name)
+	}
+
+	if e.Interval < 0 || e.Timeout < 0 {
+		return fmt.Errorf("exec collector '%s' interval and timeout cannot be negative", e.Name)
+	}
+	if e.Interval == 0 {
+		e.Interval = time.Minute
+	}
+	if e.Timeout == 0 {
+		e.Timeout = e.Interval
+	}
+
+	for name := range e.Labels {
+		if !model.LabelName(name).IsValidLegacy() {
+			return fmt.Errorf("exec collector '%s' label '%s' is not a valid label name", e.Name, name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var unmarshalerType = reflect.TypeFor[yaml.Unmarshaler]()
+
+// unusedFields returns the keys of a configuration that don't match a field of the
+// type they are decoded into, like "line 12: metrics[1].descripton". Values of
+// types that decode themselves aren't inspected.
+func unusedFields(node *yaml.Node, t reflect.Type, path string) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if reflect.PointerTo(t).Implements(unmarshalerType) {
+		return nil
+	}
+
+	var unused []string
+	switch {
+	case t.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+
+			field, ok := fields[key.Value]
+			if !ok {
+				unused = append(unused, fmt.Sprintf("line %d: %s", key.Line, joinPath(path, key.Value)))
+				continue
+			}
+			unused = append(unused, unusedFields(value, field.Type, joinPath(path, key.Value))...)
+		}
+	case t.Kind() == reflect.Map && node.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			unused = append(unused, unusedFields(node.Content[i+1], t.Elem(), joinPath(path, node.Content[i].Value))...)
+		}
+	case t.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode:
+		for i, item := range node.Content {
+			unused = append(unused, unusedFields(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+
+	return unused
+}
+
+// yamlFields returns the exported fields of a struct by their YAML key
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField, t.NumField())
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Graphite configures the listener accepting the Graphite plaintext protocol
+type Graphite struct {
+	Address  string            `yaml:"address"`  // TCP and UDP address to listen on, empty disables the listener
+	Mappings []GraphiteMapping `yaml:"mappings"` // Rules mapping dotted paths onto metrics, the first match wins
+}
+
+// Enabled reports whether the Graphite listener should be started
+func (g *Graphite) Enabled() bool {
+	return g.Address != ""
+}
+
+// Validate checks if the Graphite configuration is valid
+func (g *Graphite) Validate() error {
+	for i := range g.Mappings {
+		if err := g.Mappings[i].compile(); err != nil {
+			return fmt.Errorf("graphite mapping %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// GraphiteMapping maps dotted Graphite paths matching a glob onto a metric. Every *
+// in the glob matches a single path component and can be referenced in the name and
+// label values as ${1}, ${2}, and so on.
+type GraphiteMapping struct {
+	Match  string            `yaml:"match"`
+	Name   string            `yaml:"name"`
+	Labels map[string]string `yaml:"labels"`
+
+	re *regexp.Regexp // Used internally after parsing
+}
+
+// compile converts the glob into a regular expression
+func (m *GraphiteMapping) compile() error {
+	if m.Match == "" {
+		return fmt.Errorf("match cannot be empty")
+	}
+
+	if m.Name == "" {
+		return fmt.Errorf("name cannot be empty")
+	}
+
+	parts := strings.Split(m.Match, ".")
+	for i, part := range parts {
+		parts[i] = strings.ReplaceAll(regexp.QuoteMeta(part), `\*`, `([^.]+)`)
+	}
+
+	var err error
+	m.re, err = regexp.Compile("^" + strings.Join(parts, `\.`) + "$")
+	if err != nil {
+		return fmt.Errorf("invalid match '%s': %w", m.Match, err)
+	}
+
+	return nil
+}
+
+// Map returns the metric name and labels for the path if it matches the glob
+func (m *GraphiteMapping) Map(path string) (string, map[string]string, bool) {
+	match := m.re.FindStringSubmatchIndex(path)
+	if match == nil {
+		return "", nil, false
+	}
+
+	expand := func(template string) string {
+		return string(m.re.ExpandString(nil, template, path, match))
+	}
+
+	labels := make(map[string]string, len(m.Labels))
+	for k, v := range m.Labels {
+		labels[k] = expand(v)
+	}
+
+	return expand(m.Name), labels, true
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Job groups the metrics of a cron job under one name, along with the interval the
+// job is expected to report in and the notifiers notifying about it. The collector
+// exposes roll-up metrics of every job.
+type Job struct {
+	Name             string         `yaml:"name"`
+	Description      string         `yaml:"description"`
+	StandardMetrics  *bool          `yaml:"standard_metrics"`  // Create the standard metrics of the job, defaults to true
+	Labels           []string       `yaml:"labels"`            // Added to the labels of every metric of the job
+	ExpectedInterval time.Duration  `yaml:"expected_interval"` // Of the metrics of the job that don't set their own
+	ExpectedDuration time.Duration  `yaml:"expected_duration"` // Finished runs taking longer are slow, requires the standard metrics
+	TTL              time.Duration  `yaml:"ttl"`               // Of the metrics of the job that don't set their own
+	Notifiers        []string       `yaml:"notifiers"`         // Names of the notifiers notifying about the metrics of the job
+	Metrics          []MetricConfig `yaml:"metrics"`           // Moved to the metrics of the config on load
+}
+
+// Suffixes of the standard metrics of a job, named <job>_<suffix>. They are
+// maintained from the runs reported through the start and finish API.
+const (
+	JobDurationSuffix    = "_duration_seconds"
+	JobLastSuccessSuffix = "_last_success_timestamp_seconds"
+	JobExitCodeSuffix    = "_exit_code"
+	JobRunsSuffix        = "_runs_total"
+)
+
+// HasStandardMetrics reports whether the standard metrics of the job are created
+func (j Job) HasStandardMetrics() bool {
+	return j.StandardMetrics == nil || *j.StandardMetrics
+}
+
+// StandardJobMetrics returns the standard metrics of a job. They don't have the
+// labels of the job, runs are reported for the job as a whole. The duration metric
+// carries the expected duration of the job.
+func StandardJobMetrics(j Job) []MetricConfig {
+	job := j.Name
+	return []MetricConfig{
+		{
+			Name:             job + JobDurationSuffix,
+			Type:             MetricTypeGauge,
+			Description:      "Duration of the last run of the job",
+			Labels:           []string{},
+			Job:              job,
+			ExpectedDuration: j.ExpectedDuration,
+		},
+		{
+			Name:        job + JobLastSuccessSuffix,
+			Type:        MetricTypeGauge,
+			Description: "Unix time of the last successful run of the job",
+			Labels:      []string{},
+			Job:         job,
+		},
+		{
+			Name:        job + JobExitCodeSuffix,
+			Type:        MetricTypeGauge,
+			Description: "Exit code of the last run of the job",
+			Labels:      []string{},
+			Job:         job,
+		},
+		{
+			Name:        job + JobRunsSuffix,
+			Type:        MetricTypeCounter,
+			Description: "Runs of the job by result, success, failure, or timeout",
+			Labels:      []string{"result"},
+			Job:         job,
+		},
+	}
+}
+
+// expandJobs moves the metrics of the jobs to the metrics of the config with the
+// settings of their job applied, and adds them to the notifiers of their job.
+// Notifiers without a metrics filter already notify about every metric.
+func (c *Config) expandJobs() error {
+	for i := range c.Jobs {
+		job := &c.Jobs[i]
+		if job.Name == "" {
+			return fmt.Errorf("job %d must have a name", i)
+		}
+
+		names := make([]string, 0, len(job.Metrics))
+		for _, m := range job.Metrics {
+			if m.Job != "" && m.Job != job.Name {
+				return fmt.Errorf("metric '%s' of job '%s' belongs to job '%s'", m.Name, job.Name, m.Job)
+			}
+			m.Job = job.Name
+
+			for _, label := range job.Labels {
+				if !slices.Contains(m.Labels, label) {
+					m.Labels = append(m.Labels, label)
+				}
+			}
+			if m.ExpectedInterval == 0 {
+				m.ExpectedInterval = job.ExpectedInterval
+			}
+			if m.TTL == 0 {
+				m.TTL = job.TTL
+			}
+
+			c.Metrics = append(c.Metrics, m)
+			names = append(names, m.Name)
+		}
+		job.Metrics = nil
+
+		if job.ExpectedDuration < 0 {
+			return fmt.Errorf("job '%s' expected_duration cannot be negative", job.Name)
+		}
+		if job.ExpectedDuration > 0 && !job.HasStandardMetrics() {
+			return fmt.Errorf("job '%s' expected_duration requires the standard metrics", job.Name)
+		}
+
+		if job.HasStandardMetrics() {
+			for _, m := range StandardJobMetrics(*job) {
+				if slices.ContainsFunc(c.Metrics, func(metric MetricConfig) bool { return metric.Name == m.Name }) {
+					return fmt.Errorf("metric '%s' is reserved for the standard metrics of job '%s', set standard_metrics: false to define it", m.Name, job.Name)
+				}
+				c.Metrics = append(c.Metrics, m)
+				names = append(names, m.Name)
+			}
+		}
+
+		for _, name := range job.Notifiers {
+			idx := slices.IndexFunc(c.Notifiers, func(n Notifier) bool { return n.Name == name })
+			if idx < 0 {
+				return fmt.Errorf("job '%s' notifier '%s' is not defined", job.Name, name)
+			}
+
+			n := &c.Notifiers[idx]
+			if len(n.Metrics) > 0 {
+				n.Metrics = append(n.Metrics, names...)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateJobs checks that job names are unique and that metrics only belong to
+// defined jobs
+func (c *Config) validateJobs() error {
+	jobs := make(map[string]bool, len(c.Jobs))
+	for _, job := range c.Jobs {
+		if jobs[job.Name] {
+			return fmt.Errorf("duplicate job name: %s", job.Name)
+		}
+		jobs[job.Name] = true
+	}
+
+	for _, m := range c.Metrics {
+		if m.Job != "" && !jobs[m.Job] {
+			return fmt.Errorf("metric '%s' job '%s' is not defined", m.Name, m.Job)
+		}
+	}
+
+	return nil
+}
+
+// JobMetrics returns the names of the metrics of a job
+func (c *Config) JobMetrics(job string) []string {
+	var names []string
+	for _, m := range c.Metrics {
+		if m.Job == job {
+			names = append(names, m.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MetricTemplate expands into one metric per instance. Placeholders like {instance}
+// in the name, description, subsystem, job, and label defaults of the metric are
+// replaced with the values of the instance.
+type MetricTemplate struct {
+	Instances []TemplateInstance `yaml:"instances"`
+	Metric    MetricConfig       `yaml:"metric"`
+}
+
+// TemplateInstance holds the placeholder values of one instance of a metric
+// template. A plain string is the value of {instance}, a mapping sets a placeholder
+// per key, e.g., {db: users, host: db1} for {db} and {host}.
+type TemplateInstance map[string]string
+
+// UnmarshalYAML accepts a string or a mapping of strings
+func (t *TemplateInstance) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*t = TemplateInstance{"instance": value.Value}
+		return nil
+	}
+
+	var values map[string]string
+	if err := value.Decode(&values); err != nil {
+		return fmt.Errorf("template instance must be a string or a mapping of strings: %w", err)
+	}
+	*t = values
+	return nil
+}
+
+// expand returns the metric of the template for an instance
+func (m *MetricTemplate) expand(instance TemplateInstance) MetricConfig {
+	oldnew := make([]string, 0, len(instance)*2)
+	for k, v := range instance {
+		oldnew = append(oldnew, "{"+k+"}", v)
+	}
+	r := strings.NewReplacer(oldnew...)
+
+	metric := m.Metric
+	metric.Name = r.Replace(metric.Name)
+	metric.Description = r.Replace(metric.Description)
+	metric.Subsystem = r.Replace(metric.Subsystem)
+	metric.Job = r.Replace(metric.Job)
+
+	if metric.LabelDefaults != nil {
+		metric.LabelDefaults = maps.Clone(metric.LabelDefaults)
+		for k, v := range metric.LabelDefaults {
+			metric.LabelDefaults[k] = r.Replace(v)
+		}
+	}
+
+	return metric
+}
+
+// expandMetricTemplates appends the metrics of the metric templates to the metrics
+// of the config
+func (c *Config) expandMetricTemplates() error {
+	for i, tmpl := range c.MetricTemplates {
+		if tmpl.Metric.Name == "" {
+			return fmt.Errorf("metric template %d must have a metric name", i)
+		}
+		if len(tmpl.Instances) == 0 {
+			return fmt.Errorf("metric template '%s' must have instances", tmpl.Metric.Name)
+		}
+
+		for _, instance := range tmpl.Instances {
+			metric := tmpl.expand(instance)
+			if metric.Name == tmpl.Metric.Name {
+				return fmt.Errorf("metric template '%s' name has no placeholder of its instances", tmpl.Metric.Name)
+			}
+			c.Metrics = append(c.Metrics, metric)
+		}
+	}
+
+	return nil
+}
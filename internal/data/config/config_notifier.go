@@ -0,0 +1,110 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"time"
+)
+
+// NotifierType is the payload format of a notifier
+// ENUM(webhook, slack, alertmanager)
+type NotifierType string
+
+// Notifier sends a notification when a series of a metric with an expected interval
+// goes overdue, and optionally when it reports again. It covers setups where the
+// Prometheus to Alertmanager path may be down along with the cron host.
+type Notifier struct {
+	Name           string            `yaml:"name"`
+	Type           NotifierType      `yaml:"type"`     // webhook (generic JSON), slack, or alertmanager
+	URL            string            `yaml:"url"`      // Webhook URL, or the Alertmanager /api/v2/alerts endpoint
+	URLFile        string            `yaml:"url_file"` // File containing the URL, for webhook URLs embedding a secret
+	Headers        map[string]string `yaml:"headers"`
+	HeaderFiles    map[string]string `yaml:"header_files"`    // Headers whose values are read from files
+	Metrics        []string          `yaml:"metrics"`         // Metric names or globs to notify about, empty notifies about all
+	CheckInterval  time.Duration     `yaml:"check_interval"`  // How often overdue series are checked
+	RepeatInterval time.Duration     `yaml:"repeat_interval"` // Resend the notification while the series is overdue, 0 sends it once
+	SendResolved   bool              `yaml:"send_resolved"`   // Notify when an overdue series reports again or a slow job runs within its expected duration
+	SlowRuns       bool              `yaml:"slow_runs"`       // Also notify when the last run of a job exceeded its expected_duration
+	Timeout        time.Duration     `yaml:"timeout"`
+}
+
+// Validate checks the notifier configuration and applies defaults
+func (n *Notifier) Validate() error {
+	if n.Name == "" {
+		n.Name = n.URL
+	}
+	if n.Name == "" {
+		n.Name = n.URLFile
+	}
+
+	if (n.URL == "") == (n.URLFile == "") {
+		return fmt.Errorf("notifier '%s' must define exactly one of url or url_file", n.Name)
+	}
+	if n.URL != "" {
+		if err := n.validateURL(); err != nil {
+			return err
+		}
+	}
+
+	if n.Type == "" {
+		n.Type = NotifierTypeWebhook
+	}
+	if !n.Type.IsValid() {
+		return fmt.Errorf("unknown notifier '%s' type '%s'", n.Name, n.Type)
+	}
+
+	if n.CheckInterval == 0 {
+		n.CheckInterval = 30 * time.Second
+	}
+	if n.Timeout == 0 {
+		n.Timeout = 10 * time.Second
+	}
+
+	if n.CheckInterval < 0 || n.RepeatInterval < 0 || n.Timeout < 0 {
+		return fmt.Errorf("notifier '%s' durations cannot be negative", n.Name)
+	}
+
+	return nil
+}
+
+func (n *Notifier) validateURL() error {
+	u, err := url.Parse(n.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("notifier '%s' url must be an absolute http(s) URL", n.Name)
+	}
+	return nil
+}
+
+// LoadSecrets reads the URL and the header values from their files
+func (n *Notifier) LoadSecrets() error {
+	if n.URLFile != "" {
+		var err error
+		n.URL, err = readSecret(n.URLFile)
+		if err != nil {
+			return fmt.Errorf("error reading url file: %w", err)
+		}
+		if err := n.validateURL(); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	n.Headers, err = readHeaderFiles(n.Headers, n.HeaderFiles)
+	return err
+}
+
+// Notifies reports whether the notifier covers the metric
+func (n *Notifier) Notifies(metric string) bool {
+	if len(n.Metrics) == 0 {
+		return true
+	}
+
+	for _, pattern := range n.Metrics {
+		if ok, _ := path.Match(pattern, metric); ok {
+			return true
+		}
+	}
+
+	return false
+}
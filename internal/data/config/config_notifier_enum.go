@@ -0,0 +1,49 @@
+// Code generated by go-enum DO NOT EDIT.
+// Version:
+// Revision:
+// Build Date:
+// Built By:
+
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// NotifierTypeWebhook is a NotifierType of type webhook.
+	NotifierTypeWebhook NotifierType = "webhook"
+	// NotifierTypeSlack is a NotifierType of type slack.
+	NotifierTypeSlack NotifierType = "slack"
+	// NotifierTypeAlertmanager is a NotifierType of type alertmanager.
+	NotifierTypeAlertmanager NotifierType = "alertmanager"
+)
+
+var ErrInvalidNotifierType = errors.New("not a valid NotifierType")
+
+// String implements the Stringer interface.
+func (x NotifierType) String() string {
+	return string(x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x NotifierType) IsValid() bool {
+	_, err := ParseNotifierType(string(x))
+	return err == nil
+}
+
+var _NotifierTypeValue = map[string]NotifierType{
+	"webhook":      NotifierTypeWebhook,
+	"slack":        NotifierTypeSlack,
+	"alertmanager": NotifierTypeAlertmanager,
+}
+
+// ParseNotifierType attempts to convert a string to a NotifierType.
+func ParseNotifierType(name string) (NotifierType, error) {
+	if x, ok := _NotifierTypeValue[name]; ok {
+		return x, nil
+	}
+	return NotifierType(""), fmt.Errorf("%s is %w", name, ErrInvalidNotifierType)
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// overlayFile is the on-disk format of the overlay file
+type overlayFile struct {
+	Metrics []MetricConfig `yaml:"metrics"`
+}
+
+// Overlay persists metrics created at runtime so they survive restarts. The metrics
+// in the overlay file are merged into the configuration by LoadConfig.
+type Overlay struct {
+	path    string
+	mu      sync.Mutex
+	metrics []MetricConfig
+}
+
+// OpenOverlay reads the overlay file at path, a missing file is treated as empty
+func OpenOverlay(path string) (*Overlay, error) {
+	metrics, err := readOverlay(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Overlay{path: path, metrics: metrics}, nil
+}
+
+// readOverlay reads the metrics of an overlay file, a missing file is treated as empty
+func readOverlay(path string) ([]MetricConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading overlay file: %w", err)
+	}
+
+	var overlay overlayFile
+	if err := yaml.Unmarshal(data, &overlay); err != nil {
+		return nil, fmt.Errorf("error parsing overlay file: %w", err)
+	}
+
+	return overlay.Metrics, nil
+}
+
+// Add appends a metric to the overlay and writes it to disk
+func (o *Overlay) Add(metric MetricConfig) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.metrics = append(o.metrics, metric)
+	return o.save()
+}
+
+// Remove deletes a metric from the overlay, it's a no-op if the metric isn't part
+// of the overlay.
+func (o *Overlay) Remove(name string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	idx := slices.IndexFunc(o.metrics, func(m MetricConfig) bool { return m.Name == name })
+	if idx == -1 {
+		return nil
+	}
+
+	o.metrics = slices.Delete(o.metrics, idx, idx+1)
+	return o.save()
+}
+
+// save atomically writes the overlay to disk
+func (o *Overlay) save() error {
+	data, err := yaml.Marshal(overlayFile{Metrics: o.metrics})
+	if err != nil {
+		return fmt.Errorf("error encoding overlay file: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(o.path), filepath.Base(o.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error writing overlay file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("error writing overlay file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing overlay file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), o.path); err != nil {
+		return fmt.Errorf("error writing overlay file: %w", err)
+	}
+
+	return nil
+}
+
+// Check verifies that the overlay file can be written
+func (o *Overlay) Check() error {
+	tmp, err := os.CreateTemp(filepath.Dir(o.path), filepath.Base(o.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("overlay directory is not writable: %w", err)
+	}
+	_ = tmp.Close()
+	return os.Remove(tmp.Name())
+}
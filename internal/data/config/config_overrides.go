@@ -0,0 +1,30 @@
+package config
+
+// Overrides replace settings of the config files, e.g., with flags or environment
+// variables of a container deployment. Empty settings are left unchanged.
+type Overrides struct {
+	Address         string // web.address
+	InternalAddress string // web.internal_address
+	Namespace       string // global.namespace
+	OverlayFile     string // global.overlay_file
+	StateFile       string // global.state_file
+}
+
+// apply replaces the settings of the config with the set overrides
+func (o Overrides) apply(c *Config) {
+	if o.Address != "" {
+		c.Web.Address = o.Address
+	}
+	if o.InternalAddress != "" {
+		c.Web.InternalAddress = o.InternalAddress
+	}
+	if o.Namespace != "" {
+		c.Global.Namespace = o.Namespace
+	}
+	if o.OverlayFile != "" {
+		c.Global.OverlayFile = o.OverlayFile
+	}
+	if o.StateFile != "" {
+		c.Global.StateFile = o.StateFile
+	}
+}
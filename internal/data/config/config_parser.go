@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"maps"
 	"strings"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -102,6 +103,8 @@ func (f *MetricFactory) CreateMetric(metricConfig MetricConfig) (any, error) {
 			Name:       fullName,
 			Help:       metricConfig.Description,
 			Objectives: objectives,
+			MaxAge:     metricConfig.MaxAge,
+			AgeBuckets: metricConfig.AgeBuckets,
 		}
 
 		if len(labelNames) == 0 {
@@ -144,3 +147,21 @@ func sanitizeMetricName(name string) string {
 
 	return sanitized
 }
+
+// DuplicateNames returns the metrics whose fully qualified names are the same once
+// sanitized, by that name. Such metrics can't be registered together.
+func (c *Config) DuplicateNames() map[string][]string {
+	byName := make(map[string][]string, len(c.Metrics))
+	for _, m := range c.Metrics {
+		namespace := c.Global.Namespace
+		if m.Namespace != nil {
+			namespace = *m.Namespace
+		}
+
+		name := sanitizeMetricName(prometheus.BuildFQName(namespace, m.Subsystem, m.ExposedName()))
+		byName[name] = append(byName[name], m.Name)
+	}
+
+	maps.DeleteFunc(byName, func(_ string, names []string) bool { return len(names) < 2 })
+	return byName
+}
@@ -0,0 +1,196 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// maxRemoteConfigSize limits the size of a fetched configuration
+const maxRemoteConfigSize = 16 << 20
+
+// ErrConfigNotModified is returned by RemoteConfig.Fetch when the configuration
+// didn't change since the last fetch
+var ErrConfigNotModified = errors.New("config not modified")
+
+// IsRemoteConfig reports whether a config path is a URL rather than a file
+func IsRemoteConfig(path string) bool {
+	scheme, _, ok := strings.Cut(path, "://")
+	return ok && !strings.ContainsAny(scheme, `/\`)
+}
+
+// RemoteConfig fetches the configuration from an HTTP(S) URL. Fetches after the
+// first are conditional on the ETag of the last response.
+type RemoteConfig struct {
+	url     string
+	opts    LoadOptions
+	headers http.Header
+	client  *http.Client
+
+	etag string
+	hash string
+}
+
+// NewRemoteConfig creates a remote configuration source. Headers are "Name: value"
+// pairs sent with every request, e.g., for authorization. The configuration is read
+// in the format of the options, or the format of the URL's extension or content
+// type.
+func NewRemoteConfig(rawURL string, opts LoadOptions, headers []string) (*RemoteConfig, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported config URL scheme '%s', serve the config over http or https", u.Scheme)
+	}
+
+	r := &RemoteConfig{
+		url:     rawURL,
+		opts:    opts,
+		headers: make(http.Header),
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+
+	for _, header := range headers {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid config header '%s', expected 'Name: value'", header)
+		}
+		r.headers.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	return r, nil
+}
+
+// Load fetches and loads the configuration, like LoadConfig loads a file. Remote
+// configurations can't include other files.
+func (r *RemoteConfig) Load(ctx context.Context) (*Config, error) {
+	r.etag, r.hash = "", ""
+	return r.Fetch(ctx)
+}
+
+// Fetch loads the configuration if it changed since the last fetch, otherwise it
+// returns ErrConfigNotModified. Changes are detected by the ETag, or the content
+// for servers without ETags.
+func (r *RemoteConfig) Fetch(ctx context.Context) (*Config, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = r.headers.Clone()
+	if r.etag != "" {
+		req.Header.Set("If-None-Match", r.etag)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching config: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, ErrConfigNotModified
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching config: unexpected status %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteConfigSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("error fetching config: %w", err)
+	}
+	if len(data) > maxRemoteConfigSize {
+		return nil, fmt.Errorf("error fetching config: larger than %d bytes", maxRemoteConfigSize)
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	if hash == r.hash {
+		r.etag = resp.Header.Get("ETag")
+		return nil, ErrConfigNotModified
+	}
+
+	l, err := newConfigLoader(r.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	includes, err := l.merge(r.url, data, r.formatOf(resp))
+	if err != nil {
+		return nil, err
+	}
+	if len(includes) > 0 {
+		return nil, fmt.Errorf("config '%s': remote configs can't include files", r.url)
+	}
+
+	cfg, err := decodeConfig(l.result(), l.hash(), r.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	r.etag, r.hash = resp.Header.Get("ETag"), hash
+	return cfg, nil
+}
+
+// formatOf returns the format of a fetched configuration
+func (r *RemoteConfig) formatOf(resp *http.Response) ConfigFormat {
+	if r.opts.Format != "" {
+		return r.opts.Format
+	}
+
+	if u, err := url.Parse(r.url); err == nil {
+		if format, ok := configExtensions[path.Ext(u.Path)]; ok {
+			return format
+		}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case strings.HasSuffix(mediaType, "json"):
+		return ConfigFormatJson
+	case strings.HasSuffix(mediaType, "toml"):
+		return ConfigFormatToml
+	default:
+		return ConfigFormatYaml
+	}
+}
+
+// Poll fetches the configuration every interval until the context is canceled and
+// calls onChange with every changed configuration. Invalid configurations and
+// failed fetches are logged and retried at the next interval.
+func (r *RemoteConfig) Poll(ctx context.Context, interval time.Duration, onChange func(*Config)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cfg, err := r.Fetch(ctx)
+		switch {
+		case errors.Is(err, ErrConfigNotModified):
+			continue
+		case err != nil:
+			if ctx.Err() == nil {
+				log.Warn().Err(err).Str("url", r.url).Msg("error refreshing remote config")
+			}
+			continue
+		}
+
+		onChange(cfg)
+	}
+}
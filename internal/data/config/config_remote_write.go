@@ -0,0 +1,102 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// RemoteWrite configures an endpoint receiving the metrics through the Prometheus
+// remote write protocol
+type RemoteWrite struct {
+	URL             string            `yaml:"url"`
+	Interval        time.Duration     `yaml:"interval"`     // How often the registry is sent
+	Timeout         time.Duration     `yaml:"timeout"`      // Timeout of a single request
+	Headers         map[string]string `yaml:"headers"`      // Extra headers, e.g., X-Scope-OrgID
+	HeaderFiles     map[string]string `yaml:"header_files"` // Extra headers whose values are read from files
+	BasicAuth       *RemoteBasicAuth  `yaml:"basic_auth"`
+	BearerTokenFile string            `yaml:"bearer_token_file"`
+	Queue           RemoteQueue       `yaml:"queue"`
+}
+
+// RemoteBasicAuth contains the basic auth credentials of a remote endpoint
+type RemoteBasicAuth struct {
+	Username     string `yaml:"username"`
+	PasswordFile string `yaml:"password_file"`
+}
+
+// RemoteQueue controls buffering and retries of requests to a remote endpoint
+type RemoteQueue struct {
+	Capacity   int           `yaml:"capacity"`    // Requests buffered while the endpoint is unavailable, the oldest is dropped when full
+	MaxRetries int           `yaml:"max_retries"` // Retries of a failed request before it is dropped
+	MinBackoff time.Duration `yaml:"min_backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+}
+
+// Validate checks the remote write configuration and applies defaults
+func (r *RemoteWrite) Validate() error {
+	u, err := url.Parse(r.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("remote_write url '%s' must be an absolute http(s) URL", r.URL)
+	}
+
+	if r.Interval == 0 {
+		r.Interval = 30 * time.Second
+	}
+	if r.Timeout == 0 {
+		r.Timeout = 10 * time.Second
+	}
+	if r.Queue.Capacity == 0 {
+		r.Queue.Capacity = 10
+	}
+	if r.Queue.MaxRetries == 0 {
+		r.Queue.MaxRetries = 5
+	}
+	if r.Queue.MinBackoff == 0 {
+		r.Queue.MinBackoff = time.Second
+	}
+	if r.Queue.MaxBackoff == 0 {
+		r.Queue.MaxBackoff = 30 * time.Second
+	}
+
+	if r.Interval < 0 || r.Timeout < 0 || r.Queue.MinBackoff < 0 || r.Queue.MaxBackoff < 0 {
+		return fmt.Errorf("remote_write '%s' durations cannot be negative", r.URL)
+	}
+
+	if r.Queue.Capacity < 0 || r.Queue.MaxRetries < 0 {
+		return fmt.Errorf("remote_write '%s' queue capacity and max_retries cannot be negative", r.URL)
+	}
+
+	if r.BasicAuth != nil && r.BearerTokenFile != "" {
+		return fmt.Errorf("remote_write '%s' cannot use basic_auth and bearer_token_file together", r.URL)
+	}
+
+	return nil
+}
+
+// LoadAuthorization returns the value of the Authorization header, if any
+func (r *RemoteWrite) LoadAuthorization() (string, error) {
+	switch {
+	case r.BasicAuth != nil:
+		auth := MetricsAuth{Username: r.BasicAuth.Username, PasswordFile: r.BasicAuth.PasswordFile}
+		password, err := auth.LoadPassword()
+		if err != nil {
+			return "", err
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(auth.Username+":"+password)), nil
+	case r.BearerTokenFile != "":
+		token, err := readSecret(r.BearerTokenFile)
+		if err != nil {
+			return "", fmt.Errorf("error reading bearer token file: %w", err)
+		}
+		return "Bearer " + token, nil
+	default:
+		return "", nil
+	}
+}
+
+// LoadHeaders returns the extra headers, including those read from header files
+func (r *RemoteWrite) LoadHeaders() (map[string]string, error) {
+	return readHeaderFiles(r.Headers, r.HeaderFiles)
+}
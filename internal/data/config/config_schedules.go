@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Schedule is a command serve runs on a cron schedule, replacing a crontab entry
+// and the wrapper pushing its metrics. Every run is recorded in the schedule
+// metrics, labeled with the name of the schedule.
+type Schedule struct {
+	Name     string            `yaml:"name"`
+	Cron     string            `yaml:"cron"`     // Five field cron expression or a descriptor, e.g., @hourly or @every 10m
+	Command  string            `yaml:"command"`  // Run without a shell, use sh -c for pipes and redirects
+	Args     []string          `yaml:"args"`     // Arguments of the command
+	Dir      string            `yaml:"dir"`      // Working directory, defaults to the working directory of serve
+	Env      map[string]string `yaml:"env"`      // Added to the environment of serve
+	Timeout  time.Duration     `yaml:"timeout"`  // Stops the process group of the command after this long, 0 lets it run until it exits
+	Timezone string            `yaml:"timezone"` // Time zone of the cron expression, defaults to the local time zone
+}
+
+// Metrics recording the runs of the schedules, created when schedules are configured
+const (
+	ScheduleDurationMetric    = "schedule_duration_seconds"
+	ScheduleExitCodeMetric    = "schedule_exit_code"
+	ScheduleLastSuccessMetric = "schedule_last_success_timestamp_seconds"
+	ScheduleRunsMetric        = "schedule_runs_total"
+)
+
+// scheduleMetrics are the metrics of the schedules
+var scheduleMetrics = []MetricConfig{
+	{
+		Name:        ScheduleDurationMetric,
+		Type:        MetricTypeGauge,
+		Description: "Duration of the last run of the scheduled command",
+		Labels:      []string{"schedule"},
+	},
+	{
+		Name:        ScheduleExitCodeMetric,
+		Type:        MetricTypeGauge,
+		Description: "Exit code of the last run of the scheduled command, -1 if it didn't start or was killed",
+		Labels:      []string{"schedule"},
+	},
+	{
+		Name:        ScheduleLastSuccessMetric,
+		Type:        MetricTypeGauge,
+		Description: "Unix time of the last successful run of the scheduled command",
+		Labels:      []string{"schedule"},
+	},
+	{
+		Name:        ScheduleRunsMetric,
+		Type:        MetricTypeCounter,
+		Description: "Runs of the scheduled command by result, success, failure, timeout, or skipped",
+		Labels:      []string{"schedule", "result"},
+	},
+}
+
+// CronSchedule parses the cron expression of the schedule in its time zone
+func (s Schedule) CronSchedule() (cron.Schedule, error) {
+	spec := s.Cron
+	if s.Timezone != "" {
+		spec = "CRON_TZ=" + s.Timezone + " " + spec
+	}
+
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("schedule '%s' has an invalid cron expression: %w", s.Name, err)
+	}
+	return schedule, nil
+}
+
+// expandSchedules adds the metrics of the schedules, the names are reserved while
+// schedules are configured
+func (c *Config) expandSchedules() error {
+	if len(c.Schedules) == 0 {
+		return nil
+	}
+
+	for _, m := range scheduleMetrics {
+		if slices.ContainsFunc(c.Metrics, func(metric MetricConfig) bool { return metric.Name == m.Name }) {
+			return fmt.Errorf("metric '%s' is reserved for the schedules", m.Name)
+		}
+
+		m.Labels = slices.Clone(m.Labels)
+		c.Metrics = append(c.Metrics, m)
+	}
+
+	return nil
+}
+
+// validateSchedules checks that schedules have unique names, a command, and a
+// valid cron expression
+func (c *Config) validateSchedules() error {
+	names := make(map[string]bool, len(c.Schedules))
+	for i, s := range c.Schedules {
+		if s.Name == "" {
+			return fmt.Errorf("schedule %d must have a name", i)
+		}
+		if names[s.Name] {
+			return fmt.Errorf("duplicate schedule name: %s", s.Name)
+		}
+		names[s.Name] = true
+
+		if s.Command == "" {
+			return fmt.Errorf("schedule '%s' must define a command", s.Name)
+		}
+		if s.Timeout < 0 {
+			return fmt.Errorf("schedule '%s' timeout cannot be negative", s.Name)
+		}
+		if _, err := s.CronSchedule(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+
+	"github.com/hay-kot/cronprom/internal/data/schema"
+)
+
+// JSONSchema returns a JSON Schema of the configuration file, generated from the
+// configuration types
+func JSONSchema() map[string]any {
+	gen := schema.New("yaml", "#/$defs/")
+	gen.Required[reflect.TypeFor[MetricConfig]()] = []string{"name", "type"}
+	gen.Required[reflect.TypeFor[BucketGenerator]()] = []string{"type", "start", "count"}
+
+	gen.Enums[reflect.TypeFor[ClientAuthType]()] = enumValues(_ClientAuthTypeValue)
+	gen.Enums[reflect.TypeFor[Eviction]()] = enumValues(_EvictionValue)
+	gen.Enums[reflect.TypeFor[LabelPolicy]()] = enumValues(_LabelPolicyValue)
+	gen.Enums[reflect.TypeFor[LabelSource]()] = enumValues(_LabelSourceValue)
+	gen.Enums[reflect.TypeFor[MetricType]()] = enumValues(_MetricTypeValue)
+	gen.Enums[reflect.TypeFor[MetricsErrorHandling]()] = enumValues(_MetricsErrorHandlingValue)
+	gen.Enums[reflect.TypeFor[MissingLabels]()] = enumValues(_MissingLabelsValue)
+	gen.Enums[reflect.TypeFor[NotifierType]()] = enumValues(_NotifierTypeValue)
+	gen.Enums[reflect.TypeFor[QueueOverflow]()] = enumValues(_QueueOverflowValue)
+	gen.Enums[reflect.TypeFor[SeriesLimitAction]()] = enumValues(_SeriesLimitActionValue)
+
+	// Buckets are a list of bounds or a generator
+	gen.Types[reflect.TypeFor[Buckets]()] = &schema.Schema{OneOf: []*schema.Schema{
+		{Type: "array", Items: &schema.Schema{Type: "number"}},
+		gen.For(BucketGenerator{}),
+	}}
+	gen.Types[reflect.TypeFor[Objectives]()] = &schema.Schema{
+		Type:                 "object",
+		Description:          "Quantiles mapped to their allowed error",
+		AdditionalProperties: &schema.Schema{Type: "number"},
+	}
+	// Template instances are the value of {instance} or a mapping of placeholders
+	gen.Types[reflect.TypeFor[TemplateInstance]()] = &schema.Schema{OneOf: []*schema.Schema{
+		{Type: "string"},
+		{Type: "object", AdditionalProperties: &schema.Schema{Type: "string"}},
+	}}
+
+	root := gen.For(Config{})
+
+	// Includes are merged before the configuration is decoded
+	gen.Defs["Config"].Properties["include"] = &schema.Schema{
+		Type:        "array",
+		Description: "Config files to merge, relative to this file, may be globs",
+		Items:       &schema.Schema{Type: "string"},
+	}
+
+	return map[string]any{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title":   "cronprom configuration",
+		"$ref":    root.Ref,
+		"$defs":   gen.Defs,
+	}
+}
+
+// enumValues returns the values of an enumerated type in lexical order
+func enumValues[T ~string](values map[string]T) []any {
+	sorted := make([]any, 0, len(values))
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		sorted = append(sorted, values[name])
+	}
+	return sorted
+}
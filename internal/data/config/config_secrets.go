@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+)
+
+// readSecret reads a secret from a file, e.g., a Kubernetes secret or a systemd
+// credential. Surrounding whitespace is trimmed, an empty file is an error.
+func readSecret(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return "", fmt.Errorf("file '%s' is empty", path)
+	}
+
+	return secret, nil
+}
+
+// readHeaderFiles returns the headers with the values read from the header files
+// merged over them
+func readHeaderFiles(headers, files map[string]string) (map[string]string, error) {
+	if len(files) == 0 {
+		return headers, nil
+	}
+
+	merged := make(map[string]string, len(headers)+len(files))
+	maps.Copy(merged, headers)
+	for name, file := range files {
+		value, err := readSecret(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading header '%s': %w", name, err)
+		}
+		merged[name] = value
+	}
+
+	return merged, nil
+}
@@ -0,0 +1,418 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"gopkg.in/yaml.v3"
+)
+
+// sopsValue matches a value encrypted by sops
+var sopsValue = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.+),tag:(.+),type:(.+)\]$`)
+
+// sopsMACOnlyEncrypted initializes the MAC of files encrypted with
+// mac_only_encrypted, so their MAC differs from the MAC of the same values without
+var sopsMACOnlyEncrypted = []byte{0x8a, 0x3f, 0xd2, 0xad, 0x54, 0xce, 0x66, 0x52, 0x7b, 0x10, 0x34, 0xf3, 0xd1, 0x47, 0xbe, 0xb, 0xb, 0x97, 0x5b, 0x3b, 0xf4, 0x4f, 0x72, 0xc6, 0xfd, 0xad, 0xec, 0x81, 0x76, 0xf2, 0x7d, 0x69}
+
+// sopsMetadata is the sops section of an encrypted file. Only the data keys
+// encrypted for age recipients are supported.
+type sopsMetadata struct {
+	Age               []sopsAgeKey `yaml:"age"`
+	KeyGroups         []yaml.Node  `yaml:"key_groups"`
+	LastModified      string       `yaml:"lastmodified"`
+	MAC               string       `yaml:"mac"`
+	UnencryptedSuffix string       `yaml:"unencrypted_suffix"`
+	EncryptedSuffix   string       `yaml:"encrypted_suffix"`
+	UnencryptedRegex  string       `yaml:"unencrypted_regex"`
+	EncryptedRegex    string       `yaml:"encrypted_regex"`
+	MACOnlyEncrypted  bool         `yaml:"mac_only_encrypted"`
+
+	UnencryptedCommentRegex string `yaml:"unencrypted_comment_regex"`
+	EncryptedCommentRegex   string `yaml:"encrypted_comment_regex"`
+}
+
+// sopsAgeKey is the data key of a file encrypted for an age recipient
+type sopsAgeKey struct {
+	Recipient string `yaml:"recipient"`
+	Enc       string `yaml:"enc"`
+}
+
+// decrypt decrypts the values of a file encrypted by sops in place and removes the
+// sops section, files without a sops section are left as they are. The data key is
+// decrypted with the age identities of the loader.
+func (l *configLoader) decrypt(root *yaml.Node) error {
+	if root.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	idx := -1
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "sops" && mappingValue(root.Content[i+1], "mac") != "" {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		return nil
+	}
+
+	var meta sopsMetadata
+	if err := root.Content[idx+1].Decode(&meta); err != nil {
+		return fmt.Errorf("invalid sops metadata: %w", err)
+	}
+	if len(meta.KeyGroups) > 0 {
+		return errors.New("sops key groups are not supported, encrypt the file for age recipients")
+	}
+	if len(meta.Age) == 0 {
+		return errors.New("the file isn't encrypted for an age recipient, other sops key types are not supported")
+	}
+	if meta.UnencryptedCommentRegex != "" || meta.EncryptedCommentRegex != "" {
+		return errors.New("sops unencrypted_comment_regex and encrypted_comment_regex are not supported")
+	}
+
+	if l.identities == nil {
+		var err error
+		l.identities, err = ageIdentities(l.ageKeyFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	key, err := meta.dataKey(l.identities)
+	if err != nil {
+		return err
+	}
+
+	d, err := newSopsDecryptor(key, meta)
+	if err != nil {
+		return err
+	}
+
+	root.Content = slices.Delete(root.Content, idx, idx+2)
+	if err := d.walk(root, nil); err != nil {
+		return err
+	}
+
+	return d.verify()
+}
+
+// dataKey decrypts the data key of the file with the first matching identity
+func (m sopsMetadata) dataKey(identities []age.Identity) ([]byte, error) {
+	var errs []error
+	for _, k := range m.Age {
+		r, err := age.Decrypt(armor.NewReader(strings.NewReader(k.Enc)), identities...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("recipient %s: %w", k.Recipient, err))
+			continue
+		}
+		return io.ReadAll(r)
+	}
+	return nil, fmt.Errorf("error decrypting the sops data key: %w", errors.Join(errs...))
+}
+
+// ageIdentities reads the age identities of the key file, the SOPS_AGE_KEY
+// environment variable, and the default key file of sops
+func ageIdentities(keyFile string) ([]age.Identity, error) {
+	var identities []age.Identity
+
+	if key := os.Getenv("SOPS_AGE_KEY"); key != "" {
+		ids, err := age.ParseIdentities(strings.NewReader(key))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing SOPS_AGE_KEY: %w", err)
+		}
+		identities = append(identities, ids...)
+	}
+
+	explicit := keyFile != ""
+	if !explicit {
+		dir, err := os.UserConfigDir()
+		if err == nil {
+			keyFile = filepath.Join(dir, "sops", "age", "keys.txt")
+		}
+	}
+
+	if keyFile != "" {
+		f, err := os.Open(keyFile)
+		switch {
+		case err == nil:
+			defer f.Close()
+			ids, err := age.ParseIdentities(f)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing age key file '%s': %w", keyFile, err)
+			}
+			identities = append(identities, ids...)
+		case explicit || !errors.Is(err, os.ErrNotExist):
+			return nil, fmt.Errorf("error reading age key file: %w", err)
+		}
+	}
+
+	if len(identities) == 0 {
+		return nil, errors.New("no age key to decrypt the config, set --sops-age-key-file or SOPS_AGE_KEY")
+	}
+	return identities, nil
+}
+
+// sopsDecryptor decrypts the values of a file and computes the MAC of the
+// plaintext values like sops
+type sopsDecryptor struct {
+	key         []byte
+	meta        sopsMetadata
+	mac         hash.Hash
+	unencrypted *regexp.Regexp
+	encrypted   *regexp.Regexp
+}
+
+func newSopsDecryptor(key []byte, meta sopsMetadata) (*sopsDecryptor, error) {
+	d := &sopsDecryptor{key: key, meta: meta, mac: sha512.New()}
+	if meta.MACOnlyEncrypted {
+		d.mac.Write(sopsMACOnlyEncrypted)
+	}
+
+	var err error
+	if meta.UnencryptedRegex != "" {
+		if d.unencrypted, err = regexp.Compile(meta.UnencryptedRegex); err != nil {
+			return nil, fmt.Errorf("invalid sops unencrypted_regex: %w", err)
+		}
+	}
+	if meta.EncryptedRegex != "" {
+		if d.encrypted, err = regexp.Compile(meta.EncryptedRegex); err != nil {
+			return nil, fmt.Errorf("invalid sops encrypted_regex: %w", err)
+		}
+	}
+	return d, nil
+}
+
+// walk decrypts the values of a node, path holds the mapping keys leading to it,
+// list items share the path of their list. Sops stores the comments of encrypted
+// lists as items, they become comments of the following item again.
+func (d *sopsDecryptor) walk(node *yaml.Node, path []string) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			d.comments(key, path)
+			if err := d.walk(value, append(slices.Clip(path), key.Value)); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		var comments []string
+		items := node.Content[:0]
+		for _, item := range node.Content {
+			comment, ok, err := d.itemComment(item, path)
+			if err != nil {
+				return err
+			}
+			if ok {
+				comments = append(comments, "#"+comment)
+				continue
+			}
+
+			d.comments(item, path)
+			if len(comments) > 0 {
+				if item.HeadComment != "" {
+					comments = append(comments, item.HeadComment)
+				}
+				item.HeadComment = strings.Join(comments, "\n")
+				comments = nil
+			}
+			if err := d.walk(item, path); err != nil {
+				return err
+			}
+			items = append(items, item)
+		}
+		node.Content = items
+	case yaml.ScalarNode:
+		return d.scalar(node, path)
+	}
+	return nil
+}
+
+// scalar decrypts a scalar value and adds it to the MAC
+func (d *sopsDecryptor) scalar(node *yaml.Node, path []string) error {
+	if node.Tag == "!!null" {
+		return nil
+	}
+
+	encrypted := d.isEncrypted(path)
+	if encrypted && node.Value == "" {
+		// Sops leaves empty strings as they are, they add nothing to the MAC
+		return nil
+	}
+	if !encrypted {
+		if !d.meta.MACOnlyEncrypted {
+			var value any
+			if err := node.Decode(&value); err != nil {
+				return err
+			}
+			d.mac.Write(sopsBytes(value))
+		}
+		return nil
+	}
+
+	plaintext, valueType, err := d.decryptValue(node.Value, strings.Join(path, ":")+":")
+	if err != nil {
+		return fmt.Errorf("line %d: %s: %w", node.Line, strings.Join(path, "."), err)
+	}
+
+	var value any
+	switch valueType {
+	case "str", "bytes":
+		value, node.Tag = string(plaintext), "!!str"
+	case "int":
+		value, err = strconv.Atoi(string(plaintext))
+		node.Tag = "!!int"
+	case "float":
+		value, err = strconv.ParseFloat(string(plaintext), 64)
+		node.Tag = "!!float"
+	case "bool":
+		var b bool
+		b, err = strconv.ParseBool(string(plaintext))
+		value, plaintext, node.Tag = b, []byte(strconv.FormatBool(b)), "!!bool"
+	default:
+		return fmt.Errorf("line %d: %s: unknown sops value type '%s'", node.Line, strings.Join(path, "."), valueType)
+	}
+	if err != nil {
+		return fmt.Errorf("line %d: %s: %w", node.Line, strings.Join(path, "."), err)
+	}
+
+	node.Value, node.Style = string(plaintext), 0
+	d.mac.Write(sopsBytes(value))
+	return nil
+}
+
+// comments decrypts the comments preceding a node, comments that fail to decrypt
+// are kept as they are like sops does. Comments aren't part of the MAC.
+func (d *sopsDecryptor) comments(node *yaml.Node, path []string) {
+	if node.HeadComment == "" || !d.isEncrypted(path) {
+		return
+	}
+
+	lines := strings.Split(node.HeadComment, "\n")
+	for i, line := range lines {
+		if plaintext, _, err := d.decryptValue(strings.TrimPrefix(line, "#"), strings.Join(path, ":")+":"); err == nil {
+			lines[i] = "#" + string(plaintext)
+		}
+	}
+	node.HeadComment = strings.Join(lines, "\n")
+}
+
+// itemComment decrypts a list item that holds an encrypted comment
+func (d *sopsDecryptor) itemComment(item *yaml.Node, path []string) (string, bool, error) {
+	if item.Kind != yaml.ScalarNode || !d.isEncrypted(path) {
+		return "", false, nil
+	}
+	if m := sopsValue.FindStringSubmatch(item.Value); m == nil || m[4] != "comment" {
+		return "", false, nil
+	}
+
+	plaintext, _, err := d.decryptValue(item.Value, strings.Join(path, ":")+":")
+	if err != nil {
+		return "", false, fmt.Errorf("line %d: %s: %w", item.Line, strings.Join(path, "."), err)
+	}
+	return string(plaintext), true, nil
+}
+
+// isEncrypted reports whether the values of a path are encrypted according to the
+// suffixes and expressions the file was encrypted with
+func (d *sopsDecryptor) isEncrypted(path []string) bool {
+	encrypted := true
+	if d.meta.UnencryptedSuffix != "" && slices.ContainsFunc(path, func(k string) bool { return strings.HasSuffix(k, d.meta.UnencryptedSuffix) }) {
+		encrypted = false
+	}
+	if d.meta.EncryptedSuffix != "" {
+		encrypted = slices.ContainsFunc(path, func(k string) bool { return strings.HasSuffix(k, d.meta.EncryptedSuffix) })
+	}
+	if d.unencrypted != nil && slices.ContainsFunc(path, d.unencrypted.MatchString) {
+		encrypted = false
+	}
+	if d.encrypted != nil {
+		encrypted = slices.ContainsFunc(path, d.encrypted.MatchString)
+	}
+	return encrypted
+}
+
+// decryptValue decrypts an ENC[AES256_GCM,...] value, the additional data binds it
+// to its path
+func (d *sopsDecryptor) decryptValue(value, additionalData string) ([]byte, string, error) {
+	m := sopsValue.FindStringSubmatch(value)
+	if m == nil {
+		return nil, "", errors.New("value isn't encrypted by sops")
+	}
+
+	var parts [3][]byte
+	for i := range parts {
+		var err error
+		if parts[i], err = base64.StdEncoding.DecodeString(m[i+1]); err != nil {
+			return nil, "", fmt.Errorf("invalid encrypted value: %w", err)
+		}
+	}
+	data, iv, tag := parts[0], parts[1], parts[2]
+
+	block, err := aes.NewCipher(d.key)
+	if err != nil {
+		return nil, "", err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return nil, "", err
+	}
+
+	plaintext, err := gcm.Open(nil, iv, append(data, tag...), []byte(additionalData))
+	if err != nil {
+		return nil, "", errors.New("error decrypting value, the file was modified or the data key is wrong")
+	}
+	return plaintext, m[4], nil
+}
+
+// verify compares the MAC of the decrypted values with the MAC of the file, which
+// detects added, removed, or reordered values
+func (d *sopsDecryptor) verify() error {
+	modified, err := time.Parse(time.RFC3339, d.meta.LastModified)
+	if err != nil {
+		return fmt.Errorf("invalid sops lastmodified: %w", err)
+	}
+
+	mac, _, err := d.decryptValue(d.meta.MAC, modified.Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("sops mac: %w", err)
+	}
+
+	if string(mac) != fmt.Sprintf("%X", d.mac.Sum(nil)) {
+		return errors.New("sops mac mismatch, the file was modified after it was encrypted")
+	}
+	return nil
+}
+
+// sopsBytes returns the bytes of a value sops computes the MAC of
+func sopsBytes(value any) []byte {
+	switch v := value.(type) {
+	case string:
+		return []byte(v)
+	case float64:
+		return []byte(strconv.FormatFloat(v, 'f', -1, 64))
+	case bool:
+		// Capitalized for compatibility with the original Python implementation
+		if v {
+			return []byte("True")
+		}
+		return []byte("False")
+	default:
+		return []byte(fmt.Sprint(v))
+	}
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The fixtures in testdata/sops are encrypted by sops 3.9.4 for the age key in
+// key.txt, the plaintext of each fixture is kept next to it:
+//
+//	sops --encrypt --age <recipient> values.yml > values.sops.yml
+//	sops --encrypt --age <recipient> --encrypted-regex '^(token|url)$' regex.yml > regex.sops.yml
+//	sops --encrypt --age <recipient> --encrypted-regex '^token$' --mac-only-encrypted regex.yml > mac_only.sops.yml
+
+func TestDecryptSops(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		plaintext string
+		keyFile   string
+		edit      func(string) string // Modifies the encrypted file before it is decrypted
+		wantErr   string
+		comments  []string // Decrypted comments
+	}{
+		{
+			name:      "all values",
+			file:      "values.sops.yml",
+			plaintext: "values.yml",
+			comments:  []string{"# Namespace of every metric", "# The size of the backup", "# Database of the host"},
+		},
+		{
+			name:      "encrypted regex",
+			file:      "regex.sops.yml",
+			plaintext: "regex.yml",
+		},
+		{
+			name:      "mac of encrypted values only",
+			file:      "mac_only.sops.yml",
+			plaintext: "regex.yml",
+		},
+		{
+			name:      "values outside the regex changed with mac of encrypted values only",
+			file:      "mac_only.sops.yml",
+			plaintext: "regex.yml",
+			edit:      func(s string) string { return strings.Replace(s, "limit: 100", "limit: 200", 1) },
+		},
+		{
+			name:    "wrong key",
+			file:    "values.sops.yml",
+			keyFile: "wrong_key.txt",
+			wantErr: "error decrypting the sops data key",
+		},
+		{
+			name: "tampered mac",
+			file: "values.sops.yml",
+			edit: func(s string) string {
+				return strings.Replace(s, "mac: ENC[AES256_GCM,data:", "mac: ENC[AES256_GCM,data:AA", 1)
+			},
+			wantErr: "sops mac",
+		},
+		{
+			name: "unencrypted suffix value changed",
+			file: "values.sops.yml",
+			edit: func(s string) string {
+				return strings.Replace(s, "timeout_unencrypted: 10s", "timeout_unencrypted: 1h", 1)
+			},
+			wantErr: "sops mac mismatch",
+		},
+		{
+			name:    "value outside the regex changed",
+			file:    "regex.sops.yml",
+			edit:    func(s string) string { return strings.Replace(s, "admin: true", "admin: false", 1) },
+			wantErr: "sops mac mismatch",
+		},
+		{
+			name: "encrypted value removed",
+			file: "values.sops.yml",
+			edit: func(s string) string {
+				lines := strings.Split(s, "\n")
+				return strings.Join(slices.DeleteFunc(lines, func(l string) bool { return strings.Contains(l, "ratio:") }), "\n")
+			},
+			wantErr: "sops mac mismatch",
+		},
+		{
+			name: "encrypted value moved to another key",
+			file: "regex.sops.yml",
+			edit: func(s string) string {
+				return strings.Replace(s, "name: backup-host\n          token:", "name: backup-host\n          url:", 1)
+			},
+			wantErr: "error decrypting value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SOPS_AGE_KEY", "")
+
+			keyFile := tt.keyFile
+			if keyFile == "" {
+				keyFile = "key.txt"
+			}
+
+			data, err := os.ReadFile(filepath.Join("testdata", "sops", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.edit != nil {
+				edited := tt.edit(string(data))
+				if edited == string(data) {
+					t.Fatal("edit didn't change the file")
+				}
+				data = []byte(edited)
+			}
+
+			var doc yaml.Node
+			if err := yaml.Unmarshal(data, &doc); err != nil {
+				t.Fatal(err)
+			}
+
+			l := &configLoader{ageKeyFile: filepath.Join("testdata", "sops", keyFile)}
+			err = l.decrypt(doc.Content[0])
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("decrypt() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decrypt() error = %v", err)
+			}
+
+			var got, want any
+			if err := doc.Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			plaintext, err := os.ReadFile(filepath.Join("testdata", "sops", tt.plaintext))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.edit != nil {
+				plaintext = []byte(tt.edit(string(plaintext)))
+			}
+			if err := yaml.Unmarshal(plaintext, &want); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("decrypted values differ from the plaintext\ngot:  %v\nwant: %v", got, want)
+			}
+
+			out, err := yaml.Marshal(&doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, c := range tt.comments {
+				if !strings.Contains(string(out), c) {
+					t.Errorf("decrypted file is missing comment %q:\n%s", c, out)
+				}
+			}
+		})
+	}
+}
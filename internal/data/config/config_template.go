@@ -0,0 +1,91 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// templateFuncs are the functions available to config templates in addition to the
+// builtin functions
+var templateFuncs = template.FuncMap{
+	"env":   os.Getenv,
+	"quote": strconv.Quote,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"split": func(sep, s string) []string {
+		if s == "" {
+			return nil
+		}
+		return strings.Split(s, sep)
+	},
+	"join": func(sep string, elems []string) string {
+		return strings.Join(elems, sep)
+	},
+	// default returns the value unless it is empty, {{ env "PORT" | default "8080" }}
+	"default": func(def, value any) any {
+		if value == nil || value == "" {
+			return def
+		}
+		return value
+	},
+}
+
+// templateData is the data config templates are executed with
+type templateData struct {
+	Env    map[string]string // Environment variables
+	Values map[string]any    // Contents of the values file
+}
+
+// configTemplate renders config files as Go templates before they are parsed
+type configTemplate struct {
+	data templateData
+}
+
+// newConfigTemplate reads the values file, which may be empty for no values, and
+// captures the environment
+func newConfigTemplate(valuesFile string) (*configTemplate, error) {
+	t := &configTemplate{data: templateData{
+		Env:    make(map[string]string),
+		Values: make(map[string]any),
+	}}
+
+	for _, kv := range os.Environ() {
+		k, v, _ := strings.Cut(kv, "=")
+		t.data.Env[k] = v
+	}
+
+	if valuesFile == "" {
+		return t, nil
+	}
+
+	data, err := os.ReadFile(valuesFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading template values: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &t.data.Values); err != nil {
+		return nil, fmt.Errorf("error parsing template values '%s': %w", valuesFile, err)
+	}
+
+	return t, nil
+}
+
+// render executes a config file as a template. Referencing a missing key of the
+// values is an error.
+func (t *configTemplate) render(name string, data []byte) ([]byte, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing config template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, t.data); err != nil {
+		return nil, fmt.Errorf("error rendering config template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
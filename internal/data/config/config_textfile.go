@@ -0,0 +1,14 @@
+package config
+
+// Textfile exposes the metrics of the *.prom files of a directory like the textfile
+// collector of the node exporter, for jobs that can't reach cronprom over the
+// network. The files are read on every scrape, write them to a temporary file and
+// rename it so a scrape never reads a partial file.
+type Textfile struct {
+	Directory string `yaml:"directory"` // Empty disables the textfile collector
+}
+
+// Enabled reports whether the textfile collector should be started
+func (t *Textfile) Enabled() bool {
+	return t.Directory != ""
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"fmt"
+	"net"
+)
+
+// UDP configures the listener of the compact UDP push protocol
+type UDP struct {
+	Address string `yaml:"address"` // Address to listen on, empty disables the listener
+}
+
+// Enabled reports whether the UDP listener should be started
+func (u *UDP) Enabled() bool {
+	return u.Address != ""
+}
+
+// Validate checks if the UDP configuration is valid
+func (u *UDP) Validate() error {
+	if !u.Enabled() {
+		return nil
+	}
+
+	if _, err := net.ResolveUDPAddr("udp", u.Address); err != nil {
+		return fmt.Errorf("udp: invalid address '%s': %w", u.Address, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+)
+
+// watchDebounce is how long the watcher waits for more events before it reports a
+// change, editors and Kubernetes write a change in several steps
+const watchDebounce = time.Second
+
+// Watcher reports changes of the files of a configuration. It watches the
+// directories of the files rather than the files, so files replaced by a rename,
+// like the atomic writes of editors and the symlink swap of a Kubernetes ConfigMap
+// volume, are still followed.
+type Watcher struct {
+	fsw  *fsnotify.Watcher
+	path string
+
+	mu    sync.Mutex
+	files map[string]bool // Absolute paths of the watched files
+	dirs  map[string]bool // Watched directories
+}
+
+// NewWatcher returns a watcher of the configuration at path, a file or a directory
+// of config files. Call Watch to set the files to watch.
+func NewWatcher(path string) (*Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("error creating config watcher: %w", err)
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		fsw.Close()
+		return nil, err
+	}
+
+	return &Watcher{
+		fsw:   fsw,
+		path:  abs,
+		files: make(map[string]bool),
+		dirs:  make(map[string]bool),
+	}, nil
+}
+
+// Watch sets the files to watch, usually the files of the loaded configuration. The
+// directory of the configuration is always watched, so added files are noticed.
+func (w *Watcher) Watch(files []string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.files = make(map[string]bool, len(files))
+	dirs := make(map[string]bool)
+	if info, err := os.Stat(w.path); err == nil && info.IsDir() {
+		dirs[w.path] = true
+	}
+	for _, file := range files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return err
+		}
+		w.files[abs] = true
+		dirs[filepath.Dir(abs)] = true
+	}
+
+	for dir := range w.dirs {
+		if !dirs[dir] {
+			_ = w.fsw.Remove(dir)
+			delete(w.dirs, dir)
+		}
+	}
+	for dir := range dirs {
+		if w.dirs[dir] {
+			continue
+		}
+		if err := w.fsw.Add(dir); err != nil {
+			return fmt.Errorf("error watching config directory '%s': %w", dir, err)
+		}
+		w.dirs[dir] = true
+	}
+
+	return nil
+}
+
+// Run calls onChange once the watched files stopped changing for a moment, until the
+// watcher is closed
+func (w *Watcher) Run(onChange func()) {
+	timer := time.NewTimer(watchDebounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			if w.relevant(event) {
+				timer.Reset(watchDebounce)
+			}
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			log.Warn().Err(err).Str("path", w.path).Msg("error watching config files")
+		case <-timer.C:
+			onChange()
+		}
+	}
+}
+
+// relevant reports whether an event may change the configuration, an event of a
+// watched file, a config file of the config directory, or the data symlink of a
+// Kubernetes volume
+func (w *Watcher) relevant(event fsnotify.Event) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	name := filepath.Clean(event.Name)
+	if w.files[name] || strings.HasPrefix(filepath.Base(name), "..") {
+		return true
+	}
+
+	_, ok := configExtensions[filepath.Ext(name)]
+	return ok && filepath.Dir(name) == w.path
+}
+
+// Close stops watching the files, Run returns
+func (w *Watcher) Close() error {
+	return w.fsw.Close()
+}
@@ -0,0 +1,142 @@
+// Package schema generates JSON Schema documents from Go types so API and config
+// documentation is derived from the structs that are actually decoded.
+package schema
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is a subset of JSON Schema, compatible with OpenAPI 3.1
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+var (
+	timeType     = reflect.TypeFor[time.Time]()
+	durationType = reflect.TypeFor[time.Duration]()
+)
+
+// Generator builds schemas for Go types. Named struct types are added to Defs and
+// referenced by RefPrefix + name.
+type Generator struct {
+	Tag          string                    // Struct tag used for property names, e.g., json or yaml
+	RefPrefix    string                    // Prefix of references, e.g., #/components/schemas/
+	Enums        map[reflect.Type][]any    // Allowed values of enumerated types
+	Descriptions map[reflect.Type]string   // Descriptions of types
+	Defs         map[string]*Schema        // Definitions of named struct types
+	Required     map[reflect.Type][]string // Required properties of struct types
+	Types        map[reflect.Type]*Schema  // Schemas of types that decode themselves
+}
+
+// New creates a generator using the given struct tag and reference prefix
+func New(tag, refPrefix string) *Generator {
+	return &Generator{
+		Tag:          tag,
+		RefPrefix:    refPrefix,
+		Enums:        map[reflect.Type][]any{},
+		Descriptions: map[reflect.Type]string{},
+		Defs:         map[string]*Schema{},
+		Required:     map[reflect.Type][]string{},
+		Types:        map[reflect.Type]*Schema{},
+	}
+}
+
+// For returns the schema of v's type
+func (g *Generator) For(v any) *Schema {
+	return g.schema(reflect.TypeOf(v))
+}
+
+func (g *Generator) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if s, ok := g.Types[t]; ok {
+		return s
+	}
+
+	if values, ok := g.Enums[t]; ok {
+		return &Schema{Type: "string", Enum: values, Description: g.Descriptions[t]}
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case durationType:
+		return &Schema{Type: "string", Format: "duration", Description: "Go duration string, e.g., 30s or 5m"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		return g.structSchema(t)
+	default:
+		return &Schema{}
+	}
+}
+
+func (g *Generator) structSchema(t reflect.Type) *Schema {
+	name := t.Name()
+	if name != "" {
+		if _, ok := g.Defs[name]; ok {
+			return &Schema{Ref: g.RefPrefix + name}
+		}
+		// Reserve the name to support recursive types
+		g.Defs[name] = &Schema{}
+	}
+
+	s := &Schema{
+		Type:        "object",
+		Description: g.Descriptions[t],
+		Properties:  map[string]*Schema{},
+		Required:    g.Required[t],
+	}
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		prop, _, _ := strings.Cut(field.Tag.Get(g.Tag), ",")
+		if prop == "-" {
+			continue
+		}
+
+		if prop == "" {
+			prop = field.Name
+		}
+
+		s.Properties[prop] = g.schema(field.Type)
+	}
+
+	if name == "" {
+		return s
+	}
+
+	g.Defs[name] = s
+	return &Schema{Ref: g.RefPrefix + name}
+}
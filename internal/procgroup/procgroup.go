@@ -0,0 +1,4 @@
+// Package procgroup runs commands in process groups of their own, so a timeout
+// or an interrupt reaches the children a command started, like the commands of
+// a shell script.
+package procgroup
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package procgroup
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Set is a no-op, process groups are only used on unix
+func Set(*exec.Cmd) {}
+
+// Signal kills the process, other platforms can't signal process groups
+func Signal(p *os.Process, _ os.Signal) error {
+	return p.Kill()
+}
@@ -5,8 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"maps"
+	"math"
 	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/hay-kot/cronprom/internal/data/config"
 	"github.com/prometheus/client_golang/prometheus"
@@ -15,13 +18,66 @@ import (
 
 // MetricCollector manages all metrics defined in the configuration
 type MetricCollector struct {
-	config     *config.Config
-	registry   *prometheus.Registry
-	gauges     map[string]*prometheus.GaugeVec
-	counters   map[string]*prometheus.CounterVec
-	histograms map[string]*prometheus.HistogramVec
-	summaries  map[string]*prometheus.SummaryVec
-	mutex      sync.RWMutex
+	config        atomic.Pointer[config.Config] // Replaced by Reconcile
+	base          *prometheus.Registry          // Registry of the caller, holds the metrics describing the collector
+	registry      *prometheus.Registry          // Registry the metrics are registered with, replaced by Reconcile
+	gauges        map[string]*prometheus.GaugeVec
+	counters      map[string]*prometheus.CounterVec
+	histograms    map[string]*prometheus.HistogramVec
+	summaries     map[string]*prometheus.SummaryVec
+	infos         map[string]*infoMetric
+	enums         map[string]*enumMetric
+	slowRuns      map[string]*slowRunMetrics
+	lastPush      map[string]*prometheus.GaugeVec // <name>_last_push_timestamp_seconds of tracked metrics
+	intervals     map[string]*intervalCollector   // Overdue metrics of metrics with an expected interval
+	limitExceeded *prometheus.CounterVec          // Updates beyond the series limit of a metric
+	jobs          *jobCollector                   // Roll-up metrics of the jobs, nil without jobs
+	runs          jobRuns                         // Runs of the jobs reported through the start and finish API
+	gatherers     []ExternalGatherer              // Metrics from outside the collector, like exec collectors
+	metrics       []config.MetricConfig           // registered metrics in configuration order
+	removed       map[string]MetricState          // State of the metrics removed by a failed Reconcile
+	ttlChanged    chan struct{}                   // Signals RunExpiry that the TTLs may have changed
+	mutex         sync.RWMutex                    // Guards registration, pushes don't take it
+
+	// Registered metrics by name. The map is replaced on registration and never
+	// modified, so pushes look up metrics without locking.
+	byName atomic.Pointer[map[string]*registeredMetric]
+
+	// The metrics registry gathered by scrapes, it is only replaced once Reconcile
+	// registered all metrics with a new registry
+	exposed atomic.Pointer[prometheus.Registry]
+}
+
+// registeredMetric is a registered metric along with everything a push looks up.
+// It is built once at registration and never modified, only the runtime state of
+// the metric changes under its own lock.
+type registeredMetric struct {
+	cfg    config.MetricConfig
+	labels map[string]struct{} // Configured label names
+
+	// Vector of the metric type, info and enum metrics set theirs instead
+	gauge     *prometheus.GaugeVec
+	counter   *prometheus.CounterVec
+	histogram *prometheus.HistogramVec
+	summary   *prometheus.SummaryVec
+	info      *infoMetric
+	enum      *enumMetric
+
+	slowRun  *slowRunMetrics      // nil without an expected duration
+	lastPush *prometheus.GaugeVec // nil unless the last push is tracked
+
+	state *metricState
+}
+
+// MetricInfo describes a configured metric and its runtime state
+type MetricInfo struct {
+	Name        string            `json:"name"`
+	Type        config.MetricType `json:"type"`
+	Description string            `json:"description"`
+	Labels      []string          `json:"labels"`
+	Job         string            `json:"job,omitempty"`
+	Series      int               `json:"series"`
+	LastPush    *time.Time        `json:"last_push,omitempty"`
 }
 
 // NewMetricCollector creates a new metric collector
@@ -31,58 +87,173 @@ func NewMetricCollector(cfg *config.Config, registry *prometheus.Registry) (*Met
 	}
 
 	collector := &MetricCollector{
-		config:     cfg,
-		registry:   registry,
-		gauges:     make(map[string]*prometheus.GaugeVec),
-		counters:   make(map[string]*prometheus.CounterVec),
-		histograms: make(map[string]*prometheus.HistogramVec),
-		summaries:  make(map[string]*prometheus.SummaryVec),
+		base:          registry,
+		registry:      prometheus.NewRegistry(),
+		gauges:        make(map[string]*prometheus.GaugeVec),
+		counters:      make(map[string]*prometheus.CounterVec),
+		histograms:    make(map[string]*prometheus.HistogramVec),
+		summaries:     make(map[string]*prometheus.SummaryVec),
+		infos:         make(map[string]*infoMetric),
+		enums:         make(map[string]*enumMetric),
+		slowRuns:      make(map[string]*slowRunMetrics),
+		lastPush:      make(map[string]*prometheus.GaugeVec),
+		intervals:     make(map[string]*intervalCollector),
+		limitExceeded: newLimitExceeded(),
+		ttlChanged:    make(chan struct{}, 1),
 	}
+	collector.config.Store(cfg)
+	collector.exposed.Store(collector.registry)
+	collector.byName.Store(&map[string]*registeredMetric{})
 
 	// Register metrics from config
 	if err := collector.registerMetrics(); err != nil {
 		return nil, err
 	}
 
+	if err := registry.Register(seriesCollector{collector}); err != nil {
+		return nil, fmt.Errorf("failed to register series metric: %w", err)
+	}
+
+	if err := registry.Register(collector.limitExceeded); err != nil {
+		return nil, fmt.Errorf("failed to register series limit metric: %w", err)
+	}
+
+	if err := collector.registerJobs(); err != nil {
+		return nil, err
+	}
+
 	return collector, nil
 }
 
-// cleanLabels returns a list of labels with fillers for missing labels, labels are assumed
-// to be in order.
-func (c *MetricCollector) cleanLabels(metricName string, labels map[string]string) (map[string]string, error) {
-	const Filler = "<missing>"
-
-	for _, metricCfg := range c.config.Metrics {
-		if metricCfg.Name == metricName {
-			// Check if all labels are present
-			for _, label := range metricCfg.Labels {
-				if _, exists := labels[label]; !exists {
-					// Fill missing label
-					labels[label] = Filler
-					log.Info().Str("metric", metricName).Str("label", label).Msg("adding missing label")
-				}
+// metricConfig returns the configuration of a metric
+func (c *MetricCollector) metricConfig(name string) (config.MetricConfig, bool) {
+	m, ok := c.registered(name)
+	if !ok {
+		return config.MetricConfig{}, false
+	}
+	return m.cfg, true
+}
+
+// registered returns a registered metric by name without locking
+func (c *MetricCollector) registered(name string) (*registeredMetric, bool) {
+	m, ok := (*c.byName.Load())[name]
+	return m, ok
+}
+
+// storeRegistered replaces the registered metric of a name, a nil metric removes
+// it. The caller must hold the write lock on mutex or be the only one registering
+// metrics.
+func (c *MetricCollector) storeRegistered(name string, m *registeredMetric) {
+	byName := maps.Clone(*c.byName.Load())
+	if m == nil {
+		delete(byName, name)
+	} else {
+		byName[name] = m
+	}
+	c.byName.Store(&byName)
+}
+
+// Metrics returns all configured metrics along with their runtime state
+func (c *MetricCollector) Metrics() []MetricInfo {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	infos := make([]MetricInfo, 0, len(c.metrics))
+	for _, metricCfg := range c.metrics {
+		infos = append(infos, c.info(metricCfg))
+	}
+
+	return infos
+}
+
+// Info returns a single metric along with its runtime state
+func (c *MetricCollector) Info(name string) (MetricInfo, error) {
+	metricCfg, ok := c.metricConfig(name)
+	if !ok {
+		return MetricInfo{}, fmt.Errorf("%w: %s", ErrMetricNotFound, name)
+	}
+
+	return c.info(metricCfg), nil
+}
+
+// info describes a metric
+func (c *MetricCollector) info(metricCfg config.MetricConfig) MetricInfo {
+	info := MetricInfo{
+		Name:        metricCfg.Name,
+		Type:        metricCfg.Type,
+		Description: metricCfg.Description,
+		Labels:      metricCfg.Labels,
+		Job:         metricCfg.Job,
+	}
+
+	if info.Labels == nil {
+		info.Labels = []string{}
+	}
+
+	if m, ok := c.registered(metricCfg.Name); ok {
+		m.state.mu.Lock()
+		defer m.state.mu.Unlock()
+
+		if !m.state.lastPush.IsZero() {
+			lastPush := m.state.lastPush
+			info.LastPush = &lastPush
+		}
+		info.Series = len(m.state.series)
+	}
+
+	return info
+}
+
+// cleanLabels returns the configured labels of a metric with defaults or fillers for
+// missing labels and without extra labels. The given labels aren't modified and may
+// be nil. Missing labels of metrics that reject or drop them return an
+// ErrLabelMismatch or ErrSampleDropped.
+func (c *MetricCollector) cleanLabels(m *registeredMetric, labels map[string]string) (map[string]string, error) {
+	metricCfg := m.cfg
+	metricName := metricCfg.Name
+
+	if c.labelPolicy(metricCfg) == config.LabelPolicyStrict {
+		if err := checkLabels(metricName, metricCfg.Labels, labels, metricCfg.LabelDefaults); err != nil {
+			return nil, err
+		}
+	}
+
+	cleaned := make(map[string]string, len(metricCfg.Labels))
+	for _, label := range metricCfg.Labels {
+		value, exists := labels[label]
+		if !exists {
+			value, exists = metricCfg.LabelDefaults[label]
+		}
+
+		if !exists {
+			switch c.missingLabels(metricCfg) {
+			case config.MissingLabelsReject:
+				return nil, fmt.Errorf("%w: metric '%s' requires label '%s'", ErrLabelMismatch, metricName, label)
+			case config.MissingLabelsDrop:
+				return nil, fmt.Errorf("%w: metric '%s' is missing label '%s'", ErrSampleDropped, metricName, label)
 			}
 
-			// Remove extra labels
-			maps.DeleteFunc(labels, func(key string, _ string) bool {
-				toRemove := !slices.Contains(metricCfg.Labels, key)
-				if toRemove {
-					log.Info().Str("metric", metricName).Str("label", key).Msg("removing extra label")
-				}
+			// Fill missing label
+			value = c.labelFiller(metricCfg)
+			log.Info().Str("metric", metricName).Str("label", label).Msg("adding missing label")
+		}
 
-				return toRemove
-			})
+		cleaned[label] = value
+	}
 
-			return labels, nil
+	// Log extra labels, strict metrics rejected them already
+	for key := range labels {
+		if _, ok := m.labels[key]; !ok {
+			log.Info().Str("metric", metricName).Str("label", key).Msg("removing extra label")
 		}
 	}
 
-	return nil, fmt.Errorf("metric '%s' not found", metricName)
+	return cleaned, nil
 }
 
 // registerMetrics creates and registers all metrics defined in the configuration
 func (c *MetricCollector) registerMetrics() error {
-	for _, metricCfg := range c.config.Metrics {
+	for _, metricCfg := range c.config.Load().Metrics {
 		if err := c.registerMetric(metricCfg); err != nil {
 			return err
 		}
@@ -92,14 +263,17 @@ func (c *MetricCollector) registerMetrics() error {
 
 // registerMetric creates and registers a single metric
 func (c *MetricCollector) registerMetric(metricCfg config.MetricConfig) error {
-	namespace := c.config.Global.Namespace
+	namespace := c.namespace(metricCfg)
+	subsystem := metricCfg.Subsystem
 	metricName := metricCfg.Name
+	exposedName := metricCfg.ExposedName()
 
 	switch metricCfg.Type {
 	case config.MetricTypeGauge:
 		opts := prometheus.GaugeOpts{
 			Namespace: namespace,
-			Name:      metricName,
+			Subsystem: subsystem,
+			Name:      exposedName,
 			Help:      metricCfg.Description,
 		}
 		gaugeVec := prometheus.NewGaugeVec(opts, metricCfg.Labels)
@@ -111,7 +285,8 @@ func (c *MetricCollector) registerMetric(metricCfg config.MetricConfig) error {
 	case config.MetricTypeCounter:
 		opts := prometheus.CounterOpts{
 			Namespace: namespace,
-			Name:      metricName,
+			Subsystem: subsystem,
+			Name:      exposedName,
 			Help:      metricCfg.Description,
 		}
 		counterVec := prometheus.NewCounterVec(opts, metricCfg.Labels)
@@ -123,7 +298,8 @@ func (c *MetricCollector) registerMetric(metricCfg config.MetricConfig) error {
 	case config.MetricTypeHistogram:
 		opts := prometheus.HistogramOpts{
 			Namespace: namespace,
-			Name:      metricName,
+			Subsystem: subsystem,
+			Name:      exposedName,
 			Help:      metricCfg.Description,
 			Buckets:   metricCfg.Buckets,
 		}
@@ -136,9 +312,12 @@ func (c *MetricCollector) registerMetric(metricCfg config.MetricConfig) error {
 	case config.MetricTypeSummary:
 		opts := prometheus.SummaryOpts{
 			Namespace:  namespace,
-			Name:       metricName,
+			Subsystem:  subsystem,
+			Name:       exposedName,
 			Help:       metricCfg.Description,
 			Objectives: metricCfg.Objectives,
+			MaxAge:     metricCfg.MaxAge,
+			AgeBuckets: metricCfg.AgeBuckets,
 		}
 		summaryVec := prometheus.NewSummaryVec(opts, metricCfg.Labels)
 		if err := c.registry.Register(summaryVec); err != nil {
@@ -146,34 +325,298 @@ func (c *MetricCollector) registerMetric(metricCfg config.MetricConfig) error {
 		}
 		c.summaries[metricName] = summaryVec
 
+	case config.MetricTypeInfo:
+		if err := c.registerInfo(metricCfg); err != nil {
+			return err
+		}
+
+	case config.MetricTypeEnum:
+		if err := c.registerEnum(metricCfg); err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("unsupported metric type: %s", metricCfg.Type)
+	}
+
+	if metricCfg.ExpectedDuration > 0 {
+		if err := c.registerSlowRun(metricCfg); err != nil {
+			c.unregisterMetric(metricCfg)
+			return err
+		}
+	}
+
+	if metricCfg.ExpectedInterval > 0 {
+		if err := c.registerInterval(metricCfg); err != nil {
+			c.unregisterMetric(metricCfg)
+			return err
+		}
+	}
+
+	if metricCfg.TrackLastPush || c.config.Load().Global.TrackLastPush {
+		if err := c.registerLastPush(metricCfg); err != nil {
+			c.unregisterMetric(metricCfg)
+			return err
+		}
+	}
+
+	c.metrics = append(c.metrics, metricCfg)
+
+	labels := make(map[string]struct{}, len(metricCfg.Labels))
+	for _, label := range metricCfg.Labels {
+		labels[label] = struct{}{}
+	}
+	c.storeRegistered(metricName, &registeredMetric{
+		cfg:       metricCfg,
+		labels:    labels,
+		gauge:     c.gauges[metricName],
+		counter:   c.counters[metricName],
+		histogram: c.histograms[metricName],
+		summary:   c.summaries[metricName],
+		info:      c.infos[metricName],
+		enum:      c.enums[metricName],
+		slowRun:   c.slowRuns[metricName],
+		lastPush:  c.lastPush[metricName],
+		state:     &metricState{series: make(map[string]*series)},
+	})
+
+	return nil
+}
+
+// namespace returns the namespace of a metric, metrics may override the global
+// namespace or opt out of it with an empty namespace
+func (c *MetricCollector) namespace(metricCfg config.MetricConfig) string {
+	if metricCfg.Namespace != nil {
+		return *metricCfg.Namespace
+	}
+	return c.config.Load().Global.Namespace
+}
+
+// fqName returns the fully qualified name of a companion metric, the suffix is
+// appended to the configured name of the metric
+func (c *MetricCollector) fqName(metricCfg config.MetricConfig, suffix string) string {
+	return prometheus.BuildFQName(c.namespace(metricCfg), metricCfg.Subsystem, metricCfg.Name+suffix)
+}
+
+// exposedFQName returns the fully qualified name the metric is exposed under,
+// including its unit
+func (c *MetricCollector) exposedFQName(metricCfg config.MetricConfig) string {
+	return prometheus.BuildFQName(c.namespace(metricCfg), metricCfg.Subsystem, metricCfg.ExposedName())
+}
+
+// HasMetric reports whether a metric with the given name and type is registered
+func (c *MetricCollector) HasMetric(name string, metricType config.MetricType) bool {
+	m, ok := c.registered(name)
+	return ok && m.cfg.Type == metricType
+}
+
+// Apply updates a metric according to its configured type. Gauges are set, counters
+// are incremented, histograms and summaries observe the value, info metrics
+// replace the values of their info labels, and enum metrics activate a state.
+func (c *MetricCollector) Apply(name string, value float64, labels map[string]string) error {
+	metricCfg, ok := c.metricConfig(name)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrMetricNotFound, name)
+	}
+
+	switch metricCfg.Type {
+	case config.MetricTypeGauge:
+		return c.UpdateGauge(name, value, labels)
+	case config.MetricTypeCounter:
+		return c.IncrementCounterBy(name, value, labels)
+	case config.MetricTypeHistogram:
+		return c.ObserveHistogram(name, value, labels)
+	case config.MetricTypeSummary:
+		return c.ObserveSummary(name, value, labels)
+	case config.MetricTypeInfo:
+		return c.SetInfo(name, labels)
+	case config.MetricTypeEnum:
+		return c.SetState(name, labels)
 	default:
 		return fmt.Errorf("unsupported metric type: %s", metricCfg.Type)
 	}
+}
+
+// DynamicMetrics reports whether unknown metrics are created on their first push
+func (c *MetricCollector) DynamicMetrics() bool {
+	return c.config.Load().Global.AllowDynamicMetrics
+}
+
+// ValidateMetric validates the configuration of a metric added at runtime
+func (c *MetricCollector) ValidateMetric(metricCfg config.MetricConfig) error {
+	if err := metricCfg.Validate(); err != nil {
+		return err
+	}
+
+	return metricCfg.ValidateTTL(c.config.Load().Global.TTL)
+}
+
+// AddMetric validates and registers a new metric at runtime
+func (c *MetricCollector) AddMetric(metricCfg config.MetricConfig) error {
+	if err := c.ValidateMetric(metricCfg); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, exists := c.registered(metricCfg.Name); exists {
+		return fmt.Errorf("%w: %s", ErrMetricExists, metricCfg.Name)
+	}
+
+	if err := c.registerMetric(metricCfg); err != nil {
+		return err
+	}
+	c.notifyTTLChanged()
+
+	log.Info().Str("metric", metricCfg.Name).Str("type", metricCfg.Type.String()).Msg("metric added")
+	return nil
+}
+
+// RemoveMetric unregisters a metric and all of its series. A metric of the same name
+// may be added again afterwards, with other help or labels.
+func (c *MetricCollector) RemoveMetric(name string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.removeMetric(name) {
+		return fmt.Errorf("%w: %s", ErrMetricNotFound, name)
+	}
+
+	// A registry keeps the descriptors of unregistered metrics and rejects metrics
+	// of the same name with other help or labels
+	if err := c.rebuildRegistry(); err != nil {
+		return err
+	}
+
+	log.Info().Str("metric", name).Msg("metric removed")
+	return nil
+}
+
+// removeMetric unregisters a metric, it reports false if the metric doesn't exist.
+// The caller must hold the write lock on mutex.
+func (c *MetricCollector) removeMetric(name string) bool {
+	idx := slices.IndexFunc(c.metrics, func(m config.MetricConfig) bool { return m.Name == name })
+	if idx == -1 {
+		return false
+	}
+
+	c.unregisterMetric(c.metrics[idx])
+	c.metrics = slices.Delete(c.metrics, idx, idx+1)
+	c.storeRegistered(name, nil)
+
+	c.limitExceeded.DeletePartialMatch(prometheus.Labels{"metric": name})
+	return true
+}
+
+// collectors returns the registered collectors of a metric and its companion
+// metrics
+func (c *MetricCollector) collectors(name string) []prometheus.Collector {
+	var colls []prometheus.Collector
+
+	if gauge, ok := c.gauges[name]; ok {
+		colls = append(colls, gauge)
+	}
+	if counter, ok := c.counters[name]; ok {
+		colls = append(colls, counter)
+	}
+	if histogram, ok := c.histograms[name]; ok {
+		colls = append(colls, histogram)
+	}
+	if summary, ok := c.summaries[name]; ok {
+		colls = append(colls, summary)
+	}
+	if info, ok := c.infos[name]; ok {
+		colls = append(colls, info.vec)
+	}
+	if enum, ok := c.enums[name]; ok {
+		colls = append(colls, enum.vec)
+	}
+	if slow, ok := c.slowRuns[name]; ok {
+		colls = append(colls, slow.last, slow.total)
+	}
+	if lastPush, ok := c.lastPush[name]; ok {
+		colls = append(colls, lastPush)
+	}
+	if interval, ok := c.intervals[name]; ok {
+		colls = append(colls, interval)
+	}
 
+	return colls
+}
+
+// registerCollectors registers the collectors of a registered metric and its
+// companion metrics with a registry
+func (c *MetricCollector) registerCollectors(registry *prometheus.Registry, name string) error {
+	for _, coll := range c.collectors(name) {
+		if err := registry.Register(coll); err != nil {
+			return fmt.Errorf("failed to register '%s': %w", name, err)
+		}
+	}
 	return nil
 }
 
-// GetRegistry returns the Prometheus registry
+// rebuildRegistry registers the metrics and the job metrics with a new registry
+// that replaces the exposed one. The caller must hold the write lock on mutex.
+func (c *MetricCollector) rebuildRegistry() error {
+	registry := prometheus.NewRegistry()
+
+	var errs []error
+	for _, metricCfg := range c.metrics {
+		if err := c.registerCollectors(registry, metricCfg.Name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.jobs != nil {
+		if err := registry.Register(c.jobs); err != nil {
+			errs = append(errs, fmt.Errorf("failed to register job metrics: %w", err))
+		}
+	}
+
+	c.registry = registry
+	c.exposed.Store(registry)
+	return errors.Join(errs...)
+}
+
+// unregisterMetric removes a metric and its companion metrics from the registry
+// and the collector maps
+func (c *MetricCollector) unregisterMetric(metricCfg config.MetricConfig) {
+	name := metricCfg.Name
+
+	for _, coll := range c.collectors(name) {
+		c.registry.Unregister(coll)
+	}
+
+	delete(c.gauges, name)
+	delete(c.counters, name)
+	delete(c.histograms, name)
+	delete(c.summaries, name)
+	delete(c.infos, name)
+	delete(c.enums, name)
+	delete(c.slowRuns, name)
+	delete(c.lastPush, name)
+	delete(c.intervals, name)
+}
+
+// GetRegistry returns the Prometheus registry the collector was created with
 func (c *MetricCollector) GetRegistry() *prometheus.Registry {
-	return c.registry
+	return c.base
 }
 
 // UpdateGauge updates a gauge metric with the given value and labels
 func (c *MetricCollector) UpdateGauge(name string, value float64, labels map[string]string) error {
-	c.mutex.RLock()
-	gauge, exists := c.gauges[name]
-	c.mutex.RUnlock()
-
-	if !exists {
+	m, exists := c.registered(name)
+	if !exists || m.gauge == nil {
 		return fmt.Errorf("gauge metric '%s' not found", name)
 	}
 
-	labelsWithFillers, err := c.cleanLabels(name, labels)
+	labelsWithFillers, err := c.seriesLabels(m, labels)
 	if err != nil {
 		return err
 	}
 
-	gauge.With(labelsWithFillers).Set(value)
+	m.gauge.With(labelsWithFillers).Set(value)
+	c.touch(m, labelsWithFillers)
 	return nil
 }
 
@@ -184,57 +627,85 @@ func (c *MetricCollector) IncrementCounter(name string, labels map[string]string
 
 // IncrementCounterBy increments a counter metric by the given value with the given labels
 func (c *MetricCollector) IncrementCounterBy(name string, value float64, labels map[string]string) error {
-	c.mutex.RLock()
-	counter, exists := c.counters[name]
-	c.mutex.RUnlock()
+	return c.IncrementCounterWithExemplar(name, value, labels, nil)
+}
 
-	if !exists {
+// IncrementCounterWithExemplar increments a counter metric and attaches the exemplar
+// to the increment, a nil exemplar is a plain increment. The exemplar must pass
+// ValidateExemplar. Negative and non-finite increments return ErrInvalidValue.
+func (c *MetricCollector) IncrementCounterWithExemplar(name string, value float64, labels, exemplar map[string]string) error {
+	m, exists := c.registered(name)
+	if !exists || m.counter == nil {
 		return fmt.Errorf("counter metric '%s' not found", name)
 	}
 
-	labelsWithFillers, err := c.cleanLabels(name, labels)
+	// Counters panic on a negative increment and stay NaN after a NaN one
+	if !(value >= 0) || math.IsInf(value, 1) {
+		return fmt.Errorf("%w: counter '%s' can't be incremented by %v", ErrInvalidValue, name, value)
+	}
+
+	if exemplar == nil && m.incrementCached(value, labels) {
+		return nil
+	}
+
+	generation := m.state.generation.Load()
+	labelsWithFillers, err := c.seriesLabels(m, labels)
 	if err != nil {
 		return err
 	}
 
-	counter.With(labelsWithFillers).Add(value)
+	counter := m.counter.With(labelsWithFillers)
+	if exemplar != nil {
+		counter.(prometheus.ExemplarAdder).AddWithExemplar(value, exemplar)
+	} else {
+		counter.Add(value)
+	}
+	c.touch(m, labelsWithFillers)
+	m.cacheCounter(labelsWithFillers, counter, generation)
 	return nil
 }
 
 // ObserveHistogram observes a value in a histogram metric with the given labels
 func (c *MetricCollector) ObserveHistogram(name string, value float64, labels map[string]string) error {
-	c.mutex.RLock()
-	histogram, exists := c.histograms[name]
-	c.mutex.RUnlock()
+	return c.ObserveHistogramWithExemplar(name, value, labels, nil)
+}
 
-	if !exists {
+// ObserveHistogramWithExemplar observes a value in a histogram metric and attaches
+// the exemplar to the observation, a nil exemplar is a plain observation. The
+// exemplar must pass ValidateExemplar.
+func (c *MetricCollector) ObserveHistogramWithExemplar(name string, value float64, labels, exemplar map[string]string) error {
+	m, exists := c.registered(name)
+	if !exists || m.histogram == nil {
 		return fmt.Errorf("histogram metric '%s' not found", name)
 	}
 
-	labelsWithFillers, err := c.cleanLabels(name, labels)
+	labelsWithFillers, err := c.seriesLabels(m, labels)
 	if err != nil {
 		return err
 	}
 
-	histogram.With(labelsWithFillers).Observe(value)
+	if exemplar != nil {
+		m.histogram.With(labelsWithFillers).(prometheus.ExemplarObserver).ObserveWithExemplar(value, exemplar)
+	} else {
+		m.histogram.With(labelsWithFillers).Observe(value)
+	}
+	c.touch(m, labelsWithFillers)
 	return nil
 }
 
 // ObserveSummary observes a value in a summary metric with the given labels
 func (c *MetricCollector) ObserveSummary(name string, value float64, labels map[string]string) error {
-	c.mutex.RLock()
-	summary, exists := c.summaries[name]
-	c.mutex.RUnlock()
-
-	if !exists {
+	m, exists := c.registered(name)
+	if !exists || m.summary == nil {
 		return fmt.Errorf("summary metric '%s' not found", name)
 	}
 
-	labelsWithFillers, err := c.cleanLabels(name, labels)
+	labelsWithFillers, err := c.seriesLabels(m, labels)
 	if err != nil {
 		return err
 	}
 
-	summary.With(labelsWithFillers).Observe(value)
+	m.summary.With(labelsWithFillers).Observe(value)
+	c.touch(m, labelsWithFillers)
 	return nil
 }
@@ -0,0 +1,147 @@
+package collector
+
+import (
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// pushers is the number of goroutines pushing concurrently
+const pushers = 1000
+
+func newBenchCollector(b *testing.B) *MetricCollector {
+	b.Helper()
+
+	cfg := &config.Config{
+		Global: config.GlobalConfig{Namespace: "bench"},
+		Metrics: []config.MetricConfig{
+			{Name: "job_duration_seconds", Type: config.MetricTypeGauge, Labels: []string{"job", "host"}},
+			{Name: "job_runs_total", Type: config.MetricTypeCounter, Labels: []string{"job", "host"}, LabelDefaults: map[string]string{"host": "localhost"}},
+			{Name: "backups_total", Type: config.MetricTypeCounter},
+			{Name: "job_latency_seconds", Type: config.MetricTypeHistogram, Labels: []string{"job", "host"}, Buckets: config.Buckets{0.1, 1, 10}},
+		},
+	}
+
+	c, err := NewMetricCollector(cfg, prometheus.NewRegistry())
+	if err != nil {
+		b.Fatal(err)
+	}
+	return c
+}
+
+// runPushers runs push on pushers goroutines, each pushing to a series of its own
+func runPushers(b *testing.B, push func(labels map[string]string) error) {
+	b.Helper()
+
+	var id atomic.Int64
+	b.SetParallelism(max(1, pushers/runtime.GOMAXPROCS(0)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		labels := map[string]string{
+			"job":  "job-" + strconv.FormatInt(id.Add(1), 10),
+			"host": "localhost",
+		}
+
+		for pb.Next() {
+			if err := push(labels); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func BenchmarkConcurrentPushes(b *testing.B) {
+	b.Run("gauge", func(b *testing.B) {
+		c := newBenchCollector(b)
+		runPushers(b, func(labels map[string]string) error {
+			return c.UpdateGauge("job_duration_seconds", 1.5, labels)
+		})
+	})
+
+	b.Run("counter", func(b *testing.B) {
+		c := newBenchCollector(b)
+		runPushers(b, func(labels map[string]string) error {
+			return c.IncrementCounter("job_runs_total", labels)
+		})
+	})
+
+	b.Run("histogram", func(b *testing.B) {
+		c := newBenchCollector(b)
+		runPushers(b, func(labels map[string]string) error {
+			return c.ObserveHistogram("job_latency_seconds", 0.5, labels)
+		})
+	})
+
+	b.Run("apply", func(b *testing.B) {
+		c := newBenchCollector(b)
+		runPushers(b, func(labels map[string]string) error {
+			return c.Apply("job_runs_total", 1, labels)
+		})
+	})
+}
+
+// BenchmarkConcurrentPushesWhileScraping pushes while the registry is gathered
+// and the metrics are listed continuously
+func BenchmarkConcurrentPushesWhileScraping(b *testing.B) {
+	c := newBenchCollector(b)
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				if _, err := c.Gatherer().Gather(); err != nil {
+					b.Error(err)
+					return
+				}
+				c.Metrics()
+			}
+		}
+	}()
+
+	runPushers(b, func(labels map[string]string) error {
+		return c.UpdateGauge("job_duration_seconds", 1.5, labels)
+	})
+
+	b.StopTimer()
+	close(done)
+	<-stopped
+}
+
+// BenchmarkIncrementCounter increments a single series, the increments of the
+// configured labels take the cached path
+func BenchmarkIncrementCounter(b *testing.B) {
+	benchmarks := []struct {
+		name   string
+		metric string
+		labels map[string]string
+	}{
+		{"no labels", "backups_total", nil},
+		{"labels", "job_runs_total", map[string]string{"job": "backup", "host": "localhost"}},
+		{"defaulted labels", "job_runs_total", map[string]string{"job": "backup"}},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			c := newBenchCollector(b)
+			b.ReportAllocs()
+
+			for b.Loop() {
+				if err := c.IncrementCounter(bm.metric, bm.labels); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxCachedKey is the length of series keys built without allocating, longer keys
+// still work but are built on the heap
+const maxCachedKey = 256
+
+// incrementCached increments the counter of a series through the children cached by
+// an earlier increment, without resolving them in the metric vectors. It reports
+// false if the labels need cleaning or the series has no cached children, the
+// increment must then take the regular path which caches them.
+func (m *registeredMetric) incrementCached(value float64, labels map[string]string) bool {
+	// Only the configured labels, nothing to default, fill, or remove
+	if len(labels) != len(m.cfg.Labels) {
+		return false
+	}
+
+	var buf [maxCachedKey]byte
+	key := buf[:0]
+	for _, name := range m.cfg.Labels {
+		v, ok := labels[name]
+		if !ok {
+			return false
+		}
+		key = append(key, v...)
+		key = append(key, 0xff)
+	}
+
+	now := time.Now()
+
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+
+	s, ok := m.state.series[string(key)]
+	if !ok || s.counter == nil || (m.lastPush != nil && s.pushed == nil) {
+		return false
+	}
+
+	s.counter.Add(value)
+	if s.pushed != nil {
+		s.pushed.Set(float64(now.UnixNano()) / 1e9)
+	}
+	m.state.lastPush = now
+	s.lastPush = now
+	m.state.recency.MoveToFront(s.recency)
+
+	return true
+}
+
+// cacheCounter caches the children of a counter series for incrementCached. The
+// children are only cached if no series of the metric was deleted since generation
+// was loaded, they may have been deleted from the metric vectors then.
+func (m *registeredMetric) cacheCounter(labels map[string]string, counter prometheus.Counter, generation uint64) {
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+
+	if m.state.generation.Load() != generation {
+		return
+	}
+
+	s, ok := m.state.series[seriesKey(m.cfg.Labels, labels)]
+	if !ok || s.counter != nil {
+		return
+	}
+
+	s.counter = counter
+	if m.lastPush != nil {
+		s.pushed = m.lastPush.With(labels)
+	}
+}
@@ -0,0 +1,99 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCachedCounterAfterDelete(t *testing.T) {
+	labels := map[string]string{"host": "db1"}
+
+	tests := []struct {
+		name   string
+		delete func(c *MetricCollector) error
+	}{
+		{
+			name: "delete series",
+			delete: func(c *MetricCollector) error {
+				_, err := c.DeleteSeries("backups_total", labels)
+				return err
+			},
+		},
+		{
+			name: "delete matching",
+			delete: func(c *MetricCollector) error {
+				_, err := c.DeleteMatching("backups_total", labels)
+				return err
+			},
+		},
+		{
+			name: "reset metric",
+			delete: func(c *MetricCollector) error {
+				_, err := c.ResetMetric("backups_total")
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCollector(t, config.MetricConfig{
+				Name:          "backups_total",
+				Type:          config.MetricTypeCounter,
+				Labels:        []string{"host"},
+				TrackLastPush: true,
+			})
+
+			// The second increment goes through the cached children
+			for range 2 {
+				if err := c.IncrementCounter("backups_total", labels); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := *sample(t, c, "backups_total", labels).Value; got != 2 {
+				t.Fatalf("backups_total = %v, want 2", got)
+			}
+
+			if err := tt.delete(c); err != nil {
+				t.Fatal(err)
+			}
+
+			// The deleted children are no longer cached, the increment creates the
+			// series again from zero
+			if err := c.IncrementCounter("backups_total", labels); err != nil {
+				t.Fatal(err)
+			}
+			if got := *sample(t, c, "backups_total", labels).Value; got != 1 {
+				t.Errorf("backups_total after the delete = %v, want 1", got)
+			}
+			m, _ := c.registered("backups_total")
+			if got := testutil.CollectAndCount(m.lastPush); got != 1 {
+				t.Errorf("last push series after the delete = %d, want 1", got)
+			}
+		})
+	}
+}
+
+func TestCacheCounterStaleGeneration(t *testing.T) {
+	labels := map[string]string{"host": "db1"}
+	c := newTestCollector(t, config.MetricConfig{Name: "backups_total", Type: config.MetricTypeCounter, Labels: []string{"host"}})
+	m, _ := c.registered("backups_total")
+
+	// An increment that loaded the generation before a concurrent delete
+	generation := m.state.generation.Load()
+	counter := m.counter.With(labels)
+	counter.Inc()
+	c.touch(m, labels)
+	if _, err := c.DeleteSeries("backups_total", labels); err != nil {
+		t.Fatal(err)
+	}
+	c.touch(m, labels)
+
+	// The deleted child must not be cached for the series pushed again
+	m.cacheCounter(labels, counter, generation)
+	if m.incrementCached(1, labels) {
+		t.Error("incrementCached() used a child cached before the delete")
+	}
+}
@@ -0,0 +1,183 @@
+package collector
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// enumMetric exposes one series per state of an enum metric, like an OpenMetrics
+// stateset. The active state has a value of 1, all other states 0. The state is
+// carried in a label named after the metric.
+type enumMetric struct {
+	vec *prometheus.GaugeVec
+
+	mu      sync.Mutex
+	current map[string]map[string]string // Labels of the active state by series key
+}
+
+// registerEnum creates and registers an enum metric
+func (c *MetricCollector) registerEnum(metricCfg config.MetricConfig) error {
+	labels := append(append([]string{}, metricCfg.Labels...), metricCfg.Name)
+
+	vec := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: c.namespace(metricCfg),
+		Subsystem: metricCfg.Subsystem,
+		Name:      metricCfg.ExposedName(),
+		Help:      metricCfg.Description,
+	}, labels)
+	if err := c.registry.Register(vec); err != nil {
+		return fmt.Errorf("failed to register enum '%s': %w", metricCfg.Name, err)
+	}
+
+	c.enums[metricCfg.Name] = &enumMetric{
+		vec:     vec,
+		current: make(map[string]map[string]string),
+	}
+	return nil
+}
+
+// ValidateState checks that the labels of an update to an enum metric carry one of
+// its states in the label named after the metric
+func (c *MetricCollector) ValidateState(name string, labels map[string]string) error {
+	metricCfg, ok := c.metricConfig(name)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrMetricNotFound, name)
+	}
+
+	state, ok := labels[metricCfg.Name]
+	if !ok {
+		return fmt.Errorf("enum metric '%s' requires the state in label '%s'", name, metricCfg.Name)
+	}
+	if !slices.Contains(metricCfg.States, state) {
+		return fmt.Errorf("'%s' is not a state of enum metric '%s', try [%s]", state, name, strings.Join(metricCfg.States, ", "))
+	}
+	return nil
+}
+
+// SetState makes the state in the label named after the metric the active state
+// of the series identified by the configured labels
+func (c *MetricCollector) SetState(name string, labels map[string]string) error {
+	m, ok := c.registered(name)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrMetricNotFound, name)
+	}
+	if m.enum == nil {
+		return fmt.Errorf("enum metric '%s' not found", name)
+	}
+
+	id, err := m.enum.set(c, m, labels)
+	if err != nil {
+		return err
+	}
+
+	c.touch(m, id)
+	return nil
+}
+
+// set activates the state of the series, returning its identifying labels
+func (e *enumMetric) set(c *MetricCollector, m *registeredMetric, labels map[string]string) (map[string]string, error) {
+	metricCfg := m.cfg
+	if err := c.ValidateState(metricCfg.Name, labels); err != nil {
+		return nil, err
+	}
+	if err := c.CheckLabels(metricCfg.Name, labels); err != nil {
+		return nil, err
+	}
+
+	id := maps.Clone(labels)
+	delete(id, metricCfg.Name)
+
+	id, err := c.seriesLabels(m, id)
+	if err != nil {
+		return nil, err
+	}
+
+	active := maps.Clone(id)
+	active[metricCfg.Name] = labels[metricCfg.Name]
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	series := maps.Clone(id)
+	for _, state := range metricCfg.States {
+		series[metricCfg.Name] = state
+
+		var value float64
+		if state == active[metricCfg.Name] {
+			value = 1
+		}
+		e.vec.With(series).Set(value)
+	}
+	e.current[seriesKey(metricCfg.Labels, id)] = active
+
+	return id, nil
+}
+
+// labels returns the labels of the active state of the series identified by the labels
+func (e *enumMetric) labels(metricCfg config.MetricConfig, id map[string]string) (map[string]string, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	active, ok := e.current[seriesKey(metricCfg.Labels, id)]
+	return active, ok
+}
+
+// delete removes all states of the series identified by the labels
+func (e *enumMetric) delete(metricCfg config.MetricConfig, id map[string]string) bool {
+	key := seriesKey(metricCfg.Labels, id)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	active, ok := e.current[key]
+	if !ok {
+		return false
+	}
+
+	series := maps.Clone(active)
+	delete(series, metricCfg.Name)
+	e.vec.DeletePartialMatch(series)
+	delete(e.current, key)
+	return true
+}
+
+// deleteMatching removes the series whose labels contain the given labels,
+// returning the number of removed series
+func (e *enumMetric) deleteMatching(labels map[string]string) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var deleted int
+	for key, active := range e.current {
+		match := true
+		for k, v := range labels {
+			if active[k] != v {
+				match = false
+				break
+			}
+		}
+
+		if match {
+			delete(e.current, key)
+			deleted++
+		}
+	}
+
+	e.vec.DeletePartialMatch(labels)
+	return deleted
+}
+
+// reset removes all series
+func (e *enumMetric) reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.vec.Reset()
+	clear(e.current)
+}
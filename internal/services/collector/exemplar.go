@@ -0,0 +1,31 @@
+package collector
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+)
+
+// ValidateExemplar checks the labels of an exemplar, the client library panics when
+// it's given an invalid exemplar
+func ValidateExemplar(labels map[string]string) error {
+	var runes int
+	for name, value := range labels {
+		if !model.LabelName(name).IsValid() || strings.HasPrefix(name, model.ReservedLabelPrefix) {
+			return fmt.Errorf("exemplar label name '%s' is invalid", name)
+		}
+		if !utf8.ValidString(value) {
+			return fmt.Errorf("exemplar label '%s' is not valid UTF-8", name)
+		}
+		runes += utf8.RuneCountInString(name) + utf8.RuneCountInString(value)
+	}
+
+	if runes > prometheus.ExemplarMaxRunes {
+		return fmt.Errorf("exemplar labels have %d runes, the limit is %d", runes, prometheus.ExemplarMaxRunes)
+	}
+
+	return nil
+}
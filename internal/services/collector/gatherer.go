@@ -0,0 +1,101 @@
+package collector
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// ExternalGatherer gathers metrics from outside the collector. A metric family
+// whose name is already taken would fail every scrape, the gatherer skips such
+// families and reports them with its own metrics.
+type ExternalGatherer interface {
+	// GatherExcept returns the metric families whose names aren't taken
+	GatherExcept(taken map[string]bool) ([]*dto.MetricFamily, error)
+}
+
+// AddGatherer adds the metrics of a gatherer outside the collector, like an exec
+// collector, to the metrics of Gatherer
+func (c *MetricCollector) AddGatherer(g ExternalGatherer) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.gatherers = append(c.gatherers, g)
+}
+
+// Gatherer returns a gatherer of the registry the collector was created with, the
+// metrics registry, and the added gatherers. It sets the unit of the metric families of metrics
+// declaring one, the client library doesn't support units, and adds the global
+// external labels to every series. The added gatherers don't get the names of the
+// collector and the gatherers before them.
+func (c *MetricCollector) Gatherer() prometheus.Gatherer {
+	exposed := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		return c.exposed.Load().Gather()
+	})
+
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		c.mutex.RLock()
+		external := slices.Clone(c.gatherers)
+		c.mutex.RUnlock()
+
+		core, coreErr := prometheus.Gatherers{c.base, exposed}.Gather()
+		taken := make(map[string]bool, len(core))
+		for _, family := range core {
+			taken[family.GetName()] = true
+		}
+
+		gatherers := prometheus.Gatherers{prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+			return core, coreErr
+		})}
+		for _, g := range external {
+			families, err := g.GatherExcept(taken)
+			for _, family := range families {
+				taken[family.GetName()] = true
+			}
+			gatherers = append(gatherers, prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+				return families, err
+			}))
+		}
+
+		units := c.units()
+		labels := c.config.Load().Global.ExternalLabels
+		names := slices.Sorted(maps.Keys(labels))
+
+		families, err := gatherers.Gather()
+		for _, family := range families {
+			if unit, ok := units[family.GetName()]; ok {
+				family.Unit = &unit
+			}
+
+			if len(names) > 0 {
+				for _, metric := range family.Metric {
+					addLabels(metric, names, labels)
+				}
+			}
+		}
+
+		return families, err
+	})
+}
+
+// addLabels adds the labels a metric doesn't have yet, keeping its labels sorted
+func addLabels(metric *dto.Metric, names []string, labels map[string]string) {
+	added := false
+	for _, name := range names {
+		if slices.ContainsFunc(metric.Label, func(l *dto.LabelPair) bool { return l.GetName() == name }) {
+			continue
+		}
+
+		metric.Label = append(metric.Label, &dto.LabelPair{Name: proto.String(name), Value: proto.String(labels[name])})
+		added = true
+	}
+
+	if added {
+		slices.SortFunc(metric.Label, func(a, b *dto.LabelPair) int {
+			return strings.Compare(a.GetName(), b.GetName())
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package collector
+
+import "fmt"
+
+// GaugeOp is the operation an update applies to a gauge
+type GaugeOp string
+
+const (
+	GaugeOpSet              GaugeOp = "set"                 // Set the gauge to the value
+	GaugeOpInc              GaugeOp = "inc"                 // Increment the gauge by one
+	GaugeOpDec              GaugeOp = "dec"                 // Decrement the gauge by one
+	GaugeOpAdd              GaugeOp = "add"                 // Add the value, which may be negative
+	GaugeOpSetToCurrentTime GaugeOp = "set_to_current_time" // Set the gauge to the current unix time
+)
+
+// ParseGaugeOp parses a gauge operation, an empty string is a set
+func ParseGaugeOp(op string) (GaugeOp, error) {
+	switch GaugeOp(op) {
+	case "":
+		return GaugeOpSet, nil
+	case GaugeOpSet, GaugeOpInc, GaugeOpDec, GaugeOpAdd, GaugeOpSetToCurrentTime:
+		return GaugeOp(op), nil
+	default:
+		return "", fmt.Errorf("%s is not a valid gauge operation, try [%s, %s, %s, %s, %s]", op, GaugeOpSet, GaugeOpInc, GaugeOpDec, GaugeOpAdd, GaugeOpSetToCurrentTime)
+	}
+}
+
+// UsesValue reports whether the operation reads the value of the update
+func (op GaugeOp) UsesValue() bool {
+	return op == GaugeOpSet || op == GaugeOpAdd
+}
+
+// ApplyGauge applies an operation to a gauge. The pushed value is only compared
+// against the expected duration of the metric for sets.
+func (c *MetricCollector) ApplyGauge(name string, op GaugeOp, value float64, labels map[string]string) error {
+	if op == GaugeOpSet {
+		return c.UpdateGauge(name, value, labels)
+	}
+
+	m, exists := c.registered(name)
+	if !exists || m.gauge == nil {
+		return fmt.Errorf("gauge metric '%s' not found", name)
+	}
+
+	labelsWithFillers, err := c.seriesLabels(m, labels)
+	if err != nil {
+		return err
+	}
+
+	g := m.gauge.With(labelsWithFillers)
+	switch op {
+	case GaugeOpInc:
+		g.Inc()
+	case GaugeOpDec:
+		g.Dec()
+	case GaugeOpAdd:
+		g.Add(value)
+	case GaugeOpSetToCurrentTime:
+		g.SetToCurrentTime()
+	default:
+		return fmt.Errorf("unsupported gauge operation: %s", op)
+	}
+
+	c.touch(m, labelsWithFillers)
+	return nil
+}
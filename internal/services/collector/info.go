@@ -0,0 +1,157 @@
+package collector
+
+import (
+	"fmt"
+	"maps"
+	"sync"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// infoMetric exposes a constant 1 per series of an info metric. The configured
+// labels identify a series, a push replaces the values of its info labels.
+type infoMetric struct {
+	vec *prometheus.GaugeVec
+
+	mu      sync.Mutex
+	current map[string]map[string]string // Exposed labels by series key
+}
+
+// registerInfo creates and registers an info metric
+func (c *MetricCollector) registerInfo(metricCfg config.MetricConfig) error {
+	labels := append(append([]string{}, metricCfg.Labels...), metricCfg.InfoLabels...)
+
+	vec := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: c.namespace(metricCfg),
+		Subsystem: metricCfg.Subsystem,
+		Name:      metricCfg.ExposedName(),
+		Help:      metricCfg.Description,
+	}, labels)
+	if err := c.registry.Register(vec); err != nil {
+		return fmt.Errorf("failed to register info '%s': %w", metricCfg.Name, err)
+	}
+
+	c.infos[metricCfg.Name] = &infoMetric{
+		vec:     vec,
+		current: make(map[string]map[string]string),
+	}
+	return nil
+}
+
+// SetInfo sets the info labels of the series identified by the configured labels,
+// replacing its previous values. Missing info labels are exposed as empty values.
+func (c *MetricCollector) SetInfo(name string, labels map[string]string) error {
+	m, ok := c.registered(name)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrMetricNotFound, name)
+	}
+	if m.info == nil {
+		return fmt.Errorf("info metric '%s' not found", name)
+	}
+
+	id, err := m.info.set(c, m, labels)
+	if err != nil {
+		return err
+	}
+
+	c.touch(m, id)
+	return nil
+}
+
+// set replaces the series of the identifying labels, returning them
+func (i *infoMetric) set(c *MetricCollector, m *registeredMetric, labels map[string]string) (map[string]string, error) {
+	metricCfg := m.cfg
+	if err := c.CheckLabels(metricCfg.Name, labels); err != nil {
+		return nil, err
+	}
+
+	id := maps.Clone(labels)
+	for _, label := range metricCfg.InfoLabels {
+		delete(id, label)
+	}
+
+	id, err := c.seriesLabels(m, id)
+	if err != nil {
+		return nil, err
+	}
+
+	exposed := maps.Clone(id)
+	for _, label := range metricCfg.InfoLabels {
+		exposed[label] = labels[label]
+	}
+
+	key := seriesKey(metricCfg.Labels, id)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if prev, ok := i.current[key]; ok && !maps.Equal(prev, exposed) {
+		i.vec.Delete(prev)
+	}
+	i.vec.With(exposed).Set(1)
+	i.current[key] = exposed
+
+	return id, nil
+}
+
+// labels returns the exposed labels of the series identified by the labels
+func (i *infoMetric) labels(metricCfg config.MetricConfig, id map[string]string) (map[string]string, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	exposed, ok := i.current[seriesKey(metricCfg.Labels, id)]
+	return exposed, ok
+}
+
+// delete removes the series identified by the labels
+func (i *infoMetric) delete(metricCfg config.MetricConfig, id map[string]string) bool {
+	key := seriesKey(metricCfg.Labels, id)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	exposed, ok := i.current[key]
+	if !ok {
+		return false
+	}
+
+	i.vec.Delete(exposed)
+	delete(i.current, key)
+	return true
+}
+
+// deleteMatching removes the series whose labels contain the given labels,
+// returning the number of removed series
+func (i *infoMetric) deleteMatching(labels map[string]string) int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	var deleted int
+	for key, exposed := range i.current {
+		match := true
+		for k, v := range labels {
+			if exposed[k] != v {
+				match = false
+				break
+			}
+		}
+
+		if match {
+			i.vec.Delete(exposed)
+			delete(i.current, key)
+			deleted++
+		}
+	}
+
+	return deleted
+}
+
+// reset removes all series
+func (i *infoMetric) reset() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.vec.Reset()
+	clear(i.current)
+}
@@ -0,0 +1,124 @@
+package collector
+
+import (
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// intervalCollector computes the overdue state of every series of a metric that
+// declares an expected interval at scrape time
+type intervalCollector struct {
+	c       *MetricCollector
+	metric  config.MetricConfig
+	overdue *prometheus.Desc
+	since   *prometheus.Desc
+}
+
+// registerInterval creates and registers the overdue metrics for a metric
+func (c *MetricCollector) registerInterval(metricCfg config.MetricConfig) error {
+	metricName := metricCfg.Name
+
+	ic := &intervalCollector{
+		c:      c,
+		metric: metricCfg,
+		overdue: prometheus.NewDesc(
+			c.fqName(metricCfg, "_overdue"),
+			fmt.Sprintf("Whether %s wasn't pushed within the expected interval of %s", metricName, metricCfg.ExpectedInterval),
+			metricCfg.Labels, nil,
+		),
+		since: prometheus.NewDesc(
+			c.fqName(metricCfg, "_seconds_since_last_push"),
+			fmt.Sprintf("Seconds since the latest update of %s", metricName),
+			metricCfg.Labels, nil,
+		),
+	}
+
+	if err := c.registry.Register(ic); err != nil {
+		return fmt.Errorf("failed to register overdue metrics for '%s': %w", metricName, err)
+	}
+
+	c.intervals[metricName] = ic
+	return nil
+}
+
+func (ic *intervalCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- ic.overdue
+	ch <- ic.since
+}
+
+func (ic *intervalCollector) Collect(ch chan<- prometheus.Metric) {
+	now := time.Now()
+
+	m, ok := ic.c.registered(ic.metric.Name)
+	if !ok {
+		return
+	}
+
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+
+	for _, s := range m.state.series {
+		values := make([]string, len(ic.metric.Labels))
+		for i, name := range ic.metric.Labels {
+			values[i] = s.labels[name]
+		}
+
+		since := now.Sub(s.lastPush)
+
+		var overdue float64
+		if since > ic.metric.ExpectedInterval {
+			overdue = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(ic.overdue, prometheus.GaugeValue, overdue, values...)
+		ch <- prometheus.MustNewConstMetric(ic.since, prometheus.GaugeValue, since.Seconds(), values...)
+	}
+}
+
+// OverdueSeries is a series that wasn't pushed within the expected interval of its metric
+type OverdueSeries struct {
+	Metric           string
+	Labels           map[string]string
+	LastPush         time.Time
+	ExpectedInterval time.Duration
+}
+
+// Overdue returns the series of all metrics with an expected interval that weren't
+// pushed within it
+func (c *MetricCollector) Overdue(now time.Time) []OverdueSeries {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	var overdue []OverdueSeries
+	for _, metricCfg := range c.metrics {
+		if metricCfg.ExpectedInterval <= 0 {
+			continue
+		}
+
+		m, ok := c.registered(metricCfg.Name)
+		if !ok {
+			continue
+		}
+
+		m.state.mu.Lock()
+		for _, s := range m.state.series {
+			if now.Sub(s.lastPush) <= metricCfg.ExpectedInterval {
+				continue
+			}
+
+			overdue = append(overdue, OverdueSeries{
+				Metric:           metricCfg.Name,
+				Labels:           maps.Clone(s.labels),
+				LastPush:         s.lastPush,
+				ExpectedInterval: metricCfg.ExpectedInterval,
+			})
+		}
+		m.state.mu.Unlock()
+	}
+
+	return overdue
+}
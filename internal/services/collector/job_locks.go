@@ -0,0 +1,71 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// JobLock is a lease on a job that keeps other runs of the job from running at the
+// same time. The holder renews it before it expires, a lease of a holder that
+// crashed expires on its own.
+type JobLock struct {
+	Job       string    `json:"job"`
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AcquireLock locks a job for the holder until the TTL elapsed. Acquiring a lock
+// the holder already holds renews it, a lock held by another holder returns an
+// ErrLockHeld until it is released or expired.
+func (c *MetricCollector) AcquireLock(job, holder string, ttl time.Duration) (JobLock, error) {
+	if holder == "" {
+		return JobLock{}, errors.New("a lock holder is required")
+	}
+	if ttl <= 0 {
+		return JobLock{}, errors.New("lock ttl must be positive")
+	}
+	if err := c.ensureJob(job); err != nil {
+		return JobLock{}, err
+	}
+
+	now := time.Now()
+
+	c.runs.mu.Lock()
+	defer c.runs.mu.Unlock()
+
+	s := c.runs.state(job)
+	if s.lock != nil && s.lock.Holder != holder && now.Before(s.lock.ExpiresAt) {
+		return *s.lock, fmt.Errorf("%w: job '%s' is locked by '%s' until %s", ErrLockHeld, job, s.lock.Holder, s.lock.ExpiresAt.Format(time.RFC3339))
+	}
+
+	s.lock = &JobLock{Job: job, Holder: holder, ExpiresAt: now.Add(ttl)}
+	return *s.lock, nil
+}
+
+// ReleaseLock releases the lock of a job held by the holder. Releasing a lock that
+// expired or was never acquired succeeds, a lock of another holder returns an
+// ErrLockHeld.
+func (c *MetricCollector) ReleaseLock(job, holder string) error {
+	if _, err := c.JobMetrics(job); err != nil {
+		return err
+	}
+
+	c.runs.mu.Lock()
+	defer c.runs.mu.Unlock()
+
+	s, ok := c.runs.jobs[job]
+	if !ok {
+		return nil
+	}
+	if s.lock == nil || !time.Now().Before(s.lock.ExpiresAt) {
+		s.lock = nil
+		return nil
+	}
+	if s.lock.Holder != holder {
+		return fmt.Errorf("%w: job '%s' is locked by '%s'", ErrLockHeld, job, s.lock.Holder)
+	}
+
+	s.lock = nil
+	return nil
+}
@@ -0,0 +1,301 @@
+package collector
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/rs/zerolog/log"
+)
+
+// Statuses of a finished job run, the result label of the runs metric
+const (
+	RunSuccess = "success"
+	RunFailure = "failure"
+	RunTimeout = "timeout" // Killed at its deadline by the run wrapper
+	RunSkipped = "skipped" // Not run because another run held the lock of the job
+)
+
+// maxRunHistory is how many finished runs of a job are kept for the history
+const maxRunHistory = 20
+
+// maxRunOutput is how many bytes of the output of a run are kept, the end of
+// longer output is kept
+const maxRunOutput = 64 << 10
+
+// maxRunsInProgress is how many unfinished runs of a job are tracked, the oldest
+// is dropped beyond it so runs that never report their finish don't accumulate
+const maxRunsInProgress = 100
+
+var (
+	// ErrJobNotFound is returned when a job isn't configured
+	ErrJobNotFound = errors.New("job not found")
+	// ErrRunExists is returned when starting a run with the ID of a running run
+	ErrRunExists = errors.New("run already started")
+	// ErrLockHeld is returned when locking a job whose lock another holder holds
+	ErrLockHeld = errors.New("lock held")
+)
+
+// JobRun is a run of a job reported through the start and finish API
+type JobRun struct {
+	Job        string     `json:"job"`
+	RunID      string     `json:"run_id"`
+	StartedAt  *time.Time `json:"started_at,omitempty"` // nil when the start wasn't reported
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Status     string     `json:"status,omitempty"` // success, failure, timeout, or skipped once finished
+	ExitCode   *int       `json:"exit_code,omitempty"`
+	Duration   *float64   `json:"duration_seconds,omitempty"` // Derived from the start, nil without one
+	Output     string     `json:"output,omitempty"`           // Tail of the output the run reported with its finish
+}
+
+// runState is the state of the runs of a job
+type runState struct {
+	running map[string]time.Time // Start time by run ID
+	order   []string             // Run IDs in start order
+	lock    *JobLock             // nil while the job isn't locked
+	skipped float64              // Runs skipped because the job was locked
+	history []JobRun             // Latest finished runs, oldest first
+}
+
+// jobRuns holds the run states of the jobs. It outlives reloads, runs of jobs
+// removed from the config are kept but not exposed.
+type jobRuns struct {
+	mu      sync.Mutex
+	jobs    map[string]*runState
+	dynamic map[string]bool // Jobs created by their first run, with dynamic metrics
+}
+
+// state returns the run state of a job, creating it on first use. The caller
+// holds the lock.
+func (r *jobRuns) state(job string) *runState {
+	if r.jobs == nil {
+		r.jobs = make(map[string]*runState)
+	}
+
+	s, ok := r.jobs[job]
+	if !ok {
+		s = &runState{running: make(map[string]time.Time)}
+		r.jobs[job] = s
+	}
+	return s
+}
+
+// dynamicJobs returns the names of the jobs created by their first run
+func (r *jobRuns) dynamicJobs() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Sorted(maps.Keys(r.dynamic))
+}
+
+// JobMetrics returns the names of the metrics of a job. Unknown jobs are created
+// by their first run when dynamic metrics are allowed, their metrics are the
+// standard metrics.
+func (c *MetricCollector) JobMetrics(job string) ([]string, error) {
+	cfg := c.config.Load()
+	if slices.ContainsFunc(cfg.Jobs, func(j config.Job) bool { return j.Name == job }) {
+		return cfg.JobMetrics(job), nil
+	}
+
+	if !cfg.Global.AllowDynamicMetrics {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, job)
+	}
+
+	var names []string
+	for _, m := range config.StandardJobMetrics(config.Job{Name: job}) {
+		names = append(names, m.Name)
+	}
+	return names, nil
+}
+
+// ensureJob checks that a job is configured or creates the standard metrics of an
+// unknown job when dynamic metrics are allowed
+func (c *MetricCollector) ensureJob(job string) error {
+	cfg := c.config.Load()
+	if slices.ContainsFunc(cfg.Jobs, func(j config.Job) bool { return j.Name == job }) {
+		return nil
+	}
+
+	if !cfg.Global.AllowDynamicMetrics {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, job)
+	}
+
+	for _, m := range config.StandardJobMetrics(config.Job{Name: job}) {
+		if existing, ok := c.metricConfig(m.Name); ok {
+			if existing.Job != job {
+				return fmt.Errorf("metric '%s' of job '%s' is already defined outside of the job", m.Name, job)
+			}
+			continue
+		}
+		if err := c.AddMetric(m); err != nil && !errors.Is(err, ErrMetricExists) {
+			return fmt.Errorf("error creating metrics of job '%s': %w", job, err)
+		}
+	}
+
+	c.runs.mu.Lock()
+	if c.runs.dynamic == nil {
+		c.runs.dynamic = make(map[string]bool)
+	}
+	c.runs.dynamic[job] = true
+	c.runs.mu.Unlock()
+
+	return nil
+}
+
+// StartRun records the start of a run of a job. Without a run ID a random one is
+// generated, callers pass it to FinishRun to finish this run among concurrent ones.
+func (c *MetricCollector) StartRun(job, runID string) (JobRun, error) {
+	if err := c.ensureJob(job); err != nil {
+		return JobRun{}, err
+	}
+	if runID == "" {
+		runID = newRunID()
+	}
+
+	now := time.Now()
+
+	c.runs.mu.Lock()
+	defer c.runs.mu.Unlock()
+
+	s := c.runs.state(job)
+	if _, ok := s.running[runID]; ok {
+		return JobRun{}, fmt.Errorf("%w: %s", ErrRunExists, runID)
+	}
+
+	if len(s.order) >= maxRunsInProgress {
+		oldest := s.order[0]
+		log.Warn().Str("job", job).Str("run_id", oldest).Msg("too many runs in progress, dropping oldest run")
+		delete(s.running, oldest)
+		s.order = s.order[1:]
+	}
+
+	s.running[runID] = now
+	s.order = append(s.order, runID)
+
+	return JobRun{Job: job, RunID: runID, StartedAt: &now}, nil
+}
+
+// FinishRun records the end of a run of a job in its standard metrics. Without a
+// run ID the oldest running run finishes. An empty status is success for a zero or
+// missing exit code and failure otherwise. A finish without a matching start is
+// counted but has no duration. A skipped run is only counted as skipped overlap.
+// The run is kept in the history of the job along with the end of its output.
+func (c *MetricCollector) FinishRun(job, runID, status string, exitCode *int, output string) (JobRun, error) {
+	switch status {
+	case RunSuccess, RunFailure, RunTimeout, RunSkipped:
+	case "":
+		status = RunSuccess
+		if exitCode != nil && *exitCode != 0 {
+			status = RunFailure
+		}
+	default:
+		return JobRun{}, fmt.Errorf("invalid run status '%s', must be %s, %s, %s, or %s", status, RunSuccess, RunFailure, RunTimeout, RunSkipped)
+	}
+
+	if err := c.ensureJob(job); err != nil {
+		return JobRun{}, err
+	}
+
+	if len(output) > maxRunOutput {
+		output = output[len(output)-maxRunOutput:]
+	}
+
+	now := time.Now()
+	run := JobRun{Job: job, RunID: runID, FinishedAt: &now, Status: status, ExitCode: exitCode, Output: output}
+
+	c.runs.mu.Lock()
+	s := c.runs.state(job)
+	if status == RunSkipped {
+		s.skipped++
+		s.addHistory(run)
+		c.runs.mu.Unlock()
+		return run, nil
+	}
+	if run.RunID == "" && len(s.order) > 0 {
+		run.RunID = s.order[0]
+	}
+	if started, ok := s.running[run.RunID]; ok {
+		delete(s.running, run.RunID)
+		s.order = slices.DeleteFunc(s.order, func(id string) bool { return id == run.RunID })
+
+		duration := now.Sub(started).Seconds()
+		run.StartedAt = &started
+		run.Duration = &duration
+	}
+	s.addHistory(run)
+	c.runs.mu.Unlock()
+
+	return run, c.recordRun(run)
+}
+
+// addHistory adds a finished run to the history, dropping the oldest run beyond
+// maxRunHistory. The caller holds the lock.
+func (s *runState) addHistory(run JobRun) {
+	s.history = append(s.history, run)
+	if len(s.history) > maxRunHistory {
+		s.history = slices.Delete(s.history, 0, len(s.history)-maxRunHistory)
+	}
+}
+
+// JobRuns returns the runs in progress of a job followed by its latest finished
+// runs, newest first
+func (c *MetricCollector) JobRuns(job string) ([]JobRun, error) {
+	if _, err := c.JobMetrics(job); err != nil {
+		return nil, err
+	}
+
+	c.runs.mu.Lock()
+	defer c.runs.mu.Unlock()
+
+	// Looking up runs doesn't create the state of a job that never ran
+	s, ok := c.runs.jobs[job]
+	if !ok {
+		return []JobRun{}, nil
+	}
+
+	runs := make([]JobRun, 0, len(s.order)+len(s.history))
+	for _, id := range slices.Backward(s.order) {
+		started := s.running[id]
+		runs = append(runs, JobRun{Job: job, RunID: id, StartedAt: &started})
+	}
+	for _, run := range slices.Backward(s.history) {
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// recordRun updates the standard metrics of the job of a finished run, runs of
+// jobs that opted out of them are only removed from the runs in progress
+func (c *MetricCollector) recordRun(run JobRun) error {
+	runs := run.Job + config.JobRunsSuffix
+	if m, ok := c.metricConfig(runs); !ok || m.Job != run.Job {
+		return nil
+	}
+
+	var errs []error
+	if run.Duration != nil {
+		errs = append(errs, c.UpdateGauge(run.Job+config.JobDurationSuffix, *run.Duration, nil))
+		c.recordSlowRun(run)
+	}
+	if run.ExitCode != nil {
+		errs = append(errs, c.UpdateGauge(run.Job+config.JobExitCodeSuffix, float64(*run.ExitCode), nil))
+	}
+	if run.Status == RunSuccess {
+		errs = append(errs, c.UpdateGauge(run.Job+config.JobLastSuccessSuffix, float64(run.FinishedAt.UnixNano())/1e9, nil))
+	}
+	errs = append(errs, c.IncrementCounter(runs, map[string]string{"result": run.Status}))
+
+	return errors.Join(errs...)
+}
+
+// newRunID generates a random 64 bit run ID
+func newRunID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,168 @@
+package collector
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// jobCollector exposes roll-up metrics of the metrics of every job, its runs in
+// progress, and its skipped overlapping runs at scrape time
+type jobCollector struct {
+	c          *MetricCollector
+	lastPush   *prometheus.Desc
+	series     *prometheus.Desc
+	overdue    *prometheus.Desc
+	inProgress *prometheus.Desc
+	skipped    *prometheus.Desc
+	outputSize *prometheus.Desc
+}
+
+// registerJobs creates and registers the roll-up metrics of the jobs. Without
+// configured jobs they are only registered when runs may create jobs.
+func (c *MetricCollector) registerJobs() error {
+	cfg := c.config.Load()
+	if len(cfg.Jobs) == 0 && !cfg.Global.AllowDynamicMetrics {
+		return nil
+	}
+
+	namespace := cfg.Global.Namespace
+	jc := &jobCollector{
+		c: c,
+		lastPush: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "job_last_push_timestamp_seconds"),
+			"Unix time of the latest update of any metric of the job",
+			[]string{"job"}, nil,
+		),
+		series: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "job_series"),
+			"Number of active series of the metrics of the job",
+			[]string{"job"}, nil,
+		),
+		overdue: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "job_overdue"),
+			"Whether any series of the metrics of the job wasn't pushed within its expected interval",
+			[]string{"job"}, nil,
+		),
+		inProgress: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "job_runs_in_progress"),
+			"Number of started runs of the job that didn't report their finish",
+			[]string{"job"}, nil,
+		),
+		skipped: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "job_skipped_overlap_total"),
+			"Runs of the job skipped because another run held the lock of the job",
+			[]string{"job"}, nil,
+		),
+		outputSize: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "job_last_run_output_bytes"),
+			"Size of the output the latest finished run of the job reported",
+			[]string{"job"}, nil,
+		),
+	}
+
+	if err := c.registry.Register(jc); err != nil {
+		return fmt.Errorf("failed to register job metrics: %w", err)
+	}
+
+	c.jobs = jc
+	return nil
+}
+
+func (jc *jobCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- jc.lastPush
+	ch <- jc.series
+	ch <- jc.overdue
+	ch <- jc.inProgress
+	ch <- jc.skipped
+	ch <- jc.outputSize
+}
+
+// jobState is the runtime state of the metrics of a job
+type jobState struct {
+	lastPush time.Time
+	series   int
+	overdue  bool
+}
+
+func (jc *jobCollector) Collect(ch chan<- prometheus.Metric) {
+	now := time.Now()
+	var jobs []string
+	for _, job := range jc.c.config.Load().Jobs {
+		jobs = append(jobs, job.Name)
+	}
+	states := make(map[string]*jobState, len(jobs))
+	for _, job := range jobs {
+		states[job] = &jobState{}
+	}
+
+	// Jobs created by their first run may have been configured since
+	for _, job := range jc.c.runs.dynamicJobs() {
+		if _, ok := states[job]; !ok {
+			jobs = append(jobs, job)
+			states[job] = &jobState{}
+		}
+	}
+
+	jc.c.mutex.RLock()
+	for _, metricCfg := range jc.c.metrics {
+		state, ok := states[metricCfg.Job]
+		if !ok {
+			continue
+		}
+
+		m, ok := jc.c.registered(metricCfg.Name)
+		if !ok {
+			continue
+		}
+
+		m.state.mu.Lock()
+		if m.state.lastPush.After(state.lastPush) {
+			state.lastPush = m.state.lastPush
+		}
+		state.series += len(m.state.series)
+		if metricCfg.ExpectedInterval > 0 {
+			for _, s := range m.state.series {
+				if now.Sub(s.lastPush) > metricCfg.ExpectedInterval {
+					state.overdue = true
+					break
+				}
+			}
+		}
+		m.state.mu.Unlock()
+	}
+	jc.c.mutex.RUnlock()
+
+	for job, state := range states {
+		if !state.lastPush.IsZero() {
+			ch <- prometheus.MustNewConstMetric(jc.lastPush, prometheus.GaugeValue, float64(state.lastPush.UnixNano())/1e9, job)
+		}
+		ch <- prometheus.MustNewConstMetric(jc.series, prometheus.GaugeValue, float64(state.series), job)
+
+		var overdue float64
+		if state.overdue {
+			overdue = 1
+		}
+		ch <- prometheus.MustNewConstMetric(jc.overdue, prometheus.GaugeValue, overdue, job)
+	}
+
+	jc.collectRuns(ch, jobs)
+}
+
+// collectRuns exposes the runs in progress, the skipped overlapping runs, and the
+// output size of the latest run of the jobs
+func (jc *jobCollector) collectRuns(ch chan<- prometheus.Metric, jobs []string) {
+	runs := &jc.c.runs
+	runs.mu.Lock()
+	defer runs.mu.Unlock()
+
+	for _, job := range jobs {
+		s := runs.state(job)
+		ch <- prometheus.MustNewConstMetric(jc.inProgress, prometheus.GaugeValue, float64(len(s.running)), job)
+		ch <- prometheus.MustNewConstMetric(jc.skipped, prometheus.CounterValue, s.skipped, job)
+		if len(s.history) > 0 {
+			ch <- prometheus.MustNewConstMetric(jc.outputSize, prometheus.GaugeValue, float64(len(s.history[len(s.history)-1].Output)), job)
+		}
+	}
+}
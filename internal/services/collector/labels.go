@@ -0,0 +1,95 @@
+package collector
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+)
+
+// labelPolicy returns the label policy of a metric, metrics may override the
+// global label policy
+func (c *MetricCollector) labelPolicy(metricCfg config.MetricConfig) config.LabelPolicy {
+	if metricCfg.LabelPolicy != "" {
+		return metricCfg.LabelPolicy
+	}
+	return c.config.Load().Global.LabelPolicy
+}
+
+// missingLabels returns how a metric with the lenient label policy handles missing
+// labels, metrics may override the global setting
+func (c *MetricCollector) missingLabels(metricCfg config.MetricConfig) config.MissingLabels {
+	if metricCfg.MissingLabels != "" {
+		return metricCfg.MissingLabels
+	}
+	return c.config.Load().Global.MissingLabels
+}
+
+// labelFiller returns the value of filled missing labels of a metric
+func (c *MetricCollector) labelFiller(metricCfg config.MetricConfig) string {
+	if metricCfg.LabelFiller != nil {
+		return *metricCfg.LabelFiller
+	}
+	if filler := c.config.Load().Global.LabelFiller; filler != nil {
+		return *filler
+	}
+	return config.DefaultLabelFiller
+}
+
+// pushedLabels returns the names of all labels an update of a metric carries, the
+// info labels of info metrics and the state label of enum metrics included
+func pushedLabels(metricCfg config.MetricConfig) []string {
+	switch metricCfg.Type {
+	case config.MetricTypeInfo:
+		return slices.Concat(metricCfg.Labels, metricCfg.InfoLabels)
+	case config.MetricTypeEnum:
+		return slices.Concat(metricCfg.Labels, []string{metricCfg.Name})
+	default:
+		return metricCfg.Labels
+	}
+}
+
+// CheckLabels verifies that the labels of an update match the configured labels of
+// a metric exactly when the metric uses the strict label policy. Metrics with the
+// lenient policy accept any labels unless they reject missing labels.
+func (c *MetricCollector) CheckLabels(name string, labels map[string]string) error {
+	metricCfg, ok := c.metricConfig(name)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrMetricNotFound, name)
+	}
+
+	if c.labelPolicy(metricCfg) == config.LabelPolicyStrict {
+		return checkLabels(name, pushedLabels(metricCfg), labels, metricCfg.LabelDefaults)
+	}
+
+	if c.missingLabels(metricCfg) == config.MissingLabelsReject {
+		for _, label := range metricCfg.Labels {
+			_, defaulted := metricCfg.LabelDefaults[label]
+			if _, ok := labels[label]; !ok && !defaulted {
+				return fmt.Errorf("%w: metric '%s' requires label '%s'", ErrLabelMismatch, name, label)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkLabels returns an ErrLabelMismatch if a label without a default is missing or
+// a label is not one of the names
+func checkLabels(metricName string, names []string, labels, defaults map[string]string) error {
+	for _, name := range names {
+		_, defaulted := defaults[name]
+		if _, ok := labels[name]; !ok && !defaulted {
+			return fmt.Errorf("%w: metric '%s' requires label '%s'", ErrLabelMismatch, metricName, name)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(labels)) {
+		if !slices.Contains(names, name) {
+			return fmt.Errorf("%w: metric '%s' has no label '%s'", ErrLabelMismatch, metricName, name)
+		}
+	}
+
+	return nil
+}
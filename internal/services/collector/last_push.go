@@ -0,0 +1,26 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// registerLastPush creates and registers the last push timestamp of a metric
+func (c *MetricCollector) registerLastPush(metricCfg config.MetricConfig) error {
+	metricName := metricCfg.Name
+
+	lastPush := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: c.namespace(metricCfg),
+		Subsystem: metricCfg.Subsystem,
+		Name:      metricName + "_last_push_timestamp_seconds",
+		Help:      fmt.Sprintf("Unix time of the latest update of %s", metricName),
+	}, metricCfg.Labels)
+	if err := c.registry.Register(lastPush); err != nil {
+		return fmt.Errorf("failed to register last push timestamp for '%s': %w", metricName, err)
+	}
+
+	c.lastPush[metricName] = lastPush
+	return nil
+}
@@ -0,0 +1,145 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+)
+
+// newLimitExceeded creates the counter of updates beyond the series limit of a metric
+func newLimitExceeded() *prometheus.CounterVec {
+	return prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cronprom_series_limit_exceeded_total",
+		Help: "Number of updates that would have exceeded the max_series of a metric by action, rejected, overflow, or evicted",
+	}, []string{"metric", "action"})
+}
+
+// seriesLabels cleans the labels of an update like cleanLabels and applies the series
+// limit of the metric. Updates creating a series beyond the limit are rejected with
+// an ErrSeriesLimit or return the labels of the overflow series.
+func (c *MetricCollector) seriesLabels(m *registeredMetric, labels map[string]string) (map[string]string, error) {
+	labels, err := c.cleanLabels(m, labels)
+	if err != nil {
+		return nil, err
+	}
+
+	metricCfg, name := m.cfg, m.cfg.Name
+	if metricCfg.MaxSeries == 0 || !m.exceedsLimit(labels) {
+		return labels, nil
+	}
+
+	if metricCfg.Eviction == config.EvictionLru {
+		m.evictOldest()
+		c.limitExceeded.WithLabelValues(name, "evicted").Inc()
+		return labels, nil
+	}
+
+	if metricCfg.MaxSeriesAction == config.SeriesLimitActionOverflow {
+		c.limitExceeded.WithLabelValues(name, "overflow").Inc()
+		return overflowLabels(metricCfg), nil
+	}
+
+	c.limitExceeded.WithLabelValues(name, "rejected").Inc()
+	return nil, fmt.Errorf("%w: metric '%s' has reached %d series", ErrSeriesLimit, name, metricCfg.MaxSeries)
+}
+
+// CheckSeriesLimit returns an ErrSeriesLimit if the labels of an update would create
+// a series beyond the limit of a metric that rejects such updates. Missing labels
+// are assumed to be defaulted or filled, the update itself enforces the other label
+// policies.
+func (c *MetricCollector) CheckSeriesLimit(name string, labels map[string]string) error {
+	_, err := c.CheckSeriesLimits(name, []map[string]string{labels})
+	return err
+}
+
+// CheckSeriesLimits is CheckSeriesLimit for the updates of a batch to the same
+// metric, the series created by earlier updates count against the limit of later
+// ones. It returns the index of the first update beyond the limit.
+func (c *MetricCollector) CheckSeriesLimits(name string, labelSets []map[string]string) (int, error) {
+	m, ok := c.registered(name)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrMetricNotFound, name)
+	}
+	metricCfg := m.cfg
+
+	if metricCfg.MaxSeries == 0 || metricCfg.MaxSeriesAction == config.SeriesLimitActionOverflow || metricCfg.Eviction != "" {
+		return 0, nil
+	}
+
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+
+	created := make(map[string]bool)
+	for i, labels := range labelSets {
+		id := make(map[string]string, len(metricCfg.Labels))
+		for _, label := range metricCfg.Labels {
+			value, ok := labels[label]
+			if !ok {
+				value, ok = metricCfg.LabelDefaults[label]
+			}
+			if !ok {
+				value = c.labelFiller(metricCfg)
+			}
+			id[label] = value
+		}
+
+		key := seriesKey(metricCfg.Labels, id)
+		if _, ok := m.state.series[key]; ok || created[key] {
+			continue
+		}
+
+		if len(m.state.series)+len(created) >= metricCfg.MaxSeries {
+			c.limitExceeded.WithLabelValues(name, "rejected").Inc()
+			return i, fmt.Errorf("%w: metric '%s' has reached %d series", ErrSeriesLimit, name, metricCfg.MaxSeries)
+		}
+		created[key] = true
+	}
+
+	return 0, nil
+}
+
+// exceedsLimit reports whether the labels are a new series of a metric that has
+// reached its series limit. Concurrent updates may exceed the limit by a few series.
+func (m *registeredMetric) exceedsLimit(labels map[string]string) bool {
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+
+	if _, ok := m.state.series[seriesKey(m.cfg.Labels, labels)]; ok {
+		return false
+	}
+	return len(m.state.series) >= m.cfg.MaxSeries
+}
+
+// evictOldest deletes the series of a metric that wasn't pushed for the longest
+// time
+func (m *registeredMetric) evictOldest() {
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+
+	oldest := m.state.oldest()
+	if oldest == nil {
+		return
+	}
+
+	for _, vec := range m.vecs() {
+		vec.Delete(oldest.labels)
+	}
+	if tracked := m.tracked(); tracked != nil {
+		tracked.delete(m.cfg, oldest.labels)
+	}
+	m.state.remove(oldest)
+	m.state.generation.Add(1)
+
+	log.Debug().Str("metric", m.cfg.Name).Interface("labels", oldest.labels).Msg("series evicted")
+}
+
+// overflowLabels returns the labels of the overflow series of a metric
+func overflowLabels(metricCfg config.MetricConfig) map[string]string {
+	labels := make(map[string]string, len(metricCfg.Labels))
+	for _, label := range metricCfg.Labels {
+		labels[label] = config.OverflowLabelValue
+	}
+	return labels
+}
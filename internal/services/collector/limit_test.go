@@ -0,0 +1,112 @@
+package collector
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+)
+
+func TestSeriesLimit(t *testing.T) {
+	host := func(h string) map[string]string { return map[string]string{"host": h} }
+	overflow := host(config.OverflowLabelValue)
+
+	tests := []struct {
+		name    string
+		action  config.SeriesLimitAction
+		evict   config.Eviction
+		wantErr bool                // Whether the push of db3 is rejected
+		want    []map[string]string // Series after the pushes
+		gone    []map[string]string // Series that don't exist after the pushes
+	}{
+		{
+			name:    "reject",
+			wantErr: true,
+			want:    []map[string]string{host("db1"), host("db2")},
+			gone:    []map[string]string{host("db3")},
+		},
+		{
+			name:   "overflow",
+			action: config.SeriesLimitActionOverflow,
+			want:   []map[string]string{host("db1"), host("db2"), overflow},
+			gone:   []map[string]string{host("db3")},
+		},
+		{
+			name:  "lru",
+			evict: config.EvictionLru,
+			want:  []map[string]string{host("db1"), host("db3")},
+			gone:  []map[string]string{host("db2"), overflow},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCollector(t, config.MetricConfig{
+				Name:            "backup_size_bytes",
+				Type:            config.MetricTypeGauge,
+				Labels:          []string{"host"},
+				MaxSeries:       2,
+				MaxSeriesAction: tt.action,
+				Eviction:        tt.evict,
+			})
+
+			// db1 is pushed again after db2, db2 is the least recently pushed
+			for _, h := range []string{"db1", "db2", "db1"} {
+				if err := c.UpdateGauge("backup_size_bytes", 1, host(h)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := c.UpdateGauge("backup_size_bytes", 1, host("db3"))
+			if tt.wantErr != errors.Is(err, ErrSeriesLimit) {
+				t.Fatalf("UpdateGauge() of db3 = %v, want ErrSeriesLimit %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatal(err)
+			}
+
+			for _, labels := range tt.want {
+				if !c.HasSeries("backup_size_bytes", labels) {
+					t.Errorf("series %v doesn't exist", labels)
+				}
+			}
+			for _, labels := range tt.gone {
+				if c.HasSeries("backup_size_bytes", labels) {
+					t.Errorf("series %v exists", labels)
+				}
+			}
+		})
+	}
+}
+
+func TestEvictionAfterDelete(t *testing.T) {
+	c := newTestCollector(t, config.MetricConfig{
+		Name:      "backup_size_bytes",
+		Type:      config.MetricTypeGauge,
+		Labels:    []string{"host"},
+		MaxSeries: 2,
+		Eviction:  config.EvictionLru,
+	})
+
+	for _, h := range []string{"db1", "db2"} {
+		if err := c.UpdateGauge("backup_size_bytes", 1, map[string]string{"host": h}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The deleted series frees its slot and is never evicted
+	if _, err := c.DeleteSeries("backup_size_bytes", map[string]string{"host": "db1"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range []string{"db3", "db4"} {
+		if err := c.UpdateGauge("backup_size_bytes", 1, map[string]string{"host": h}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for h, want := range map[string]bool{"db1": false, "db2": false, "db3": true, "db4": true} {
+		if got := c.HasSeries("backup_size_bytes", map[string]string{"host": h}); got != want {
+			t.Errorf("HasSeries(%s) = %v, want %v", h, got, want)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	dto "github.com/prometheus/client_model/go"
+)
+
+var (
+	// ErrMetricNotFound is returned when a metric isn't configured
+	ErrMetricNotFound = errors.New("metric not found")
+	// ErrMetricExists is returned when adding a metric that's already configured
+	ErrMetricExists = errors.New("metric already exists")
+	// ErrLabelMismatch is returned when the labels of an update don't match the
+	// configured labels of a metric with the strict label policy
+	ErrLabelMismatch = errors.New("label mismatch")
+	// ErrSampleDropped is returned for updates with missing labels of a metric
+	// that drops such samples, callers treat it as a successful update
+	ErrSampleDropped = errors.New("sample dropped")
+	// ErrSeriesLimit is returned for updates that would create a series beyond the
+	// max_series of a metric
+	ErrSeriesLimit = errors.New("series limit reached")
+	// ErrInvalidValue is returned for counter increments that are negative, NaN,
+	// or infinite, which a counter can't take
+	ErrInvalidValue = errors.New("invalid value")
+)
+
+// MetricSamples is the current state of all series of a metric
+type MetricSamples struct {
+	Name    string            `json:"name"`
+	Type    config.MetricType `json:"type"`
+	Samples []Sample          `json:"samples"`
+}
+
+// Sample is the current value of a single series. Gauges and counters set Value,
+// histograms and summaries set Count, Sum, and their Buckets or Quantiles.
+type Sample struct {
+	Labels    map[string]string  `json:"labels"`
+	Value     *float64           `json:"value,omitempty"`
+	Count     *uint64            `json:"count,omitempty"`
+	Sum       *float64           `json:"sum,omitempty"`
+	Buckets   map[string]uint64  `json:"buckets,omitempty"`
+	Quantiles map[string]float64 `json:"quantiles,omitempty"`
+}
+
+// Samples gathers the current samples of a metric from the registry
+func (c *MetricCollector) Samples(name string) (MetricSamples, error) {
+	metricCfg, ok := c.metricConfig(name)
+	if !ok {
+		return MetricSamples{}, fmt.Errorf("%w: %s", ErrMetricNotFound, name)
+	}
+
+	result := MetricSamples{
+		Name:    name,
+		Type:    metricCfg.Type,
+		Samples: []Sample{},
+	}
+
+	families, err := c.exposed.Load().Gather()
+	if err != nil {
+		return MetricSamples{}, fmt.Errorf("failed to gather metrics: %w", err)
+	}
+
+	fqName := c.exposedFQName(metricCfg)
+	for _, family := range families {
+		if family.GetName() != fqName {
+			continue
+		}
+
+		for _, m := range family.GetMetric() {
+			result.Samples = append(result.Samples, toSample(m))
+		}
+	}
+
+	return result, nil
+}
+
+// toSample converts a gathered metric into a sample, dropping NaN values which
+// can't be represented in JSON
+func toSample(m *dto.Metric) Sample {
+	s := Sample{Labels: make(map[string]string, len(m.GetLabel()))}
+	for _, lp := range m.GetLabel() {
+		s.Labels[lp.GetName()] = lp.GetValue()
+	}
+
+	number := func(v float64) *float64 {
+		if math.IsNaN(v) {
+			return nil
+		}
+		return &v
+	}
+
+	formatFloat := func(v float64) string {
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+
+	switch {
+	case m.GetGauge() != nil:
+		s.Value = number(m.GetGauge().GetValue())
+	case m.GetCounter() != nil:
+		s.Value = number(m.GetCounter().GetValue())
+	case m.GetHistogram() != nil:
+		h := m.GetHistogram()
+		count := h.GetSampleCount()
+		s.Count = &count
+		s.Sum = number(h.GetSampleSum())
+		s.Buckets = make(map[string]uint64, len(h.GetBucket()))
+		for _, b := range h.GetBucket() {
+			s.Buckets[formatFloat(b.GetUpperBound())] = b.GetCumulativeCount()
+		}
+	case m.GetSummary() != nil:
+		sum := m.GetSummary()
+		count := sum.GetSampleCount()
+		s.Count = &count
+		s.Sum = number(sum.GetSampleSum())
+		s.Quantiles = make(map[string]float64, len(sum.GetQuantile()))
+		for _, q := range sum.GetQuantile() {
+			if !math.IsNaN(q.GetValue()) {
+				s.Quantiles[formatFloat(q.GetQuantile())] = q.GetValue()
+			}
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,162 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+)
+
+// Reconcile applies a reloaded configuration to the collector. Metrics removed from
+// the configuration are unregistered, new metrics are registered, and changed
+// metrics are registered again with the values of their series restored where the
+// type and labels allow it. Metrics added at runtime are kept. A change of the
+// global namespace or track_last_push registers every metric again.
+//
+// The metrics are registered with a new registry that replaces the exposed one
+// once all metrics are registered, the client library doesn't allow changing the
+// help or labels of a metric within a registry. Metrics that fail to register are
+// reported in the returned error, changed metrics keep their previous definition
+// in that case. A failed Reconcile keeps exposing the previous registry and keeps
+// the state of the metrics it removed, so reconciling the previous configuration
+// again restores their values.
+func (c *MetricCollector) Reconcile(cfg *config.Config) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	defer c.notifyTTLChanged()
+
+	prev := c.config.Load()
+	rebuild := prev.Global.Namespace != cfg.Global.Namespace || prev.Global.TrackLastPush != cfg.Global.TrackLastPush
+
+	configured := make(map[string]config.MetricConfig, len(cfg.Metrics))
+	for _, metricCfg := range cfg.Metrics {
+		configured[metricCfg.Name] = metricCfg
+	}
+
+	removed := make(map[string]MetricState)
+	for _, metricCfg := range prev.Metrics {
+		if _, ok := configured[metricCfg.Name]; ok {
+			continue
+		}
+		if m, ok := c.registered(metricCfg.Name); ok {
+			if state, ok := m.snapshot(); ok {
+				removed[metricCfg.Name] = state
+			}
+		}
+		if c.removeMetric(metricCfg.Name) {
+			log.Info().Str("metric", metricCfg.Name).Msg("metric removed")
+		}
+	}
+
+	// Metrics added at runtime keep their definition
+	var runtime []config.MetricConfig
+	for _, metricCfg := range c.metrics {
+		if _, ok := configured[metricCfg.Name]; !ok {
+			runtime = append(runtime, metricCfg)
+		}
+	}
+
+	c.config.Store(cfg)
+	c.registry = prometheus.NewRegistry()
+
+	metrics := slices.Concat(cfg.Metrics, runtime)
+
+	var errs []error
+	for _, metricCfg := range metrics {
+		m, ok := c.registered(metricCfg.Name)
+		switch {
+		case !ok:
+			if err := c.registerMetric(metricCfg); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if state, ok := c.removed[metricCfg.Name]; ok {
+				restored := c.restoreMetric(state)
+				log.Info().Str("metric", metricCfg.Name).Int("restored", restored).Msg("metric restored")
+				continue
+			}
+			log.Info().Str("metric", metricCfg.Name).Str("type", metricCfg.Type.String()).Msg("metric added")
+		case rebuild || !reflect.DeepEqual(m.cfg, metricCfg):
+			if err := c.replaceMetric(m, metricCfg); err != nil {
+				errs = append(errs, err)
+			}
+		default:
+			if err := c.registerCollectors(c.registry, metricCfg.Name); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	// Keep the metrics in configuration order, replaced metrics were appended
+	order := make(map[string]int, len(metrics))
+	for i, metricCfg := range metrics {
+		order[metricCfg.Name] = i
+	}
+	slices.SortStableFunc(c.metrics, func(a, b config.MetricConfig) int {
+		return order[a.Name] - order[b.Name]
+	})
+
+	c.jobs = nil
+	if err := c.registerJobs(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		c.removed = removed
+		return errors.Join(errs...)
+	}
+
+	c.removed = nil
+	c.exposed.Store(c.registry)
+	return nil
+}
+
+// restoreMetric restores the series of a metric that was registered again, returning
+// the number of restored series. Series that don't fit its definition are skipped,
+// summaries start over like they do on Restore.
+func (c *MetricCollector) restoreMetric(state MetricState) int {
+	m, ok := c.registered(state.Name)
+	if !ok || m.cfg.Type != state.Type || state.Type == config.MetricTypeSummary {
+		return 0
+	}
+
+	var restored int
+	budget := uint64(maxReplayedObservations)
+	for _, s := range state.Series {
+		if c.restoreSeries(m, s, &budget) == nil {
+			restored++
+		}
+	}
+	return restored
+}
+
+// replaceMetric registers a metric again with a new definition and restores the
+// values of its series. If the new definition fails to register the previous one
+// is restored. The caller must hold the write lock on mutex.
+func (c *MetricCollector) replaceMetric(m *registeredMetric, metricCfg config.MetricConfig) error {
+	state, hasState := m.snapshot()
+	c.removeMetric(m.cfg.Name)
+
+	err := c.registerMetric(metricCfg)
+	if err != nil {
+		if rerr := c.registerMetric(m.cfg); rerr != nil {
+			return fmt.Errorf("error restoring metric '%s': %w", m.cfg.Name, errors.Join(err, rerr))
+		}
+	}
+
+	var restored int
+	if hasState {
+		restored = c.restoreMetric(state)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("metric", metricCfg.Name).Int("restored", restored).Int("reset", len(state.Series)-restored).Msg("metric changed")
+	return nil
+}
@@ -0,0 +1,174 @@
+package collector
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func newTestCollector(t *testing.T, metrics ...config.MetricConfig) *MetricCollector {
+	t.Helper()
+
+	c, err := NewMetricCollector(testConfig(metrics...), prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func testConfig(metrics ...config.MetricConfig) *config.Config {
+	return &config.Config{
+		Global:  config.GlobalConfig{Namespace: "test"},
+		Metrics: metrics,
+	}
+}
+
+// sample returns the exposed sample of a series, failing the test if it isn't
+// exposed
+func sample(t *testing.T, c *MetricCollector, name string, labels map[string]string) Sample {
+	t.Helper()
+
+	samples, err := c.Samples(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range samples.Samples {
+		if maps.Equal(s.Labels, labels) {
+			return s
+		}
+	}
+	t.Fatalf("series %s%v isn't exposed", name, labels)
+	return Sample{}
+}
+
+func TestReconcileRollback(t *testing.T) {
+	backups := config.MetricConfig{Name: "backups_total", Type: config.MetricTypeCounter, Labels: []string{"host"}}
+	size := config.MetricConfig{Name: "backup_size_bytes", Type: config.MetricTypeGauge}
+	// The last push metric of tracked collides with the name of invalid
+	tracked := config.MetricConfig{Name: "restores", Type: config.MetricTypeGauge, TrackLastPush: true}
+	invalid := config.MetricConfig{Name: "restores_last_push_timestamp_seconds", Type: config.MetricTypeGauge}
+
+	c := newTestCollector(t, backups, size)
+	prev := c.config.Load()
+
+	labels := map[string]string{"host": "db1"}
+	if err := c.IncrementCounterBy("backups_total", 5, labels); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.UpdateGauge("backup_size_bytes", 42, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Dropping backups_total while the invalid metric fails to register
+	if err := c.Reconcile(testConfig(size, tracked, invalid)); err == nil {
+		t.Fatal("Reconcile() of an invalid metric succeeded")
+	}
+	if got := *sample(t, c, "backup_size_bytes", nil).Value; got != 42 {
+		t.Errorf("backup_size_bytes after the failed reload = %v, want 42", got)
+	}
+
+	if err := c.Reconcile(prev); err != nil {
+		t.Fatalf("Reconcile() of the previous config: %v", err)
+	}
+	if got := *sample(t, c, "backups_total", labels).Value; got != 5 {
+		t.Errorf("backups_total after the rollback = %v, want 5", got)
+	}
+	if got := *sample(t, c, "backup_size_bytes", nil).Value; got != 42 {
+		t.Errorf("backup_size_bytes after the rollback = %v, want 42", got)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	size := config.MetricConfig{Name: "backup_size_bytes", Type: config.MetricTypeGauge, Labels: []string{"host"}}
+	labels := map[string]string{"host": "db1"}
+
+	described := size
+	described.Description = "Size of the last backup"
+
+	relabeled := size
+	relabeled.Labels = []string{"host", "region"}
+
+	retyped := size
+	retyped.Type = config.MetricTypeCounter
+
+	tests := []struct {
+		name    string
+		next    []config.MetricConfig
+		metrics []string          // Registered metrics after the reload
+		labels  map[string]string // Labels of the series after the reload
+		want    float64           // Value of the series, 0 if it doesn't exist
+	}{
+		{
+			name:    "unchanged",
+			next:    []config.MetricConfig{size},
+			metrics: []string{"backup_size_bytes"},
+			labels:  labels,
+			want:    42,
+		},
+		{
+			name:    "added",
+			next:    []config.MetricConfig{size, {Name: "backups_total", Type: config.MetricTypeCounter}},
+			metrics: []string{"backup_size_bytes", "backups_total"},
+			labels:  labels,
+			want:    42,
+		},
+		{
+			name:   "removed",
+			labels: labels,
+		},
+		{
+			name:    "description changed",
+			next:    []config.MetricConfig{described},
+			metrics: []string{"backup_size_bytes"},
+			labels:  labels,
+			want:    42,
+		},
+		{
+			name:    "label added",
+			next:    []config.MetricConfig{relabeled},
+			metrics: []string{"backup_size_bytes"},
+			labels:  map[string]string{"host": "db1", "region": config.DefaultLabelFiller},
+			want:    42,
+		},
+		{
+			name:    "type changed",
+			next:    []config.MetricConfig{retyped},
+			metrics: []string{"backup_size_bytes"},
+			labels:  labels,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCollector(t, size)
+			if err := c.UpdateGauge("backup_size_bytes", 42, labels); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := c.Reconcile(testConfig(tt.next...)); err != nil {
+				t.Fatal(err)
+			}
+
+			var names []string
+			for _, m := range c.Metrics() {
+				names = append(names, m.Name)
+			}
+			if !slices.Equal(names, tt.metrics) {
+				t.Errorf("metrics = %v, want %v", names, tt.metrics)
+			}
+
+			if tt.want == 0 {
+				if c.HasSeries("backup_size_bytes", tt.labels) {
+					t.Errorf("series %v still exists", tt.labels)
+				}
+				return
+			}
+			if got := *sample(t, c, "backup_size_bytes", tt.labels).Value; got != tt.want {
+				t.Errorf("backup_size_bytes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
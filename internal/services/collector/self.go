@@ -0,0 +1,24 @@
+package collector
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var seriesDesc = prometheus.NewDesc(
+	"cronprom_series",
+	"Number of active series per configured metric",
+	[]string{"metric"}, nil,
+)
+
+// seriesCollector exposes the number of active series of every metric
+type seriesCollector struct {
+	c *MetricCollector
+}
+
+func (s seriesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- seriesDesc
+}
+
+func (s seriesCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, info := range s.c.Metrics() {
+		ch <- prometheus.MustNewConstMetric(seriesDesc, prometheus.GaugeValue, float64(info.Series), info.Name)
+	}
+}
@@ -0,0 +1,325 @@
+package collector
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+)
+
+// series is the runtime state of a single label set of a metric
+type series struct {
+	key      string
+	labels   map[string]string
+	lastPush time.Time
+	recency  *list.Element // Element of the series in metricState.recency
+
+	// Children of counter series cached by cacheCounter, nil until cached
+	counter prometheus.Counter
+	pushed  prometheus.Gauge
+}
+
+// metricState is the runtime state of a metric
+type metricState struct {
+	mu       sync.Mutex
+	lastPush time.Time // Zero until the first push
+	series   map[string]*series
+
+	// The series ordered by their last push, most recent first, so the oldest
+	// series is evicted or expired without scanning all of them
+	recency list.List
+
+	// Incremented whenever series are deleted, after they are deleted from the
+	// metric vectors
+	generation atomic.Uint64
+}
+
+// seriesKey builds a unique key for the label values of a series, in the order of
+// the configured label names
+func seriesKey(labelNames []string, labels map[string]string) string {
+	var b strings.Builder
+	for _, name := range labelNames {
+		b.WriteString(labels[name])
+		b.WriteByte(0xff)
+	}
+	return b.String()
+}
+
+// record sets the last push of the series of key, adding the series with a copy of
+// the labels if it doesn't exist. The caller holds mu.
+func (st *metricState) record(key string, labels map[string]string, at time.Time) *series {
+	s, ok := st.series[key]
+	if ok {
+		st.recency.Remove(s.recency)
+	} else {
+		s = &series{key: key, labels: maps.Clone(labels)}
+		st.series[key] = s
+	}
+	s.lastPush = at
+
+	// Pushes are the most recent, only restored series are placed further back
+	mark := st.recency.Front()
+	for mark != nil && mark.Value.(*series).lastPush.After(at) {
+		mark = mark.Next()
+	}
+	if mark == nil {
+		s.recency = st.recency.PushBack(s)
+	} else {
+		s.recency = st.recency.InsertBefore(s, mark)
+	}
+	return s
+}
+
+// remove deletes a series from the state. The caller holds mu.
+func (st *metricState) remove(s *series) {
+	delete(st.series, s.key)
+	st.recency.Remove(s.recency)
+}
+
+// oldest returns the series that wasn't pushed for the longest time, nil without
+// series. The caller holds mu.
+func (st *metricState) oldest() *series {
+	if e := st.recency.Back(); e != nil {
+		return e.Value.(*series)
+	}
+	return nil
+}
+
+// touch records a successful update of a series
+func (c *MetricCollector) touch(m *registeredMetric, labels map[string]string) {
+	now := time.Now()
+	key := seriesKey(m.cfg.Labels, labels)
+
+	if m.lastPush != nil {
+		m.lastPush.With(labels).Set(float64(now.UnixNano()) / 1e9)
+	}
+
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+
+	m.state.lastPush = now
+	m.state.record(key, labels, now)
+}
+
+// trackedSeries is implemented by metrics that keep track of their series because
+// the exposed labels differ from the labels identifying a series
+type trackedSeries interface {
+	set(c *MetricCollector, m *registeredMetric, labels map[string]string) (map[string]string, error)
+	labels(metricCfg config.MetricConfig, id map[string]string) (map[string]string, bool)
+	delete(metricCfg config.MetricConfig, id map[string]string) bool
+	deleteMatching(labels map[string]string) int
+	reset()
+}
+
+// tracked returns the info or enum metric of a metric, or nil for all other types
+func (m *registeredMetric) tracked() trackedSeries {
+	switch {
+	case m.info != nil:
+		return m.info
+	case m.enum != nil:
+		return m.enum
+	default:
+		return nil
+	}
+}
+
+// vecs returns the metric vector of a metric along with the vectors of its companion
+// metrics. Info and enum metrics only return their companion vectors, their series
+// are removed through tracked.
+func (m *registeredMetric) vecs() []*prometheus.MetricVec {
+	var vecs []*prometheus.MetricVec
+
+	switch {
+	case m.gauge != nil:
+		vecs = append(vecs, m.gauge.MetricVec)
+	case m.counter != nil:
+		vecs = append(vecs, m.counter.MetricVec)
+	case m.histogram != nil:
+		vecs = append(vecs, m.histogram.MetricVec)
+	case m.summary != nil:
+		vecs = append(vecs, m.summary.MetricVec)
+	}
+
+	if m.lastPush != nil {
+		vecs = append(vecs, m.lastPush.MetricVec)
+	}
+
+	return vecs
+}
+
+// DeleteMatching deletes all series of a metric whose labels contain the given
+// labels, returning the number of deleted series. Labels the metric doesn't define
+// never match.
+func (c *MetricCollector) DeleteMatching(name string, labels map[string]string) (int, error) {
+	m, ok := c.registered(name)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrMetricNotFound, name)
+	}
+
+	for label := range labels {
+		if _, ok := m.labels[label]; !ok {
+			return 0, nil
+		}
+	}
+
+	vecs := m.vecs()
+	tracked := m.tracked()
+
+	var deleted int
+	for i, vec := range vecs {
+		n := vec.DeletePartialMatch(labels)
+		if i == 0 && tracked == nil {
+			deleted = n
+		}
+	}
+
+	if tracked != nil {
+		deleted = tracked.deleteMatching(labels)
+	}
+
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+
+	for _, s := range m.state.series {
+		matches := true
+		for k, v := range labels {
+			if s.labels[k] != v {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			m.state.remove(s)
+		}
+	}
+	m.state.generation.Add(1)
+
+	return deleted, nil
+}
+
+// DeleteSeries deletes the series of a metric with exactly the given labels,
+// missing labels are filled like they are for updates. It reports whether the
+// series existed.
+func (c *MetricCollector) DeleteSeries(name string, labels map[string]string) (bool, error) {
+	m, ok := c.registered(name)
+	if !ok {
+		return false, fmt.Errorf("%w: %s", ErrMetricNotFound, name)
+	}
+
+	labels, err := c.cleanLabels(m, labels)
+	if errors.Is(err, ErrSampleDropped) {
+		// Series without all labels are never created
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	vecs := m.vecs()
+	tracked := m.tracked()
+
+	var deleted bool
+	for i, vec := range vecs {
+		ok := vec.Delete(labels)
+		if i == 0 && tracked == nil {
+			deleted = ok
+		}
+	}
+
+	if tracked != nil {
+		deleted = tracked.delete(m.cfg, labels)
+	}
+
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+
+	if s, ok := m.state.series[seriesKey(m.cfg.Labels, labels)]; ok {
+		m.state.remove(s)
+	}
+	m.state.generation.Add(1)
+
+	return deleted, nil
+}
+
+// ResetMetric deletes all series of a metric, returning the number of deleted series
+func (c *MetricCollector) ResetMetric(name string) (int, error) {
+	m, ok := c.registered(name)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrMetricNotFound, name)
+	}
+
+	vecs := m.vecs()
+	tracked := m.tracked()
+
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+
+	for _, vec := range vecs {
+		vec.Reset()
+	}
+	if tracked != nil {
+		tracked.reset()
+	}
+
+	deleted := len(m.state.series)
+	clear(m.state.series)
+	m.state.recency.Init()
+	m.state.generation.Add(1)
+
+	log.Info().Str("metric", name).Int("series", deleted).Msg("metric reset")
+	return deleted, nil
+}
+
+// HasSeries reports whether a metric has the series of the labels, missing labels
+// are assumed to be defaulted or filled
+func (c *MetricCollector) HasSeries(name string, labels map[string]string) bool {
+	m, ok := c.registered(name)
+	if !ok {
+		return false
+	}
+	metricCfg := m.cfg
+
+	id := make(map[string]string, len(metricCfg.Labels))
+	for _, label := range metricCfg.Labels {
+		value, ok := labels[label]
+		if !ok {
+			value, ok = metricCfg.LabelDefaults[label]
+		}
+		if !ok {
+			value = c.labelFiller(metricCfg)
+		}
+		id[label] = value
+	}
+
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+
+	_, ok = m.state.series[seriesKey(metricCfg.Labels, id)]
+	return ok
+}
+
+// Resolve finds the configuration of a metric by its configured or fully qualified name
+func (c *MetricCollector) Resolve(name string) (config.MetricConfig, bool) {
+	if metricCfg, ok := c.metricConfig(name); ok {
+		return metricCfg, true
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	for _, metricCfg := range c.metrics {
+		if c.exposedFQName(metricCfg) == name {
+			return metricCfg, true
+		}
+	}
+
+	return config.MetricConfig{}, false
+}
@@ -0,0 +1,137 @@
+package collector
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+)
+
+// slowRunMetrics are the companion metrics of the duration metric of a job that
+// declares an expected duration. They describe the runs of the job and have no
+// labels, deleting series of the duration metric leaves them alone.
+type slowRunMetrics struct {
+	expected time.Duration
+	last     prometheus.Gauge   // 1 if the last finished run exceeded the expected duration
+	total    prometheus.Counter // number of runs that exceeded the expected duration
+}
+
+// SlowRun is the last finished run of a job that exceeded its expected duration
+type SlowRun struct {
+	Job              string
+	Metric           string // The duration metric of the job
+	RunID            string
+	FinishedAt       time.Time
+	Duration         time.Duration
+	ExpectedDuration time.Duration
+}
+
+// registerSlowRun creates and registers the slow run metrics for the duration
+// metric of a job, named after the job. Runs are reported per job, so the metrics
+// don't take the labels of the duration metric.
+func (c *MetricCollector) registerSlowRun(metricCfg config.MetricConfig) error {
+	namespace := c.namespace(metricCfg)
+	subsystem := metricCfg.Subsystem
+	job := metricCfg.Job
+
+	last := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      job + "_slow_run",
+		Help:      fmt.Sprintf("Whether the last run of %s exceeded its expected duration of %s", job, metricCfg.ExpectedDuration),
+	})
+	if err := c.registry.Register(last); err != nil {
+		return fmt.Errorf("failed to register slow run gauge for '%s': %w", job, err)
+	}
+
+	total := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      job + "_slow_runs_total",
+		Help:      fmt.Sprintf("Total number of runs of %s that exceeded its expected duration of %s", job, metricCfg.ExpectedDuration),
+	})
+	if err := c.registry.Register(total); err != nil {
+		c.registry.Unregister(last)
+		return fmt.Errorf("failed to register slow run counter for '%s': %w", job, err)
+	}
+
+	c.slowRuns[metricCfg.Name] = &slowRunMetrics{
+		expected: metricCfg.ExpectedDuration,
+		last:     last,
+		total:    total,
+	}
+
+	return nil
+}
+
+// recordSlowRun compares the measured duration of a finished run against the
+// expected duration of its job, if it declares one
+func (c *MetricCollector) recordSlowRun(run JobRun) {
+	m, ok := c.registered(run.Job + config.JobDurationSuffix)
+	if !ok || m.slowRun == nil || run.Duration == nil {
+		return
+	}
+
+	slow := m.slowRun
+	if *run.Duration <= slow.expected.Seconds() {
+		slow.last.Set(0)
+		return
+	}
+
+	slow.last.Set(1)
+	slow.total.Inc()
+
+	log.Warn().
+		Str("job", run.Job).
+		Str("run_id", run.RunID).
+		Float64("duration", *run.Duration).
+		Dur("expected", slow.expected).
+		Msg("run exceeded expected duration")
+}
+
+// SlowRuns returns the jobs with an expected duration whose last finished run with
+// a measured duration exceeded it
+func (c *MetricCollector) SlowRuns() []SlowRun {
+	c.mutex.RLock()
+	expected := make(map[string]config.MetricConfig)
+	for _, metricCfg := range c.metrics {
+		if metricCfg.Job != "" && metricCfg.ExpectedDuration > 0 {
+			expected[metricCfg.Job] = metricCfg
+		}
+	}
+	c.mutex.RUnlock()
+
+	c.runs.mu.Lock()
+	defer c.runs.mu.Unlock()
+
+	var slow []SlowRun
+	for job, metricCfg := range expected {
+		s, ok := c.runs.jobs[job]
+		if !ok {
+			continue
+		}
+
+		for _, run := range slices.Backward(s.history) {
+			if run.Duration == nil {
+				continue
+			}
+
+			duration := time.Duration(*run.Duration * float64(time.Second))
+			if duration > metricCfg.ExpectedDuration {
+				slow = append(slow, SlowRun{
+					Job:              job,
+					Metric:           metricCfg.Name,
+					RunID:            run.RunID,
+					FinishedAt:       *run.FinishedAt,
+					Duration:         duration,
+					ExpectedDuration: metricCfg.ExpectedDuration,
+				})
+			}
+			break
+		}
+	}
+	return slow
+}
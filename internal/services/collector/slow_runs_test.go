@@ -0,0 +1,46 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRecordSlowRun(t *testing.T) {
+	job := config.Job{Name: "backup", ExpectedDuration: time.Nanosecond}
+	cfg := testConfig(config.StandardJobMetrics(job)...)
+	cfg.Jobs = []config.Job{job}
+
+	c, err := NewMetricCollector(cfg, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		if _, err := c.StartRun("backup", "run"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.FinishRun("backup", "run", "", nil, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	slow := c.slowRuns["backup_duration_seconds"]
+	if got := testutil.ToFloat64(slow.last); got != 1 {
+		t.Errorf("backup_slow_run = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(slow.total); got != 2 {
+		t.Errorf("backup_slow_runs_total = %v, want 2", got)
+	}
+
+	// Deleting the series of the duration metric keeps the slow run metrics
+	if _, err := c.ResetMetric("backup_duration_seconds"); err != nil {
+		t.Fatal(err)
+	}
+	if got := testutil.ToFloat64(slow.total); got != 2 {
+		t.Errorf("backup_slow_runs_total after the reset = %v, want 2", got)
+	}
+}
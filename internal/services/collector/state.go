@@ -0,0 +1,441 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/rs/zerolog/log"
+)
+
+// stateVersion is the version of the state file format
+const stateVersion = 1
+
+// errSummaryRestore is returned for summary series, whose quantiles can't be
+// recreated from the saved count and sum
+var errSummaryRestore = errors.New("summaries aren't restored")
+
+// maxReplayedObservations is the most observations of histogram series that a
+// restore replays in total, every observation is replayed
+const maxReplayedObservations = 10_000_000
+
+// State is a point in time copy of the series values of the collector
+type State struct {
+	Version int           `json:"version"`
+	Time    time.Time     `json:"time"`
+	Metrics []MetricState `json:"metrics"`
+}
+
+// MetricState holds the series of a metric
+type MetricState struct {
+	Name   string            `json:"name"`
+	Type   config.MetricType `json:"type"`
+	Series []SeriesState     `json:"series"`
+}
+
+// SeriesState holds the value of a single label set. Gauges and counters use the
+// value, histograms and summaries the count and sum, and histograms the buckets.
+type SeriesState struct {
+	Labels   map[string]string `json:"labels"`
+	Value    Float             `json:"value,omitempty"`
+	Count    uint64            `json:"count,omitempty"`
+	Sum      Float             `json:"sum,omitempty"`
+	Buckets  []BucketState     `json:"buckets,omitempty"`
+	LastPush time.Time         `json:"last_push"`
+}
+
+// BucketState is the cumulative count of a histogram bucket
+type BucketState struct {
+	UpperBound Float  `json:"le"`
+	Count      uint64 `json:"count"`
+}
+
+// Float is a value of the state. JSON numbers can't be NaN or infinite, such
+// values are encoded as the strings "NaN", "+Inf", and "-Inf" of the exposition
+// format instead.
+type Float float64
+
+func (f Float) MarshalJSON() ([]byte, error) {
+	v := float64(f)
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return []byte(`"` + strconv.FormatFloat(v, 'g', -1, 64) + `"`), nil
+	}
+	return json.Marshal(v)
+}
+
+func (f *Float) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	if s, err := strconv.Unquote(string(data)); err == nil {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("invalid value '%s'", s)
+		}
+		*f = Float(v)
+		return nil
+	}
+
+	var v float64
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*f = Float(v)
+	return nil
+}
+
+// State returns the current values of the pushed series
+func (c *MetricCollector) State() State {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	state := State{Version: stateVersion, Time: time.Now(), Metrics: []MetricState{}}
+	for _, metricCfg := range c.metrics {
+		m, ok := c.registered(metricCfg.Name)
+		if !ok {
+			continue
+		}
+
+		if metric, ok := m.snapshot(); ok {
+			state.Metrics = append(state.Metrics, metric)
+		}
+	}
+
+	return state
+}
+
+// snapshot returns the state of the pushed series of a metric, it reports false
+// for metrics without series
+func (m *registeredMetric) snapshot() (MetricState, bool) {
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+
+	if len(m.state.series) == 0 {
+		return MetricState{}, false
+	}
+
+	metric := MetricState{Name: m.cfg.Name, Type: m.cfg.Type}
+	for _, s := range m.state.series {
+		ss, err := m.seriesState(s.labels)
+		if err != nil {
+			log.Warn().Err(err).Str("metric", m.cfg.Name).Msg("error reading series value")
+			continue
+		}
+
+		ss.LastPush = s.lastPush
+		metric.Series = append(metric.Series, ss)
+	}
+
+	return metric, true
+}
+
+// seriesState reads the current value of a series
+func (m *registeredMetric) seriesState(labels map[string]string) (SeriesState, error) {
+	metricCfg := m.cfg
+
+	var (
+		metric prometheus.Metric
+		err    error
+	)
+
+	switch metricCfg.Type {
+	case config.MetricTypeInfo, config.MetricTypeEnum:
+		// The info labels or the active state are the state of the series
+		exposed, ok := m.tracked().labels(metricCfg, labels)
+		if !ok {
+			return SeriesState{}, fmt.Errorf("%s series not found", metricCfg.Type)
+		}
+		return SeriesState{Labels: maps.Clone(exposed), Value: 1}, nil
+	case config.MetricTypeGauge:
+		metric, err = m.gauge.GetMetricWith(labels)
+	case config.MetricTypeCounter:
+		metric, err = m.counter.GetMetricWith(labels)
+	case config.MetricTypeHistogram:
+		var o prometheus.Observer
+		o, err = m.histogram.GetMetricWith(labels)
+		metric, _ = o.(prometheus.Metric)
+	case config.MetricTypeSummary:
+		var o prometheus.Observer
+		o, err = m.summary.GetMetricWith(labels)
+		metric, _ = o.(prometheus.Metric)
+	default:
+		return SeriesState{}, fmt.Errorf("unsupported metric type: %s", metricCfg.Type)
+	}
+	if err != nil {
+		return SeriesState{}, err
+	}
+
+	var pb dto.Metric
+	if err := metric.Write(&pb); err != nil {
+		return SeriesState{}, err
+	}
+
+	s := SeriesState{Labels: labels}
+	switch metricCfg.Type {
+	case config.MetricTypeGauge:
+		s.Value = Float(pb.GetGauge().GetValue())
+	case config.MetricTypeCounter:
+		s.Value = Float(pb.GetCounter().GetValue())
+	case config.MetricTypeHistogram:
+		s.Count = pb.GetHistogram().GetSampleCount()
+		s.Sum = Float(pb.GetHistogram().GetSampleSum())
+		for _, b := range pb.GetHistogram().GetBucket() {
+			s.Buckets = append(s.Buckets, BucketState{UpperBound: Float(b.GetUpperBound()), Count: b.GetCumulativeCount()})
+		}
+	case config.MetricTypeSummary:
+		s.Count = pb.GetSummary().GetSampleCount()
+		s.Sum = Float(pb.GetSummary().GetSampleSum())
+	}
+
+	return s, nil
+}
+
+// Restore applies a state to the collector, returning the number of restored
+// series. Gauges and counters are set to the saved value, the current value of a
+// restored series is discarded so restoring a state twice doesn't add it twice.
+// Metrics that no longer exist or changed their type or labels are skipped.
+//
+// Histograms can't be set directly, their observations are replayed instead. Their
+// buckets and counts are exact while the sum is approximated when observations
+// exceeded the largest bucket. Series whose observations exceed the
+// maxReplayedObservations left to replay by the restore are skipped. Summaries
+// aren't restored, their quantiles can't be recreated from the saved count and sum.
+func (c *MetricCollector) Restore(state State) (int, error) {
+	if state.Version != stateVersion {
+		return 0, fmt.Errorf("unsupported state version: %d", state.Version)
+	}
+
+	var restored int
+	budget := uint64(maxReplayedObservations)
+	for _, metric := range state.Metrics {
+		m, ok := c.registered(metric.Name)
+		if !ok || m.cfg.Type != metric.Type {
+			log.Warn().Str("metric", metric.Name).Msg("skipping state of unknown metric")
+			continue
+		}
+		if metric.Type == config.MetricTypeSummary {
+			log.Info().Str("metric", metric.Name).Int("series", len(metric.Series)).Msg("summaries aren't restored, their quantiles reset")
+			continue
+		}
+
+		for _, s := range metric.Series {
+			if err := c.restoreSeries(m, s, &budget); err != nil {
+				log.Warn().Err(err).Str("metric", metric.Name).Msg("skipping state of series")
+				continue
+			}
+			restored++
+		}
+	}
+
+	return restored, nil
+}
+
+// restoreSeries applies the saved value of a series and keeps its last push time.
+// The replayed observations are taken from the budget of the restore.
+func (c *MetricCollector) restoreSeries(m *registeredMetric, s SeriesState, budget *uint64) error {
+	metricCfg := m.cfg
+
+	var (
+		labels map[string]string
+		err    error
+	)
+
+	// The saved labels of info and enum series include the info labels or the
+	// active state
+	if tracked := m.tracked(); tracked != nil {
+		if labels, err = tracked.set(c, m, s.Labels); err != nil {
+			return err
+		}
+	} else if labels, err = c.seriesLabels(m, s.Labels); err != nil {
+		return err
+	}
+
+	if s.Count > *budget {
+		return fmt.Errorf("%d observations exceed the %d left to replay", s.Count, *budget)
+	}
+	for _, b := range s.Buckets {
+		if b.Count > s.Count {
+			return fmt.Errorf("bucket count %d exceeds the count %d", b.Count, s.Count)
+		}
+	}
+
+	switch metricCfg.Type {
+	case config.MetricTypeInfo, config.MetricTypeEnum:
+		// Set above
+	case config.MetricTypeGauge:
+		gauge, err := m.gauge.GetMetricWith(labels)
+		if err != nil {
+			return err
+		}
+		gauge.Set(float64(s.Value))
+	case config.MetricTypeCounter:
+		// Adding a negative value panics
+		if !(s.Value >= 0) || math.IsInf(float64(s.Value), 1) {
+			return fmt.Errorf("%w: counter value %v", ErrInvalidValue, float64(s.Value))
+		}
+		m.clearSeries(m.counter.MetricVec, labels)
+		counter, err := m.counter.GetMetricWith(labels)
+		if err != nil {
+			return err
+		}
+		counter.Add(float64(s.Value))
+	case config.MetricTypeHistogram:
+		m.clearSeries(m.histogram.MetricVec, labels)
+		histogram, err := m.histogram.GetMetricWith(labels)
+		if err != nil {
+			return err
+		}
+		replayHistogram(histogram, s)
+		*budget -= s.Count
+	case config.MetricTypeSummary:
+		return errSummaryRestore
+	default:
+		return fmt.Errorf("unsupported metric type: %s", metricCfg.Type)
+	}
+
+	if m.lastPush != nil {
+		m.lastPush.With(labels).Set(float64(s.LastPush.UnixNano()) / 1e9)
+	}
+
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+
+	if s.LastPush.After(m.state.lastPush) {
+		m.state.lastPush = s.LastPush
+	}
+	m.state.record(seriesKey(metricCfg.Labels, labels), labels, s.LastPush)
+
+	return nil
+}
+
+// clearSeries deletes the current value of a series before its saved value is
+// applied
+func (m *registeredMetric) clearSeries(vec *prometheus.MetricVec, labels map[string]string) {
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+
+	vec.Delete(labels)
+	if s, ok := m.state.series[seriesKey(m.cfg.Labels, labels)]; ok {
+		m.state.remove(s)
+	}
+	m.state.generation.Add(1)
+}
+
+// replayHistogram observes the upper bound of each bucket once per observation of
+// the bucket. Observations above the largest bucket share the rest of the sum.
+func replayHistogram(histogram prometheus.Observer, s SeriesState) {
+	var (
+		prev    uint64
+		sum     float64
+		largest = math.Inf(-1)
+	)
+
+	for _, b := range s.Buckets {
+		if b.Count < prev {
+			continue
+		}
+
+		for range b.Count - prev {
+			histogram.Observe(float64(b.UpperBound))
+		}
+		sum += float64(b.Count-prev) * float64(b.UpperBound)
+		prev = b.Count
+		largest = float64(b.UpperBound)
+	}
+
+	if s.Count <= prev {
+		return
+	}
+
+	overflow := s.Count - prev
+	value := (float64(s.Sum) - sum) / float64(overflow)
+	if value <= largest {
+		value = math.Nextafter(largest, math.Inf(1))
+	}
+
+	for range overflow {
+		histogram.Observe(value)
+	}
+}
+
+// LoadState restores the state file, a missing file is not an error
+func (c *MetricCollector) LoadState(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("error reading state file: %w", err)
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("error decoding state file: %w", err)
+	}
+
+	restored, err := c.Restore(state)
+	if err != nil {
+		return fmt.Errorf("error restoring state file: %w", err)
+	}
+
+	log.Info().Str("path", path).Int("series", restored).Time("saved", state.Time).Msg("restored state")
+	return nil
+}
+
+// SaveState atomically writes the current state to the state file
+func (c *MetricCollector) SaveState(path string) error {
+	data, err := json.Marshal(c.State())
+	if err != nil {
+		return fmt.Errorf("error encoding state file: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error writing state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("error writing state file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing state file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error writing state file: %w", err)
+	}
+
+	return nil
+}
+
+// RunCheckpoint writes the state file every interval until the context is canceled
+func (c *MetricCollector) RunCheckpoint(ctx context.Context, path string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.SaveState(path); err != nil {
+				log.Error().Err(err).Msg("error checkpointing state")
+			}
+		}
+	}
+}
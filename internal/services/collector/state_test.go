@@ -0,0 +1,212 @@
+package collector
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+)
+
+func TestRestoreSkipsSummaries(t *testing.T) {
+	latency := config.MetricConfig{Name: "backup_latency_seconds", Type: config.MetricTypeSummary}
+
+	src := newTestCollector(t, latency)
+	for _, v := range []float64{1, 2, 30} {
+		if err := src.ObserveSummary("backup_latency_seconds", v, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dst := newTestCollector(t, latency)
+	restored, err := dst.Restore(src.State())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored != 0 {
+		t.Errorf("Restore() restored %d summary series, want 0", restored)
+	}
+	if dst.HasSeries("backup_latency_seconds", nil) {
+		t.Error("summary series was restored")
+	}
+}
+
+func TestRestoreTwice(t *testing.T) {
+	metrics := []config.MetricConfig{
+		{Name: "backups_total", Type: config.MetricTypeCounter, Labels: []string{"host"}},
+		{Name: "backup_size_bytes", Type: config.MetricTypeGauge, Labels: []string{"host"}},
+		{Name: "backup_duration_seconds", Type: config.MetricTypeHistogram, Labels: []string{"host"}, Buckets: config.Buckets{1, 10}},
+	}
+	labels := map[string]string{"host": "db1"}
+
+	src := newTestCollector(t, metrics...)
+	if err := src.IncrementCounterBy("backups_total", 3, labels); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.UpdateGauge("backup_size_bytes", 42, labels); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []float64{0.5, 5} {
+		if err := src.ObserveHistogram("backup_duration_seconds", v, labels); err != nil {
+			t.Fatal(err)
+		}
+	}
+	state := src.State()
+
+	dst := newTestCollector(t, metrics...)
+	if err := dst.IncrementCounterBy("backups_total", 7, labels); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 2 {
+		restored, err := dst.Restore(state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if restored != 3 {
+			t.Errorf("restore %d: restored %d series, want 3", i, restored)
+		}
+
+		if got := *sample(t, dst, "backups_total", labels).Value; got != 3 {
+			t.Errorf("restore %d: backups_total = %v, want 3", i, got)
+		}
+		if got := *sample(t, dst, "backup_size_bytes", labels).Value; got != 42 {
+			t.Errorf("restore %d: backup_size_bytes = %v, want 42", i, got)
+		}
+		h := sample(t, dst, "backup_duration_seconds", labels)
+		if *h.Count != 2 || h.Buckets["1"] != 1 || h.Buckets["10"] != 2 {
+			t.Errorf("restore %d: backup_duration_seconds count %d buckets %v, want 2 map[1:1 10:2]", i, *h.Count, h.Buckets)
+		}
+	}
+
+	// Increments after the restore count from the restored value
+	if err := dst.IncrementCounterBy("backups_total", 1, labels); err != nil {
+		t.Fatal(err)
+	}
+	if got := *sample(t, dst, "backups_total", labels).Value; got != 4 {
+		t.Errorf("backups_total after an increment = %v, want 4", got)
+	}
+}
+
+func TestStateRoundTrip(t *testing.T) {
+	metrics := []config.MetricConfig{
+		{Name: "backup_size_bytes", Type: config.MetricTypeGauge, Labels: []string{"host"}},
+		{Name: "backups_total", Type: config.MetricTypeCounter, Labels: []string{"host"}},
+		{Name: "backup_duration_seconds", Type: config.MetricTypeHistogram, Buckets: config.Buckets{1, 10}},
+	}
+
+	gauges := map[string]float64{
+		"db1": 42,
+		"db2": math.NaN(),
+		"db3": math.Inf(1),
+		"db4": math.Inf(-1),
+	}
+
+	src := newTestCollector(t, metrics...)
+	for host, v := range gauges {
+		if err := src.UpdateGauge("backup_size_bytes", v, map[string]string{"host": host}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := src.IncrementCounterBy("backups_total", 5, map[string]string{"host": "db1"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []float64{0.5, 5, 50} {
+		if err := src.ObserveHistogram("backup_duration_seconds", v, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := src.SaveState(path); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := newTestCollector(t, metrics...)
+	if err := dst.LoadState(path); err != nil {
+		t.Fatal(err)
+	}
+
+	for host, want := range gauges {
+		// Samples leave out NaN values
+		got := math.NaN()
+		if v := sample(t, dst, "backup_size_bytes", map[string]string{"host": host}).Value; v != nil {
+			got = *v
+		}
+		if got != want && !(math.IsNaN(got) && math.IsNaN(want)) {
+			t.Errorf("backup_size_bytes{host=%s} = %v, want %v", host, got, want)
+		}
+	}
+	if got := *sample(t, dst, "backups_total", map[string]string{"host": "db1"}).Value; got != 5 {
+		t.Errorf("backups_total = %v, want 5", got)
+	}
+	h := sample(t, dst, "backup_duration_seconds", nil)
+	if *h.Count != 3 || h.Buckets["1"] != 1 || h.Buckets["10"] != 2 {
+		t.Errorf("backup_duration_seconds count %d buckets %v, want 3 map[1:1 10:2]", *h.Count, h.Buckets)
+	}
+
+	// The series keep their last push, they don't look freshly pushed
+	got := lastPushes(dst.State())
+	for series, want := range lastPushes(src.State()) {
+		if !got[series].Equal(want) {
+			t.Errorf("%s last push = %v, want %v", series, got[series], want)
+		}
+	}
+}
+
+// lastPushes returns the last push of the series of a state by metric name and labels
+func lastPushes(state State) map[string]time.Time {
+	pushes := make(map[string]time.Time)
+	for _, metric := range state.Metrics {
+		for _, s := range metric.Series {
+			pushes[fmt.Sprint(metric.Name, s.Labels)] = s.LastPush
+		}
+	}
+	return pushes
+}
+
+func TestLoadStateMissingFile(t *testing.T) {
+	c := newTestCollector(t)
+	if err := c.LoadState(filepath.Join(t.TempDir(), "state.json")); err != nil {
+		t.Errorf("LoadState() of a missing file = %v, want nil", err)
+	}
+}
+
+func TestRestoreReplayBudget(t *testing.T) {
+	duration := config.MetricConfig{Name: "backup_duration_seconds", Type: config.MetricTypeHistogram, Labels: []string{"host"}, Buckets: config.Buckets{1}}
+	c := newTestCollector(t, duration)
+
+	state := State{
+		Version: stateVersion,
+		Time:    time.Now(),
+		Metrics: []MetricState{{
+			Name: duration.Name,
+			Type: duration.Type,
+			Series: []SeriesState{
+				{Labels: map[string]string{"host": "db1"}, Count: maxReplayedObservations + 1, Sum: 1},
+				{Labels: map[string]string{"host": "db2"}, Count: 2, Sum: 1, Buckets: []BucketState{{UpperBound: 1, Count: 2}}},
+				{Labels: map[string]string{"host": "db3"}, Count: 1, Buckets: []BucketState{{UpperBound: 1, Count: 2}}},
+			},
+		}},
+	}
+
+	restored, err := c.Restore(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored != 1 {
+		t.Errorf("Restore() restored %d series, want 1", restored)
+	}
+
+	// Beyond the budget, and a bucket count above the series count
+	for _, host := range []string{"db1", "db3"} {
+		if c.HasSeries(duration.Name, map[string]string{"host": host}) {
+			t.Errorf("series of %s was restored", host)
+		}
+	}
+	if got := *sample(t, c, duration.Name, map[string]string{"host": "db2"}).Count; got != 2 {
+		t.Errorf("count of db2 = %d, want 2", got)
+	}
+}
@@ -0,0 +1,122 @@
+package collector
+
+import (
+	"context"
+	"time"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/rs/zerolog/log"
+)
+
+// ttl returns the time after which series of the metric expire, 0 never expires
+func (c *MetricCollector) ttl(metricCfg config.MetricConfig) time.Duration {
+	if metricCfg.TTL > 0 {
+		return metricCfg.TTL
+	}
+	return c.config.Load().Global.TTL
+}
+
+// RunExpiry removes expired series until the context is canceled. The check
+// interval is derived from the shortest TTL so series don't outlive it by much,
+// it is derived again when Reconcile or AddMetric change the metrics.
+func (c *MetricCollector) RunExpiry(ctx context.Context) {
+	interval := c.expiryInterval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.ttlChanged:
+			if next := c.expiryInterval(); next != interval {
+				interval = next
+				ticker.Reset(interval)
+				log.Debug().Dur("interval", interval).Msg("series expiry interval changed")
+			}
+		case now := <-ticker.C:
+			c.ExpireSeries(now)
+		}
+	}
+}
+
+// notifyTTLChanged wakes RunExpiry to derive its interval again, it never blocks
+func (c *MetricCollector) notifyTTLChanged() {
+	select {
+	case c.ttlChanged <- struct{}{}:
+	default:
+	}
+}
+
+// expiryInterval returns the interval between expiry checks for the current TTLs
+func (c *MetricCollector) expiryInterval() time.Duration {
+	shortest := c.config.Load().Global.TTL
+
+	c.mutex.RLock()
+	for _, metricCfg := range c.metrics {
+		if metricCfg.TTL > 0 && (shortest == 0 || metricCfg.TTL < shortest) {
+			shortest = metricCfg.TTL
+		}
+	}
+	c.mutex.RUnlock()
+
+	// Nothing expires yet, the interval is derived again once a TTL is configured
+	if shortest == 0 {
+		return time.Minute
+	}
+	return min(max(shortest/4, time.Second), time.Minute)
+}
+
+// ExpireSeries removes the series that weren't pushed within the TTL of their
+// metric, returning the number of removed series
+func (c *MetricCollector) ExpireSeries(now time.Time) int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	var removed int
+	for _, metricCfg := range c.metrics {
+		ttl := c.ttl(metricCfg)
+		if ttl <= 0 {
+			continue
+		}
+
+		m, ok := c.registered(metricCfg.Name)
+		if !ok {
+			continue
+		}
+
+		removed += m.expire(now, ttl)
+	}
+
+	return removed
+}
+
+// expire removes the series of a metric that weren't pushed within the TTL,
+// returning the number of removed series
+func (m *registeredMetric) expire(now time.Time, ttl time.Duration) int {
+	metricCfg := m.cfg
+
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+
+	vecs := m.vecs()
+	tracked := m.tracked()
+
+	// The series are ordered by their last push, the expired ones are at the back
+	var removed int
+	for s := m.state.oldest(); s != nil && now.Sub(s.lastPush) >= ttl; s = m.state.oldest() {
+		for _, vec := range vecs {
+			vec.Delete(s.labels)
+		}
+		if tracked != nil {
+			tracked.delete(metricCfg, s.labels)
+		}
+		m.state.remove(s)
+		m.state.generation.Add(1)
+		removed++
+
+		log.Debug().Str("metric", metricCfg.Name).Interface("labels", s.labels).Msg("series expired")
+	}
+
+	return removed
+}
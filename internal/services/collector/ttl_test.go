@@ -0,0 +1,97 @@
+package collector
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+)
+
+func TestExpiryIntervalFollowsMetrics(t *testing.T) {
+	c := newTestCollector(t)
+	if got := c.expiryInterval(); got != time.Minute {
+		t.Errorf("expiryInterval() without TTL = %v, want %v", got, time.Minute)
+	}
+
+	// A metric added at runtime with a short TTL wakes RunExpiry
+	added := config.MetricConfig{Name: "heartbeat", Type: config.MetricTypeGauge, TTL: 8 * time.Second}
+	if err := c.AddMetric(added); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-c.ttlChanged:
+	default:
+		t.Error("AddMetric() didn't signal the TTL change")
+	}
+	if got := c.expiryInterval(); got != 2*time.Second {
+		t.Errorf("expiryInterval() after AddMetric = %v, want %v", got, 2*time.Second)
+	}
+
+	// A reload with a shorter TTL
+	reloaded := config.MetricConfig{Name: "backups_total", Type: config.MetricTypeCounter, TTL: 2 * time.Second}
+	if err := c.Reconcile(testConfig(reloaded)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-c.ttlChanged:
+	default:
+		t.Error("Reconcile() didn't signal the TTL change")
+	}
+	if got := c.expiryInterval(); got != time.Second {
+		t.Errorf("expiryInterval() after Reconcile = %v, want %v", got, time.Second)
+	}
+}
+
+func TestExpireSeries(t *testing.T) {
+	metrics := []config.MetricConfig{
+		{Name: "heartbeat", Type: config.MetricTypeGauge, Labels: []string{"host"}, TTL: time.Minute},
+		{Name: "backups_total", Type: config.MetricTypeCounter, Labels: []string{"host"}},
+	}
+	c := newTestCollector(t, metrics...)
+
+	now := time.Now()
+	series := func(pushed ...time.Duration) []SeriesState {
+		var s []SeriesState
+		for i, ago := range pushed {
+			s = append(s, SeriesState{Labels: map[string]string{"host": fmt.Sprintf("db%d", i+1)}, Value: 1, LastPush: now.Add(-ago)})
+		}
+		return s
+	}
+
+	// The restored series keep their last push, db1 and db3 are past the TTL
+	_, err := c.Restore(State{
+		Version: stateVersion,
+		Time:    now,
+		Metrics: []MetricState{
+			{Name: "heartbeat", Type: config.MetricTypeGauge, Series: series(2*time.Minute, 30*time.Second, time.Hour)},
+			{Name: "backups_total", Type: config.MetricTypeCounter, Series: series(time.Hour)},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := c.ExpireSeries(now); got != 2 {
+		t.Errorf("ExpireSeries() = %d, want 2", got)
+	}
+
+	for name, hosts := range map[string]map[string]bool{
+		"heartbeat":     {"db1": false, "db2": true, "db3": false},
+		"backups_total": {"db1": true}, // No TTL
+	} {
+		for host, want := range hosts {
+			if got := c.HasSeries(name, map[string]string{"host": host}); got != want {
+				t.Errorf("HasSeries(%s, %s) = %v, want %v", name, host, got, want)
+			}
+		}
+	}
+
+	// A push keeps a series from expiring
+	if err := c.UpdateGauge("heartbeat", 1, map[string]string{"host": "db2"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.ExpireSeries(now.Add(45 * time.Second)); got != 0 {
+		t.Errorf("ExpireSeries() after the push = %d, want 0", got)
+	}
+}
@@ -0,0 +1,15 @@
+package collector
+
+// units maps the fully qualified names of metrics to their unit
+func (c *MetricCollector) units() map[string]string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	units := make(map[string]string)
+	for _, metricCfg := range c.metrics {
+		if metricCfg.Unit != "" {
+			units[c.exposedFQName(metricCfg)] = metricCfg.Unit
+		}
+	}
+	return units
+}
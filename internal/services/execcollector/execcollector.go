@@ -0,0 +1,197 @@
+// Package execcollector runs commands on an interval and exposes the metrics they
+// output, for numbers that are read out of a log file or a database rather than
+// pushed.
+package execcollector
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	runSuccess = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cronprom_exec_collector_success",
+			Help: "Whether the last run of the exec collector succeeded and its output was parsed",
+		},
+		[]string{"collector"},
+	)
+	runDuration = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cronprom_exec_collector_duration_seconds",
+			Help: "Duration of the last run of the exec collector",
+		},
+		[]string{"collector"},
+	)
+	conflicts = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cronprom_exec_collector_conflicts",
+			Help: "Number of metrics of the exec collector skipped at the last scrape because cronprom or another collector already exposes them",
+		},
+		[]string{"collector"},
+	)
+)
+
+// RegisterMetrics registers the metrics describing the exec collectors
+func RegisterMetrics(reg prometheus.Registerer) {
+	reg.MustRegister(runSuccess, runDuration, conflicts)
+}
+
+// waitDelay is how long a run waits for the output of processes the killed command
+// left behind, like the children of a shell
+const waitDelay = 5 * time.Second
+
+// Collector runs the command of an exec collector on its interval. It is a
+// gatherer of the metrics of the last successful run, a failed run removes them.
+type Collector struct {
+	cfg config.ExecCollector
+
+	mu       sync.RWMutex
+	families []*dto.MetricFamily
+}
+
+// New creates an exec collector
+func New(cfg config.ExecCollector) *Collector {
+	return &Collector{cfg: cfg}
+}
+
+// Run runs the command right away and then on every interval until the context
+// is canceled
+func (c *Collector) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		c.collect(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Gather returns the metrics of the last successful run
+func (c *Collector) Gather() ([]*dto.MetricFamily, error) {
+	return c.GatherExcept(nil)
+}
+
+// GatherExcept is Gather skipping the metrics whose names are taken, they are
+// counted by cronprom_exec_collector_conflicts
+func (c *Collector) GatherExcept(taken map[string]bool) ([]*dto.MetricFamily, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	// Gatherers add labels to the families, they get copies
+	families := make([]*dto.MetricFamily, 0, len(c.families))
+	var skipped []string
+	for _, family := range c.families {
+		if taken[family.GetName()] {
+			skipped = append(skipped, family.GetName())
+			continue
+		}
+		families = append(families, proto.Clone(family).(*dto.MetricFamily))
+	}
+
+	if len(skipped) > 0 {
+		log.Debug().Str("collector", c.cfg.Name).Strs("metrics", skipped).Msg("skipping metrics of exec collector exposed elsewhere")
+	}
+	conflicts.WithLabelValues(c.cfg.Name).Set(float64(len(skipped)))
+
+	return families, nil
+}
+
+// collect runs the command once and replaces the metrics with its output
+func (c *Collector) collect(ctx context.Context) {
+	start := time.Now()
+	families, err := c.run(ctx)
+	runDuration.WithLabelValues(c.cfg.Name).Set(time.Since(start).Seconds())
+
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Warn().Err(err).Str("collector", c.cfg.Name).Msg("exec collector failed")
+		}
+		runSuccess.WithLabelValues(c.cfg.Name).Set(0)
+		families = nil
+	} else {
+		runSuccess.WithLabelValues(c.cfg.Name).Set(1)
+	}
+
+	c.mu.Lock()
+	c.families = families
+	c.mu.Unlock()
+}
+
+// run runs the command and parses its output
+func (c *Collector) run(ctx context.Context) ([]*dto.MetricFamily, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.cfg.Command, c.cfg.Args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = waitDelay
+	if len(c.cfg.Env) > 0 {
+		cmd.Env = os.Environ()
+		for k, v := range c.cfg.Env {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+	}
+
+	if err := cmd.Run(); err != nil {
+		if reason := strings.TrimSpace(stderr.String()); reason != "" {
+			return nil, fmt.Errorf("%w: %s", err, reason)
+		}
+		return nil, err
+	}
+
+	return parseOutput(stdout.Bytes(), c.cfg.Labels)
+}
+
+// parseOutput parses the output of a command in the Prometheus text format, which
+// includes plain "name value" lines as untyped samples, and adds the labels to
+// every series that doesn't have them
+func parseOutput(out []byte, labels map[string]string) ([]*dto.MetricFamily, error) {
+	var parser expfmt.TextParser
+	parsed, err := parser.TextToMetricFamilies(bytes.NewReader(out))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing output: %w", err)
+	}
+
+	names := slices.Sorted(maps.Keys(labels))
+
+	families := make([]*dto.MetricFamily, 0, len(parsed))
+	for _, name := range slices.Sorted(maps.Keys(parsed)) {
+		family := parsed[name]
+		for _, m := range family.Metric {
+			for _, label := range names {
+				if !slices.ContainsFunc(m.Label, func(l *dto.LabelPair) bool { return l.GetName() == label }) {
+					m.Label = append(m.Label, &dto.LabelPair{Name: proto.String(label), Value: proto.String(labels[label])})
+				}
+			}
+			slices.SortFunc(m.Label, func(a, b *dto.LabelPair) int {
+				return strings.Compare(a.GetName(), b.GetName())
+			})
+		}
+		families = append(families, family)
+	}
+
+	return families, nil
+}
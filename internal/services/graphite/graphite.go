@@ -0,0 +1,244 @@
+// Package graphite implements a listener for the Graphite plaintext protocol that
+// maps dotted metric paths onto configured metrics.
+package graphite
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/hay-kot/cronprom/internal/services/collector"
+	"github.com/rs/zerolog/log"
+)
+
+// maxPacketSize is the largest UDP datagram accepted
+const maxPacketSize = 65535
+
+// Ingester validates and applies a sample like a push to the API
+type Ingester interface {
+	Ingest(ctx context.Context, peer netip.Addr, name string, metricType config.MetricType, value float64, labels map[string]string) error
+}
+
+// Server accepts Graphite plaintext lines over TCP and UDP
+type Server struct {
+	cfg       config.Graphite
+	allowed   []netip.Prefix // Networks allowed to send lines, empty allows all
+	collector *collector.MetricCollector
+	ingester  Ingester
+}
+
+// New creates a new Graphite server. Connections and datagrams are only accepted
+// from the allowed networks, the web.allow_cidrs of the push API. The collector
+// resolves the metric of a path.
+func New(cfg config.Graphite, allowed []netip.Prefix, collector *collector.MetricCollector, ingester Ingester) *Server {
+	return &Server{
+		cfg:       cfg,
+		allowed:   allowed,
+		collector: collector,
+		ingester:  ingester,
+	}
+}
+
+// ListenAndServe listens on the configured address for TCP and UDP until the
+// context is canceled, it returns once the lines being read are applied
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.cfg.Address)
+	if err != nil {
+		return fmt.Errorf("graphite: %w", err)
+	}
+
+	pc, err := net.ListenPacket("udp", s.cfg.Address)
+	if err != nil {
+		_ = ln.Close()
+		return fmt.Errorf("graphite: %w", err)
+	}
+
+	// The listeners and connections are closed when the context is canceled, which
+	// happens on return as well so a failing listener doesn't wait for them forever
+	var conns sync.WaitGroup
+	defer conns.Wait()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	context.AfterFunc(ctx, func() {
+		_ = ln.Close()
+		_ = pc.Close()
+	})
+
+	log.Info().Str("addr", s.cfg.Address).Msg("starting Graphite listener")
+
+	conns.Add(1)
+	go func() {
+		defer conns.Done()
+		s.serveUDP(ctx, pc)
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("graphite: %w", err)
+		}
+
+		conns.Add(1)
+		go func() {
+			defer conns.Done()
+			s.serveConn(ctx, conn)
+		}()
+	}
+}
+
+// serveConn reads lines from a TCP connection until it is closed, connections
+// from networks that aren't allowed are closed right away
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+
+	peer := peerAddr(conn.RemoteAddr())
+	if !s.allows(peer) {
+		log.Debug().Stringer("addr", conn.RemoteAddr()).Msg("graphite: connection from a network that isn't allowed")
+		return
+	}
+
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		s.handleLine(ctx, peer, scanner.Text())
+	}
+}
+
+// serveUDP reads datagrams, each containing one or more lines
+func (s *Server) serveUDP(ctx context.Context, pc net.PacketConn) {
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+				log.Error().Err(err).Msg("graphite: error reading UDP packet")
+			}
+			return
+		}
+
+		peer := peerAddr(addr)
+		if !s.allows(peer) {
+			log.Debug().Stringer("addr", addr).Msg("graphite: packet from a network that isn't allowed")
+			continue
+		}
+
+		for line := range strings.Lines(string(buf[:n])) {
+			s.handleLine(ctx, peer, line)
+		}
+	}
+}
+
+// handleLine applies a single sample, invalid lines, unknown metrics, and rejected
+// samples are dropped
+func (s *Server) handleLine(ctx context.Context, peer netip.Addr, line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+
+	path, tags, value, err := parseLine(line)
+	if err != nil {
+		log.Debug().Err(err).Str("line", line).Msg("graphite: invalid line")
+		return
+	}
+
+	name, labels := s.mapPath(path)
+	for k, v := range tags {
+		if _, exists := labels[k]; !exists {
+			labels[k] = v
+		}
+	}
+
+	metricCfg, ok := s.collector.Resolve(name)
+	if !ok {
+		log.Debug().Str("path", path).Str("metric", name).Msg("graphite: no metric for path")
+		return
+	}
+
+	err = s.ingester.Ingest(ctx, peer, metricCfg.Name, metricCfg.Type, value, labels)
+	if err != nil {
+		log.Warn().Err(err).Str("metric", metricCfg.Name).Stringer("addr", peer).Msg("graphite: sample rejected")
+	}
+}
+
+// allows reports whether lines of the peer are accepted
+func (s *Server) allows(peer netip.Addr) bool {
+	if len(s.allowed) == 0 {
+		return true
+	}
+
+	for _, p := range s.allowed {
+		if p.Contains(peer) {
+			return true
+		}
+	}
+	return false
+}
+
+// peerAddr returns the IP address of a peer, it is invalid for addresses that
+// aren't IP addresses
+func peerAddr(addr net.Addr) netip.Addr {
+	ap, err := netip.ParseAddrPort(addr.String())
+	if err != nil {
+		return netip.Addr{}
+	}
+	return ap.Addr().Unmap()
+}
+
+// mapPath returns the metric name and labels for a path using the first matching
+// mapping. Unmapped paths use the path with dots replaced by underscores.
+func (s *Server) mapPath(path string) (string, map[string]string) {
+	for _, m := range s.cfg.Mappings {
+		if name, labels, ok := m.Map(path); ok {
+			return name, labels
+		}
+	}
+
+	return strings.ReplaceAll(path, ".", "_"), map[string]string{}
+}
+
+// parseLine parses a "path value [timestamp]" line. The path may carry tags in the
+// "path;tag=value" format. The timestamp is ignored, samples are recorded on arrival.
+func parseLine(line string) (string, map[string]string, float64, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 2 && len(fields) != 3 {
+		return "", nil, 0, fmt.Errorf("expected 'path value [timestamp]'")
+	}
+
+	value, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return "", nil, 0, fmt.Errorf("invalid value '%s'", fields[1])
+	}
+
+	path, rawTags, _ := strings.Cut(fields[0], ";")
+	if path == "" {
+		return "", nil, 0, fmt.Errorf("path cannot be empty")
+	}
+
+	tags := make(map[string]string)
+	if rawTags != "" {
+		for tag := range strings.SplitSeq(rawTags, ";") {
+			k, v, ok := strings.Cut(tag, "=")
+			if !ok || k == "" {
+				return "", nil, 0, fmt.Errorf("invalid tag '%s'", tag)
+			}
+			tags[k] = v
+		}
+	}
+
+	return path, tags, value, nil
+}
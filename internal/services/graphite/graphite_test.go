@@ -0,0 +1,241 @@
+package graphite
+
+import (
+	"context"
+	"maps"
+	"math"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/hay-kot/cronprom/internal/services/collector"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		wantPath  string
+		wantTags  map[string]string
+		wantValue float64
+		wantErr   bool
+	}{
+		{
+			name:      "path and value",
+			line:      "backup.duration 12.5",
+			wantPath:  "backup.duration",
+			wantTags:  map[string]string{},
+			wantValue: 12.5,
+		},
+		{
+			name:      "timestamp is ignored",
+			line:      "backup.duration 12.5 1700000000",
+			wantPath:  "backup.duration",
+			wantTags:  map[string]string{},
+			wantValue: 12.5,
+		},
+		{
+			name:      "tags",
+			line:      "backup.duration;host=db1;job=nightly 3",
+			wantPath:  "backup.duration",
+			wantTags:  map[string]string{"host": "db1", "job": "nightly"},
+			wantValue: 3,
+		},
+		{
+			name:      "empty tag value",
+			line:      "backup;host= 1",
+			wantPath:  "backup",
+			wantTags:  map[string]string{"host": ""},
+			wantValue: 1,
+		},
+		{
+			name:      "surrounding whitespace",
+			line:      "  backup\t-1  ",
+			wantPath:  "backup",
+			wantTags:  map[string]string{},
+			wantValue: -1,
+		},
+		{
+			name:      "special values",
+			line:      "backup +Inf",
+			wantPath:  "backup",
+			wantTags:  map[string]string{},
+			wantValue: math.Inf(1),
+		},
+		{name: "empty line", line: "", wantErr: true},
+		{name: "missing value", line: "backup.duration", wantErr: true},
+		{name: "too many fields", line: "backup.duration 1 1700000000 extra", wantErr: true},
+		{name: "invalid value", line: "backup.duration abc", wantErr: true},
+		{name: "empty path", line: ";host=db1 1", wantErr: true},
+		{name: "tag without value", line: "backup;host 1", wantErr: true},
+		{name: "empty tag key", line: "backup;=db1 1", wantErr: true},
+		{name: "empty tag", line: "backup;host=db1; 1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, tags, value, err := parseLine(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLine() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if path != tt.wantPath {
+				t.Errorf("parseLine() path = %q, want %q", path, tt.wantPath)
+			}
+			if !maps.Equal(tags, tt.wantTags) {
+				t.Errorf("parseLine() tags = %v, want %v", tags, tt.wantTags)
+			}
+			if value != tt.wantValue {
+				t.Errorf("parseLine() value = %v, want %v", value, tt.wantValue)
+			}
+		})
+	}
+}
+
+// sample is a sample received by a recorder
+type sample struct {
+	name   string
+	labels map[string]string
+}
+
+// recorder is an Ingester recording the samples it receives
+type recorder struct {
+	samples []sample
+}
+
+func (r *recorder) Ingest(_ context.Context, _ netip.Addr, name string, _ config.MetricType, _ float64, labels map[string]string) error {
+	r.samples = append(r.samples, sample{name: name, labels: labels})
+	return nil
+}
+
+// peerConn overrides the remote address of a connection
+type peerConn struct {
+	net.Conn
+	addr net.Addr
+}
+
+func (c *peerConn) RemoteAddr() net.Addr {
+	return c.addr
+}
+
+func newTestServer(t *testing.T, allowed ...string) (*Server, *recorder) {
+	t.Helper()
+
+	cfg := config.Graphite{
+		Mappings: []config.GraphiteMapping{
+			{Match: "cron.*.*.duration", Name: "job_duration_seconds", Labels: map[string]string{"environment": "${1}", "job_name": "${2}"}},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	coll, err := collector.NewMetricCollector(&config.Config{
+		Metrics: []config.MetricConfig{
+			{Name: "job_duration_seconds", Type: config.MetricTypeGauge, Labels: []string{"environment", "job_name"}},
+		},
+	}, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prefixes := make([]netip.Prefix, len(allowed))
+	for i, cidr := range allowed {
+		prefixes[i] = netip.MustParsePrefix(cidr)
+	}
+
+	rec := &recorder{}
+	return New(cfg, prefixes, coll, rec), rec
+}
+
+func TestHandleLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want []sample
+	}{
+		{
+			name: "mapped path",
+			line: "cron.prod.backup.duration 12.5\n",
+			want: []sample{{name: "job_duration_seconds", labels: map[string]string{"environment": "prod", "job_name": "backup"}}},
+		},
+		{
+			name: "labels of the mapping take precedence over tags",
+			line: "cron.prod.backup.duration;environment=dev;host=db1 12.5",
+			want: []sample{{name: "job_duration_seconds", labels: map[string]string{"environment": "prod", "job_name": "backup", "host": "db1"}}},
+		},
+		{
+			name: "unmapped path with the name of a metric",
+			line: "job.duration.seconds;environment=prod;job_name=backup 1",
+			want: []sample{{name: "job_duration_seconds", labels: map[string]string{"environment": "prod", "job_name": "backup"}}},
+		},
+		{name: "path without a metric", line: "cron.prod.backup.size 1"},
+		{name: "invalid line", line: "cron.prod.backup.duration"},
+		{name: "blank line", line: "  \n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, rec := newTestServer(t)
+			s.handleLine(context.Background(), netip.MustParseAddr("10.1.2.3"), tt.line)
+
+			if len(rec.samples) != len(tt.want) {
+				t.Fatalf("ingested %d samples, want %d", len(rec.samples), len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := rec.samples[i]
+				if got.name != want.name || !maps.Equal(got.labels, want.labels) {
+					t.Errorf("sample %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestServeConnAllowedNetworks(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		addr    net.Addr
+		want    int
+	}{
+		{name: "no networks allow all", addr: &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 4000}, want: 1},
+		{name: "allowed network", allowed: []string{"10.0.0.0/8"}, addr: &net.TCPAddr{IP: net.ParseIP("10.1.2.3"), Port: 4000}, want: 1},
+		{name: "other network", allowed: []string{"10.0.0.0/8"}, addr: &net.TCPAddr{IP: net.ParseIP("192.168.1.1"), Port: 4000}},
+		{name: "IPv4-mapped IPv6 peer", allowed: []string{"10.0.0.0/8"}, addr: &net.TCPAddr{IP: net.ParseIP("::ffff:10.1.2.3"), Port: 4000}, want: 1},
+		{name: "peer without an IP address", allowed: []string{"10.0.0.0/8"}, addr: pipeAddr{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, rec := newTestServer(t, tt.allowed...)
+
+			client, server := net.Pipe()
+			done := make(chan struct{})
+			go func() {
+				s.serveConn(context.Background(), &peerConn{Conn: server, addr: tt.addr})
+				close(done)
+			}()
+
+			// Fails once the server closed a connection that isn't allowed
+			_, _ = client.Write([]byte("cron.prod.backup.duration 12.5\n"))
+			_ = client.Close()
+			<-done
+
+			if len(rec.samples) != tt.want {
+				t.Errorf("ingested %d samples, want %d", len(rec.samples), tt.want)
+			}
+		})
+	}
+}
+
+// pipeAddr is the address of an in-memory connection
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return "pipe" }
@@ -0,0 +1,242 @@
+// Package notifier sends dead man's switch notifications for series that stop
+// reporting within the expected interval of their metric, and optionally for jobs
+// whose last run exceeded their expected duration.
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/hay-kot/cronprom/internal/services/collector"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+)
+
+var notificationsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "cronprom_notifications_total",
+		Help: "Number of notification requests by notifier and result, sent or failed",
+	},
+	[]string{"notifier", "result"},
+)
+
+// RegisterMetrics registers the metrics describing the notifiers
+func RegisterMetrics(reg prometheus.Registerer) {
+	reg.MustRegister(notificationsTotal)
+}
+
+// Source reports the series that are overdue and the jobs that ran slow
+type Source interface {
+	Overdue(now time.Time) []collector.OverdueSeries
+	SlowRuns() []collector.SlowRun
+}
+
+// Status is the state of an alert in a notification
+type Status string
+
+const (
+	StatusFiring   Status = "firing"
+	StatusResolved Status = "resolved"
+)
+
+// Alert is an overdue series or a slow run along with the state sent in the
+// notification
+type Alert struct {
+	Status  Status
+	Series  collector.OverdueSeries
+	SlowRun *collector.SlowRun // nil for an overdue series
+}
+
+// alertState tracks a firing alert between checks
+type alertState struct {
+	alert    Alert
+	lastSent time.Time
+}
+
+// Notifier periodically checks for overdue series and notifies about them,
+// repeating the notification while they stay overdue
+type Notifier struct {
+	cfg    config.Notifier
+	source Source
+	client *http.Client
+	repeat time.Duration
+	firing map[string]*alertState
+}
+
+// New creates a new notifier for the source
+func New(cfg config.Notifier, source Source) *Notifier {
+	repeat := cfg.RepeatInterval
+
+	// Alertmanager resolves alerts that aren't resent, keep them alive every check
+	if cfg.Type == config.NotifierTypeAlertmanager && (repeat == 0 || repeat > cfg.CheckInterval) {
+		repeat = cfg.CheckInterval
+	}
+
+	return &Notifier{
+		cfg:    cfg,
+		source: source,
+		client: &http.Client{Timeout: cfg.Timeout},
+		repeat: repeat,
+		firing: make(map[string]*alertState),
+	}
+}
+
+// Run checks for overdue series every check interval until the context is canceled
+func (n *Notifier) Run(ctx context.Context) {
+	log.Info().Str("notifier", n.cfg.Name).Str("type", n.cfg.Type.String()).Msg("starting notifier")
+
+	ticker := time.NewTicker(n.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			n.check(ctx, now)
+		}
+	}
+}
+
+// check compares the overdue series with the firing alerts and sends the new,
+// repeated, and resolved alerts in a single notification
+func (n *Notifier) check(ctx context.Context, now time.Time) {
+	current := make(map[string]Alert)
+	for _, s := range n.source.Overdue(now) {
+		if n.cfg.Notifies(s.Metric) {
+			current[alertKey(s)] = Alert{Series: s}
+		}
+	}
+	if n.cfg.SlowRuns {
+		for _, run := range n.source.SlowRuns() {
+			if n.cfg.Notifies(run.Metric) {
+				current["slow_run:"+run.Job] = Alert{SlowRun: &run}
+			}
+		}
+	}
+
+	var (
+		alerts   []Alert
+		sent     []*alertState
+		resolved []string
+	)
+
+	for _, key := range slices.Sorted(maps.Keys(current)) {
+		state, ok := n.firing[key]
+		if !ok {
+			state = &alertState{}
+			n.firing[key] = state
+		}
+		state.alert = current[key]
+		state.alert.Status = StatusFiring
+
+		if state.lastSent.IsZero() || (n.repeat > 0 && now.Sub(state.lastSent) >= n.repeat) {
+			alerts = append(alerts, state.alert)
+			sent = append(sent, state)
+		}
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(n.firing)) {
+		if _, ok := current[key]; ok {
+			continue
+		}
+
+		// Alerts that were never delivered don't need to be resolved
+		state := n.firing[key]
+		if !n.cfg.SendResolved || state.lastSent.IsZero() {
+			delete(n.firing, key)
+			continue
+		}
+
+		// Kept firing until the resolve is delivered, a failed one is sent again
+		alert := state.alert
+		alert.Status = StatusResolved
+		alerts = append(alerts, alert)
+		resolved = append(resolved, key)
+	}
+
+	if len(alerts) == 0 {
+		return
+	}
+
+	if err := n.send(ctx, alerts, now); err != nil {
+		notificationsTotal.WithLabelValues(n.cfg.Name, "failed").Inc()
+		log.Error().Err(err).Str("notifier", n.cfg.Name).Int("alerts", len(alerts)).Msg("failed to send notification")
+		return
+	}
+
+	notificationsTotal.WithLabelValues(n.cfg.Name, "sent").Inc()
+	for _, state := range sent {
+		state.lastSent = now
+	}
+	for _, key := range resolved {
+		delete(n.firing, key)
+	}
+}
+
+// send posts the alerts in the format of the notifier
+func (n *Notifier) send(ctx context.Context, alerts []Alert, now time.Time) error {
+	var payload any
+	switch n.cfg.Type {
+	case config.NotifierTypeSlack:
+		payload = newSlackMessage(alerts, now)
+	case config.NotifierTypeAlertmanager:
+		payload = newAlertmanagerAlerts(alerts, now, n.repeat)
+	default:
+		payload = newWebhookPayload(n.cfg.Name, alerts, now)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "cronprom")
+	for k, v := range n.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("server returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// alertKey identifies the series of an alert
+func alertKey(s collector.OverdueSeries) string {
+	return s.Metric + formatLabels(s.Labels)
+}
+
+// formatLabels formats labels like the exposition format, sorted by name
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		pairs = append(pairs, k+"="+strconv.Quote(labels[k]))
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
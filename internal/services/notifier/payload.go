@@ -0,0 +1,155 @@
+package notifier
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+)
+
+// Kinds of the alerts of the generic webhook payload
+const (
+	alertOverdue = "overdue"
+	alertSlowRun = "slow_run"
+)
+
+// webhookAlert is a single alert of the generic webhook payload, the fields of
+// the other kind are omitted
+type webhookAlert struct {
+	Alert            string            `json:"alert"` // overdue or slow_run
+	Status           Status            `json:"status"`
+	Metric           string            `json:"metric"`
+	Labels           map[string]string `json:"labels"`
+	LastPush         time.Time         `json:"last_push"`
+	ExpectedInterval string            `json:"expected_interval,omitempty"`
+	OverdueSeconds   float64           `json:"overdue_seconds,omitempty"` // Time since the series was due
+	Job              string            `json:"job,omitempty"`
+	RunID            string            `json:"run_id,omitempty"`
+	DurationSeconds  float64           `json:"duration_seconds,omitempty"`
+	ExpectedDuration string            `json:"expected_duration,omitempty"`
+}
+
+// webhookPayload is the body of generic webhook notifications
+type webhookPayload struct {
+	Notifier string         `json:"notifier"`
+	Alerts   []webhookAlert `json:"alerts"`
+}
+
+func newWebhookPayload(notifier string, alerts []Alert, now time.Time) webhookPayload {
+	payload := webhookPayload{Notifier: notifier, Alerts: make([]webhookAlert, 0, len(alerts))}
+	for _, a := range alerts {
+		if r := a.SlowRun; r != nil {
+			payload.Alerts = append(payload.Alerts, webhookAlert{
+				Alert:            alertSlowRun,
+				Status:           a.Status,
+				Metric:           r.Metric,
+				Labels:           map[string]string{"job": r.Job},
+				LastPush:         r.FinishedAt,
+				Job:              r.Job,
+				RunID:            r.RunID,
+				DurationSeconds:  r.Duration.Seconds(),
+				ExpectedDuration: r.ExpectedDuration.String(),
+			})
+			continue
+		}
+
+		payload.Alerts = append(payload.Alerts, webhookAlert{
+			Alert:            alertOverdue,
+			Status:           a.Status,
+			Metric:           a.Series.Metric,
+			Labels:           a.Series.Labels,
+			LastPush:         a.Series.LastPush,
+			ExpectedInterval: a.Series.ExpectedInterval.String(),
+			OverdueSeconds:   max(now.Sub(a.Series.LastPush)-a.Series.ExpectedInterval, 0).Seconds(),
+		})
+	}
+	return payload
+}
+
+// slackMessage is the body of a Slack incoming webhook
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+func newSlackMessage(alerts []Alert, now time.Time) slackMessage {
+	lines := make([]string, 0, len(alerts))
+	for _, a := range alerts {
+		if r := a.SlowRun; r != nil {
+			if a.Status == StatusResolved {
+				lines = append(lines, fmt.Sprintf(":white_check_mark: *%s* finished within its expected duration again", r.Job))
+				continue
+			}
+
+			lines = append(lines, fmt.Sprintf(":snail: *%s* run %s took %s, expected at most %s", r.Job, r.RunID, r.Duration.Round(time.Second), r.ExpectedDuration))
+			continue
+		}
+
+		s := a.Series
+		since := now.Sub(s.LastPush).Round(time.Second)
+
+		if a.Status == StatusResolved {
+			lines = append(lines, fmt.Sprintf(":white_check_mark: *%s* %s is reporting again", s.Metric, formatLabels(s.Labels)))
+			continue
+		}
+
+		lines = append(lines, fmt.Sprintf(":rotating_light: *%s* %s hasn't reported for %s, expected every %s", s.Metric, formatLabels(s.Labels), since, s.ExpectedInterval))
+	}
+
+	return slackMessage{Text: strings.Join(lines, "\n")}
+}
+
+// alertmanagerAlert is an alert of the Alertmanager v2 API
+type alertmanagerAlert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	StartsAt    time.Time         `json:"startsAt"`
+	EndsAt      time.Time         `json:"endsAt"`
+}
+
+// newAlertmanagerAlerts converts the alerts for the Alertmanager v2 API. Firing
+// alerts end a few resend intervals in the future, so they resolve on their own
+// if the notifier stops.
+func newAlertmanagerAlerts(alerts []Alert, now time.Time, resend time.Duration) []alertmanagerAlert {
+	out := make([]alertmanagerAlert, 0, len(alerts))
+	for _, a := range alerts {
+		endsAt := now.Add(4 * resend)
+		if a.Status == StatusResolved {
+			endsAt = now
+		}
+
+		if r := a.SlowRun; r != nil {
+			out = append(out, alertmanagerAlert{
+				Labels: map[string]string{
+					"alertname": "CronpromSlowRun",
+					"job":       r.Job,
+					"metric":    r.Metric,
+				},
+				Annotations: map[string]string{
+					"summary":     fmt.Sprintf("%s took longer than its expected duration of %s", r.Job, r.ExpectedDuration),
+					"description": fmt.Sprintf("The run %s of %s took %s and finished at %s", r.RunID, r.Job, r.Duration.Round(time.Second), r.FinishedAt.Format(time.RFC3339)),
+				},
+				StartsAt: r.FinishedAt,
+				EndsAt:   endsAt,
+			})
+			continue
+		}
+
+		s := a.Series
+
+		labels := make(map[string]string, len(s.Labels)+2)
+		maps.Copy(labels, s.Labels)
+		labels["alertname"] = "CronpromSeriesOverdue"
+		labels["metric"] = s.Metric
+
+		out = append(out, alertmanagerAlert{
+			Labels: labels,
+			Annotations: map[string]string{
+				"summary":     fmt.Sprintf("%s hasn't reported within %s", s.Metric, s.ExpectedInterval),
+				"description": fmt.Sprintf("The series %s%s was last pushed at %s", s.Metric, formatLabels(s.Labels), s.LastPush.Format(time.RFC3339)),
+			},
+			StartsAt: s.LastPush.Add(s.ExpectedInterval),
+			EndsAt:   endsAt,
+		})
+	}
+	return out
+}
@@ -0,0 +1,306 @@
+// Package remotewrite ships the state of the registry to Prometheus compatible
+// receivers using the remote write protocol.
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	remotev1 "github.com/hay-kot/cronprom/pkg/pb/cronprom/remote/v1"
+	"github.com/klauspost/compress/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	queueLength = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cronprom_remote_write_queue_length",
+			Help: "Number of requests waiting to be sent to the remote write endpoint",
+		},
+		[]string{"url"},
+	)
+
+	requestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cronprom_remote_write_requests_total",
+			Help: "Number of remote write requests by result: sent, failed, or dropped",
+		},
+		[]string{"url", "result"},
+	)
+)
+
+// RegisterMetrics registers the metrics describing the remote write exporters
+func RegisterMetrics(reg prometheus.Registerer) {
+	reg.MustRegister(queueLength, requestsTotal)
+}
+
+// Exporter periodically converts the registry to time series and sends them to a
+// remote write endpoint. Requests are queued while the endpoint is unavailable and
+// retried with an exponential backoff.
+type Exporter struct {
+	cfg      config.RemoteWrite
+	gatherer prometheus.Gatherer
+	client   *http.Client
+	auth     string
+	queue    chan []byte
+}
+
+// New creates a new exporter for the endpoint
+func New(cfg config.RemoteWrite, gatherer prometheus.Gatherer) (*Exporter, error) {
+	auth, err := cfg.LoadAuthorization()
+	if err != nil {
+		return nil, fmt.Errorf("remote_write '%s': %w", cfg.URL, err)
+	}
+
+	cfg.Headers, err = cfg.LoadHeaders()
+	if err != nil {
+		return nil, fmt.Errorf("remote_write '%s': %w", cfg.URL, err)
+	}
+
+	return &Exporter{
+		cfg:      cfg,
+		gatherer: gatherer,
+		client:   &http.Client{Timeout: cfg.Timeout},
+		auth:     auth,
+		queue:    make(chan []byte, cfg.Queue.Capacity),
+	}, nil
+}
+
+// Run gathers and sends the registry every interval until the context is canceled
+func (e *Exporter) Run(ctx context.Context) {
+	log.Info().Str("url", e.cfg.URL).Dur("interval", e.cfg.Interval).Msg("starting remote write exporter")
+
+	go e.send(ctx)
+
+	ticker := time.NewTicker(e.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			body, err := e.encode(time.Now())
+			if err != nil {
+				log.Error().Err(err).Str("url", e.cfg.URL).Msg("remote write: error encoding registry")
+				continue
+			}
+			e.enqueue(body)
+			queueLength.WithLabelValues(e.cfg.URL).Set(float64(len(e.queue)))
+		}
+	}
+}
+
+// enqueue adds a request to the queue, dropping the oldest request when it is full
+func (e *Exporter) enqueue(body []byte) {
+	for {
+		select {
+		case e.queue <- body:
+			return
+		default:
+		}
+
+		select {
+		case <-e.queue:
+			requestsTotal.WithLabelValues(e.cfg.URL, "dropped").Inc()
+			log.Warn().Str("url", e.cfg.URL).Msg("remote write: queue full, dropping oldest request")
+		default:
+		}
+	}
+}
+
+// send delivers the queued requests in order
+func (e *Exporter) send(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case body := <-e.queue:
+			queueLength.WithLabelValues(e.cfg.URL).Set(float64(len(e.queue)))
+			e.deliver(ctx, body)
+		}
+	}
+}
+
+// deliver sends a request, retrying recoverable errors with an exponential backoff
+func (e *Exporter) deliver(ctx context.Context, body []byte) {
+	backoff := e.cfg.Queue.MinBackoff
+
+	for attempt := 0; ; attempt++ {
+		retry, err := e.post(ctx, body)
+		if err == nil {
+			requestsTotal.WithLabelValues(e.cfg.URL, "sent").Inc()
+			return
+		}
+
+		if !retry || attempt >= e.cfg.Queue.MaxRetries {
+			requestsTotal.WithLabelValues(e.cfg.URL, "failed").Inc()
+			log.Error().Err(err).Str("url", e.cfg.URL).Int("attempts", attempt+1).Msg("remote write: dropping request")
+			return
+		}
+
+		log.Warn().Err(err).Str("url", e.cfg.URL).Dur("backoff", backoff).Msg("remote write: retrying request")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, e.cfg.Queue.MaxBackoff)
+	}
+}
+
+// post sends a single request and reports whether a failure may be retried
+func (e *Exporter) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	req.Header.Set("User-Agent", "cronprom")
+	for k, v := range e.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	if e.auth != "" {
+		req.Header.Set("Authorization", e.auth)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return false, nil
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("server returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+
+	// Only server errors and rate limiting are worth retrying
+	return resp.StatusCode/100 == 5 || resp.StatusCode == http.StatusTooManyRequests, err
+}
+
+// encode gathers the registry into a snappy compressed write request
+func (e *Exporter) encode(now time.Time) ([]byte, error) {
+	families, err := e.gatherer.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := proto.Marshal(WriteRequest(families, now))
+	if err != nil {
+		return nil, err
+	}
+
+	return snappy.Encode(nil, data), nil
+}
+
+// WriteRequest converts metric families into a write request with every sample at
+// the given time. Histograms and summaries are split into their classic series.
+func WriteRequest(families []*dto.MetricFamily, now time.Time) *remotev1.WriteRequest {
+	ts := now.UnixMilli()
+	req := &remotev1.WriteRequest{}
+
+	for _, mf := range families {
+		name := mf.GetName()
+		req.Metadata = append(req.Metadata, &remotev1.MetricMetadata{
+			Type:             metadataType(mf.GetType()),
+			MetricFamilyName: name,
+			Help:             mf.GetHelp(),
+			Unit:             mf.GetUnit(),
+		})
+
+		for _, m := range mf.GetMetric() {
+			add := func(suffix string, value float64, extra ...string) {
+				req.Timeseries = append(req.Timeseries, &remotev1.TimeSeries{
+					Labels:  labels(name+suffix, m.GetLabel(), extra...),
+					Samples: []*remotev1.Sample{{Value: value, Timestamp: ts}},
+				})
+			}
+
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				add("", m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add("", m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				add("", m.GetUntyped().GetValue())
+			case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+				h := m.GetHistogram()
+				for _, b := range h.GetBucket() {
+					add("_bucket", float64(b.GetCumulativeCount()), "le", formatFloat(b.GetUpperBound()))
+				}
+				add("_bucket", float64(h.GetSampleCount()), "le", "+Inf")
+				add("_sum", h.GetSampleSum())
+				add("_count", float64(h.GetSampleCount()))
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.GetQuantile() {
+					add("", q.GetValue(), "quantile", formatFloat(q.GetQuantile()))
+				}
+				add("_sum", s.GetSampleSum())
+				add("_count", float64(s.GetSampleCount()))
+			}
+		}
+	}
+
+	return req
+}
+
+// labels returns the sorted labels of a series including its name
+func labels(name string, pairs []*dto.LabelPair, extra ...string) []*remotev1.Label {
+	out := make([]*remotev1.Label, 0, len(pairs)+1+len(extra)/2)
+	out = append(out, &remotev1.Label{Name: "__name__", Value: name})
+	for _, lp := range pairs {
+		out = append(out, &remotev1.Label{Name: lp.GetName(), Value: lp.GetValue()})
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		out = append(out, &remotev1.Label{Name: extra[i], Value: extra[i+1]})
+	}
+
+	slices.SortFunc(out, func(a, b *remotev1.Label) int { return strings.Compare(a.Name, b.Name) })
+	return out
+}
+
+func metadataType(t dto.MetricType) remotev1.MetricMetadata_MetricType {
+	switch t {
+	case dto.MetricType_COUNTER:
+		return remotev1.MetricMetadata_METRIC_TYPE_COUNTER
+	case dto.MetricType_GAUGE:
+		return remotev1.MetricMetadata_METRIC_TYPE_GAUGE
+	case dto.MetricType_HISTOGRAM:
+		return remotev1.MetricMetadata_METRIC_TYPE_HISTOGRAM
+	case dto.MetricType_GAUGE_HISTOGRAM:
+		return remotev1.MetricMetadata_METRIC_TYPE_GAUGEHISTOGRAM
+	case dto.MetricType_SUMMARY:
+		return remotev1.MetricMetadata_METRIC_TYPE_SUMMARY
+	default:
+		return remotev1.MetricMetadata_METRIC_TYPE_UNSPECIFIED
+	}
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
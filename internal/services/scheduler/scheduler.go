@@ -0,0 +1,197 @@
+// Package scheduler runs the commands of the configured schedules and records
+// every run in the schedule metrics.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/hay-kot/cronprom/internal/procgroup"
+	"github.com/robfig/cron/v3"
+	"github.com/rs/zerolog/log"
+)
+
+// Results of a run, the result label of the runs metric
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+	ResultTimeout = "timeout"
+	ResultSkipped = "skipped" // The previous run was still running
+)
+
+// waitDelay is how long a run waits for the process group of the command to exit
+// after SIGTERM before the command is killed
+const waitDelay = 5 * time.Second
+
+// outputTail is how many bytes of the output of a failed run are logged
+const outputTail = 1024
+
+// Recorder records the runs, implemented by the metric collector
+type Recorder interface {
+	UpdateGauge(name string, value float64, labels map[string]string) error
+	IncrementCounter(name string, labels map[string]string) error
+}
+
+// entry is a schedule along with its parsed cron expression
+type entry struct {
+	cfg      config.Schedule
+	schedule cron.Schedule
+	running  atomic.Bool
+}
+
+// Scheduler runs the commands of the schedules at their cron expressions. A run
+// is skipped while the previous run of the schedule is still running.
+type Scheduler struct {
+	entries  []*entry
+	recorder Recorder
+}
+
+// New creates a scheduler of the schedules
+func New(schedules []config.Schedule, recorder Recorder) (*Scheduler, error) {
+	s := &Scheduler{recorder: recorder}
+
+	for _, cfg := range schedules {
+		schedule, err := cfg.CronSchedule()
+		if err != nil {
+			return nil, err
+		}
+		s.entries = append(s.entries, &entry{cfg: cfg, schedule: schedule})
+	}
+
+	return s, nil
+}
+
+// Run runs the schedules until the context is canceled, the commands of running
+// schedules are killed then
+func (s *Scheduler) Run(ctx context.Context) {
+	c := cron.New()
+	for _, e := range s.entries {
+		c.Schedule(e.schedule, cron.FuncJob(func() { s.run(ctx, e) }))
+		log.Info().
+			Str("schedule", e.cfg.Name).
+			Str("cron", e.cfg.Cron).
+			Time("next", e.schedule.Next(time.Now())).
+			Msg("scheduled command")
+	}
+
+	c.Start()
+	<-ctx.Done()
+	<-c.Stop().Done()
+}
+
+// run runs the command of a schedule once and records the run
+func (s *Scheduler) run(ctx context.Context, e *entry) {
+	labels := map[string]string{"schedule": e.cfg.Name}
+
+	if !e.running.CompareAndSwap(false, true) {
+		log.Warn().Str("schedule", e.cfg.Name).Msg("previous run still running, skipping run")
+		s.recordResult(e, ResultSkipped)
+		return
+	}
+	defer e.running.Store(false)
+
+	if e.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.cfg.Timeout)
+		defer cancel()
+	}
+
+	var output tail
+	cmd := exec.CommandContext(ctx, e.cfg.Command, e.cfg.Args...)
+	cmd.Dir = e.cfg.Dir
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	cmd.WaitDelay = waitDelay
+	cmd.Cancel = func() error {
+		return procgroup.Signal(cmd.Process, syscall.SIGTERM)
+	}
+	procgroup.Set(cmd)
+	if len(e.cfg.Env) > 0 {
+		cmd.Env = os.Environ()
+		for k, v := range e.cfg.Env {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+	}
+
+	log.Debug().Str("schedule", e.cfg.Name).Msg("running scheduled command")
+
+	start := time.Now()
+	err := cmd.Run()
+	duration := time.Since(start)
+
+	if ctx.Err() != nil && cmd.Process != nil {
+		// Children left behind by the command would keep running past the timeout
+		_ = procgroup.Signal(cmd.Process, syscall.SIGKILL)
+	}
+
+	exitCode := -1
+	if cmd.ProcessState != nil {
+		exitCode = cmd.ProcessState.ExitCode()
+	}
+
+	result := ResultSuccess
+	switch {
+	case err == nil:
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result = ResultTimeout
+	default:
+		result = ResultFailure
+	}
+
+	s.record(config.ScheduleDurationMetric, duration.Seconds(), labels)
+	s.record(config.ScheduleExitCodeMetric, float64(exitCode), labels)
+	if result == ResultSuccess {
+		s.record(config.ScheduleLastSuccessMetric, float64(time.Now().Unix()), labels)
+	}
+	s.recordResult(e, result)
+
+	event := log.Info()
+	if result != ResultSuccess {
+		event = log.Warn().Err(err).Str("output", strings.TrimSpace(output.String()))
+	}
+	event.
+		Str("schedule", e.cfg.Name).
+		Str("result", result).
+		Int("exit_code", exitCode).
+		Dur("duration", duration).
+		Msg("scheduled command finished")
+}
+
+// recordResult counts a run by its result
+func (s *Scheduler) recordResult(e *entry, result string) {
+	labels := map[string]string{"schedule": e.cfg.Name, "result": result}
+	if err := s.recorder.IncrementCounter(config.ScheduleRunsMetric, labels); err != nil {
+		log.Error().Err(err).Str("schedule", e.cfg.Name).Msg("error recording scheduled run")
+	}
+}
+
+// record sets a gauge of a schedule
+func (s *Scheduler) record(name string, value float64, labels map[string]string) {
+	if err := s.recorder.UpdateGauge(name, value, labels); err != nil {
+		log.Error().Err(err).Str("schedule", labels["schedule"]).Str("metric", name).Msg("error recording scheduled run")
+	}
+}
+
+// tail keeps the last bytes written to it
+type tail struct {
+	buf []byte
+}
+
+func (t *tail) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > outputTail {
+		t.buf = t.buf[len(t.buf)-outputTail:]
+	}
+	return len(p), nil
+}
+
+func (t *tail) String() string {
+	return string(t.buf)
+}
@@ -0,0 +1,213 @@
+// Package textfile exposes the metrics of the *.prom files of a directory, like the
+// textfile collector of the node exporter.
+package textfile
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/proto"
+)
+
+// Collector reads the *.prom files of a directory on every gather. Files that fail
+// to parse, conflict with the metrics of other files, or declare metrics cronprom
+// already exposes are skipped and reported by cronprom_textfile_error, gathering
+// never fails so a broken file doesn't fail the scrape.
+type Collector struct {
+	dir string
+}
+
+// New creates a textfile collector of the directory
+func New(dir string) *Collector {
+	return &Collector{dir: dir}
+}
+
+// Gather returns the metrics of the files along with the modification time and
+// the error state of every file
+func (c *Collector) Gather() ([]*dto.MetricFamily, error) {
+	return c.GatherExcept(nil)
+}
+
+// GatherExcept is Gather skipping the files that declare a metric whose name is taken
+func (c *Collector) GatherExcept(taken map[string]bool) ([]*dto.MetricFamily, error) {
+	m := newMerger(taken)
+	var mtimes, errs []*dto.Metric
+
+	entries, readErr := os.ReadDir(c.dir)
+	if readErr != nil {
+		log.Warn().Err(readErr).Str("directory", c.dir).Msg("error reading textfile directory")
+	}
+
+	// Entries are sorted by name
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".prom" {
+			continue
+		}
+
+		mtime, err := m.addFile(filepath.Join(c.dir, entry.Name()))
+		if err != nil {
+			log.Warn().Err(err).Str("file", entry.Name()).Msg("error reading textfile")
+			errs = append(errs, fileMetric(entry.Name(), 1))
+			continue
+		}
+
+		errs = append(errs, fileMetric(entry.Name(), 0))
+		mtimes = append(mtimes, fileMetric(entry.Name(), mtime))
+	}
+
+	scrapeError := 0.0
+	if readErr != nil {
+		scrapeError = 1
+	}
+
+	families := m.result()
+	families = append(families,
+		gaugeFamily("cronprom_textfile_scrape_error", "Whether the textfile directory couldn't be read", []*dto.Metric{{Gauge: &dto.Gauge{Value: proto.Float64(scrapeError)}}}),
+	)
+	if len(mtimes) > 0 {
+		families = append(families, gaugeFamily("cronprom_textfile_mtime_seconds", "Unix time of the last modification of the textfile", mtimes))
+	}
+	if len(errs) > 0 {
+		families = append(families, gaugeFamily("cronprom_textfile_error", "Whether the textfile couldn't be read or parsed, or conflicts with another file or the metrics of cronprom", errs))
+	}
+
+	return families, nil
+}
+
+// merger merges the metric families of the files
+type merger struct {
+	families map[string]*dto.MetricFamily
+	series   map[string]string // Series to the file that defined it
+	taken    map[string]bool   // Names of metrics exposed outside of the files
+}
+
+func newMerger(taken map[string]bool) *merger {
+	return &merger{
+		families: make(map[string]*dto.MetricFamily),
+		series:   make(map[string]string),
+		taken:    taken,
+	}
+}
+
+// ownMetrics are the names of the metrics describing the files
+var ownMetrics = map[string]bool{
+	"cronprom_textfile_scrape_error":  true,
+	"cronprom_textfile_mtime_seconds": true,
+	"cronprom_textfile_error":         true,
+}
+
+// addFile parses a file and merges its metrics, returning the modification time of
+// the file. A file whose metrics conflict with those of a merged file or with metrics
+// exposed outside of the files isn't merged.
+func (m *merger) addFile(path string) (float64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var parser expfmt.TextParser
+	parsed, err := parser.TextToMetricFamilies(bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+
+	name := filepath.Base(path)
+	added := make(map[string]bool)
+	for familyName, family := range parsed {
+		if m.taken[familyName] || ownMetrics[familyName] {
+			return 0, fmt.Errorf("metric '%s' is already exposed by cronprom", familyName)
+		}
+		if prev, ok := m.families[familyName]; ok {
+			if prev.GetType() != family.GetType() {
+				return 0, fmt.Errorf("metric '%s' is a %s, another file declares it a %s", familyName,
+					strings.ToLower(family.GetType().String()), strings.ToLower(prev.GetType().String()))
+			}
+			if prev.GetHelp() != family.GetHelp() {
+				return 0, fmt.Errorf("metric '%s' has a different help than in another file", familyName)
+			}
+		}
+
+		for _, metric := range family.Metric {
+			slices.SortFunc(metric.Label, func(a, b *dto.LabelPair) int {
+				return strings.Compare(a.GetName(), b.GetName())
+			})
+
+			key := seriesKey(familyName, metric)
+			if prev, ok := m.series[key]; ok {
+				return 0, fmt.Errorf("series %s is already defined in '%s'", key, prev)
+			}
+			if added[key] {
+				return 0, fmt.Errorf("series %s is defined more than once", key)
+			}
+			added[key] = true
+		}
+	}
+
+	for key := range added {
+		m.series[key] = name
+	}
+	for familyName, family := range parsed {
+		if prev, ok := m.families[familyName]; ok {
+			prev.Metric = append(prev.Metric, family.Metric...)
+			continue
+		}
+		m.families[familyName] = family
+	}
+
+	return float64(info.ModTime().UnixNano()) / 1e9, nil
+}
+
+// result returns the merged metric families in lexical order
+func (m *merger) result() []*dto.MetricFamily {
+	families := make([]*dto.MetricFamily, 0, len(m.families))
+	for _, name := range slices.Sorted(maps.Keys(m.families)) {
+		families = append(families, m.families[name])
+	}
+	return families
+}
+
+// seriesKey identifies a series by its name and sorted labels
+func seriesKey(name string, metric *dto.Metric) string {
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteByte('{')
+	for i, l := range metric.Label {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=%q", l.GetName(), l.GetValue())
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// fileMetric returns a gauge sample of a file
+func fileMetric(file string, value float64) *dto.Metric {
+	return &dto.Metric{
+		Label: []*dto.LabelPair{{Name: proto.String("file"), Value: proto.String(file)}},
+		Gauge: &dto.Gauge{Value: proto.Float64(value)},
+	}
+}
+
+// gaugeFamily returns a gauge metric family
+func gaugeFamily(name, help string, metrics []*dto.Metric) *dto.MetricFamily {
+	return &dto.MetricFamily{
+		Name:   proto.String(name),
+		Help:   proto.String(help),
+		Type:   dto.MetricType_GAUGE.Enum(),
+		Metric: metrics,
+	}
+}
@@ -0,0 +1,174 @@
+package textfile
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestMergerAddFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		taken      map[string]bool
+		merged     string // Content of a file merged before the file
+		file       string
+		wantErr    bool
+		wantSeries []string
+	}{
+		{
+			name:       "single file",
+			file:       "# TYPE backup_size_bytes gauge\nbackup_size_bytes{host=\"db1\"} 42\n",
+			wantSeries: []string{`backup_size_bytes{host="db1"}`},
+		},
+		{
+			name:       "untyped samples",
+			file:       "backup_ok 1\n",
+			wantSeries: []string{"backup_ok{}"},
+		},
+		{
+			name:       "labels are sorted",
+			file:       "backup_ok{job=\"nightly\",host=\"db1\"} 1\n",
+			wantSeries: []string{`backup_ok{host="db1",job="nightly"}`},
+		},
+		{
+			name:       "other series of a merged metric",
+			merged:     "# TYPE backup_size_bytes gauge\nbackup_size_bytes{host=\"db1\"} 42\n",
+			file:       "# TYPE backup_size_bytes gauge\nbackup_size_bytes{host=\"db2\"} 7\n",
+			wantSeries: []string{`backup_size_bytes{host="db1"}`, `backup_size_bytes{host="db2"}`},
+		},
+		{
+			name:       "series of a merged file",
+			merged:     "backup_ok{host=\"db1\"} 1\n",
+			file:       "backup_ok{host=\"db1\"} 0\n",
+			wantErr:    true,
+			wantSeries: []string{`backup_ok{host="db1"}`},
+		},
+		{
+			name:       "different type than a merged file",
+			merged:     "# TYPE backup_size_bytes gauge\nbackup_size_bytes{host=\"db1\"} 42\n",
+			file:       "# TYPE backup_size_bytes counter\nbackup_size_bytes{host=\"db2\"} 7\n",
+			wantErr:    true,
+			wantSeries: []string{`backup_size_bytes{host="db1"}`},
+		},
+		{
+			name:       "different help than a merged file",
+			merged:     "# HELP backup_ok Whether the backup succeeded\nbackup_ok{host=\"db1\"} 1\n",
+			file:       "# HELP backup_ok Backup result\nbackup_ok{host=\"db2\"} 1\n",
+			wantErr:    true,
+			wantSeries: []string{`backup_ok{host="db1"}`},
+		},
+		{
+			name:    "metric exposed by cronprom",
+			taken:   map[string]bool{"cron_monitor_backup_ok": true},
+			file:    "cron_monitor_backup_ok 1\n",
+			wantErr: true,
+		},
+		{
+			name:    "metric describing the files",
+			file:    "cronprom_textfile_error{file=\"other.prom\"} 0\n",
+			wantErr: true,
+		},
+		{
+			name:    "conflicting file is not merged",
+			file:    "backup_ok 1\n# TYPE backup_size_bytes gauge\nbackup_size_bytes 1\nbackup_size_bytes 2\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid syntax",
+			file:    "backup_ok{host=db1} 1\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			m := newMerger(tt.taken)
+
+			if tt.merged != "" {
+				path := filepath.Join(dir, "merged.prom")
+				if err := os.WriteFile(path, []byte(tt.merged), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := m.addFile(path); err != nil {
+					t.Fatalf("addFile() of the merged file: %v", err)
+				}
+			}
+
+			path := filepath.Join(dir, "file.prom")
+			if err := os.WriteFile(path, []byte(tt.file), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			mtime, err := m.addFile(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("addFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && mtime <= 0 {
+				t.Errorf("addFile() mtime = %v, want the modification time of the file", mtime)
+			}
+
+			var series []string
+			for _, family := range m.result() {
+				for _, metric := range family.Metric {
+					series = append(series, seriesKey(family.GetName(), metric))
+				}
+			}
+			slices.Sort(series)
+			if !slices.Equal(series, tt.wantSeries) {
+				t.Errorf("merged series = %q, want %q", series, tt.wantSeries)
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := newMerger(nil).addFile(filepath.Join(t.TempDir(), "missing.prom")); err == nil {
+			t.Error("addFile() of a missing file succeeded")
+		}
+	})
+}
+
+func TestGatherExceptSkipsTakenMetrics(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"backup.prom": "backup_ok 1\n",
+		"runs.prom":   "# TYPE cron_monitor_job_runs_total counter\ncron_monitor_job_runs_total 3\n",
+		"notes.txt":   "cron_monitor_job_runs_total 3\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	families, err := New(dir).GatherExcept(map[string]bool{"cron_monitor_job_runs_total": true})
+	if err != nil {
+		t.Fatalf("GatherExcept() error = %v, the scrape must not fail", err)
+	}
+
+	fileErrors := map[string]float64{}
+	var names []string
+	for _, family := range families {
+		names = append(names, family.GetName())
+		if family.GetName() != "cronprom_textfile_error" {
+			continue
+		}
+		for _, metric := range family.Metric {
+			fileErrors[metric.Label[0].GetValue()] = metric.GetGauge().GetValue()
+		}
+	}
+
+	if slices.Contains(names, "cron_monitor_job_runs_total") {
+		t.Error("taken metric of a file was gathered")
+	}
+	if !slices.Contains(names, "backup_ok") {
+		t.Error("metric of the other file wasn't gathered")
+	}
+
+	want := map[string]float64{"backup.prom": 0, "runs.prom": 1}
+	if !maps.Equal(fileErrors, want) {
+		t.Errorf("cronprom_textfile_error = %v, want %v", fileErrors, want)
+	}
+}
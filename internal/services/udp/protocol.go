@@ -0,0 +1,195 @@
+package udp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"maps"
+	"math"
+	"slices"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+)
+
+// MaxPacketSize is the largest datagram accepted, it keeps a packet within a single
+// Ethernet frame so it is never fragmented. Larger updates must be pushed over HTTP.
+const MaxPacketSize = 1400
+
+// version is the protocol version in the header of every packet
+const version = 1
+
+// magic identifies packets of the protocol
+var magic = [2]byte{'C', 'P'}
+
+// Packet layout, all integers are big endian:
+//
+//	magic     2 bytes  "CP"
+//	version   1 byte   1
+//	type      1 byte   see typeCodes
+//	value     8 bytes  IEEE 754 float64
+//	name      uvarint length followed by the bytes of the metric name
+//	labels    uvarint count followed by the length prefixed key and value of each label
+//	checksum  4 bytes  CRC-32 (Castagnoli) of all preceding bytes
+const headerSize = 2 + 1 + 1 + 8
+
+const checksumSize = 4
+
+var (
+	ErrPacketTooLarge = errors.New("packet too large")
+	ErrInvalidPacket  = errors.New("invalid packet")
+	ErrChecksum       = errors.New("checksum mismatch")
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// typeCodes are the metric types by their code in the packet header
+var typeCodes = []config.MetricType{
+	1: config.MetricTypeGauge,
+	2: config.MetricTypeCounter,
+	3: config.MetricTypeHistogram,
+	4: config.MetricTypeSummary,
+	5: config.MetricTypeInfo,
+	6: config.MetricTypeEnum,
+}
+
+// Packet is a single update sent over UDP. Gauges are set, counters are
+// incremented by the value, and histograms and summaries observe it.
+type Packet struct {
+	Name   string
+	Type   config.MetricType
+	Value  float64
+	Labels map[string]string
+}
+
+// Encode encodes the packet, it returns an ErrPacketTooLarge if the encoded packet
+// exceeds MaxPacketSize
+func Encode(p Packet) ([]byte, error) {
+	code := slices.Index(typeCodes, p.Type)
+	if code <= 0 {
+		return nil, fmt.Errorf("%w: unsupported metric type '%s'", ErrInvalidPacket, p.Type)
+	}
+
+	buf := make([]byte, 0, MaxPacketSize)
+	buf = append(buf, magic[:]...)
+	buf = append(buf, version, byte(code))
+	buf = binary.BigEndian.AppendUint64(buf, math.Float64bits(p.Value))
+	buf = appendString(buf, p.Name)
+
+	buf = binary.AppendUvarint(buf, uint64(len(p.Labels)))
+	for _, k := range slices.Sorted(maps.Keys(p.Labels)) {
+		buf = appendString(buf, k)
+		buf = appendString(buf, p.Labels[k])
+	}
+
+	buf = binary.BigEndian.AppendUint32(buf, crc32.Checksum(buf, castagnoli))
+
+	if len(buf) > MaxPacketSize {
+		return nil, fmt.Errorf("%w: %d bytes exceed the limit of %d bytes", ErrPacketTooLarge, len(buf), MaxPacketSize)
+	}
+
+	return buf, nil
+}
+
+// Decode decodes a packet, verifying its size and checksum
+func Decode(data []byte) (Packet, error) {
+	if len(data) > MaxPacketSize {
+		return Packet{}, fmt.Errorf("%w: %d bytes exceed the limit of %d bytes", ErrPacketTooLarge, len(data), MaxPacketSize)
+	}
+
+	if len(data) < headerSize+checksumSize {
+		return Packet{}, fmt.Errorf("%w: %d bytes is too short", ErrInvalidPacket, len(data))
+	}
+
+	body, sum := data[:len(data)-checksumSize], data[len(data)-checksumSize:]
+	if crc32.Checksum(body, castagnoli) != binary.BigEndian.Uint32(sum) {
+		return Packet{}, ErrChecksum
+	}
+
+	if body[0] != magic[0] || body[1] != magic[1] {
+		return Packet{}, fmt.Errorf("%w: unknown magic", ErrInvalidPacket)
+	}
+	if body[2] != version {
+		return Packet{}, fmt.Errorf("%w: unsupported version %d", ErrInvalidPacket, body[2])
+	}
+
+	code := int(body[3])
+	if code == 0 || code >= len(typeCodes) {
+		return Packet{}, fmt.Errorf("%w: unknown metric type %d", ErrInvalidPacket, code)
+	}
+
+	p := Packet{
+		Type:  typeCodes[code],
+		Value: math.Float64frombits(binary.BigEndian.Uint64(body[4:headerSize])),
+	}
+
+	r := reader{buf: body[headerSize:]}
+	p.Name = r.string()
+
+	count := r.uvarint()
+	if count > uint64(len(r.buf)) {
+		// Every label takes at least two bytes, the count can't exceed the rest
+		return Packet{}, fmt.Errorf("%w: invalid label count", ErrInvalidPacket)
+	}
+
+	p.Labels = make(map[string]string, count)
+	for range count {
+		k := r.string()
+		p.Labels[k] = r.string()
+	}
+
+	if r.err != nil {
+		return Packet{}, r.err
+	}
+	if len(r.buf) > 0 {
+		return Packet{}, fmt.Errorf("%w: %d trailing bytes", ErrInvalidPacket, len(r.buf))
+	}
+	if p.Name == "" {
+		return Packet{}, fmt.Errorf("%w: empty metric name", ErrInvalidPacket)
+	}
+
+	return p, nil
+}
+
+// appendString appends the length prefixed string
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// reader reads the fields of a packet, the first error is kept and all later reads
+// return zero values
+type reader struct {
+	buf []byte
+	err error
+}
+
+func (r *reader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.err = fmt.Errorf("%w: truncated length", ErrInvalidPacket)
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *reader) string() string {
+	n := r.uvarint()
+	if r.err != nil {
+		return ""
+	}
+
+	if n > uint64(len(r.buf)) {
+		r.err = fmt.Errorf("%w: truncated string", ErrInvalidPacket)
+		return ""
+	}
+
+	s := string(r.buf[:n])
+	r.buf = r.buf[n:]
+	return s
+}
@@ -0,0 +1,139 @@
+package udp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+)
+
+// seal appends the checksum to a packet without one
+func seal(body []byte) []byte {
+	return binary.BigEndian.AppendUint32(body, crc32.Checksum(body, castagnoli))
+}
+
+// header returns the header of a packet of the type code and value
+func header(code byte, value float64) []byte {
+	buf := append(magic[:], version, code)
+	return binary.BigEndian.AppendUint64(buf, math.Float64bits(value))
+}
+
+func TestDecode(t *testing.T) {
+	encoded, err := Encode(Packet{
+		Name:   "backup_duration_seconds",
+		Type:   config.MetricTypeGauge,
+		Value:  12.5,
+		Labels: map[string]string{"host": "db1", "job": "nightly"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	corrupted := bytes.Clone(encoded)
+	corrupted[headerSize] ^= 0xff
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    Packet
+		wantErr error
+	}{
+		{
+			name: "encoded packet",
+			data: encoded,
+			want: Packet{
+				Name:   "backup_duration_seconds",
+				Type:   config.MetricTypeGauge,
+				Value:  12.5,
+				Labels: map[string]string{"host": "db1", "job": "nightly"},
+			},
+		},
+		{
+			name: "no labels",
+			data: seal(append(header(2, 1), 3, 'r', 'u', 'n', 0)),
+			want: Packet{Name: "run", Type: config.MetricTypeCounter, Value: 1, Labels: map[string]string{}},
+		},
+		{
+			name:    "too large",
+			data:    make([]byte, MaxPacketSize+1),
+			wantErr: ErrPacketTooLarge,
+		},
+		{
+			name:    "too short",
+			data:    seal(header(1, 1)[:headerSize-1]),
+			wantErr: ErrInvalidPacket,
+		},
+		{
+			name:    "checksum mismatch",
+			data:    corrupted,
+			wantErr: ErrChecksum,
+		},
+		{
+			name:    "unknown magic",
+			data:    seal(append([]byte{'X', 'X', version, 1, 0, 0, 0, 0, 0, 0, 0, 0}, 1, 'a', 0)),
+			wantErr: ErrInvalidPacket,
+		},
+		{
+			name:    "unsupported version",
+			data:    seal(append([]byte{'C', 'P', version + 1, 1, 0, 0, 0, 0, 0, 0, 0, 0}, 1, 'a', 0)),
+			wantErr: ErrInvalidPacket,
+		},
+		{
+			name:    "type code zero",
+			data:    seal(append(header(0, 1), 1, 'a', 0)),
+			wantErr: ErrInvalidPacket,
+		},
+		{
+			name:    "unknown type code",
+			data:    seal(append(header(byte(len(typeCodes)), 1), 1, 'a', 0)),
+			wantErr: ErrInvalidPacket,
+		},
+		{
+			name:    "truncated name",
+			data:    seal(append(header(1, 1), 5, 'a', 'b')),
+			wantErr: ErrInvalidPacket,
+		},
+		{
+			name:    "missing label count",
+			data:    seal(append(header(1, 1), 1, 'a')),
+			wantErr: ErrInvalidPacket,
+		},
+		{
+			name:    "label count exceeds the packet",
+			data:    seal(append(header(1, 1), 1, 'a', 100, 1, 'k')),
+			wantErr: ErrInvalidPacket,
+		},
+		{
+			name:    "truncated label value",
+			data:    seal(append(header(1, 1), 1, 'a', 1, 1, 'k', 3, 'v')),
+			wantErr: ErrInvalidPacket,
+		},
+		{
+			name:    "trailing bytes",
+			data:    seal(append(header(1, 1), 1, 'a', 0, 'x')),
+			wantErr: ErrInvalidPacket,
+		},
+		{
+			name:    "empty name",
+			data:    seal(append(header(1, 1), 0, 0)),
+			wantErr: ErrInvalidPacket,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.data)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Decode() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
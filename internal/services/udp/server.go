@@ -0,0 +1,115 @@
+// Package udp implements a compact fire-and-forget push protocol over UDP for jobs
+// that can't block on an HTTP request. Delivery is best-effort, packets that are
+// lost, too large, corrupted, or invalid are dropped without a response.
+package udp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/hay-kot/cronprom/internal/services/collector"
+	"github.com/rs/zerolog/log"
+)
+
+// Ingester validates and applies the update of a packet like a push to the API
+type Ingester interface {
+	Ingest(ctx context.Context, peer netip.Addr, name string, metricType config.MetricType, value float64, labels map[string]string) error
+}
+
+// Server accepts packets of the UDP push protocol
+type Server struct {
+	cfg      config.UDP
+	allowed  []netip.Prefix // Networks allowed to send packets, empty allows all
+	ingester Ingester
+}
+
+// New creates a new UDP server. Packets are only accepted from the allowed
+// networks, the web.allow_cidrs of the push API.
+func New(cfg config.UDP, allowed []netip.Prefix, ingester Ingester) *Server {
+	return &Server{
+		cfg:      cfg,
+		allowed:  allowed,
+		ingester: ingester,
+	}
+}
+
+// ListenAndServe listens on the configured address until the context is canceled
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	pc, err := net.ListenPacket("udp", s.cfg.Address)
+	if err != nil {
+		return fmt.Errorf("udp: %w", err)
+	}
+
+	stop := context.AfterFunc(ctx, func() { _ = pc.Close() })
+	defer stop()
+
+	log.Info().Str("addr", s.cfg.Address).Msg("starting UDP listener")
+
+	// One byte more than the limit to detect oversized packets, which would be
+	// truncated otherwise
+	buf := make([]byte, MaxPacketSize+1)
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("udp: %w", err)
+		}
+
+		s.handlePacket(ctx, addr, buf[:n])
+	}
+}
+
+// handlePacket applies the update of a packet. Packets from networks that aren't
+// allowed, invalid packets, unknown metrics, and rejected updates are dropped.
+func (s *Server) handlePacket(ctx context.Context, addr net.Addr, data []byte) {
+	peer := peerAddr(addr)
+	if !s.allows(peer) {
+		log.Debug().Stringer("addr", addr).Msg("udp: packet from a network that isn't allowed")
+		return
+	}
+
+	p, err := Decode(data)
+	if err != nil {
+		log.Debug().Err(err).Stringer("addr", addr).Msg("udp: invalid packet")
+		return
+	}
+
+	err = s.ingester.Ingest(ctx, peer, p.Name, p.Type, p.Value, p.Labels)
+	if errors.Is(err, collector.ErrMetricNotFound) {
+		log.Debug().Str("metric", p.Name).Str("type", p.Type.String()).Msg("udp: no metric for packet")
+		return
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("metric", p.Name).Stringer("addr", addr).Msg("udp: update rejected")
+	}
+}
+
+// allows reports whether packets of the peer are accepted
+func (s *Server) allows(peer netip.Addr) bool {
+	if len(s.allowed) == 0 {
+		return true
+	}
+
+	for _, p := range s.allowed {
+		if p.Contains(peer) {
+			return true
+		}
+	}
+	return false
+}
+
+// peerAddr returns the IP address of a peer, it is invalid for addresses that
+// aren't IP addresses
+func peerAddr(addr net.Addr) netip.Addr {
+	ap, err := netip.ParseAddrPort(addr.String())
+	if err != nil {
+		return netip.Addr{}
+	}
+	return ap.Addr().Unmap()
+}
@@ -0,0 +1,70 @@
+package udp
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+)
+
+// recorder is an Ingester recording the updates it receives
+type recorder struct {
+	peers []netip.Addr
+}
+
+func (r *recorder) Ingest(_ context.Context, peer netip.Addr, _ string, _ config.MetricType, _ float64, _ map[string]string) error {
+	r.peers = append(r.peers, peer)
+	return nil
+}
+
+func TestHandlePacketAllowedNetworks(t *testing.T) {
+	packet, err := Encode(Packet{Name: "backup_runs_total", Type: config.MetricTypeCounter, Value: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		allowed []string
+		addr    net.Addr
+		want    bool
+	}{
+		{name: "no networks allow all", addr: &net.UDPAddr{IP: net.ParseIP("203.0.113.7"), Port: 4000}, want: true},
+		{name: "allowed network", allowed: []string{"10.0.0.0/8"}, addr: &net.UDPAddr{IP: net.ParseIP("10.1.2.3"), Port: 4000}, want: true},
+		{name: "other network", allowed: []string{"10.0.0.0/8"}, addr: &net.UDPAddr{IP: net.ParseIP("192.168.1.1"), Port: 4000}},
+		{name: "IPv4-mapped IPv6 peer", allowed: []string{"10.0.0.0/8"}, addr: &net.UDPAddr{IP: net.ParseIP("::ffff:10.1.2.3"), Port: 4000}, want: true},
+		{name: "IPv6 peer outside of an IPv4 network", allowed: []string{"10.0.0.0/8"}, addr: &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 4000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed := make([]netip.Prefix, len(tt.allowed))
+			for i, cidr := range tt.allowed {
+				allowed[i] = netip.MustParsePrefix(cidr)
+			}
+
+			rec := &recorder{}
+			New(config.UDP{}, allowed, rec).handlePacket(context.Background(), tt.addr, packet)
+
+			if got := len(rec.peers) == 1; got != tt.want {
+				t.Fatalf("packet ingested = %v, want %v", got, tt.want)
+			}
+			if tt.want && !rec.peers[0].Is4() {
+				t.Errorf("peer = %v, want the unmapped IPv4 address", rec.peers[0])
+			}
+		})
+	}
+}
+
+func TestHandlePacketDropsInvalidPackets(t *testing.T) {
+	rec := &recorder{}
+	s := New(config.UDP{}, nil, rec)
+	addr := &net.UDPAddr{IP: net.ParseIP("10.1.2.3"), Port: 4000}
+
+	s.handlePacket(context.Background(), addr, []byte("backup_runs_total:1|c"))
+	if len(rec.peers) != 0 {
+		t.Error("packet that isn't in the protocol was ingested")
+	}
+}
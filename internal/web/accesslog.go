@@ -0,0 +1,57 @@
+package web
+
+import (
+	"context"
+	"io"
+	"slices"
+	"sync"
+)
+
+// maxLoggedMetrics limits the metric names logged for a single request
+const maxLoggedMetrics = 10
+
+type accessInfoCtxKey struct{}
+
+// accessInfo collects details for the access log that are only known to the
+// handlers and the middleware further down the chain
+type accessInfo struct {
+	mu      sync.Mutex
+	key     string   // Name of the API key that authenticated the request
+	metrics []string // Metrics affected by the request
+}
+
+// noteKey records the name of the API key for the access log
+func noteKey(ctx context.Context, name string) {
+	if info, ok := ctx.Value(accessInfoCtxKey{}).(*accessInfo); ok {
+		info.mu.Lock()
+		info.key = name
+		info.mu.Unlock()
+	}
+}
+
+// noteMetric records a metric affected by the request for the access log
+func noteMetric(ctx context.Context, name string) {
+	info, ok := ctx.Value(accessInfoCtxKey{}).(*accessInfo)
+	if !ok {
+		return
+	}
+
+	info.mu.Lock()
+	defer info.mu.Unlock()
+
+	if len(info.metrics) < maxLoggedMetrics && !slices.Contains(info.metrics, name) {
+		info.metrics = append(info.metrics, name)
+	}
+}
+
+// countingReader counts the bytes read from a request body
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,84 @@
+package web
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+)
+
+type apiKeyCtxKey struct{}
+
+// APIKeyFromContext returns the API key that authenticated the request, if any
+func APIKeyFromContext(ctx context.Context) (config.APIKey, bool) {
+	key, ok := ctx.Value(apiKeyCtxKey{}).(config.APIKey)
+	return key, ok
+}
+
+// BearerAuth rejects requests that don't carry the token of one of the keys in the
+// Authorization header. The matching key is stored in the request context so
+// handlers can enforce its scope. When no keys are provided all requests are allowed.
+func BearerAuth(keys []config.APIKey) Middleware {
+	return func(next http.Handler) http.Handler {
+		if len(keys) == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+
+			var key config.APIKey
+			if ok && strings.EqualFold(scheme, "Bearer") {
+				key, ok = findKey(keys, strings.TrimSpace(token))
+			} else {
+				ok = false
+			}
+
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="cronprom"`)
+				writeError(w, http.StatusUnauthorized, CodeUnauthorized, http.StatusText(http.StatusUnauthorized))
+				return
+			}
+
+			noteKey(r.Context(), key.Name)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyCtxKey{}, key)))
+		})
+	}
+}
+
+// findKey compares the token against all known keys in constant time
+func findKey(keys []config.APIKey, token string) (config.APIKey, bool) {
+	var (
+		found config.APIKey
+		ok    bool
+	)
+
+	for _, k := range keys {
+		if subtle.ConstantTimeCompare([]byte(k.Token), []byte(token)) == 1 {
+			found, ok = k, true
+		}
+	}
+
+	return found, ok
+}
+
+// RequireAdmin rejects requests that weren't authenticated with a key allowed to
+// use the admin API. Without configured keys the admin API is disabled rather than
+// open. It must be applied after BearerAuth.
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := APIKeyFromContext(r.Context())
+		if !ok {
+			writeError(w, http.StatusForbidden, CodeForbidden, "The admin API requires an API key with admin access, see auth.keys")
+			return
+		}
+		if !key.Admin {
+			writeError(w, http.StatusForbidden, CodeForbidden, "API key is not allowed to use the admin API")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
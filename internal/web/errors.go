@@ -0,0 +1,67 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Machine-readable error codes returned in the body of error responses
+const (
+	CodeBadRequest       = "bad_request"
+	CodeInvalidBody      = "invalid_body"
+	CodeValidationFailed = "validation_failed"
+	CodeTypeMismatch     = "type_mismatch"
+	CodeLabelMismatch    = "label_mismatch"
+	CodeSeriesLimit      = "series_limit"
+	CodeMetricNotFound   = "metric_not_found"
+	CodeMetricExists     = "metric_exists"
+	CodeBodyTooLarge     = "body_too_large"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeInternal         = "internal_error"
+	CodeQueueFull        = "queue_full"
+	CodeJobNotFound      = "job_not_found"
+	CodeRunExists        = "run_exists"
+	CodeLockHeld         = "lock_held"
+)
+
+// ErrorResponse is the body of every error response
+type ErrorResponse struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"` // Matches the request_id of the server logs
+}
+
+// writeError responds with a JSON error body and the given status code. The ID set
+// by the RequestID middleware is included in the body.
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{Code: code, Message: msg, RequestID: h.Get(RequestIDHeader)})
+}
+
+// pushError is a failed update along with the status code to respond with
+type pushError struct {
+	status int
+	code   string
+	msg    string
+}
+
+func (e *pushError) Error() string {
+	return e.msg
+}
+
+// write responds with the error
+func (e *pushError) write(w http.ResponseWriter) {
+	writeError(w, e.status, e.code, e.msg)
+}
+
+// NotFoundHandler responds with a JSON 404 for requests that match no route
+func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, CodeNotFound, "no route for "+r.URL.Path)
+}
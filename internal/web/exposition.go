@@ -0,0 +1,99 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// parseExposition parses a body in the Prometheus text exposition format
+func parseExposition(body []byte) (map[string]*dto.MetricFamily, error) {
+	var parser expfmt.TextParser
+
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing exposition format: %w", err)
+	}
+
+	return families, nil
+}
+
+// expositionUpdates converts parsed metric families into updates of the configured
+// metrics. Untyped samples take the type of the configured metric, for histograms
+// and summaries each sample is observed. Extra labels (e.g., a Pushgateway grouping
+// key) are added to every sample and may not be overridden by the sample labels.
+func (h *MetricHandler) expositionUpdates(families map[string]*dto.MetricFamily, extra map[string]string) ([]MetricUpdate, *pushError) {
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var updates []MetricUpdate
+	for _, name := range names {
+		family := families[name]
+
+		metricCfg, ok := h.collector.Resolve(name)
+		if !ok {
+			return nil, &pushError{http.StatusNotFound, CodeMetricNotFound, fmt.Sprintf("metric '%s' not found", name)}
+		}
+
+		if !compatibleType(family.GetType(), metricCfg.Type) {
+			return nil, &pushError{http.StatusUnprocessableEntity, CodeTypeMismatch, fmt.Sprintf("metric '%s' is a %s, cannot push %s samples", metricCfg.Name, metricCfg.Type, family.GetType())}
+		}
+
+		for _, m := range family.GetMetric() {
+			labels := make(map[string]string, len(m.GetLabel())+len(extra))
+			for _, lp := range m.GetLabel() {
+				if _, ok := extra[lp.GetName()]; ok {
+					return nil, &pushError{http.StatusUnprocessableEntity, CodeValidationFailed, fmt.Sprintf("metric '%s' has label '%s' that is already part of the grouping key", name, lp.GetName())}
+				}
+				labels[lp.GetName()] = lp.GetValue()
+			}
+
+			for k, v := range extra {
+				labels[k] = v
+			}
+
+			var value float64
+			switch {
+			case m.GetGauge() != nil:
+				value = m.GetGauge().GetValue()
+			case m.GetCounter() != nil:
+				value = m.GetCounter().GetValue()
+			default:
+				value = m.GetUntyped().GetValue()
+			}
+
+			updates = append(updates, MetricUpdate{
+				Name:   metricCfg.Name,
+				Type:   metricCfg.Type.String(),
+				Value:  value,
+				Labels: labels,
+			})
+		}
+	}
+
+	return updates, nil
+}
+
+// compatibleType reports whether samples of a family type can be applied to a
+// configured metric type
+func compatibleType(familyType dto.MetricType, metricType config.MetricType) bool {
+	switch familyType {
+	case dto.MetricType_UNTYPED:
+		return true
+	case dto.MetricType_GAUGE:
+		return metricType == config.MetricTypeGauge
+	case dto.MetricType_COUNTER:
+		return metricType == config.MetricTypeCounter
+	default:
+		// Pre-aggregated histograms and summaries can't be merged into the collector
+		return false
+	}
+}
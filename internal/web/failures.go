@@ -0,0 +1,52 @@
+package web
+
+import (
+	"sync"
+	"time"
+)
+
+// maxFailures is the number of recent push failures kept for the status page
+const maxFailures = 50
+
+// PushFailure is a rejected push, kept for the status page
+type PushFailure struct {
+	Time      time.Time
+	RequestID string
+	Metric    string
+	Code      string
+	Message   string
+}
+
+// failureLog is a ring buffer of the most recent push failures
+type failureLog struct {
+	mu      sync.Mutex
+	entries []PushFailure
+	next    int
+}
+
+// add records a failure, replacing the oldest one when the log is full
+func (l *failureLog) add(f PushFailure) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.entries) < maxFailures {
+		l.entries = append(l.entries, f)
+		return
+	}
+
+	l.entries[l.next] = f
+	l.next = (l.next + 1) % maxFailures
+}
+
+// recent returns the recorded failures, newest first
+func (l *failureLog) recent() []PushFailure {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	out := make([]PushFailure, 0, len(l.entries))
+	for i := range l.entries {
+		idx := (l.next - 1 - i + 2*len(l.entries)) % len(l.entries)
+		out = append(out, l.entries[idx])
+	}
+	return out
+}
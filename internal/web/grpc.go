@@ -0,0 +1,279 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/netip"
+	"runtime/debug"
+	"strings"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	cronpromv1 "github.com/hay-kot/cronprom/pkg/pb/cronprom/v1"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// NewGRPCServer creates a gRPC server exposing the MetricService. It enforces the
+// same allowed networks, API keys, and body size limit as the push API, and serves
+// TLS when it is configured for the web server.
+func NewGRPCServer(cfg config.Web, keys []config.APIKey, h *MetricHandler) (*grpc.Server, error) {
+	guard := grpcGuard{keys: keys, allowed: cfg.AllowedNetworks(), source: cfg.SourceLabel}
+
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(grpcRecoverUnary, guard.unary),
+		grpc.ChainStreamInterceptor(grpcRecoverStream, guard.stream),
+	}
+
+	if cfg.MaxBodySize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(int(cfg.MaxBodySize)))
+	}
+
+	if cfg.TLS.Enabled() {
+		tlsCfg, err := TLSConfig(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsCfg)))
+	}
+
+	server := grpc.NewServer(opts...)
+	cronpromv1.RegisterMetricServiceServer(server, &metricService{h: h})
+	return server, nil
+}
+
+// grpcGuard authenticates gRPC calls like the AllowCIDRs and BearerAuth middleware
+type grpcGuard struct {
+	keys    []config.APIKey
+	allowed []netip.Prefix
+	source  config.SourceLabel
+}
+
+// authenticate checks the peer address and the bearer token in the "authorization"
+// metadata, the matching key is stored in the returned context
+func (g grpcGuard) authenticate(ctx context.Context) (context.Context, error) {
+	if len(g.allowed) > 0 {
+		var addr netip.Addr
+		if p, ok := peer.FromContext(ctx); ok {
+			if ap, err := netip.ParseAddrPort(p.Addr.String()); err == nil {
+				addr = ap.Addr().Unmap()
+			}
+		}
+
+		if !containsAddr(g.allowed, addr) {
+			return nil, status.Error(codes.PermissionDenied, http.StatusText(http.StatusForbidden))
+		}
+	}
+
+	if len(g.keys) == 0 {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		scheme, token, ok := strings.Cut(v, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			continue
+		}
+
+		if key, ok := findKey(g.keys, strings.TrimSpace(token)); ok {
+			return context.WithValue(ctx, apiKeyCtxKey{}, key), nil
+		}
+	}
+
+	return nil, status.Error(codes.Unauthenticated, http.StatusText(http.StatusUnauthorized))
+}
+
+func (g grpcGuard) unary(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := g.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(withGRPCSource(ctx, g.source), req)
+}
+
+func (g grpcGuard) stream(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := g.authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authedStream{ServerStream: ss, ctx: withGRPCSource(ctx, g.source)})
+}
+
+// grpcRecoverUnary recovers from panics in unary handlers like the Recoverer
+// middleware, grpc-go doesn't recover them and the server would crash
+func grpcRecoverUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if rvr := recover(); rvr != nil {
+			logGRPCPanic(rvr, info.FullMethod)
+			err = status.Error(codes.Internal, http.StatusText(http.StatusInternalServerError))
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// grpcRecoverStream recovers from panics in stream handlers
+func grpcRecoverStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if rvr := recover(); rvr != nil {
+			logGRPCPanic(rvr, info.FullMethod)
+			err = status.Error(codes.Internal, http.StatusText(http.StatusInternalServerError))
+		}
+	}()
+	return handler(srv, ss)
+}
+
+func logGRPCPanic(rvr any, method string) {
+	log.Error().
+		Interface("panic", rvr).
+		Bytes("stack", debug.Stack()).
+		Str("method", method).
+		Msg("recovered from panic")
+}
+
+// authedStream overrides the context of a stream with the authenticated context
+type authedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authedStream) Context() context.Context {
+	return s.ctx
+}
+
+// metricService implements the MetricService on top of the push API
+type metricService struct {
+	cronpromv1.UnimplementedMetricServiceServer
+	h *MetricHandler
+}
+
+func (s *metricService) Push(ctx context.Context, req *cronpromv1.PushRequest) (*cronpromv1.PushResponse, error) {
+	if err := s.push(ctx, updateFromProto(req.GetUpdate())); err != nil {
+		return nil, err.grpcStatus()
+	}
+	return &cronpromv1.PushResponse{}, nil
+}
+
+func (s *metricService) PushBatch(ctx context.Context, req *cronpromv1.PushBatchRequest) (*cronpromv1.PushBatchResponse, error) {
+	if len(req.GetUpdates()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Batch must contain at least one update")
+	}
+
+	updates := make([]MetricUpdate, len(req.GetUpdates()))
+	for i, u := range req.GetUpdates() {
+		updates[i] = updateFromProto(u)
+	}
+
+	resp, _ := s.h.pushBatch(ctx, updates, s.h.asyncDefault())
+
+	out := &cronpromv1.PushBatchResponse{Status: resp.Status}
+	for _, r := range resp.Results {
+		out.Results = append(out.Results, resultToProto(r))
+	}
+	return out, nil
+}
+
+func (s *metricService) PushStream(stream grpc.ClientStreamingServer[cronpromv1.PushStreamRequest, cronpromv1.PushStreamResponse]) error {
+	resp := &cronpromv1.PushStreamResponse{}
+
+	for i := 0; ; i++ {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(resp)
+		}
+		if err != nil {
+			return err
+		}
+
+		update := updateFromProto(req.GetUpdate())
+		if perr := s.push(stream.Context(), update); perr != nil {
+			resp.Rejected++
+			resp.Errors = append(resp.Errors, resultToProto(BatchResult{
+				Index:  i,
+				Name:   update.Name,
+				Status: "rejected",
+				Code:   perr.code,
+				Error:  perr.msg,
+			}))
+			continue
+		}
+		resp.Applied++
+	}
+}
+
+// push validates and applies a single update
+func (s *metricService) push(ctx context.Context, update MetricUpdate) *pushError {
+	metricType, perr := s.h.prepareUpdate(ctx, &update)
+	if perr != nil {
+		return perr
+	}
+
+	if s.h.asyncDefault() {
+		if !s.h.enqueue(ctx, []config.MetricType{metricType}, []MetricUpdate{update}) {
+			return errQueueFull
+		}
+		return nil
+	}
+
+	if err := s.h.applyUpdate(ctx, metricType, update); err != nil {
+		return &pushError{http.StatusInternalServerError, CodeInternal, err.Error()}
+	}
+
+	return nil
+}
+
+// grpcStatus converts the error into a gRPC status with a matching code
+func (e *pushError) grpcStatus() error {
+	code := codes.Internal
+	switch e.status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		code = codes.InvalidArgument
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusRequestEntityTooLarge:
+		code = codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	}
+	return status.Error(code, e.msg)
+}
+
+func updateFromProto(u *cronpromv1.MetricUpdate) MetricUpdate {
+	labels := u.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+
+	update := MetricUpdate{
+		Name:   u.GetName(),
+		Type:   u.GetType(),
+		Value:  u.GetValue(),
+		Labels: labels,
+		Help:   u.GetHelp(),
+		Op:     u.GetOp(),
+	}
+
+	if e := u.GetExemplar(); e != nil {
+		update.Exemplar = &Exemplar{TraceID: e.GetTraceId(), Labels: e.GetLabels()}
+	}
+
+	return update
+}
+
+func resultToProto(r BatchResult) *cronpromv1.BatchResult {
+	return &cronpromv1.BatchResult{
+		Index:  int32(r.Index),
+		Name:   r.Name,
+		Status: r.Status,
+		Code:   r.Code,
+		Error:  r.Error,
+	}
+}
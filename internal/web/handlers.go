@@ -2,25 +2,48 @@
 package web
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"math"
+	"mime"
 	"net/http"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/hay-kot/cronprom/internal/data/config"
 	"github.com/hay-kot/cronprom/internal/services/collector"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/hay-kot/cronprom/pkg/validate"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
 )
 
 // MetricHandler handles metric update requests
 type MetricHandler struct {
 	collector *collector.MetricCollector
+	validator validate.Validator
+	overlay   *config.Overlay
+	deltas    deltaTracker // Last values of cumulative OTLP series
+	failures  failureLog   // Recent rejected pushes shown on the status page
+	events    eventHub     // Subscribers of the live update stream
+	queue     *updateQueue // Updates applied in the background, nil unless enabled
 }
 
-// NewMetricHandler creates a new metric handler
-func NewMetricHandler(collector *collector.MetricCollector) *MetricHandler {
+// NewMetricHandler creates a new metric handler. The validator is run for every
+// push after the built-in validation, metrics created through the admin API are
+// persisted to the overlay. Both may be nil.
+func NewMetricHandler(collector *collector.MetricCollector, validator validate.Validator, overlay *config.Overlay) *MetricHandler {
 	return &MetricHandler{
 		collector: collector,
+		validator: validator,
+		overlay:   overlay,
+		deltas:    deltaTracker{exists: collector.HasSeries},
 	}
 }
 
@@ -30,72 +53,802 @@ type MetricUpdate struct {
 	Type   string            `json:"type"`
 	Value  float64           `json:"value"`
 	Labels map[string]string `json:"labels"`
+	Help   string            `json:"help,omitempty"` // Description of a metric created on the fly
+	Op     string            `json:"op,omitempty"`   // Gauge operation: set (default), inc, dec, add, or set_to_current_time
+
+	// Exemplar attached to the increment of a counter or the observation of a
+	// histogram, its value is the value of the update
+	Exemplar *Exemplar `json:"exemplar,omitempty"`
+
+	// dynamic is the unknown metric created by the update, it is registered when
+	// the update is applied so rejected pushes don't leave metrics behind
+	dynamic *config.MetricConfig
+}
+
+// Exemplar links an update to a trace, exposed through the OpenMetrics format
+type Exemplar struct {
+	TraceID string            `json:"trace_id,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// labels returns the exemplar labels including the trace ID
+func (e *Exemplar) labels() map[string]string {
+	labels := make(map[string]string, len(e.Labels)+1)
+	maps.Copy(labels, e.Labels)
+	if e.TraceID != "" {
+		labels["trace_id"] = e.TraceID
+	}
+	return labels
+}
+
+// BatchResult is the outcome of a single update in a batch push
+type BatchResult struct {
+	Index  int    `json:"index"`
+	Name   string `json:"name"`
+	Status string `json:"status"` // applied, rejected, or skipped
+	Code   string `json:"code,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BatchResponse is the response body of a batch push
+type BatchResponse struct {
+	Status  string        `json:"status"` // success, queued, rejected, or partial
+	Results []BatchResult `json:"results"`
 }
 
-// PushHandler handles requests to update metrics
+// PushHandler handles requests to update metrics. The body is either a JSON
+// MetricUpdate, or samples in the Prometheus text exposition format when sent with
+// a text/plain content type.
 func (h *MetricHandler) PushHandler(w http.ResponseWriter, r *http.Request) {
-	// Only allow POST method
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "text/plain" {
+		body, perr := readPooledBody(r)
+		if perr != nil {
+			perr.write(w)
+			return
+		}
+		defer releaseBody(body)
+
+		h.pushExposition(w, r, body.Bytes())
 		return
 	}
 
-	// Read request body
-	body, err := io.ReadAll(r.Body)
+	// Parse JSON
+	var update MetricUpdate
+	if perr := decodeJSON(r, &update); perr != nil {
+		perr.write(w)
+		return
+	}
+
+	async, perr := h.async(r)
+	if perr != nil {
+		perr.write(w)
+		return
+	}
+
+	metricType, perr := h.prepareUpdate(r.Context(), &update)
+	if perr != nil {
+		perr.write(w)
+		return
+	}
+
+	if async {
+		if !h.enqueue(r.Context(), []config.MetricType{metricType}, []MetricUpdate{update}) {
+			errQueueFull.write(w)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"status":"queued"}`))
+		return
+	}
+
+	if err := h.applyUpdate(r.Context(), metricType, update); err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+
+	// Return success
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(`{"status":"success"}`))
+}
+
+// pushExposition applies samples in the text exposition format, the samples are
+// only applied if all of them are valid. Counter samples increment the counter.
+func (h *MetricHandler) pushExposition(w http.ResponseWriter, r *http.Request, body []byte) {
+	families, err := parseExposition(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidBody, err.Error())
+		return
+	}
+
+	updates, perr := h.expositionUpdates(families, nil)
+	if perr != nil {
+		perr.write(w)
+		return
+	}
+
+	types := make([]config.MetricType, len(updates))
+	for i := range updates {
+		metricType, perr := h.prepareUpdate(r.Context(), &updates[i])
+		if perr != nil {
+			perr.write(w)
+			return
+		}
+		types[i] = metricType
+	}
+
+	if _, perr := h.checkBatch(r.Context(), updates); perr != nil {
+		perr.write(w)
+		return
+	}
+
+	for i, update := range updates {
+		if err := h.applyUpdate(r.Context(), types[i], update); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(`{"status":"success"}`))
+}
+
+// PushBatchHandler handles requests to update many metrics at once. The updates are
+// only applied if every update in the batch is valid, the response reports the
+// outcome of each update.
+func (h *MetricHandler) PushBatchHandler(w http.ResponseWriter, r *http.Request) {
+	body, perr := readBody(r)
+	if perr != nil {
+		perr.write(w)
+		return
+	}
+
+	var updates []MetricUpdate
+	if err := json.Unmarshal(body, &updates); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidBody, "Error parsing JSON")
+		return
+	}
+
+	if len(updates) == 0 {
+		writeError(w, http.StatusUnprocessableEntity, CodeValidationFailed, "Batch must contain at least one update")
+		return
+	}
+
+	async, perr := h.async(r)
+	if perr != nil {
+		perr.write(w)
+		return
+	}
+
+	resp, status := h.pushBatch(r.Context(), updates, async)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// pushBatch validates every update of the batch and applies or queues them only if
+// all are valid. Applying isn't transactional, if an update fails to apply after
+// the validation, e.g., because its metric was removed meanwhile, the updates
+// before it stay applied, the rest are still applied, and the batch is reported as
+// partial. It returns the outcome of each update and the status code to respond
+// with.
+func (h *MetricHandler) pushBatch(ctx context.Context, updates []MetricUpdate, async bool) (BatchResponse, int) {
+	resp := BatchResponse{
+		Status:  "success",
+		Results: make([]BatchResult, len(updates)),
+	}
+
+	// Validate every update before applying any of them
+	metricTypes := make([]config.MetricType, len(updates))
+	for i := range updates {
+		resp.Results[i] = BatchResult{Index: i, Name: updates[i].Name, Status: "skipped"}
+
+		metricType, perr := h.prepareUpdate(ctx, &updates[i])
+		if perr != nil {
+			resp.Status = "rejected"
+			resp.Results[i].Status = "rejected"
+			resp.Results[i].Code = perr.code
+			resp.Results[i].Error = perr.msg
+			continue
+		}
+		metricTypes[i] = metricType
+	}
+
+	if resp.Status != "rejected" {
+		if i, perr := h.checkBatch(ctx, updates); perr != nil {
+			resp.Status = "rejected"
+			resp.Results[i].Status = "rejected"
+			resp.Results[i].Code = perr.code
+			resp.Results[i].Error = perr.msg
+		}
+	}
+
+	status := http.StatusOK
+	if resp.Status == "rejected" {
+		status = http.StatusUnprocessableEntity
+	} else if async {
+		resp.Status, status = "queued", http.StatusAccepted
+		if !h.enqueue(ctx, metricTypes, updates) {
+			resp.Status, status = "rejected", errQueueFull.status
+		}
+
+		for i := range resp.Results {
+			resp.Results[i].Status = resp.Status
+			if status == errQueueFull.status {
+				resp.Results[i].Code = errQueueFull.code
+				resp.Results[i].Error = errQueueFull.msg
+			}
+		}
+	} else {
+		for i, update := range updates {
+			if err := h.applyUpdate(ctx, metricTypes[i], update); err != nil {
+				resp.Status = "partial"
+				resp.Results[i].Status = "rejected"
+				resp.Results[i].Code = CodeInternal
+				resp.Results[i].Error = err.Error()
+				status = http.StatusInternalServerError
+				continue
+			}
+			resp.Results[i].Status = "applied"
+		}
+	}
+
+	return resp, status
+}
+
+// DeleteRequest is the body of a series deletion, Reset deletes all series of the
+// metric instead of the series with the given labels
+type DeleteRequest struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels"`
+	Reset  bool              `json:"reset,omitempty"`
+}
+
+// DeleteResponse is the response body of a series deletion
+type DeleteResponse struct {
+	Status  string `json:"status"`
+	Deleted int    `json:"deleted"` // Number of deleted series
+}
+
+// DeleteHandler deletes a series of a metric, or all of its series when the request
+// asks for a reset. Deleting a series that doesn't exist succeeds.
+func (h *MetricHandler) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	body, perr := readBody(r)
+	if perr != nil {
+		perr.write(w)
+		return
+	}
+
+	var req DeleteRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidBody, "Error parsing JSON request body")
+		return
+	}
+
+	noteMetric(r.Context(), req.Name)
+
+	if req.Name == "" {
+		writeError(w, http.StatusUnprocessableEntity, CodeValidationFailed, "Metric name is required")
+		return
+	}
+
+	if key, ok := APIKeyFromContext(r.Context()); ok && !key.Allows(req.Name) {
+		writeError(w, http.StatusForbidden, CodeForbidden, fmt.Sprintf("API key '%s' is not allowed to push to metric '%s'", key.Name, req.Name))
+		return
+	}
+
+	var (
+		deleted int
+		err     error
+	)
+	if req.Reset {
+		deleted, err = h.collector.ResetMetric(req.Name)
+	} else {
+		var ok bool
+		ok, err = h.collector.DeleteSeries(req.Name, req.Labels)
+		if ok {
+			deleted = 1
+		}
+	}
+
 	if err != nil {
-		http.Error(w, "Error reading request body", http.StatusBadRequest)
+		if errors.Is(err, collector.ErrMetricNotFound) {
+			writeError(w, http.StatusNotFound, CodeMetricNotFound, err.Error())
+			return
+		}
+		if errors.Is(err, collector.ErrLabelMismatch) {
+			writeError(w, http.StatusUnprocessableEntity, CodeLabelMismatch, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(DeleteResponse{Status: "success", Deleted: deleted})
+}
+
+// readBody reads the request body, respecting the body size limit
+func readBody(r *http.Request) ([]byte, *pushError) {
 	defer r.Body.Close()
 
-	// Parse JSON
-	var update MetricUpdate
-	if err := json.Unmarshal(body, &update); err != nil {
-		http.Error(w, "Error parsing JSON", http.StatusBadRequest)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, bodyError(err)
+	}
+
+	return body, nil
+}
+
+// maxPooledBody is the capacity of body buffers above which they are dropped
+// instead of pooled, so a single large push doesn't pin its buffer
+const maxPooledBody = 1 << 20
+
+var bodyPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// readPooledBody reads the request body like readBody into a pooled buffer, the
+// caller must return it with releaseBody once the body is no longer used
+func readPooledBody(r *http.Request) (*bytes.Buffer, *pushError) {
+	defer r.Body.Close()
+
+	body := bodyPool.Get().(*bytes.Buffer)
+	body.Reset()
+
+	if _, err := body.ReadFrom(r.Body); err != nil {
+		releaseBody(body)
+		return nil, bodyError(err)
+	}
+
+	return body, nil
+}
+
+// releaseBody returns a buffer of readPooledBody to the pool
+func releaseBody(body *bytes.Buffer) {
+	if body.Cap() > maxPooledBody {
 		return
 	}
+	bodyPool.Put(body)
+}
 
+// decodeJSON decodes a single JSON value from the request body as it is read. The
+// body size limit applies through MaxBodySize, trailing data is an error.
+func decodeJSON(r *http.Request, v any) *pushError {
+	defer r.Body.Close()
+
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(v); err != nil {
+		return decodeError(err)
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return decodeError(err)
+	}
+
+	return nil
+}
+
+// decodeError converts an error decoding a JSON body into a push error, a nil
+// error is trailing data after the decoded value
+func decodeError(err error) *pushError {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return bodyError(err)
+	}
+	return &pushError{http.StatusBadRequest, CodeInvalidBody, "Error parsing JSON"}
+}
+
+// bodyError converts an error reading the request body into a push error
+func bodyError(err error) *pushError {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return &pushError{http.StatusRequestEntityTooLarge, CodeBodyTooLarge, fmt.Sprintf("Request body exceeds %d bytes", maxBytesErr.Limit)}
+	}
+
+	return &pushError{http.StatusBadRequest, CodeBadRequest, "Error reading request body"}
+}
+
+// prepareUpdate validates the update and runs the custom validators, which may
+// modify it. It returns the parsed metric type of the update, rejected updates are
+// recorded for the status page.
+func (h *MetricHandler) prepareUpdate(ctx context.Context, update *MetricUpdate) (config.MetricType, *pushError) {
+	noteMetric(ctx, update.Name)
+
+	metricType, perr := h.validateUpdate(ctx, update)
+	if perr != nil {
+		h.reject(ctx, update.Name, perr)
+	}
+	return metricType, perr
+}
+
+// reject records a rejected update for the status page
+func (h *MetricHandler) reject(ctx context.Context, name string, perr *pushError) {
+	pushUpdates.WithLabelValues("rejected").Inc()
+	h.failures.add(PushFailure{
+		Time:      time.Now(),
+		RequestID: RequestIDFromContext(ctx),
+		Metric:    name,
+		Code:      perr.code,
+		Message:   perr.msg,
+	})
+}
+
+// checkBatch checks prepared updates of a push against each other, each of them is
+// valid on its own. The series created by all updates count against the series
+// limit of their metric, and the updates creating the same dynamic metric must
+// agree on its type and labels. It returns the index of the first rejected update.
+func (h *MetricHandler) checkBatch(ctx context.Context, updates []MetricUpdate) (int, *pushError) {
+	var (
+		names   []string
+		indexes = make(map[string][]int)
+	)
+	for i, update := range updates {
+		if _, ok := indexes[update.Name]; !ok {
+			names = append(names, update.Name)
+		}
+		indexes[update.Name] = append(indexes[update.Name], i)
+	}
+
+	rejected, perr := len(updates), (*pushError)(nil)
+	for _, name := range names {
+		first := updates[indexes[name][0]]
+
+		if first.dynamic != nil {
+			for _, i := range indexes[name][1:] {
+				if i < rejected && (updates[i].dynamic.Type != first.dynamic.Type || !slices.Equal(updates[i].dynamic.Labels, first.dynamic.Labels)) {
+					rejected = i
+					perr = &pushError{http.StatusUnprocessableEntity, CodeLabelMismatch, fmt.Sprintf("updates creating metric '%s' differ in type or labels", name)}
+				}
+			}
+			continue
+		}
+
+		labelSets := make([]map[string]string, len(indexes[name]))
+		for j, i := range indexes[name] {
+			labelSets[j] = updates[i].Labels
+		}
+
+		j, err := h.collector.CheckSeriesLimits(name, labelSets)
+		if errors.Is(err, collector.ErrSeriesLimit) && indexes[name][j] < rejected {
+			rejected = indexes[name][j]
+			perr = &pushError{http.StatusUnprocessableEntity, CodeSeriesLimit, err.Error()}
+		}
+	}
+
+	if perr != nil {
+		h.reject(ctx, updates[rejected].Name, perr)
+	}
+	return rejected, perr
+}
+
+// validateUpdate runs the built-in and custom validation of an update
+func (h *MetricHandler) validateUpdate(ctx context.Context, update *MetricUpdate) (config.MetricType, *pushError) {
 	// Validate the update
 	if update.Name == "" {
-		http.Error(w, "Metric name is required", http.StatusBadRequest)
-		return
+		return "", &pushError{http.StatusUnprocessableEntity, CodeValidationFailed, "Metric name is required"}
+	}
+
+	// Enforce the scope of the API key used to authenticate
+	if key, ok := APIKeyFromContext(ctx); ok && !key.Allows(update.Name) {
+		return "", &pushError{http.StatusForbidden, CodeForbidden, fmt.Sprintf("API key '%s' is not allowed to push to metric '%s'", key.Name, update.Name)}
 	}
 
 	metricType, err := config.ParseMetricType(update.Type)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+		return "", &pushError{http.StatusUnprocessableEntity, CodeValidationFailed, err.Error()}
+	}
+
+	if update.Op != "" {
+		if metricType != config.MetricTypeGauge {
+			return "", &pushError{http.StatusUnprocessableEntity, CodeValidationFailed, fmt.Sprintf("op is only supported for gauges, not %s", metricType)}
+		}
+		if _, err := collector.ParseGaugeOp(update.Op); err != nil {
+			return "", &pushError{http.StatusUnprocessableEntity, CodeValidationFailed, err.Error()}
+		}
+	}
+
+	if update.Exemplar != nil {
+		if metricType != config.MetricTypeCounter && metricType != config.MetricTypeHistogram {
+			return "", &pushError{http.StatusUnprocessableEntity, CodeValidationFailed, fmt.Sprintf("exemplars are only supported for counters and histograms, not %s", metricType)}
+		}
+
+		labels := update.Exemplar.labels()
+		if len(labels) == 0 {
+			return "", &pushError{http.StatusUnprocessableEntity, CodeValidationFailed, "exemplar requires a trace_id or labels"}
+		}
+		if err := collector.ValidateExemplar(labels); err != nil {
+			return "", &pushError{http.StatusUnprocessableEntity, CodeValidationFailed, err.Error()}
+		}
+	}
+
+	dynamic := false
+	if !h.collector.HasMetric(update.Name, metricType) {
+		metricCfg, ok := h.collector.Resolve(update.Name)
+		switch {
+		case ok && metricCfg.Name == update.Name:
+			return "", &pushError{http.StatusUnprocessableEntity, CodeTypeMismatch, fmt.Sprintf("metric '%s' is a %s, not a %s", update.Name, metricCfg.Type, metricType)}
+		case !ok && h.collector.DynamicMetrics():
+			dynamic = true
+		default:
+			return "", &pushError{http.StatusNotFound, CodeMetricNotFound, fmt.Sprintf("%s metric '%s' not found", metricType, update.Name)}
+		}
+	}
+
+	h.labelSource(ctx, update)
+
+	// Run custom validators
+	if h.validator != nil {
+		v := validate.Update{
+			Name:   update.Name,
+			Type:   update.Type,
+			Op:     update.Op,
+			Value:  update.Value,
+			Labels: update.Labels,
+		}
+
+		if err := h.validator.Validate(ctx, &v); err != nil {
+			if errors.Is(err, validate.ErrRejected) {
+				return "", &pushError{http.StatusUnprocessableEntity, CodeValidationFailed, err.Error()}
+			}
+			return "", &pushError{http.StatusInternalServerError, CodeInternal, err.Error()}
+		}
+
+		update.Value = v.Value
+		update.Labels = v.Labels
+	}
+
+	// Counters can't decrease, checked after the validators which may change the value
+	if metricType == config.MetricTypeCounter && (!(update.Value >= 0) || math.IsInf(update.Value, 1)) {
+		return "", &pushError{http.StatusUnprocessableEntity, CodeValidationFailed, fmt.Sprintf("counter increment must be a finite non-negative number, not %v", update.Value)}
+	}
+
+	// The metric of a dynamic update is made from its labels, there is nothing to
+	// check them against
+	if dynamic {
+		metricCfg := dynamicMetric(metricType, *update)
+		if err := h.collector.ValidateMetric(metricCfg); err != nil {
+			return "", &pushError{http.StatusUnprocessableEntity, CodeValidationFailed, fmt.Sprintf("error creating metric '%s': %s", update.Name, err)}
+		}
+		update.dynamic = &metricCfg
+		return metricType, nil
+	}
+
+	if metricType == config.MetricTypeEnum {
+		if err := h.collector.ValidateState(update.Name, update.Labels); err != nil {
+			return "", &pushError{http.StatusUnprocessableEntity, CodeValidationFailed, err.Error()}
+		}
+	}
+
+	if err := h.collector.CheckLabels(update.Name, update.Labels); err != nil {
+		return "", &pushError{http.StatusUnprocessableEntity, CodeLabelMismatch, err.Error()}
+	}
+
+	if err := h.collector.CheckSeriesLimit(update.Name, update.Labels); err != nil {
+		return "", &pushError{http.StatusUnprocessableEntity, CodeSeriesLimit, err.Error()}
+	}
+
+	return metricType, nil
+}
+
+// dynamicMetric returns the configuration of an unknown metric created by the first
+// update pushed to it. Histograms and summaries use the default buckets and
+// objectives of the client library.
+func dynamicMetric(metricType config.MetricType, update MetricUpdate) config.MetricConfig {
+	metricCfg := config.MetricConfig{
+		Name:        update.Name,
+		Description: update.Help,
+		Type:        metricType,
+		Labels:      slices.Sorted(maps.Keys(update.Labels)),
 	}
 
-	// Process the update based on metric type
-	var updateErr error
+	switch metricType {
+	case config.MetricTypeHistogram:
+		metricCfg.Buckets = prometheus.DefBuckets
+	case config.MetricTypeSummary:
+		metricCfg.Objectives = config.Objectives{0.5: 0.05, 0.9: 0.01, 0.99: 0.001}
+	}
+
+	return metricCfg
+}
+
+// applyUpdate applies a prepared update to the collector based on the metric type
+// and publishes it to the live stream. The metric of a dynamic update is registered
+// first, unless an earlier update already created it.
+func (h *MetricHandler) applyUpdate(ctx context.Context, metricType config.MetricType, update MetricUpdate) error {
+	if update.dynamic != nil {
+		if err := h.collector.AddMetric(*update.dynamic); err != nil && !errors.Is(err, collector.ErrMetricExists) {
+			return fmt.Errorf("error creating metric '%s': %w", update.Name, err)
+		}
+	}
+
+	var err error
 	switch metricType {
 	case config.MetricTypeGauge:
-		updateErr = h.collector.UpdateGauge(update.Name, update.Value, update.Labels)
+		var op collector.GaugeOp
+		op, err = collector.ParseGaugeOp(update.Op)
+		if err == nil {
+			err = h.collector.ApplyGauge(update.Name, op, update.Value, update.Labels)
+		}
 	case config.MetricTypeCounter:
-		updateErr = h.collector.IncrementCounterBy(update.Name, update.Value, update.Labels)
+		if update.Exemplar != nil {
+			err = h.collector.IncrementCounterWithExemplar(update.Name, update.Value, update.Labels, update.Exemplar.labels())
+		} else {
+			err = h.collector.IncrementCounterBy(update.Name, update.Value, update.Labels)
+		}
 	case config.MetricTypeHistogram:
-		updateErr = h.collector.ObserveHistogram(update.Name, update.Value, update.Labels)
+		if update.Exemplar != nil {
+			err = h.collector.ObserveHistogramWithExemplar(update.Name, update.Value, update.Labels, update.Exemplar.labels())
+		} else {
+			err = h.collector.ObserveHistogram(update.Name, update.Value, update.Labels)
+		}
 	case config.MetricTypeSummary:
-		updateErr = h.collector.ObserveSummary(update.Name, update.Value, update.Labels)
+		err = h.collector.ObserveSummary(update.Name, update.Value, update.Labels)
+	case config.MetricTypeInfo:
+		err = h.collector.SetInfo(update.Name, update.Labels)
+	case config.MetricTypeEnum:
+		err = h.collector.SetState(update.Name, update.Labels)
 	default:
-		http.Error(w, fmt.Sprintf("Unsupported metric type: %s", update.Type), http.StatusBadRequest)
+		return fmt.Errorf("unsupported metric type: %s", update.Type)
+	}
+
+	if errors.Is(err, collector.ErrSampleDropped) {
+		pushUpdates.WithLabelValues("dropped").Inc()
+		log.Debug().Err(err).Str("metric", update.Name).Msg("update dropped")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	pushUpdates.WithLabelValues("applied").Inc()
+
+	if h.events.active() {
+		h.events.publish(MetricEvent{
+			Time:   time.Now(),
+			Name:   update.Name,
+			Type:   metricType.String(),
+			Op:     update.Op,
+			Labels: maps.Clone(update.Labels),
+			Value:  update.Value,
+			Source: sourceIP(ctx),
+		})
+	}
+
+	return nil
+}
+
+// ListMetricsHandler returns all configured metrics along with their runtime state
+func (h *MetricHandler) ListMetricsHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(h.collector.Metrics())
+}
+
+// GetMetricHandler returns the current samples of a metric
+func (h *MetricHandler) GetMetricHandler(w http.ResponseWriter, r *http.Request) {
+	samples, err := h.collector.Samples(r.PathValue("name"))
+	if err != nil {
+		if errors.Is(err, collector.ErrMetricNotFound) {
+			writeError(w, http.StatusNotFound, CodeMetricNotFound, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
-	if updateErr != nil {
-		http.Error(w, updateErr.Error(), http.StatusBadRequest)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(samples)
+}
+
+// CreateMetricHandler defines a new metric at runtime. The body mirrors the metric
+// configuration and may be JSON or YAML.
+func (h *MetricHandler) CreateMetricHandler(w http.ResponseWriter, r *http.Request) {
+	body, perr := readBody(r)
+	if perr != nil {
+		perr.write(w)
 		return
 	}
 
-	// Return success
-	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write([]byte(`{"status":"success"}`))
+	// JSON is a subset of YAML, decoding as YAML keeps the field names of the config file
+	var metricCfg config.MetricConfig
+	if err := yaml.Unmarshal(body, &metricCfg); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidBody, "Error parsing metric definition")
+		return
+	}
+
+	if err := h.collector.AddMetric(metricCfg); err != nil {
+		if errors.Is(err, collector.ErrMetricExists) {
+			writeError(w, http.StatusConflict, CodeMetricExists, err.Error())
+			return
+		}
+		writeError(w, http.StatusUnprocessableEntity, CodeValidationFailed, err.Error())
+		return
+	}
+
+	if h.overlay != nil {
+		if err := h.overlay.Add(metricCfg); err != nil {
+			_ = h.collector.RemoveMetric(metricCfg.Name)
+			writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+			return
+		}
+	}
+
+	info, err := h.collector.Info(metricCfg.Name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(info)
+}
+
+// DeleteMetricHandler unregisters a metric and removes all of its series
+func (h *MetricHandler) DeleteMetricHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	if err := h.collector.RemoveMetric(name); err != nil {
+		if errors.Is(err, collector.ErrMetricNotFound) {
+			writeError(w, http.StatusNotFound, CodeMetricNotFound, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+
+	if h.overlay != nil {
+		if err := h.overlay.Remove(name); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
-// PrometheusHandler exposes metrics in Prometheus format
-func (h *MetricHandler) PrometheusHandler(w http.ResponseWriter, r *http.Request) {
-	registry := h.collector.GetRegistry()
-	handler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
-	handler.ServeHTTP(w, r)
+// RestoreResponse is the response body of a snapshot restore
+type RestoreResponse struct {
+	Status   string `json:"status"`
+	Restored int    `json:"restored"` // Number of restored series
+}
+
+// SnapshotHandler returns the values, label sets, and last push times of all series
+func (h *MetricHandler) SnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	// Encoded before writing so an encoding error isn't sent as a truncated 200
+	data, err := json.Marshal(h.collector.State())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="cronprom-snapshot.json"`)
+	_, _ = w.Write(append(data, '\n'))
+}
+
+// RestoreHandler loads a snapshot taken by SnapshotHandler. The restored series are
+// set to their saved values, so restoring it again changes nothing, summaries
+// aren't restored.
+// The body is limited to maxSnapshotSize by its route.
+func (h *MetricHandler) RestoreHandler(w http.ResponseWriter, r *http.Request) {
+	var state collector.State
+	if err := json.NewDecoder(r.Body).Decode(&state); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, CodeBodyTooLarge, fmt.Sprintf("Request body exceeds %d bytes", maxBytesErr.Limit))
+			return
+		}
+		writeError(w, http.StatusBadRequest, CodeInvalidBody, "Error parsing snapshot")
+		return
+	}
+
+	restored, err := h.collector.Restore(state)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, CodeValidationFailed, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(RestoreResponse{Status: "success", Restored: restored})
 }
@@ -0,0 +1,103 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/hay-kot/cronprom/internal/services/collector"
+	"github.com/hay-kot/cronprom/pkg/validate"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func newTestHandler(t *testing.T, validator validate.Validator) (*MetricHandler, *collector.MetricCollector) {
+	t.Helper()
+
+	cfg := &config.Config{
+		Global: config.GlobalConfig{AllowDynamicMetrics: true},
+		Metrics: []config.MetricConfig{
+			{Name: "backup_runs_total", Type: config.MetricTypeCounter, Labels: []string{"host"}, MaxSeries: 2},
+			{Name: "backup_size_bytes", Type: config.MetricTypeGauge, Labels: []string{"host"}, LabelPolicy: config.LabelPolicyStrict},
+		},
+	}
+
+	coll, err := collector.NewMetricCollector(cfg, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewMetricHandler(coll, validator, nil), coll
+}
+
+func TestPushBatchSeriesLimit(t *testing.T) {
+	h, coll := newTestHandler(t, nil)
+
+	if err := coll.IncrementCounterBy("backup_runs_total", 1, map[string]string{"host": "db1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each update is within the limit on its own, together they create two series
+	// beyond the existing one
+	updates := []MetricUpdate{
+		{Name: "backup_runs_total", Type: "counter", Value: 1, Labels: map[string]string{"host": "db1"}},
+		{Name: "backup_runs_total", Type: "counter", Value: 1, Labels: map[string]string{"host": "db2"}},
+		{Name: "backup_runs_total", Type: "counter", Value: 1, Labels: map[string]string{"host": "db2"}},
+		{Name: "backup_runs_total", Type: "counter", Value: 1, Labels: map[string]string{"host": "db3"}},
+	}
+
+	resp, status := h.pushBatch(context.Background(), updates, false)
+	if status != http.StatusUnprocessableEntity || resp.Status != "rejected" {
+		t.Fatalf("pushBatch() = %d %s, want %d rejected", status, resp.Status, http.StatusUnprocessableEntity)
+	}
+
+	for i, want := range []string{"skipped", "skipped", "skipped", "rejected"} {
+		if got := resp.Results[i].Status; got != want {
+			t.Errorf("result %d status = %s, want %s", i, got, want)
+		}
+	}
+	if code := resp.Results[3].Code; code != CodeSeriesLimit {
+		t.Errorf("result 3 code = %s, want %s", code, CodeSeriesLimit)
+	}
+
+	if coll.HasSeries("backup_runs_total", map[string]string{"host": "db2"}) {
+		t.Error("series of a rejected batch was created")
+	}
+}
+
+func TestPushBatchPartial(t *testing.T) {
+	// Removing the gauge while the last update is validated makes the valid update
+	// of the gauge fail to apply
+	var coll *collector.MetricCollector
+	validator := validate.Func(func(_ context.Context, u *validate.Update) error {
+		if u.Labels["host"] == "db2" {
+			return coll.RemoveMetric("backup_size_bytes")
+		}
+		return nil
+	})
+
+	h, c := newTestHandler(t, validator)
+	coll = c
+
+	updates := []MetricUpdate{
+		{Name: "backup_runs_total", Type: "counter", Value: 1, Labels: map[string]string{"host": "db1"}},
+		{Name: "backup_size_bytes", Type: "gauge", Value: 42, Labels: map[string]string{"host": "db1"}},
+		{Name: "backup_runs_total", Type: "counter", Value: 1, Labels: map[string]string{"host": "db2"}},
+	}
+
+	resp, status := h.pushBatch(context.Background(), updates, false)
+	if status != http.StatusInternalServerError || resp.Status != "partial" {
+		t.Fatalf("pushBatch() = %d %s, want %d partial", status, resp.Status, http.StatusInternalServerError)
+	}
+
+	for i, want := range []string{"applied", "rejected", "applied"} {
+		if got := resp.Results[i].Status; got != want {
+			t.Errorf("result %d status = %s, want %s", i, got, want)
+		}
+	}
+
+	for _, host := range []string{"db1", "db2"} {
+		if !coll.HasSeries("backup_runs_total", map[string]string{"host": host}) {
+			t.Errorf("update for host %s of a partial batch wasn't applied", host)
+		}
+	}
+}
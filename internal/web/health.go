@@ -0,0 +1,169 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// healthCheckTimeout bounds the time spent on the checks of a readiness probe
+const healthCheckTimeout = 5 * time.Second
+
+// HealthCheck is a dependency that must be available for the server to be ready
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// Health tracks the liveness and readiness of the server. The server is ready
+// once it is marked ready and every check passes, it is marked not ready again
+// when it starts to drain on shutdown.
+type Health struct {
+	ready   atomic.Bool
+	version string
+	commit  string
+	started time.Time
+	metrics func() int // Number of registered metrics
+
+	mu         sync.RWMutex
+	checks     []HealthCheck
+	configHash string
+	lastReload time.Time
+}
+
+// NewHealth creates a server health that isn't ready yet. The version and commit
+// are reported by the health endpoint along with the number of metrics.
+func NewHealth(version, commit string, metrics func() int) *Health {
+	return &Health{
+		version: version,
+		commit:  commit,
+		started: time.Now(),
+		metrics: metrics,
+	}
+}
+
+// ConfigLoaded records the hash and load time of the active configuration
+func (h *Health) ConfigLoaded(hash string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.configHash = hash
+	h.lastReload = time.Now()
+}
+
+// SetReady marks whether the server accepts traffic
+func (h *Health) SetReady(ready bool) {
+	h.ready.Store(ready)
+}
+
+// AddCheck registers a readiness check
+func (h *Health) AddCheck(name string, check func(ctx context.Context) error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, HealthCheck{Name: name, Check: check})
+}
+
+// ReadinessResponse is the response body of the readiness probe
+type ReadinessResponse struct {
+	Status string            `json:"status"`           // ready or not_ready
+	Checks map[string]string `json:"checks,omitempty"` // ok or the error of each check
+}
+
+// HealthResponse is the response body of the health endpoint
+type HealthResponse struct {
+	Status        string            `json:"status"` // ok, or degraded when a check fails
+	Version       string            `json:"version"`
+	Commit        string            `json:"commit"`
+	Uptime        string            `json:"uptime"`
+	UptimeSeconds float64           `json:"uptime_seconds"`
+	ConfigHash    string            `json:"config_hash"`
+	LastReload    time.Time         `json:"last_reload"`
+	Metrics       int               `json:"metrics"`
+	Ready         bool              `json:"ready"`
+	Checks        map[string]string `json:"checks,omitempty"` // ok or the error of each check, e.g., storage
+}
+
+// HealthHandler describes the running server for operators. It always responds
+// with a 200 as long as the process is up, failing checks only degrade the status.
+func (h *Health) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	readiness, ready := h.readiness(r.Context())
+	uptime := time.Since(h.started)
+
+	h.mu.RLock()
+	resp := HealthResponse{
+		Status:        "ok",
+		Version:       h.version,
+		Commit:        h.commit,
+		Uptime:        uptime.Round(time.Second).String(),
+		UptimeSeconds: uptime.Seconds(),
+		ConfigHash:    h.configHash,
+		LastReload:    h.lastReload,
+		Ready:         ready,
+		Checks:        readiness.Checks,
+	}
+	h.mu.RUnlock()
+
+	if h.metrics != nil {
+		resp.Metrics = h.metrics()
+	}
+
+	for _, result := range resp.Checks {
+		if result != "ok" {
+			resp.Status = "degraded"
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// LivezHandler reports that the process is up
+func (h *Health) LivezHandler(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(`{"status":"ok"}`))
+}
+
+// ReadyzHandler reports whether the server should receive traffic, responding with
+// a 503 while starting, draining, or when a check fails
+func (h *Health) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	resp, ok := h.readiness(r.Context())
+
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// readiness runs the checks and reports whether the server is ready
+func (h *Health) readiness(ctx context.Context) (ReadinessResponse, bool) {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	h.mu.RLock()
+	checks := h.checks
+	h.mu.RUnlock()
+
+	resp := ReadinessResponse{Status: "ready", Checks: make(map[string]string, len(checks))}
+	ok := h.ready.Load()
+
+	for _, c := range checks {
+		if err := c.Check(ctx); err != nil {
+			resp.Checks[c.Name] = err.Error()
+			ok = false
+			continue
+		}
+		resp.Checks[c.Name] = "ok"
+	}
+
+	if !ok {
+		resp.Status = "not_ready"
+	}
+
+	return resp, ok
+}
@@ -0,0 +1,230 @@
+package web
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+)
+
+// influxPoint is a single line of the InfluxDB line protocol
+type influxPoint struct {
+	measurement string
+	tags        map[string]string
+	fields      map[string]float64
+}
+
+// InfluxWriteHandler accepts samples in the InfluxDB line protocol. Every field of a
+// point updates the metric named "<measurement>_<field>", or "<measurement>" for the
+// field "value". Tags become labels and string fields are ignored. The samples are
+// only applied if all of them are valid.
+func (h *MetricHandler) InfluxWriteHandler(w http.ResponseWriter, r *http.Request) {
+	body, perr := readBody(r)
+	if perr != nil {
+		perr.write(w)
+		return
+	}
+
+	points, err := parseLineProtocol(string(body))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidBody, err.Error())
+		return
+	}
+
+	var updates []MetricUpdate
+	for _, p := range points {
+		for _, field := range slices.Sorted(maps.Keys(p.fields)) {
+			name := p.measurement
+			if field != "value" {
+				name += "_" + field
+			}
+
+			metricCfg, ok := h.collector.Resolve(name)
+			if !ok {
+				writeError(w, http.StatusNotFound, CodeMetricNotFound, fmt.Sprintf("metric '%s' not found", name))
+				return
+			}
+
+			updates = append(updates, MetricUpdate{
+				Name:   metricCfg.Name,
+				Type:   metricCfg.Type.String(),
+				Value:  p.fields[field],
+				Labels: maps.Clone(p.tags),
+			})
+		}
+	}
+
+	types := make([]config.MetricType, len(updates))
+	for i := range updates {
+		metricType, perr := h.prepareUpdate(r.Context(), &updates[i])
+		if perr != nil {
+			perr.write(w)
+			return
+		}
+		types[i] = metricType
+	}
+
+	if _, perr := h.checkBatch(r.Context(), updates); perr != nil {
+		perr.write(w)
+		return
+	}
+
+	for i, update := range updates {
+		if err := h.applyUpdate(r.Context(), types[i], update); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseLineProtocol parses "measurement[,tag=value...] field=value[,...] [timestamp]"
+// lines. Timestamps are ignored, samples are recorded on arrival.
+func parseLineProtocol(body string) ([]influxPoint, error) {
+	var points []influxPoint
+
+	lineNo := 0
+	for line := range strings.Lines(body) {
+		lineNo++
+
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		p, err := parseInfluxLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		points = append(points, p)
+	}
+
+	return points, nil
+}
+
+// parseInfluxLine parses a single line of the line protocol
+func parseInfluxLine(line string) (influxPoint, error) {
+	sections := splitUnescaped(line, ' ')
+	if len(sections) != 2 && len(sections) != 3 {
+		return influxPoint{}, fmt.Errorf("expected measurement, fields, and an optional timestamp")
+	}
+
+	keys := splitUnescaped(sections[0], ',')
+	p := influxPoint{
+		measurement: unescapeInflux(keys[0]),
+		tags:        make(map[string]string, len(keys)-1),
+		fields:      make(map[string]float64),
+	}
+
+	if p.measurement == "" {
+		return influxPoint{}, fmt.Errorf("measurement cannot be empty")
+	}
+
+	for _, tag := range keys[1:] {
+		k, v, ok := cutUnescaped(tag, '=')
+		if !ok || k == "" {
+			return influxPoint{}, fmt.Errorf("invalid tag '%s'", tag)
+		}
+		p.tags[unescapeInflux(k)] = unescapeInflux(v)
+	}
+
+	for _, field := range splitUnescaped(sections[1], ',') {
+		k, v, ok := cutUnescaped(field, '=')
+		if !ok || k == "" || v == "" {
+			return influxPoint{}, fmt.Errorf("invalid field '%s'", field)
+		}
+
+		// String fields can't be represented as a sample
+		if strings.HasPrefix(v, `"`) {
+			continue
+		}
+
+		value, err := parseInfluxValue(v)
+		if err != nil {
+			return influxPoint{}, fmt.Errorf("invalid value of field '%s': %w", k, err)
+		}
+		p.fields[unescapeInflux(k)] = value
+	}
+
+	return p, nil
+}
+
+// parseInfluxValue parses a float, integer (1i), unsigned (1u), or boolean field value
+func parseInfluxValue(v string) (float64, error) {
+	switch v {
+	case "t", "T", "true", "True", "TRUE":
+		return 1, nil
+	case "f", "F", "false", "False", "FALSE":
+		return 0, nil
+	}
+
+	if trimmed, ok := strings.CutSuffix(v, "i"); ok {
+		n, err := strconv.ParseInt(trimmed, 10, 64)
+		return float64(n), err
+	}
+
+	if trimmed, ok := strings.CutSuffix(v, "u"); ok {
+		n, err := strconv.ParseUint(trimmed, 10, 64)
+		return float64(n), err
+	}
+
+	return strconv.ParseFloat(v, 64)
+}
+
+// splitUnescaped splits s around sep, ignoring separators that are escaped with a
+// backslash or inside double quotes
+func splitUnescaped(s string, sep byte) []string {
+	var (
+		parts  []string
+		start  int
+		quoted bool
+	)
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case sep:
+			if !quoted {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+// cutUnescaped slices s around the first unescaped instance of sep
+func cutUnescaped(s string, sep byte) (string, string, bool) {
+	parts := splitUnescaped(s, sep)
+	if len(parts) < 2 {
+		return s, "", false
+	}
+
+	return parts[0], s[len(parts[0])+1:], true
+}
+
+// unescapeInflux removes the backslashes escaping commas, spaces, and equal signs
+func unescapeInflux(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(`, =\`, s[i+1]) >= 0 {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
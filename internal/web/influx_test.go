@@ -0,0 +1,106 @@
+package web
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLineProtocol(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []influxPoint
+		wantErr bool
+	}{
+		{
+			name: "value field",
+			body: "backup_size value=42",
+			want: []influxPoint{{measurement: "backup_size", tags: map[string]string{}, fields: map[string]float64{"value": 42}}},
+		},
+		{
+			name: "tags fields and timestamp",
+			body: "backup,host=db1,job=nightly duration=1.5,files=10i,ok=t 1700000000000000000",
+			want: []influxPoint{{
+				measurement: "backup",
+				tags:        map[string]string{"host": "db1", "job": "nightly"},
+				fields:      map[string]float64{"duration": 1.5, "files": 10, "ok": 1},
+			}},
+		},
+		{
+			name: "escaped separators",
+			body: `my\ backup,path=/var/lib\,data,a\=b=c\ d value=1`,
+			want: []influxPoint{{
+				measurement: "my backup",
+				tags:        map[string]string{"path": "/var/lib,data", "a=b": "c d"},
+				fields:      map[string]float64{"value": 1},
+			}},
+		},
+		{
+			name: "string fields are skipped",
+			body: `backup status="done, ok",size=3u,failed=false`,
+			want: []influxPoint{{measurement: "backup", tags: map[string]string{}, fields: map[string]float64{"size": 3, "failed": 0}}},
+		},
+		{
+			name: "comments and blank lines",
+			body: "# written by a script\n\nbackup value=1\n  \nbackup value=2\n",
+			want: []influxPoint{
+				{measurement: "backup", tags: map[string]string{}, fields: map[string]float64{"value": 1}},
+				{measurement: "backup", tags: map[string]string{}, fields: map[string]float64{"value": 2}},
+			},
+		},
+		{
+			name: "empty body",
+			body: "",
+			want: nil,
+		},
+		{name: "missing fields", body: "backup", wantErr: true},
+		{name: "too many sections", body: "backup value=1 1700000000 extra", wantErr: true},
+		{name: "empty measurement", body: ",host=db1 value=1", wantErr: true},
+		{name: "tag without value", body: "backup,host value=1", wantErr: true},
+		{name: "empty tag key", body: "backup,=db1 value=1", wantErr: true},
+		{name: "field without value", body: "backup value=", wantErr: true},
+		{name: "invalid float", body: "backup value=abc", wantErr: true},
+		{name: "invalid integer", body: "backup value=1.5i", wantErr: true},
+		{name: "negative unsigned", body: "backup value=-1u", wantErr: true},
+		{name: "error on a later line", body: "backup value=1\nbackup value=x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLineProtocol(tt.body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLineProtocol() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseLineProtocol() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitUnescaped(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		sep  byte
+		want []string
+	}{
+		{name: "no separator", s: "backup", sep: ',', want: []string{"backup"}},
+		{name: "empty", s: "", sep: ',', want: []string{""}},
+		{name: "split", s: "a,b,c", sep: ',', want: []string{"a", "b", "c"}},
+		{name: "empty parts", s: ",a,", sep: ',', want: []string{"", "a", ""}},
+		{name: "escaped separator", s: `a\,b,c`, sep: ',', want: []string{`a\,b`, "c"}},
+		{name: "escaped backslash", s: `a\\,b`, sep: ',', want: []string{`a\\`, "b"}},
+		{name: "quoted separator", s: `a="b c" d`, sep: ' ', want: []string{`a="b c"`, "d"}},
+		{name: "escaped quote", s: `a="b \" c" d`, sep: ' ', want: []string{`a="b \" c"`, "d"}},
+		{name: "trailing backslash", s: `a,b\`, sep: ',', want: []string{"a", `b\`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitUnescaped(tt.s, tt.sep); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitUnescaped(%q) = %q, want %q", tt.s, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/hay-kot/cronprom/internal/services/collector"
+)
+
+// Ingest validates and applies an update received by a listener outside of the
+// HTTP and gRPC APIs, like the UDP and Graphite listeners. The update passes the
+// same validation as a push. The listeners aren't authenticated, so unknown metrics
+// are never created, and the caller checks the peer against the allowed networks.
+func (h *MetricHandler) Ingest(ctx context.Context, peer netip.Addr, name string, metricType config.MetricType, value float64, labels map[string]string) error {
+	if !h.collector.HasMetric(name, metricType) {
+		return fmt.Errorf("%w: %s %s", collector.ErrMetricNotFound, metricType, name)
+	}
+
+	// The peer is shown as the source of the update in the live stream
+	ctx = context.WithValue(ctx, clientIPCtxKey{}, peer)
+
+	update := MetricUpdate{
+		Name:   name,
+		Type:   metricType.String(),
+		Value:  value,
+		Labels: labels,
+	}
+
+	if _, perr := h.prepareUpdate(ctx, &update); perr != nil {
+		return perr
+	}
+
+	return h.applyUpdate(ctx, metricType, update)
+}
@@ -0,0 +1,103 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/hay-kot/cronprom/internal/services/collector"
+)
+
+func TestIngest(t *testing.T) {
+	peer := netip.MustParseAddr("10.1.2.3")
+
+	tests := []struct {
+		name       string
+		metric     string
+		metricType config.MetricType
+		value      float64
+		labels     map[string]string
+		wantErr    error
+		wantCode   string
+	}{
+		{
+			name:       "valid update",
+			metric:     "backup_size_bytes",
+			metricType: config.MetricTypeGauge,
+			value:      42,
+			labels:     map[string]string{"host": "db1"},
+		},
+		{
+			name:       "unknown metrics aren't created",
+			metric:     "backup_files",
+			metricType: config.MetricTypeGauge,
+			value:      1,
+			wantErr:    collector.ErrMetricNotFound,
+		},
+		{
+			name:       "other type than the metric",
+			metric:     "backup_size_bytes",
+			metricType: config.MetricTypeCounter,
+			value:      1,
+			labels:     map[string]string{"host": "db1"},
+			wantErr:    collector.ErrMetricNotFound,
+		},
+		{
+			name:       "extra label of a strict metric",
+			metric:     "backup_size_bytes",
+			metricType: config.MetricTypeGauge,
+			value:      42,
+			labels:     map[string]string{"host": "db1", "disk": "sda"},
+			wantCode:   CodeLabelMismatch,
+		},
+		{
+			name:       "negative counter increment",
+			metric:     "backup_runs_total",
+			metricType: config.MetricTypeCounter,
+			value:      -1,
+			labels:     map[string]string{"host": "db1"},
+			wantCode:   CodeValidationFailed,
+		},
+		{
+			name:       "series beyond max_series",
+			metric:     "backup_runs_total",
+			metricType: config.MetricTypeCounter,
+			value:      1,
+			labels:     map[string]string{"host": "db3"},
+			wantCode:   CodeSeriesLimit,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, coll := newTestHandler(t, nil)
+			for _, host := range []string{"db1", "db2"} {
+				if err := coll.IncrementCounterBy("backup_runs_total", 1, map[string]string{"host": host}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := h.Ingest(context.Background(), peer, tt.metric, tt.metricType, tt.value, tt.labels)
+
+			var perr *pushError
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Ingest() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantCode != "":
+				if !errors.As(err, &perr) || perr.code != tt.wantCode {
+					t.Fatalf("Ingest() error = %v, want code %s", err, tt.wantCode)
+				}
+			case err != nil:
+				t.Fatalf("Ingest() error = %v", err)
+			}
+
+			if _, ok := coll.Resolve("backup_files"); ok {
+				t.Error("Ingest() created a dynamic metric")
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	httpRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cronprom_http_requests_total",
+			Help: "Number of HTTP requests by route and status code",
+		},
+		[]string{"handler", "code"},
+	)
+
+	httpDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cronprom_http_request_duration_seconds",
+			Help:    "Latency of HTTP requests by route",
+			Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+		},
+		[]string{"handler"},
+	)
+
+	pushUpdates = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cronprom_push_updates_total",
+			Help: "Number of pushed metric updates by result, applied, rejected, dropped, or failed when a queued update couldn't be applied",
+		},
+		[]string{"result"},
+	)
+
+	queueDepth = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cronprom_queue_depth",
+			Help: "Number of updates waiting in the ingestion queue",
+		},
+	)
+
+	queueCoalesced = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "cronprom_queue_coalesced_total",
+			Help: "Number of queued updates merged into a pending update of the same series",
+		},
+	)
+
+	queueOverflow = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cronprom_queue_overflow_total",
+			Help: "Number of updates that didn't fit into the ingestion queue by action, rejected or dropped",
+		},
+		[]string{"action"},
+	)
+)
+
+// RegisterMetrics registers the metrics describing the HTTP and gRPC APIs
+func RegisterMetrics(reg prometheus.Registerer) {
+	reg.MustRegister(httpRequests, httpDuration, pushUpdates, queueDepth, queueCoalesced, queueOverflow)
+}
+
+// instrumentRoute records the request count and latency of a route, labeled with
+// its pattern
+func instrumentRoute(pattern string, h http.Handler) http.Handler {
+	labels := prometheus.Labels{"handler": pattern}
+
+	return promhttp.InstrumentHandlerDuration(
+		httpDuration.MustCurryWith(labels),
+		promhttp.InstrumentHandlerCounter(httpRequests.MustCurryWith(labels), h),
+	)
+}
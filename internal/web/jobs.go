@@ -0,0 +1,183 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hay-kot/cronprom/internal/services/collector"
+)
+
+// JobStartRequest is the optional body of a job run start
+type JobStartRequest struct {
+	RunID string `json:"run_id,omitempty"` // Generated when empty, pass it to finish
+}
+
+// JobFinishRequest is the optional body of a job run finish
+type JobFinishRequest struct {
+	RunID    string `json:"run_id,omitempty"`    // Finishes the oldest running run when empty
+	Status   string `json:"status,omitempty"`    // success, failure, timeout, or skipped, defaults to failure for a non-zero exit code
+	ExitCode *int   `json:"exit_code,omitempty"` // Exit code of the job
+	Output   string `json:"output,omitempty"`    // Tail of the output of the job, kept in the history of the job
+}
+
+// JobLockRequest is the body of a job lock request
+type JobLockRequest struct {
+	Holder string  `json:"holder"`                // Identifies the run holding the lock, e.g., its run ID
+	TTL    float64 `json:"ttl_seconds,omitempty"` // Lease duration, the holder renews the lock before it expires, defaults to 60
+}
+
+// defaultLockTTL is the lease duration of locks that don't set one
+const defaultLockTTL = time.Minute
+
+// JobStartHandler records the start of a run of a job. Scripts bracket their
+// execution with a start and a finish, the server derives the duration and the
+// in-progress and result metrics of the job.
+func (h *MetricHandler) JobStartHandler(w http.ResponseWriter, r *http.Request) {
+	job := r.PathValue("name")
+
+	var req JobStartRequest
+	if perr := h.readJobRequest(r, job, &req); perr != nil {
+		perr.write(w)
+		return
+	}
+
+	run, err := h.collector.StartRun(job, req.RunID)
+	if err != nil {
+		if errors.Is(err, collector.ErrRunExists) {
+			writeError(w, http.StatusConflict, CodeRunExists, err.Error())
+			return
+		}
+		writeError(w, http.StatusUnprocessableEntity, CodeValidationFailed, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(run)
+}
+
+// JobFinishHandler records the end of a run of a job
+func (h *MetricHandler) JobFinishHandler(w http.ResponseWriter, r *http.Request) {
+	job := r.PathValue("name")
+
+	var req JobFinishRequest
+	if perr := h.readJobRequest(r, job, &req); perr != nil {
+		perr.write(w)
+		return
+	}
+
+	run, err := h.collector.FinishRun(job, req.RunID, req.Status, req.ExitCode, req.Output)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, CodeValidationFailed, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(run)
+}
+
+// JobRunsHandler returns the runs in progress and the latest finished runs of a
+// job along with their output, newest first
+func (h *MetricHandler) JobRunsHandler(w http.ResponseWriter, r *http.Request) {
+	runs, err := h.collector.JobRuns(r.PathValue("name"))
+	if err != nil {
+		if errors.Is(err, collector.ErrJobNotFound) {
+			writeError(w, http.StatusNotFound, CodeJobNotFound, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(runs)
+}
+
+// JobLockHandler acquires or renews the lock of a job, keeping overlapping runs of
+// the job from running at the same time
+func (h *MetricHandler) JobLockHandler(w http.ResponseWriter, r *http.Request) {
+	job := r.PathValue("name")
+
+	var req JobLockRequest
+	if perr := h.readJobRequest(r, job, &req); perr != nil {
+		perr.write(w)
+		return
+	}
+
+	ttl := defaultLockTTL
+	if req.TTL != 0 {
+		ttl = time.Duration(req.TTL * float64(time.Second))
+	}
+
+	lock, err := h.collector.AcquireLock(job, req.Holder, ttl)
+	if err != nil {
+		if errors.Is(err, collector.ErrLockHeld) {
+			writeError(w, http.StatusConflict, CodeLockHeld, err.Error())
+			return
+		}
+		writeError(w, http.StatusUnprocessableEntity, CodeValidationFailed, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(lock)
+}
+
+// JobUnlockHandler releases the lock of a job held by the holder of the request
+func (h *MetricHandler) JobUnlockHandler(w http.ResponseWriter, r *http.Request) {
+	job := r.PathValue("name")
+
+	var req JobLockRequest
+	if perr := h.readJobRequest(r, job, &req); perr != nil {
+		perr.write(w)
+		return
+	}
+
+	if err := h.collector.ReleaseLock(job, req.Holder); err != nil {
+		if errors.Is(err, collector.ErrLockHeld) {
+			writeError(w, http.StatusConflict, CodeLockHeld, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readJobRequest checks that the job exists, or may be created by its first run,
+// and that the API key of the request may push to all of its metrics, and decodes
+// the body, which may be empty. Jobs without metrics need an unrestricted key.
+func (h *MetricHandler) readJobRequest(r *http.Request, job string, v any) *pushError {
+	metrics, err := h.collector.JobMetrics(job)
+	if err != nil {
+		return &pushError{http.StatusNotFound, CodeJobNotFound, err.Error()}
+	}
+
+	if key, ok := APIKeyFromContext(r.Context()); ok && len(key.Metrics) > 0 {
+		allowed := len(metrics) > 0
+		for _, name := range metrics {
+			allowed = allowed && key.Allows(name)
+		}
+		if !allowed {
+			return &pushError{http.StatusForbidden, CodeForbidden, fmt.Sprintf("API key '%s' is not allowed to push to job '%s'", key.Name, job)}
+		}
+	}
+
+	body, perr := readBody(r)
+	if perr != nil {
+		return perr
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return &pushError{http.StatusBadRequest, CodeInvalidBody, "Error parsing JSON request body"}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+)
+
+// Listen opens the listener of the web server. Addresses with the unix:// scheme
+// listen on a unix domain socket, a stale socket left behind by a previous run is
+// removed and the configured permissions and ownership are applied.
+func Listen(cfg config.Web) (net.Listener, error) {
+	path, ok := cfg.UnixSocket()
+	if !ok {
+		return net.Listen("tcp", cfg.Address)
+	}
+
+	if fi, err := os.Stat(path); err == nil {
+		if fi.Mode().Type() != fs.ModeSocket {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("error removing stale socket: %w", err)
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := chownSocket(path, cfg); err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+
+	return ln, nil
+}
+
+// chownSocket applies the configured permissions and ownership to the socket
+func chownSocket(path string, cfg config.Web) error {
+	if mode := cfg.SocketFileMode(); mode != 0 {
+		if err := os.Chmod(path, mode); err != nil {
+			return fmt.Errorf("error setting socket mode: %w", err)
+		}
+	}
+
+	if cfg.SocketUser == "" && cfg.SocketGroup == "" {
+		return nil
+	}
+
+	uid, gid := -1, -1
+
+	if cfg.SocketUser != "" {
+		u, err := user.Lookup(cfg.SocketUser)
+		if err != nil {
+			var unknown user.UnknownUserError
+			if !errors.As(err, &unknown) {
+				return err
+			}
+			if u, err = user.LookupId(cfg.SocketUser); err != nil {
+				return fmt.Errorf("unknown socket user '%s'", cfg.SocketUser)
+			}
+		}
+		uid, _ = strconv.Atoi(u.Uid)
+	}
+
+	if cfg.SocketGroup != "" {
+		g, err := user.LookupGroup(cfg.SocketGroup)
+		if err != nil {
+			var unknown user.UnknownGroupError
+			if !errors.As(err, &unknown) {
+				return err
+			}
+			if g, err = user.LookupGroupId(cfg.SocketGroup); err != nil {
+				return fmt.Errorf("unknown socket group '%s'", cfg.SocketGroup)
+			}
+		}
+		gid, _ = strconv.Atoi(g.Gid)
+	}
+
+	if err := os.Chown(path, uid, gid); err != nil {
+		return fmt.Errorf("error setting socket ownership: %w", err)
+	}
+
+	return nil
+}
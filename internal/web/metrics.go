@@ -0,0 +1,149 @@
+package web
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/expfmt"
+	"github.com/rs/zerolog/log"
+)
+
+// MetricsHandler serves the registry in the format negotiated through the Accept
+// header, OpenMetrics or the classic text format, compressed with the configured
+// encodings the client accepts. Without configured encodings gzip and zstd are offered.
+// OpenMetrics includes the units of the metrics and is only compressed with gzip.
+//
+// The limits of concurrent scrapes and their duration apply to both formats, so
+// they are enforced here rather than by promhttp.
+func MetricsHandler(cfg config.Web, gatherer prometheus.Gatherer) http.Handler {
+	opts := promhttp.HandlerOpts{
+		ErrorLog:          promLogger{},
+		ErrorHandling:     errorHandling(cfg.MetricsErrorHandling),
+		EnableOpenMetrics: cfg.OpenMetrics(),
+	}
+
+	for _, c := range cfg.MetricsCompression {
+		opts.OfferedCompressions = append(opts.OfferedCompressions, promhttp.Compression(c))
+	}
+
+	handler := promhttp.HandlerFor(gatherer, opts)
+
+	// Only gzip is offered in OpenMetrics unless it's excluded from the configured encodings
+	offerGzip := len(cfg.MetricsCompression) == 0 || slices.Contains(cfg.MetricsCompression, "gzip")
+
+	var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		format := expfmt.NegotiateIncludingOpenMetrics(r.Header)
+		if !opts.EnableOpenMetrics || format.FormatType() != expfmt.TypeOpenMetrics {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		serveOpenMetrics(w, r, gatherer, format, offerGzip, opts.ErrorHandling)
+	})
+
+	if cfg.MetricsTimeout > 0 {
+		h = http.TimeoutHandler(h, cfg.MetricsTimeout, fmt.Sprintf("Exceeded configured timeout of %v.\n", cfg.MetricsTimeout))
+	}
+
+	if n := cfg.MetricsMaxRequestsInFlight; n > 0 {
+		h = limitInFlight(h, n)
+	}
+
+	return h
+}
+
+// errorHandling converts the configured error handling of /metrics
+func errorHandling(h config.MetricsErrorHandling) promhttp.HandlerErrorHandling {
+	switch h {
+	case config.MetricsErrorHandlingContinue:
+		return promhttp.ContinueOnError
+	case config.MetricsErrorHandlingPanic:
+		return promhttp.PanicOnError
+	default:
+		return promhttp.HTTPErrorOnError
+	}
+}
+
+// limitInFlight rejects requests with 503 while n requests are being served
+func limitInFlight(next http.Handler, n int) http.Handler {
+	inFlight := make(chan struct{}, n)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case inFlight <- struct{}{}:
+			defer func() { <-inFlight }()
+		default:
+			http.Error(w, fmt.Sprintf("Limit of concurrent requests reached (%d), try again later.", n), http.StatusServiceUnavailable)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// serveOpenMetrics writes the OpenMetrics format including the UNIT metadata, which
+// promhttp doesn't write. Errors gathering the metrics are handled like promhttp does.
+func serveOpenMetrics(w http.ResponseWriter, r *http.Request, gatherer prometheus.Gatherer, format expfmt.Format, offerGzip bool, onError promhttp.HandlerErrorHandling) {
+	families, err := gatherer.Gather()
+	if err != nil {
+		promLogger{}.Println("error gathering metrics:", err)
+
+		switch onError {
+		case promhttp.PanicOnError:
+			panic(err)
+		case promhttp.HTTPErrorOnError:
+			http.Error(w, "An error has occurred while serving metrics:\n\n"+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// ContinueOnError serves the metrics that were gathered
+	}
+
+	w.Header().Set("Content-Type", string(format))
+
+	var out io.Writer = w
+	if offerGzip && acceptsGzip(r) {
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		out = gz
+	}
+
+	enc := expfmt.NewEncoder(out, format, expfmt.WithUnit())
+	for _, family := range families {
+		if err := enc.Encode(family); err != nil {
+			promLogger{}.Println("error encoding and sending metric family:", err)
+			return
+		}
+	}
+
+	if closer, ok := enc.(expfmt.Closer); ok {
+		if err := closer.Close(); err != nil {
+			promLogger{}.Println("error encoding and sending metric family:", err)
+		}
+	}
+}
+
+// acceptsGzip reports whether the client accepts gzip encoded responses
+func acceptsGzip(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(enc), ";")
+		if strings.EqualFold(strings.TrimSpace(name), "gzip") && strings.ReplaceAll(params, " ", "") != "q=0" {
+			return true
+		}
+	}
+	return false
+}
+
+// promLogger writes errors of the promhttp handler to the application log
+type promLogger struct{}
+
+func (promLogger) Println(v ...any) {
+	log.Error().Msg(strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
+}
@@ -0,0 +1,145 @@
+package web
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// responseRecorder captures the status code and body size written by a handler
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if rr.status == 0 {
+		rr.status = status
+	}
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+	n, err := rr.ResponseWriter.Write(b)
+	rr.size += n
+	return n, err
+}
+
+// Unwrap allows http.ResponseController to reach the underlying writer
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
+
+func (rr *responseRecorder) Status() int {
+	if rr.status == 0 {
+		return http.StatusOK
+	}
+	return rr.status
+}
+
+// AccessLog logs every request at the given level with the client, status, latency,
+// body sizes, and for pushes the API key and the affected metrics
+func AccessLog(level zerolog.Level) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &responseRecorder{ResponseWriter: w}
+
+			info := &accessInfo{}
+			body := &countingReader{ReadCloser: r.Body}
+			r.Body = body
+
+			next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), accessInfoCtxKey{}, info)))
+
+			event := log.WithLevel(level).
+				Str("request_id", RequestIDFromContext(r.Context())).
+				Str("method", r.Method).
+				Str("path", r.URL.Path).
+				Str("remote", ClientIP(r).String()).
+				Int("status", rec.Status()).
+				Dur("latency", time.Since(start)).
+				Int64("bytes_in", body.n).
+				Int("bytes_out", rec.size)
+
+			info.mu.Lock()
+			if info.key != "" {
+				event = event.Str("key", info.key)
+			}
+			if len(info.metrics) > 0 {
+				event = event.Strs("metrics", info.metrics)
+			}
+			info.mu.Unlock()
+
+			event.Msg("request")
+		})
+	}
+}
+
+// Recoverer recovers from panics in handlers, logging the stack trace and responding
+// with a 500.
+func Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rvr := recover(); rvr != nil {
+				if rvr == http.ErrAbortHandler {
+					panic(rvr)
+				}
+
+				log.Error().
+					Interface("panic", rvr).
+					Bytes("stack", debug.Stack()).
+					Str("request_id", RequestIDFromContext(r.Context())).
+					Str("method", r.Method).
+					Str("path", r.URL.Path).
+					Msg("recovered from panic")
+
+				writeError(w, http.StatusInternalServerError, CodeInternal, http.StatusText(http.StatusInternalServerError))
+			}
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// MaxBodySize limits the size of request bodies to n bytes. Handlers reading past
+// the limit receive an *http.MaxBytesError. A limit of 0 disables the check.
+func MaxBodySize(n int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		if n <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// BasicAuth rejects requests that don't provide the username and password
+func BasicAuth(username, password string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, pass, ok := r.BasicAuth()
+
+			userMatch := subtle.ConstantTimeCompare([]byte(user), []byte(username))
+			passMatch := subtle.ConstantTimeCompare([]byte(pass), []byte(password))
+			if !ok || userMatch&passMatch != 1 {
+				w.Header().Set("WWW-Authenticate", `Basic realm="cronprom", charset="UTF-8"`)
+				writeError(w, http.StatusUnauthorized, CodeUnauthorized, http.StatusText(http.StatusUnauthorized))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package web
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type clientIPCtxKey struct{}
+
+// ClientIP returns the IP address of the client that made the request, as resolved
+// by the RealIP middleware. It falls back to the address of the connection peer.
+func ClientIP(r *http.Request) netip.Addr {
+	if addr, ok := r.Context().Value(clientIPCtxKey{}).(netip.Addr); ok {
+		return addr
+	}
+	return remoteAddr(r)
+}
+
+// remoteAddr parses the address of the connection peer
+func remoteAddr(r *http.Request) netip.Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	addr, _ := netip.ParseAddr(host)
+	return addr.Unmap()
+}
+
+// containsAddr reports whether any of the prefixes contains the address
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// RealIP resolves the client IP of each request. The X-Forwarded-For header is only
+// consulted when the connection comes from a trusted proxy, in which case the
+// right-most address that isn't a trusted proxy is used.
+func RealIP(trusted []netip.Prefix) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client := remoteAddr(r)
+
+			if containsAddr(trusted, client) {
+				hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+				for i := len(hops) - 1; i >= 0; i-- {
+					addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+					if err != nil {
+						break
+					}
+
+					client = addr.Unmap()
+					if !containsAddr(trusted, client) {
+						break
+					}
+				}
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPCtxKey{}, client)))
+		})
+	}
+}
+
+// AllowCIDRs rejects requests from clients outside of the allowed networks. When no
+// networks are provided all requests are allowed.
+func AllowCIDRs(allowed []netip.Prefix) Middleware {
+	return func(next http.Handler) http.Handler {
+		if len(allowed) == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !containsAddr(allowed, ClientIP(r)) {
+				writeError(w, http.StatusForbidden, CodeForbidden, http.StatusText(http.StatusForbidden))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,508 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/hay-kot/cronprom/internal/data/schema"
+	"github.com/hay-kot/cronprom/internal/services/collector"
+)
+
+// openAPIVersion is the version of the API described by the document
+const openAPIVersion = "1.0.0"
+
+// openAPISpec is built once from the handler types on first use
+var openAPISpec = sync.OnceValue(func() []byte {
+	data, err := json.Marshal(buildOpenAPI())
+	if err != nil {
+		panic(err)
+	}
+	return data
+})
+
+// OpenAPIHandler serves the OpenAPI document describing the API
+func OpenAPIHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(openAPISpec())
+}
+
+// SwaggerUIHandler serves a Swagger UI page for the OpenAPI document
+func SwaggerUIHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(swaggerUI))
+}
+
+const swaggerUI = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8" />
+  <title>cronprom API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css" />
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({ url: "/api/v1/openapi.json", dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>
+`
+
+type (
+	object    = map[string]any
+	operation = map[string]any
+)
+
+// buildOpenAPI describes the API, request and response schemas are generated from
+// the handler types.
+func buildOpenAPI() object {
+	gen := schema.New("json", "#/components/schemas/")
+	gen.Enums[reflect.TypeFor[config.MetricType]()] = []any{
+		config.MetricTypeGauge, config.MetricTypeCounter, config.MetricTypeHistogram, config.MetricTypeSummary, config.MetricTypeInfo, config.MetricTypeEnum,
+	}
+	gen.Required[reflect.TypeFor[MetricUpdate]()] = []string{"name", "type", "value"}
+	gen.Types[reflect.TypeFor[collector.Float]()] = &schema.Schema{
+		OneOf:       []*schema.Schema{{Type: "number"}, {Type: "string", Enum: []any{"NaN", "+Inf", "-Inf"}}},
+		Description: "Number, non-finite values are the strings NaN, +Inf, and -Inf",
+	}
+
+	// Metric definitions mirror the config file and use its field names
+	cfgGen := schema.New("yaml", "#/components/schemas/")
+	cfgGen.Enums = gen.Enums
+	cfgGen.Defs = gen.Defs
+	cfgGen.Required[reflect.TypeFor[config.MetricConfig]()] = []string{"name", "type"}
+
+	jsonBody := func(s *schema.Schema) object {
+		return object{"content": object{"application/json": object{"schema": s}}}
+	}
+
+	response := func(description string, s *schema.Schema) object {
+		r := object{"description": description}
+		if s != nil {
+			r["content"] = object{"application/json": object{"schema": s}}
+		}
+		return r
+	}
+
+	errorSchema := gen.For(ErrorResponse{})
+	errorResponse := func(description string) object {
+		return response(description, errorSchema)
+	}
+
+	nameParam := object{
+		"name":     "name",
+		"in":       "path",
+		"required": true,
+		"schema":   &schema.Schema{Type: "string"},
+	}
+
+	asyncParam := object{
+		"name":        "async",
+		"in":          "query",
+		"description": "Queue the updates and answer with 202 Accepted before they are applied, requires the ingestion queue. Defaults to web.queue.async when the queue is enabled.",
+		"schema":      &schema.Schema{Type: "boolean"},
+	}
+
+	pushAuth := []object{{"bearerAuth": []string{}}}
+	readAuth := []object{{"basicAuth": []string{}}}
+
+	update := gen.For(MetricUpdate{})
+	text := object{"text/plain": object{"schema": &schema.Schema{Type: "string", Description: "Prometheus text exposition format"}}}
+
+	pushBody := jsonBody(update)
+	pushBody["content"].(object)["text/plain"] = text["text/plain"]
+
+	pushgateway := func(summary string, withBody bool, status, description string) operation {
+		op := operation{
+			"summary":  summary,
+			"tags":     []string{"pushgateway"},
+			"security": pushAuth,
+			"parameters": []object{{
+				"name":        "grouping",
+				"in":          "path",
+				"required":    true,
+				"description": "Job name followed by optional label name and value pairs",
+				"schema":      &schema.Schema{Type: "string"},
+			}},
+			"responses": object{
+				status: response(description, nil),
+				"400":  errorResponse("Invalid grouping key or body"),
+				"401":  errorResponse("Missing or invalid token"),
+				"404":  errorResponse("Unknown metric"),
+				"422":  errorResponse("Samples failed validation"),
+			},
+		}
+		if withBody {
+			op["requestBody"] = object{"required": true, "content": text}
+		}
+		return op
+	}
+
+	paths := object{
+		"/api/v1/push": object{
+			"post": operation{
+				"summary":     "Push a metric update",
+				"tags":        []string{"push"},
+				"security":    pushAuth,
+				"parameters":  []object{asyncParam},
+				"requestBody": pushBody,
+				"responses": object{
+					"200": response("Update applied", nil),
+					"202": response("Update queued", nil),
+					"400": errorResponse("Malformed request body"),
+					"401": errorResponse("Missing or invalid token"),
+					"403": errorResponse("Token not allowed to push to the metric"),
+					"404": errorResponse("Unknown metric"),
+					"413": errorResponse("Request body too large"),
+					"422": errorResponse("Update failed validation"),
+					"503": errorResponse("Ingestion queue is full"),
+				},
+			},
+			"delete": operation{
+				"summary":     "Delete the series with the given labels, or all series of the metric with reset",
+				"tags":        []string{"push"},
+				"security":    pushAuth,
+				"requestBody": jsonBody(gen.For(DeleteRequest{})),
+				"responses": object{
+					"200": response("Series deleted", gen.For(DeleteResponse{})),
+					"400": errorResponse("Malformed request body"),
+					"401": errorResponse("Missing or invalid token"),
+					"403": errorResponse("Token not allowed to push to the metric"),
+					"404": errorResponse("Unknown metric"),
+					"413": errorResponse("Request body too large"),
+					"422": errorResponse("Metric name is missing or the labels don't match a strict metric"),
+				},
+			},
+		},
+		"/api/v1/push/batch": object{
+			"post": operation{
+				"summary":     "Push many metric updates, applied only if all are valid",
+				"tags":        []string{"push"},
+				"security":    pushAuth,
+				"parameters":  []object{asyncParam},
+				"requestBody": jsonBody(gen.For([]MetricUpdate{})),
+				"responses": object{
+					"200": response("All updates applied", gen.For(BatchResponse{})),
+					"202": response("All updates queued", gen.For(BatchResponse{})),
+					"400": errorResponse("Malformed request body"),
+					"401": errorResponse("Missing or invalid token"),
+					"413": errorResponse("Request body too large"),
+					"422": response("At least one update is invalid, none were applied", gen.For(BatchResponse{})),
+					"500": response("Valid updates failed to apply, the others were applied and the status is partial", gen.For(BatchResponse{})),
+					"503": response("Ingestion queue is full, none were queued", gen.For(BatchResponse{})),
+				},
+			},
+		},
+		"/api/v1/write": object{
+			"post": operation{
+				"summary":  "Push samples in the InfluxDB line protocol",
+				"tags":     []string{"push"},
+				"security": pushAuth,
+				"requestBody": object{
+					"required": true,
+					"content":  object{"text/plain": object{"schema": &schema.Schema{Type: "string", Description: "InfluxDB line protocol"}}},
+				},
+				"responses": object{
+					"204": response("Samples applied", nil),
+					"400": errorResponse("Malformed line protocol"),
+					"401": errorResponse("Missing or invalid token"),
+					"404": errorResponse("Unknown metric"),
+					"413": errorResponse("Request body too large"),
+					"422": errorResponse("Samples failed validation"),
+				},
+			},
+		},
+		"/v1/metrics": object{
+			"post": operation{
+				"summary":  "Push metrics in the OTLP/HTTP format",
+				"tags":     []string{"push"},
+				"security": pushAuth,
+				"requestBody": object{
+					"required": true,
+					"content": object{
+						"application/x-protobuf": object{"schema": &schema.Schema{Type: "string", Format: "binary", Description: "ExportMetricsServiceRequest"}},
+						"application/json":       object{"schema": &schema.Schema{Type: "object", Description: "ExportMetricsServiceRequest"}},
+					},
+				},
+				"responses": object{
+					"200": object{"description": "ExportMetricsServiceResponse, rejected data points are reported as partial success"},
+					"400": errorResponse("Malformed request body"),
+					"401": errorResponse("Missing or invalid token"),
+					"413": errorResponse("Request body too large"),
+					"415": errorResponse("Unsupported content type"),
+				},
+			},
+		},
+		"/api/v1/receive": object{
+			"post": operation{
+				"summary":  "Push samples with the Prometheus remote write protocol",
+				"tags":     []string{"push"},
+				"security": pushAuth,
+				"requestBody": object{
+					"required": true,
+					"content":  object{"application/x-protobuf": object{"schema": &schema.Schema{Type: "string", Format: "binary", Description: "Snappy compressed WriteRequest"}}},
+				},
+				"responses": object{
+					"204": response("Samples applied, series of unknown metrics are skipped", nil),
+					"400": errorResponse("Malformed request body"),
+					"401": errorResponse("Missing or invalid token"),
+					"413": errorResponse("Request body too large"),
+				},
+			},
+		},
+		"/api/v1/jobs/{name}/start": object{
+			"post": operation{
+				"summary":     "Record the start of a run of a job",
+				"description": "The body is optional. The server derives the duration of the run from its start and finish.",
+				"tags":        []string{"jobs"},
+				"security":    pushAuth,
+				"parameters":  []object{nameParam},
+				"requestBody": jsonBody(gen.For(JobStartRequest{})),
+				"responses": object{
+					"201": response("Run started", gen.For(collector.JobRun{})),
+					"400": errorResponse("Malformed request body"),
+					"401": errorResponse("Missing or invalid token"),
+					"403": errorResponse("Token not allowed to push to the metrics of the job"),
+					"404": errorResponse("Unknown job, unless dynamic metrics are allowed"),
+					"409": errorResponse("A run with the ID is already running"),
+					"422": errorResponse("Standard metrics of a new job conflict with existing metrics"),
+				},
+			},
+		},
+		"/api/v1/jobs/{name}/finish": object{
+			"post": operation{
+				"summary":     "Record the end of a run of a job",
+				"description": "The body is optional. Without a run ID the oldest running run finishes, a finish without a start is counted without a duration. The run is recorded in the standard metrics of the job.",
+				"tags":        []string{"jobs"},
+				"security":    pushAuth,
+				"parameters":  []object{nameParam},
+				"requestBody": jsonBody(gen.For(JobFinishRequest{})),
+				"responses": object{
+					"200": response("Run finished", gen.For(collector.JobRun{})),
+					"400": errorResponse("Malformed request body"),
+					"401": errorResponse("Missing or invalid token"),
+					"403": errorResponse("Token not allowed to push to the metrics of the job"),
+					"404": errorResponse("Unknown job, unless dynamic metrics are allowed"),
+					"422": errorResponse("Invalid status"),
+				},
+			},
+		},
+		"/api/v1/jobs/{name}/lock": object{
+			"post": operation{
+				"summary":     "Acquire or renew the lock of a job",
+				"description": "Keeps overlapping runs of the job from running at the same time. The lock expires after its TTL unless the holder renews it.",
+				"tags":        []string{"jobs"},
+				"security":    pushAuth,
+				"parameters":  []object{nameParam},
+				"requestBody": jsonBody(gen.For(JobLockRequest{})),
+				"responses": object{
+					"200": response("Lock acquired", gen.For(collector.JobLock{})),
+					"400": errorResponse("Malformed request body"),
+					"401": errorResponse("Missing or invalid token"),
+					"403": errorResponse("Token not allowed to push to the metrics of the job"),
+					"404": errorResponse("Unknown job, unless dynamic metrics are allowed"),
+					"409": errorResponse("Another holder holds the lock"),
+					"422": errorResponse("Missing holder or invalid TTL"),
+				},
+			},
+			"delete": operation{
+				"summary":     "Release the lock of a job",
+				"tags":        []string{"jobs"},
+				"security":    pushAuth,
+				"parameters":  []object{nameParam},
+				"requestBody": jsonBody(gen.For(JobLockRequest{})),
+				"responses": object{
+					"204": response("Lock released or not held", nil),
+					"400": errorResponse("Malformed request body"),
+					"401": errorResponse("Missing or invalid token"),
+					"403": errorResponse("Token not allowed to push to the metrics of the job"),
+					"404": errorResponse("Unknown job"),
+					"409": errorResponse("Another holder holds the lock"),
+				},
+			},
+		},
+		"/api/v1/metrics": object{
+			"get": operation{
+				"summary":  "List configured metrics and their runtime state",
+				"tags":     []string{"query"},
+				"security": readAuth,
+				"responses": object{
+					"200": response("Configured metrics", gen.For([]collector.MetricInfo{})),
+				},
+			},
+		},
+		"/api/v1/metrics/{name}": object{
+			"get": operation{
+				"summary":    "Get the current samples of a metric",
+				"tags":       []string{"query"},
+				"security":   readAuth,
+				"parameters": []object{nameParam},
+				"responses": object{
+					"200": response("Current samples", gen.For(collector.MetricSamples{})),
+					"404": errorResponse("Unknown metric"),
+				},
+			},
+		},
+		"/api/v1/jobs/{name}/runs": object{
+			"get": operation{
+				"summary":     "Get the history of the runs of a job",
+				"description": "The runs in progress followed by the latest finished runs along with the output they reported, newest first.",
+				"tags":        []string{"jobs"},
+				"security":    readAuth,
+				"parameters":  []object{nameParam},
+				"responses": object{
+					"200": response("Runs of the job", gen.For([]collector.JobRun{})),
+					"404": errorResponse("Unknown job"),
+				},
+			},
+		},
+		"/api/v1/stream": object{
+			"get": operation{
+				"summary":     "Stream applied metric updates",
+				"description": "Server-sent events, every update is sent as a \"metric\" event with a JSON encoded MetricEvent as data.",
+				"tags":        []string{"query"},
+				"security":    readAuth,
+				"parameters": []object{{
+					"name":        "metric",
+					"in":          "query",
+					"description": "Only stream updates of this metric",
+					"schema":      &schema.Schema{Type: "string"},
+				}},
+				"responses": object{
+					"200": object{
+						"description": "Event stream",
+						"content": object{"text/event-stream": object{
+							"schema": gen.For(MetricEvent{}),
+						}},
+					},
+					"404": errorResponse("Unknown metric"),
+				},
+			},
+		},
+		"/api/v1/admin/metrics": object{
+			"post": operation{
+				"summary":     "Define a new metric at runtime",
+				"tags":        []string{"admin"},
+				"security":    pushAuth,
+				"requestBody": jsonBody(cfgGen.For(config.MetricConfig{})),
+				"responses": object{
+					"201": response("Metric created", gen.For(collector.MetricInfo{})),
+					"400": errorResponse("Malformed metric definition"),
+					"403": errorResponse("Token not allowed to use the admin API, or no admin key configured"),
+					"409": errorResponse("Metric already exists"),
+					"422": errorResponse("Invalid metric definition"),
+				},
+			},
+		},
+		"/api/v1/admin/metrics/{name}": object{
+			"delete": operation{
+				"summary":    "Remove a metric and all of its series",
+				"tags":       []string{"admin"},
+				"security":   pushAuth,
+				"parameters": []object{nameParam},
+				"responses": object{
+					"204": response("Metric removed", nil),
+					"403": errorResponse("Token not allowed to use the admin API, or no admin key configured"),
+					"404": errorResponse("Unknown metric"),
+				},
+			},
+		},
+		"/api/v1/admin/snapshot": object{
+			"post": operation{
+				"summary":  "Dump the values, label sets, and last push times of all series",
+				"tags":     []string{"admin"},
+				"security": pushAuth,
+				"responses": object{
+					"200": response("Snapshot", gen.For(collector.State{})),
+					"403": errorResponse("Token not allowed to use the admin API, or no admin key configured"),
+				},
+			},
+		},
+		"/api/v1/admin/restore": object{
+			"post": operation{
+				"summary":     "Load a snapshot, the series are set to their saved values and summaries start over",
+				"tags":        []string{"admin"},
+				"security":    pushAuth,
+				"requestBody": jsonBody(gen.For(collector.State{})),
+				"responses": object{
+					"200": response("Snapshot restored", gen.For(RestoreResponse{})),
+					"400": errorResponse("Malformed snapshot"),
+					"403": errorResponse("Token not allowed to use the admin API, or no admin key configured"),
+					"422": errorResponse("Unsupported snapshot version"),
+				},
+			},
+		},
+		"/metrics/job/{grouping}": object{
+			"put":    pushgateway("Replace all series of the grouping key", true, "200", "Samples applied"),
+			"post":   pushgateway("Replace the series of the grouping key for the pushed metrics", true, "200", "Samples applied"),
+			"delete": pushgateway("Delete all series of the grouping key", false, "202", "Series deleted"),
+		},
+		"/": object{
+			"get": operation{
+				"summary":  "HTML status page with metrics, current values, and recent push errors",
+				"tags":     []string{"exposition"},
+				"security": readAuth,
+				"responses": object{
+					"200": object{"description": "Status page", "content": object{"text/html": object{}}},
+				},
+			},
+		},
+		"/metrics": object{
+			"get": operation{
+				"summary":  "Prometheus exposition endpoint",
+				"tags":     []string{"exposition"},
+				"security": readAuth,
+				"responses": object{
+					"200": object{"description": "Metrics in the OpenMetrics or classic text format, negotiated with the Accept header"},
+				},
+			},
+		},
+		"/livez": object{
+			"get": operation{
+				"summary":   "Liveness probe",
+				"tags":      []string{"health"},
+				"responses": object{"200": response("Process is up", nil)},
+			},
+		},
+		"/readyz": object{
+			"get": operation{
+				"summary": "Readiness probe",
+				"tags":    []string{"health"},
+				"responses": object{
+					"200": response("Server accepts traffic", gen.For(ReadinessResponse{})),
+					"503": response("Server is starting, draining, or a dependency is unavailable", gen.For(ReadinessResponse{})),
+				},
+			},
+		},
+		"/health": object{
+			"get": operation{
+				"summary":   "Build, uptime, configuration, and storage status of the server",
+				"tags":      []string{"health"},
+				"responses": object{"200": response("Process is up", gen.For(HealthResponse{}))},
+			},
+		},
+	}
+
+	return object{
+		"openapi": "3.1.0",
+		"info": object{
+			"title":       "cronprom",
+			"description": "Push based metric collection for cron jobs",
+			"version":     openAPIVersion,
+		},
+		"paths": paths,
+		"components": object{
+			"schemas": gen.Defs,
+			"securitySchemes": object{
+				"bearerAuth": object{"type": "http", "scheme": "bearer"},
+				"basicAuth":  object{"type": "http", "scheme": "basic"},
+			},
+		},
+	}
+}
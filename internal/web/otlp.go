@@ -0,0 +1,457 @@
+package web
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// maxDecompressedBody is the largest gzip decompressed OTLP request accepted
+	maxDecompressedBody = 64 << 20
+
+	// maxHistogramObservations is the largest number of observations a single
+	// histogram data point may expand to
+	maxHistogramObservations = 10000
+)
+
+// otlpSample is an OTLP data point converted to values of a configured metric
+type otlpSample struct {
+	metric config.MetricConfig
+	labels map[string]string
+	value  float64   // Value of number data points, or the sum of histogram data points
+	values []float64 // Observations of histogram data points
+	kind   string    // gauge, sum, or histogram
+}
+
+// deltaTracker converts cumulative OTLP data points and remote write samples into
+// deltas by remembering the last values of every series. A changed start time marks
+// a restarted producer, e.g., the next run of a cron job, and resets the series.
+// The values are only kept in memory, the first values of a series after a restart
+// of cronprom are a baseline if the series was restored from the state file.
+//
+// Series the collector no longer has, after they expired, were evicted, or were
+// deleted, are pruned once they weren't seen for deltaPruneInterval. Samples of
+// new series are rejected while maxDeltaSeries series are tracked.
+type deltaTracker struct {
+	mu     sync.Mutex
+	series map[string]deltaState
+	exists func(name string, labels map[string]string) bool // Reports whether the collector has a series
+	pruned time.Time                                        // Time of the last pruning
+}
+
+type deltaState struct {
+	name   string
+	labels map[string]string
+	start  uint64
+	values []float64
+	seen   time.Time
+}
+
+const (
+	// maxDeltaSeries is the largest number of cumulative series tracked at once
+	maxDeltaSeries = 100_000
+
+	// deltaPruneInterval is how often and how long after their last sample series
+	// the collector no longer has are pruned
+	deltaPruneInterval = time.Minute
+)
+
+// errTooManyDeltaSeries rejects samples of new cumulative series beyond maxDeltaSeries
+var errTooManyDeltaSeries = fmt.Errorf("more than %d cumulative series are tracked", maxDeltaSeries)
+
+// delta returns the difference to the previous values of the series and stores the
+// new values. The values are returned unchanged for new or reset series. New
+// series that were counted before, e.g., series restored after a restart, return
+// zeros, their values only become the baseline of the next ones.
+func (t *deltaTracker) delta(name string, labels map[string]string, start uint64, values []float64, counted bool) ([]float64, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.series == nil {
+		t.series = make(map[string]deltaState)
+	}
+
+	now := time.Now()
+	if now.Sub(t.pruned) >= deltaPruneInterval {
+		t.prune(now)
+	}
+
+	key := deltaKey(name, labels)
+	prev, ok := t.series[key]
+	if !ok && len(t.series) >= maxDeltaSeries {
+		return nil, errTooManyDeltaSeries
+	}
+	t.series[key] = deltaState{name: name, labels: labels, start: start, values: values, seen: now}
+
+	if !ok && counted {
+		return make([]float64, len(values)), nil
+	}
+	if !ok || prev.start != start || len(prev.values) != len(values) {
+		return values, nil
+	}
+
+	deltas := make([]float64, len(values))
+	for i, v := range values {
+		if v < prev.values[i] || math.IsNaN(prev.values[i]) {
+			// Counter reset without a new start time
+			return values, nil
+		}
+		deltas[i] = v - prev.values[i]
+	}
+
+	return deltas, nil
+}
+
+// prune drops the series the collector no longer has. Recently seen series are
+// kept, their first sample may not have been applied yet. The caller holds the lock.
+func (t *deltaTracker) prune(now time.Time) {
+	t.pruned = now
+	if t.exists == nil {
+		return
+	}
+
+	for key, s := range t.series {
+		if now.Sub(s.seen) >= deltaPruneInterval && !t.exists(s.name, s.labels) {
+			delete(t.series, key)
+		}
+	}
+}
+
+// cumulativeDelta returns the increase of the values of a cumulative series since
+// its previous values. The first values of a series the collector already has are
+// only its baseline.
+func (h *MetricHandler) cumulativeDelta(name string, labels map[string]string, start uint64, values []float64) ([]float64, error) {
+	return h.deltas.delta(name, labels, start, values, h.collector.HasSeries(name, labels))
+}
+
+// OTLPHandler accepts metrics in the OTLP/HTTP format, encoded as protobuf or JSON.
+// Gauge, Sum, and Histogram data points update the configured metric with the same
+// name, dots in names and attributes are replaced by underscores. Data points that
+// can't be applied are reported as rejected in the partial success of the response.
+func (h *MetricHandler) OTLPHandler(w http.ResponseWriter, r *http.Request) {
+	body, perr := readBody(r)
+	if perr != nil {
+		perr.write(w)
+		return
+	}
+
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeInvalidBody, "Error decompressing request body")
+			return
+		}
+
+		body, err = io.ReadAll(io.LimitReader(zr, maxDecompressedBody))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeInvalidBody, "Error decompressing request body")
+			return
+		}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	var (
+		req       colmetricspb.ExportMetricsServiceRequest
+		unmarshal = proto.Unmarshal
+		marshal   = proto.Marshal
+	)
+
+	switch mediaType {
+	case "application/x-protobuf":
+	case "application/json":
+		unmarshal = protojson.Unmarshal
+		marshal = protojson.Marshal
+	default:
+		writeError(w, http.StatusUnsupportedMediaType, CodeBadRequest, fmt.Sprintf("unsupported content type '%s'", mediaType))
+		return
+	}
+
+	if err := unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidBody, "Error parsing OTLP request")
+		return
+	}
+
+	var (
+		rejected int64
+		errs     []string
+	)
+
+	reject := func(err string) {
+		rejected++
+		if len(errs) < 10 {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, sample := range h.otlpSamples(&req, reject) {
+		if err := h.applyOTLPSample(r, sample); err != nil {
+			reject(err.Error())
+		}
+	}
+
+	resp := &colmetricspb.ExportMetricsServiceResponse{}
+	if rejected > 0 {
+		resp.PartialSuccess = &colmetricspb.ExportMetricsPartialSuccess{
+			RejectedDataPoints: rejected,
+			ErrorMessage:       strings.Join(errs, "; "),
+		}
+	}
+
+	data, err := marshal(resp)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
+
+// otlpSamples converts the data points of the request into samples of configured
+// metrics, data points that can't be converted are passed to reject
+func (h *MetricHandler) otlpSamples(req *colmetricspb.ExportMetricsServiceRequest, reject func(string)) []otlpSample {
+	var samples []otlpSample
+
+	for _, rm := range req.GetResourceMetrics() {
+		resource := otlpLabels(rm.GetResource().GetAttributes())
+
+		// Follow the Prometheus conventions for the job and instance labels
+		if v, ok := resource["service_name"]; ok {
+			resource["job"] = v
+		}
+		if v, ok := resource["service_instance_id"]; ok {
+			resource["instance"] = v
+		}
+
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, m := range sm.GetMetrics() {
+				name := otlpName(m.GetName())
+
+				metricCfg, ok := h.collector.Resolve(name)
+				if !ok {
+					reject(fmt.Sprintf("metric '%s' not found", name))
+					continue
+				}
+
+				labels := func(attrs []*commonpb.KeyValue) map[string]string {
+					l := maps.Clone(resource)
+					maps.Copy(l, otlpLabels(attrs))
+					return l
+				}
+
+				switch {
+				case m.GetGauge() != nil:
+					for _, dp := range m.GetGauge().GetDataPoints() {
+						if noRecordedValue(dp.GetFlags()) {
+							continue
+						}
+						samples = append(samples, otlpSample{metric: metricCfg, labels: labels(dp.GetAttributes()), value: numberValue(dp), kind: "gauge"})
+					}
+				case m.GetSum() != nil:
+					sum := m.GetSum()
+					cumulative := sum.GetAggregationTemporality() == metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+					for _, dp := range sum.GetDataPoints() {
+						if noRecordedValue(dp.GetFlags()) {
+							continue
+						}
+						s := otlpSample{metric: metricCfg, labels: labels(dp.GetAttributes()), value: numberValue(dp), kind: "sum"}
+
+						// Counters are incremented by the delta of cumulative sums
+						if cumulative && metricCfg.Type == config.MetricTypeCounter {
+							deltas, err := h.cumulativeDelta(metricCfg.Name, s.labels, dp.GetStartTimeUnixNano(), []float64{s.value})
+							if err != nil {
+								reject(fmt.Sprintf("metric '%s': %s", name, err))
+								continue
+							}
+							s.value = deltas[0]
+						}
+						samples = append(samples, s)
+					}
+				case m.GetHistogram() != nil:
+					hist := m.GetHistogram()
+					cumulative := hist.GetAggregationTemporality() == metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+					for _, dp := range hist.GetDataPoints() {
+						if noRecordedValue(dp.GetFlags()) {
+							continue
+						}
+						s, err := h.histogramSample(metricCfg, labels(dp.GetAttributes()), dp, cumulative)
+						if err != nil {
+							reject(err.Error())
+							continue
+						}
+						samples = append(samples, s)
+					}
+				default:
+					reject(fmt.Sprintf("metric '%s' has an unsupported data type", name))
+				}
+			}
+		}
+	}
+
+	return samples
+}
+
+// histogramSample expands a histogram data point into observations. A data point
+// with a single observation and a sum is observed exactly, otherwise every count is
+// observed at the upper bound of its bucket.
+func (h *MetricHandler) histogramSample(metricCfg config.MetricConfig, labels map[string]string, dp *metricspb.HistogramDataPoint, cumulative bool) (otlpSample, error) {
+	if metricCfg.Type != config.MetricTypeHistogram && metricCfg.Type != config.MetricTypeSummary {
+		return otlpSample{}, fmt.Errorf("metric '%s' is a %s, cannot push histogram data points", metricCfg.Name, metricCfg.Type)
+	}
+
+	bounds := dp.GetExplicitBounds()
+	counts := dp.GetBucketCounts()
+
+	// Values are the count, the sum, and the bucket counts
+	values := make([]float64, 0, len(counts)+2)
+	values = append(values, float64(dp.GetCount()), dp.GetSum())
+	for _, c := range counts {
+		values = append(values, float64(c))
+	}
+
+	if cumulative {
+		var err error
+		if values, err = h.cumulativeDelta(metricCfg.Name, labels, dp.GetStartTimeUnixNano(), values); err != nil {
+			return otlpSample{}, fmt.Errorf("metric '%s': %w", metricCfg.Name, err)
+		}
+	}
+
+	count, sum, bucketCounts := values[0], values[1], values[2:]
+	if count > maxHistogramObservations {
+		return otlpSample{}, fmt.Errorf("metric '%s' data point has more than %d observations", metricCfg.Name, maxHistogramObservations)
+	}
+
+	s := otlpSample{metric: metricCfg, labels: labels, value: sum, kind: "histogram"}
+
+	if count == 1 && dp.Sum != nil {
+		s.values = []float64{sum}
+		return s, nil
+	}
+
+	for i, n := range bucketCounts {
+		var v float64
+		switch {
+		case i < len(bounds):
+			v = bounds[i]
+		case dp.Max != nil:
+			v = dp.GetMax()
+		case len(bounds) > 0:
+			v = bounds[len(bounds)-1]
+		case count > 0:
+			v = sum / count
+		}
+
+		for range int(n) {
+			s.values = append(s.values, v)
+		}
+	}
+
+	return s, nil
+}
+
+// applyOTLPSample validates and applies a sample to the collector
+func (h *MetricHandler) applyOTLPSample(r *http.Request, s otlpSample) error {
+	if s.kind == "gauge" && s.metric.Type == config.MetricTypeCounter {
+		return fmt.Errorf("metric '%s' is a counter, cannot push gauge data points", s.metric.Name)
+	}
+
+	update := MetricUpdate{
+		Name:   s.metric.Name,
+		Type:   s.metric.Type.String(),
+		Value:  s.value,
+		Labels: s.labels,
+	}
+
+	metricType, perr := h.prepareUpdate(r.Context(), &update)
+	if perr != nil {
+		return perr
+	}
+
+	if s.kind != "histogram" {
+		return h.applyUpdate(r.Context(), metricType, update)
+	}
+
+	for _, v := range s.values {
+		update.Value = v
+		if err := h.applyUpdate(r.Context(), metricType, update); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// noRecordedValue reports whether the data point flags mark a missing value
+func noRecordedValue(flags uint32) bool {
+	return flags&uint32(metricspb.DataPointFlags_DATA_POINT_FLAGS_NO_RECORDED_VALUE_MASK) != 0
+}
+
+// numberValue returns the value of a number data point as a float
+func numberValue(dp *metricspb.NumberDataPoint) float64 {
+	if v, ok := dp.GetValue().(*metricspb.NumberDataPoint_AsInt); ok {
+		return float64(v.AsInt)
+	}
+	return dp.GetAsDouble()
+}
+
+// otlpLabels converts attributes into labels, attributes that aren't scalars are dropped
+func otlpLabels(attrs []*commonpb.KeyValue) map[string]string {
+	labels := make(map[string]string, len(attrs))
+	for _, kv := range attrs {
+		var value string
+		switch v := kv.GetValue().GetValue().(type) {
+		case *commonpb.AnyValue_StringValue:
+			value = v.StringValue
+		case *commonpb.AnyValue_BoolValue:
+			value = strconv.FormatBool(v.BoolValue)
+		case *commonpb.AnyValue_IntValue:
+			value = strconv.FormatInt(v.IntValue, 10)
+		case *commonpb.AnyValue_DoubleValue:
+			value = strconv.FormatFloat(v.DoubleValue, 'g', -1, 64)
+		default:
+			continue
+		}
+		labels[otlpName(kv.GetKey())] = value
+	}
+	return labels
+}
+
+// otlpName replaces the characters that aren't valid in Prometheus names with underscores
+func otlpName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == ':' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// deltaKey identifies a series for delta tracking
+func deltaKey(name string, labels map[string]string) string {
+	var b strings.Builder
+	b.WriteString(name)
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		b.WriteString("\xff" + k + "=" + labels[k])
+	}
+	return b.String()
+}
@@ -0,0 +1,167 @@
+package web
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestDeltaTracker(t *testing.T) {
+	type sample struct {
+		start   uint64
+		values  []float64
+		counted bool
+		want    []float64
+	}
+
+	tests := []struct {
+		name    string
+		samples []sample
+	}{
+		{
+			name: "first values are returned unchanged",
+			samples: []sample{
+				{start: 1, values: []float64{5}, want: []float64{5}},
+			},
+		},
+		{
+			name: "increase since the previous values",
+			samples: []sample{
+				{start: 1, values: []float64{5, 10}, want: []float64{5, 10}},
+				{start: 1, values: []float64{7, 10}, want: []float64{2, 0}},
+				{start: 1, values: []float64{10, 13}, want: []float64{3, 3}},
+			},
+		},
+		{
+			name: "new start time resets the series",
+			samples: []sample{
+				{start: 1, values: []float64{5}, want: []float64{5}},
+				{start: 2, values: []float64{8}, want: []float64{8}},
+				{start: 2, values: []float64{9}, want: []float64{1}},
+			},
+		},
+		{
+			name: "decrease resets the series",
+			samples: []sample{
+				{start: 1, values: []float64{5, 5}, want: []float64{5, 5}},
+				{start: 1, values: []float64{6, 2}, want: []float64{6, 2}},
+			},
+		},
+		{
+			name: "changed number of values resets the series",
+			samples: []sample{
+				{start: 1, values: []float64{5}, want: []float64{5}},
+				{start: 1, values: []float64{6, 1}, want: []float64{6, 1}},
+			},
+		},
+		{
+			name: "previous NaN resets the series",
+			samples: []sample{
+				{start: 1, values: []float64{math.NaN()}, want: []float64{math.NaN()}},
+				{start: 1, values: []float64{4}, want: []float64{4}},
+			},
+		},
+		{
+			name: "counted series start from a baseline",
+			samples: []sample{
+				{start: 1, values: []float64{5}, counted: true, want: []float64{0}},
+				{start: 1, values: []float64{7}, counted: true, want: []float64{2}},
+			},
+		},
+	}
+
+	equal := func(a, b float64) bool { return a == b || math.IsNaN(a) && math.IsNaN(b) }
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tracker deltaTracker
+			labels := map[string]string{"job": "backup"}
+
+			for i, s := range tt.samples {
+				got, err := tracker.delta("backup_runs_total", labels, s.start, s.values, s.counted)
+				if err != nil {
+					t.Fatalf("delta() of sample %d: %v", i, err)
+				}
+				if !slices.EqualFunc(got, s.want, equal) {
+					t.Errorf("delta() of sample %d = %v, want %v", i, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDeltaTrackerSeriesAreIndependent(t *testing.T) {
+	var tracker deltaTracker
+
+	a := map[string]string{"job": "a"}
+	b := map[string]string{"job": "b"}
+
+	_, _ = tracker.delta("runs_total", a, 1, []float64{5}, false)
+	_, _ = tracker.delta("runs_total", b, 1, []float64{1}, false)
+
+	got, _ := tracker.delta("runs_total", a, 1, []float64{6}, false)
+	if !slices.Equal(got, []float64{1}) {
+		t.Errorf("delta() = %v, want [1]", got)
+	}
+
+	got, _ = tracker.delta("other_total", a, 1, []float64{6}, false)
+	if !slices.Equal(got, []float64{6}) {
+		t.Errorf("delta() of another metric = %v, want [6]", got)
+	}
+}
+
+func TestDeltaTrackerPrune(t *testing.T) {
+	exists := map[string]bool{"kept": true}
+	tracker := deltaTracker{
+		exists: func(_ string, labels map[string]string) bool { return exists[labels["job"]] },
+	}
+
+	for _, job := range []string{"kept", "expired", "recent"} {
+		if _, err := tracker.delta("runs_total", map[string]string{"job": job}, 1, []float64{1}, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Now()
+	for key, s := range tracker.series {
+		if s.labels["job"] != "recent" {
+			s.seen = now.Add(-deltaPruneInterval)
+			tracker.series[key] = s
+		}
+	}
+
+	tracker.prune(now)
+
+	var tracked []string
+	for _, s := range tracker.series {
+		tracked = append(tracked, s.labels["job"])
+	}
+	slices.Sort(tracked)
+	if want := []string{"kept", "recent"}; !slices.Equal(tracked, want) {
+		t.Errorf("tracked series after prune = %v, want %v", tracked, want)
+	}
+}
+
+func TestDeltaTrackerLimit(t *testing.T) {
+	tracker := deltaTracker{series: make(map[string]deltaState, maxDeltaSeries)}
+	for i := range maxDeltaSeries {
+		labels := map[string]string{"job": strconv.Itoa(i)}
+		tracker.series[deltaKey("runs_total", labels)] = deltaState{name: "runs_total", labels: labels, start: 1, values: []float64{1}}
+	}
+	tracker.pruned = time.Now()
+
+	if _, err := tracker.delta("runs_total", map[string]string{"job": "new"}, 1, []float64{1}, false); !errors.Is(err, errTooManyDeltaSeries) {
+		t.Errorf("delta() of a new series error = %v, want %v", err, errTooManyDeltaSeries)
+	}
+
+	got, err := tracker.delta("runs_total", map[string]string{"job": "0"}, 1, []float64{3}, false)
+	if err != nil {
+		t.Fatalf("delta() of a tracked series: %v", err)
+	}
+	if !slices.Equal(got, []float64{2}) {
+		t.Errorf("delta() of a tracked series = %v, want [2]", got)
+	}
+}
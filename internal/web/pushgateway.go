@@ -0,0 +1,150 @@
+package web
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+)
+
+// PushgatewayHandler implements the Pushgateway API on top of the collector. The
+// grouping key is taken from the path, /metrics/job/<job>{/<label>/<value>}, and
+// the body is in the Prometheus text exposition format.
+//
+//   - PUT replaces all series of the grouping key
+//   - POST replaces the series of the grouping key for the pushed metrics only
+//   - DELETE deletes all series of the grouping key
+func (h *MetricHandler) PushgatewayHandler(w http.ResponseWriter, r *http.Request) {
+	grouping, err := parseGroupingKey(r.PathValue("rest"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+		return
+	}
+
+	key, hasKey := APIKeyFromContext(r.Context())
+
+	var (
+		updates []MetricUpdate
+		types   []config.MetricType
+	)
+
+	if r.Method != http.MethodDelete {
+		body, perr := readBody(r)
+		if perr != nil {
+			perr.write(w)
+			return
+		}
+
+		families, err := parseExposition(body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeInvalidBody, err.Error())
+			return
+		}
+
+		updates, perr = h.expositionUpdates(families, grouping)
+		if perr != nil {
+			perr.write(w)
+			return
+		}
+
+		// Validate every update before modifying the group
+		types = make([]config.MetricType, len(updates))
+		for i := range updates {
+			metricType, perr := h.prepareUpdate(r.Context(), &updates[i])
+			if perr != nil {
+				perr.write(w)
+				return
+			}
+			types[i] = metricType
+		}
+	}
+
+	// Remove the previous series of the group. Observations of histograms and
+	// summaries accumulate, so only a delete removes their series.
+	replace := map[string]bool{}
+	if r.Method == http.MethodPost {
+		for _, u := range updates {
+			replace[u.Name] = true
+		}
+	} else {
+		for _, m := range h.collector.Metrics() {
+			if !hasKey || key.Allows(m.Name) {
+				replace[m.Name] = true
+			}
+		}
+	}
+
+	for _, m := range h.collector.Metrics() {
+		if !replace[m.Name] {
+			continue
+		}
+
+		if r.Method != http.MethodDelete && (m.Type == config.MetricTypeHistogram || m.Type == config.MetricTypeSummary) {
+			continue
+		}
+
+		if _, err := h.collector.DeleteMatching(m.Name, grouping); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+			return
+		}
+	}
+
+	for i, u := range updates {
+		if err := h.applyUpdate(r.Context(), types[i], u); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+			return
+		}
+	}
+
+	if r.Method == http.MethodDelete {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// parseGroupingKey parses the grouping key from the path following /metrics/job/,
+// supporting the base64 encoding of label values with the @base64 suffix.
+func parseGroupingKey(path string) (map[string]string, error) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) == 0 || parts[0] == "" {
+		return nil, fmt.Errorf("job name is required")
+	}
+
+	// The first part is the value of the job label
+	parts = append([]string{"job"}, parts...)
+	if len(parts)%2 != 0 {
+		return nil, fmt.Errorf("grouping key must consist of label name and value pairs")
+	}
+
+	grouping := make(map[string]string, len(parts)/2)
+	for i := 0; i < len(parts); i += 2 {
+		name, value := parts[i], parts[i+1]
+
+		if trimmed, ok := strings.CutSuffix(name, "@base64"); ok {
+			decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+			if err != nil {
+				return nil, fmt.Errorf("invalid base64 value for label '%s': %w", trimmed, err)
+			}
+			name, value = trimmed, string(decoded)
+		}
+
+		if name == "" {
+			return nil, fmt.Errorf("empty label name in grouping key")
+		}
+
+		if _, ok := grouping[name]; ok {
+			return nil, fmt.Errorf("duplicate label '%s' in grouping key", name)
+		}
+		grouping[name] = value
+	}
+
+	if grouping["job"] == "" {
+		return nil, fmt.Errorf("job name is required")
+	}
+
+	return grouping, nil
+}
@@ -0,0 +1,266 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"github.com/rs/zerolog/log"
+)
+
+// errQueueFull rejects pushes that don't fit into the ingestion queue
+var errQueueFull = &pushError{http.StatusServiceUnavailable, CodeQueueFull, "Ingestion queue is full, retry later"}
+
+// queuedUpdate is a validated update waiting to be applied
+type queuedUpdate struct {
+	ctx        context.Context // Context of the push without its cancellation
+	metricType config.MetricType
+	update     MetricUpdate
+	key        string // Coalescing key, empty for updates that are never coalesced
+	barrier    string // Key of pending updates later updates must not be coalesced into
+}
+
+// updateQueue holds validated updates until they are applied in the background.
+// Its memory is bounded by the capacity, coalesced updates take no extra room.
+type updateQueue struct {
+	cfg config.Queue
+
+	mu      sync.Mutex
+	pending []*queuedUpdate
+	byKey   map[string]*queuedUpdate // Pending updates that may be coalesced
+}
+
+func newUpdateQueue(cfg config.Queue) *updateQueue {
+	return &updateQueue{
+		cfg:   cfg,
+		byKey: make(map[string]*queuedUpdate),
+	}
+}
+
+// coalesceKey returns the key pending updates are coalesced by. Gauge sets, info,
+// and enum updates replace a pending update of the series, counter increments
+// without an exemplar are added to it. Other updates are never coalesced.
+func coalesceKey(metricType config.MetricType, update MetricUpdate) string {
+	var kind string
+	switch {
+	case metricType == config.MetricTypeGauge && (update.Op == "" || update.Op == "set"):
+		kind = "set"
+	case metricType == config.MetricTypeCounter && update.Exemplar == nil:
+		kind = "add"
+	case metricType == config.MetricTypeInfo, metricType == config.MetricTypeEnum:
+		kind = "set"
+	default:
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(kind)
+	b.WriteByte(0xff)
+	b.WriteString(update.Name)
+	for _, k := range slices.Sorted(maps.Keys(update.Labels)) {
+		b.WriteByte(0xff)
+		b.WriteString(k)
+		b.WriteByte(0xfe)
+		b.WriteString(update.Labels[k])
+	}
+	return b.String()
+}
+
+// add queues the updates, they are queued together or not at all. It reports false
+// if the queue is full and rejects updates that don't fit.
+func (q *updateQueue) add(items ...*queuedUpdate) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.cfg.Overflow == config.QueueOverflowReject && len(q.pending)+q.needed(items) > q.cfg.Capacity {
+		queueOverflow.WithLabelValues("rejected").Add(float64(len(items)))
+		return false
+	}
+
+	for _, item := range items {
+		if item.barrier != "" {
+			delete(q.byKey, item.barrier)
+		}
+
+		if prev, ok := q.byKey[item.key]; ok && item.key != "" {
+			if strings.HasPrefix(item.key, "add") {
+				prev.update.Value += item.update.Value
+			} else {
+				prev.update = item.update
+			}
+			prev.ctx = item.ctx
+			queueCoalesced.Inc()
+			continue
+		}
+
+		if len(q.pending) >= q.cfg.Capacity {
+			oldest := q.pending[0]
+			q.pending[0] = nil
+			q.pending = q.pending[1:]
+			if q.byKey[oldest.key] == oldest {
+				delete(q.byKey, oldest.key)
+			}
+			queueOverflow.WithLabelValues("dropped").Inc()
+		}
+
+		q.pending = append(q.pending, item)
+		if item.key != "" {
+			q.byKey[item.key] = item
+		}
+	}
+
+	queueDepth.Set(float64(len(q.pending)))
+	return true
+}
+
+// needed returns the number of entries the updates take at most, updates that are
+// coalesced into a pending update take none
+func (q *updateQueue) needed(items []*queuedUpdate) int {
+	var n int
+	for _, item := range items {
+		if _, ok := q.byKey[item.key]; !ok || item.key == "" {
+			n++
+		}
+	}
+	return n
+}
+
+// take removes and returns all pending updates
+func (q *updateQueue) take() []*queuedUpdate {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	pending := q.pending
+	q.pending = make([]*queuedUpdate, 0, len(pending))
+	clear(q.byKey)
+	queueDepth.Set(0)
+
+	return pending
+}
+
+// EnableQueue makes the push handlers queue valid updates of async pushes and
+// answer with 202 Accepted instead of applying them, RunQueue applies the queued
+// updates.
+func (h *MetricHandler) EnableQueue(cfg config.Queue) {
+	h.queue = newUpdateQueue(cfg)
+}
+
+// RunQueue applies the queued updates every flush interval until the context is
+// canceled. It returns right away if the queue isn't enabled.
+func (h *MetricHandler) RunQueue(ctx context.Context) {
+	if h.queue == nil {
+		return
+	}
+
+	ticker := time.NewTicker(h.queue.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.FlushQueue()
+		}
+	}
+}
+
+// FlushQueue applies all queued updates. The pushers were already answered, so
+// failed updates are counted, logged, and shown on the status page.
+func (h *MetricHandler) FlushQueue() {
+	if h.queue == nil {
+		return
+	}
+
+	for _, item := range h.queue.take() {
+		err := h.applyQueued(item)
+		if err == nil {
+			continue
+		}
+
+		requestID := RequestIDFromContext(item.ctx)
+		pushUpdates.WithLabelValues("failed").Inc()
+		h.failures.add(PushFailure{
+			Time:      time.Now(),
+			RequestID: requestID,
+			Metric:    item.update.Name,
+			Code:      CodeInternal,
+			Message:   err.Error(),
+		})
+		log.Warn().Err(err).Str("metric", item.update.Name).Str("request_id", requestID).Msg("error applying queued update")
+	}
+}
+
+// applyQueued applies a queued update. A panic is returned as an error, it would
+// crash the server from the flush goroutine and lose the rest of the queue.
+func (h *MetricHandler) applyQueued(item *queuedUpdate) (err error) {
+	defer func() {
+		if rvr := recover(); rvr != nil {
+			log.Error().
+				Interface("panic", rvr).
+				Bytes("stack", debug.Stack()).
+				Str("metric", item.update.Name).
+				Msg("recovered from panic applying queued update")
+			err = fmt.Errorf("panic applying update: %v", rvr)
+		}
+	}()
+
+	return h.applyUpdate(item.ctx, item.metricType, item.update)
+}
+
+// async reports whether the updates of a push are queued. The async query
+// parameter selects the mode, the queue configuration sets the default.
+func (h *MetricHandler) async(r *http.Request) (bool, *pushError) {
+	param := r.URL.Query().Get("async")
+	if param == "" {
+		return h.asyncDefault(), nil
+	}
+
+	async, err := strconv.ParseBool(param)
+	if err != nil {
+		return false, &pushError{http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("Invalid async parameter '%s'", param)}
+	}
+
+	if async && h.queue == nil {
+		return false, &pushError{http.StatusBadRequest, CodeBadRequest, "Async pushes require the ingestion queue, see web.queue"}
+	}
+
+	return async, nil
+}
+
+// asyncDefault reports whether pushes that don't select a mode are queued
+func (h *MetricHandler) asyncDefault() bool {
+	return h.queue != nil && h.queue.cfg.AsyncDefault()
+}
+
+// enqueue queues validated updates, it reports false if the queue is full
+func (h *MetricHandler) enqueue(ctx context.Context, metricTypes []config.MetricType, updates []MetricUpdate) bool {
+	ctx = context.WithoutCancel(ctx)
+
+	items := make([]*queuedUpdate, len(updates))
+	for i, update := range updates {
+		items[i] = &queuedUpdate{
+			ctx:        ctx,
+			metricType: metricTypes[i],
+			update:     update,
+			key:        coalesceKey(metricTypes[i], update),
+		}
+
+		// Other gauge operations depend on the value set before them
+		if items[i].key == "" && metricTypes[i] == config.MetricTypeGauge {
+			set := update
+			set.Op = ""
+			items[i].barrier = coalesceKey(metricTypes[i], set)
+		}
+	}
+
+	return h.queue.add(items...)
+}
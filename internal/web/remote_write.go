@@ -0,0 +1,136 @@
+package web
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	remotev1 "github.com/hay-kot/cronprom/pkg/pb/cronprom/remote/v1"
+	"github.com/klauspost/compress/snappy"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/proto"
+)
+
+// staleNaN is the NaN Prometheus writes as the last sample of a series that
+// disappeared, a staleness marker rather than a value
+const staleNaN uint64 = 0x7ff0000000000002
+
+// RemoteWriteHandler accepts snappy compressed Prometheus remote write requests.
+// Every series updates the configured metric of the same name: gauges take the
+// latest sample, counters are incremented by the increase of the series, and
+// histograms and summaries observe every sample. Series of unknown metrics are
+// skipped, as agents usually forward more than cronprom is configured for.
+// Staleness markers are skipped too, they aren't values.
+func (h *MetricHandler) RemoteWriteHandler(w http.ResponseWriter, r *http.Request) {
+	body, perr := readBody(r)
+	if perr != nil {
+		perr.write(w)
+		return
+	}
+
+	if n, err := snappy.DecodedLen(body); err != nil || n > maxDecompressedBody {
+		writeError(w, http.StatusBadRequest, CodeInvalidBody, "Error decompressing request body")
+		return
+	}
+
+	data, err := snappy.Decode(nil, body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidBody, "Error decompressing request body")
+		return
+	}
+
+	var req remotev1.WriteRequest
+	if err := proto.Unmarshal(data, &req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidBody, "Error parsing remote write request")
+		return
+	}
+
+	var applied, skipped, rejected int
+	for _, ts := range req.GetTimeseries() {
+		name, labels := seriesLabels(ts.GetLabels())
+
+		metricCfg, ok := h.collector.Resolve(name)
+		if !ok {
+			skipped++
+			continue
+		}
+
+		samples := slices.SortedFunc(slices.Values(ts.GetSamples()), func(a, b *remotev1.Sample) int {
+			return cmp.Compare(a.GetTimestamp(), b.GetTimestamp())
+		})
+		samples = slices.DeleteFunc(samples, func(s *remotev1.Sample) bool {
+			return math.Float64bits(s.GetValue()) == staleNaN
+		})
+		if metricCfg.Type == config.MetricTypeGauge && len(samples) > 0 {
+			samples = samples[len(samples)-1:]
+		}
+
+		for _, sample := range samples {
+			update := MetricUpdate{
+				Name:   metricCfg.Name,
+				Type:   metricCfg.Type.String(),
+				Value:  sample.GetValue(),
+				Labels: labels,
+			}
+
+			if metricCfg.Type == config.MetricTypeCounter {
+				deltas, err := h.cumulativeDelta(metricCfg.Name, labels, 0, []float64{update.Value})
+				if err != nil {
+					log.Warn().Err(err).Str("metric", metricCfg.Name).Msg("remote write: sample rejected")
+					rejected++
+					continue
+				}
+				update.Value = deltas[0]
+			}
+
+			if err := h.applyRemoteSample(r, update); err != nil {
+				log.Warn().Err(err).Str("metric", metricCfg.Name).Msg("remote write: sample rejected")
+				rejected++
+				continue
+			}
+			applied++
+		}
+	}
+
+	log.Debug().
+		Int("applied", applied).
+		Int("skipped", skipped).
+		Int("rejected", rejected).
+		Msg("remote write request")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// applyRemoteSample validates and applies a single sample
+func (h *MetricHandler) applyRemoteSample(r *http.Request, update MetricUpdate) error {
+	metricType, perr := h.prepareUpdate(r.Context(), &update)
+	if perr != nil {
+		return perr
+	}
+
+	if err := h.applyUpdate(r.Context(), metricType, update); err != nil {
+		return fmt.Errorf("error applying sample: %w", err)
+	}
+
+	return nil
+}
+
+// seriesLabels splits the labels of a series into its name and the other labels
+func seriesLabels(pairs []*remotev1.Label) (string, map[string]string) {
+	var name string
+	labels := make(map[string]string, len(pairs))
+	for _, l := range pairs {
+		if l.GetName() == "__name__" {
+			name = l.GetValue()
+			continue
+		}
+		if !strings.HasPrefix(l.GetName(), "__") {
+			labels[l.GetName()] = l.GetValue()
+		}
+	}
+	return name, labels
+}
@@ -0,0 +1,60 @@
+package web
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestIDHeader is the header carrying the ID of a request
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength limits the length of request IDs accepted from clients
+const maxRequestIDLength = 128
+
+type requestIDCtxKey struct{}
+
+// RequestIDFromContext returns the ID of the request, if any
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDCtxKey{}).(string)
+	return id
+}
+
+// RequestID propagates the X-Request-ID header of the request, or generates a new
+// ID when it is missing or invalid. The ID is stored in the request context and
+// echoed in the response so clients can correlate failures with the server logs.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDCtxKey{}, id)))
+	})
+}
+
+// newRequestID generates a random 128 bit ID
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// validRequestID reports whether a client provided ID is safe to log and echo,
+// only printable ASCII without spaces is accepted
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,92 @@
+package web
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Middleware wraps an http.Handler with cross-cutting behavior
+type Middleware func(http.Handler) http.Handler
+
+// Chain wraps h with the given middleware, the first middleware is the outermost.
+func Chain(h http.Handler, mw ...Middleware) http.Handler {
+	for _, m := range slices.Backward(mw) {
+		h = m(h)
+	}
+	return h
+}
+
+// Router is a dedicated http.ServeMux with a middleware chain applied to every
+// route. Patterns use the Go 1.22 "METHOD /path/{param}" syntax. Requests to a
+// registered path with another method are answered with a 405 and an Allow header.
+type Router struct {
+	mux        *http.ServeMux
+	middleware []Middleware
+	allowed    map[string][]string // Methods registered per path
+
+	once    sync.Once
+	handler http.Handler
+}
+
+// NewRouter creates a new router with the given global middleware
+func NewRouter(mw ...Middleware) *Router {
+	return &Router{
+		mux:        http.NewServeMux(),
+		middleware: mw,
+		allowed:    make(map[string][]string),
+	}
+}
+
+// Use appends middleware to the global chain. It must be called before the
+// router starts serving requests.
+func (r *Router) Use(mw ...Middleware) {
+	r.middleware = append(r.middleware, mw...)
+}
+
+// Handle registers a handler for the pattern, wrapped in any route specific middleware.
+// Requests are counted and timed per pattern.
+func (r *Router) Handle(pattern string, h http.Handler, mw ...Middleware) {
+	r.mux.Handle(pattern, instrumentRoute(pattern, Chain(h, mw...)))
+
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok {
+		return
+	}
+
+	// The first method registered for a path also registers the catch-all for
+	// the other methods
+	if _, exists := r.allowed[path]; !exists {
+		r.mux.Handle(path, r.methodNotAllowed(path))
+	}
+
+	r.allowed[path] = append(r.allowed[path], method)
+	if method == http.MethodGet {
+		r.allowed[path] = append(r.allowed[path], http.MethodHead)
+	}
+}
+
+// methodNotAllowed responds with a 405 listing the methods registered for the path
+func (r *Router) methodNotAllowed(path string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		allow := strings.Join(r.allowed[path], ", ")
+		w.Header().Set("Allow", allow)
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method "+req.Method+" not allowed, use "+allow)
+	})
+}
+
+// HandleFunc registers a handler function for the pattern, wrapped in any route
+// specific middleware
+func (r *Router) HandleFunc(pattern string, fn http.HandlerFunc, mw ...Middleware) {
+	r.Handle(pattern, fn, mw...)
+}
+
+// ServeHTTP implements http.Handler
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.once.Do(func() {
+		r.handler = Chain(r.mux, r.middleware...)
+	})
+
+	r.handler.ServeHTTP(w, req)
+}
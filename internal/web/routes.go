@@ -0,0 +1,117 @@
+package web
+
+import (
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+)
+
+// maxSnapshotSize limits the body of restores, snapshots may exceed max_body_size
+const maxSnapshotSize = 256 << 20
+
+// Credentials are the secrets used to authenticate requests, loaded at startup
+type Credentials struct {
+	PushKeys        []config.APIKey // Keys accepted by the push API, empty disables auth
+	MetricsUsername string          // Basic auth username for /metrics, empty disables auth
+	MetricsPassword string
+}
+
+// Routes builds the routers for the application. The push APIs are served by the
+// public router, the exposition, health, and admin endpoints by the internal router.
+// Both are the same router unless an internal address is configured. Additional
+// middleware is applied after the built-in request ID, logging, recovery, and CORS
+// middleware.
+func Routes(cfg config.Web, creds Credentials, h *MetricHandler, health *Health, metrics http.Handler, mw ...Middleware) (public, internal *Router) {
+	mw = append([]Middleware{RealIP(cfg.TrustedProxyNetworks()), RequestID, AccessLog(cfg.RequestLogLevel()), Recoverer, CORS(cfg.CORS)}, mw...)
+
+	public = NewRouter(mw...)
+
+	internal = public
+	if cfg.InternalAddress != "" {
+		internal = NewRouter(mw...)
+		internal.HandleFunc("/", NotFoundHandler)
+	}
+	public.HandleFunc("/", NotFoundHandler)
+
+	r := public
+
+	var metricsMW []Middleware
+	if creds.MetricsUsername != "" {
+		metricsMW = append(metricsMW, BasicAuth(creds.MetricsUsername, creds.MetricsPassword))
+	}
+
+	push := []Middleware{AllowCIDRs(cfg.AllowedNetworks()), BearerAuth(creds.PushKeys), SourceLabel(cfg.SourceLabel), MaxBodySize(cfg.MaxBodySize)}
+
+	r.HandleFunc("POST /api/v1/push", h.PushHandler, push...)
+	r.HandleFunc("DELETE /api/v1/push", h.DeleteHandler, push...)
+	r.HandleFunc("POST /api/v1/push/batch", h.PushBatchHandler, push...)
+	r.HandleFunc("POST /api/v1/write", h.InfluxWriteHandler, push...)
+	r.HandleFunc("POST /v1/metrics", h.OTLPHandler, push...) // OTLP/HTTP
+	r.HandleFunc("POST /api/v1/receive", h.RemoteWriteHandler, push...)
+	r.HandleFunc("POST /api/v1/jobs/{name}/start", h.JobStartHandler, push...)
+	r.HandleFunc("POST /api/v1/jobs/{name}/finish", h.JobFinishHandler, push...)
+	r.HandleFunc("POST /api/v1/jobs/{name}/lock", h.JobLockHandler, push...)
+	r.HandleFunc("DELETE /api/v1/jobs/{name}/lock", h.JobUnlockHandler, push...)
+
+	// Pushgateway compatible API
+	r.HandleFunc("PUT /metrics/job/{rest...}", h.PushgatewayHandler, push...)
+	r.HandleFunc("POST /metrics/job/{rest...}", h.PushgatewayHandler, push...)
+	r.HandleFunc("DELETE /metrics/job/{rest...}", h.PushgatewayHandler, push...)
+
+	r = internal
+	r.HandleFunc("GET /{$}", h.StatusHandler, metricsMW...)
+	r.Handle("GET /metrics", metrics, metricsMW...)
+	r.HandleFunc("GET /api/v1/metrics", h.ListMetricsHandler, metricsMW...)
+	r.HandleFunc("GET /api/v1/metrics/{name}", h.GetMetricHandler, metricsMW...)
+	r.HandleFunc("GET /api/v1/stream", h.StreamHandler, metricsMW...)
+	r.HandleFunc("GET /api/v1/jobs/{name}/runs", h.JobRunsHandler, metricsMW...)
+
+	admin := []Middleware{AllowCIDRs(cfg.AllowedNetworks()), BearerAuth(creds.PushKeys), RequireAdmin}
+
+	r.HandleFunc("POST /api/v1/admin/metrics", h.CreateMetricHandler, append(admin, MaxBodySize(cfg.MaxBodySize))...)
+	r.HandleFunc("DELETE /api/v1/admin/metrics/{name}", h.DeleteMetricHandler, admin...)
+	r.HandleFunc("POST /api/v1/admin/snapshot", h.SnapshotHandler, admin...)
+	r.HandleFunc("POST /api/v1/admin/restore", h.RestoreHandler, append(admin, MaxBodySize(maxSnapshotSize))...)
+
+	probes := []*Router{internal}
+	if internal != public {
+		// Keep the push listener checkable by load balancers
+		probes = append(probes, public)
+	}
+
+	for _, p := range probes {
+		p.HandleFunc("GET /livez", health.LivezHandler)
+		p.HandleFunc("GET /readyz", health.ReadyzHandler)
+		p.HandleFunc("GET /health", health.HealthHandler)
+	}
+
+	if cfg.PProf {
+		r.HandleFunc("GET /debug/pprof/{name...}", PProfHandler, metricsMW...)
+		r.HandleFunc("POST /debug/pprof/{name...}", PProfHandler, metricsMW...)
+	}
+
+	r.HandleFunc("GET /api/v1/openapi.json", OpenAPIHandler)
+	if cfg.SwaggerUI {
+		r.HandleFunc("GET /api/v1/docs", SwaggerUIHandler)
+	}
+
+	return public, internal
+}
+
+// PProfHandler serves the runtime profiles of net/http/pprof under /debug/pprof/
+func PProfHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.PathValue("name") {
+	case "cmdline":
+		pprof.Cmdline(w, r)
+	case "profile":
+		pprof.Profile(w, r)
+	case "symbol":
+		pprof.Symbol(w, r)
+	case "trace":
+		pprof.Trace(w, r)
+	default:
+		// The index also serves the named profiles, e.g., heap and goroutine
+		pprof.Index(w, r)
+	}
+}
@@ -0,0 +1,99 @@
+package web
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+	"google.golang.org/grpc/metadata"
+)
+
+type sourceCtxKey struct{}
+
+// source is the label identifying the pusher of a request
+type source struct {
+	label string
+	value string // Empty when the source is unknown, e.g., a missing header
+}
+
+// SourceLabel resolves the source label of each request, it must run after
+// BearerAuth to label pushes with the name of their API key
+func SourceLabel(cfg config.SourceLabel) Middleware {
+	return func(next http.Handler) http.Handler {
+		if !cfg.Enabled() {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var value string
+			switch cfg.From {
+			case config.LabelSourceIp:
+				value = ClientIP(r).String()
+			case config.LabelSourceHeader:
+				value = r.Header.Get(cfg.Header)
+			case config.LabelSourceKey:
+				if key, ok := APIKeyFromContext(r.Context()); ok {
+					value = key.Name
+				}
+			}
+
+			ctx := context.WithValue(r.Context(), sourceCtxKey{}, source{label: cfg.Name, value: value})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// withGRPCSource resolves the source label of a gRPC call from its peer, metadata,
+// or API key
+func withGRPCSource(ctx context.Context, cfg config.SourceLabel) context.Context {
+	if !cfg.Enabled() {
+		return ctx
+	}
+
+	var value string
+	switch cfg.From {
+	case config.LabelSourceIp:
+		value = sourceIP(ctx)
+	case config.LabelSourceHeader:
+		md, _ := metadata.FromIncomingContext(ctx)
+		if values := md.Get(strings.ToLower(cfg.Header)); len(values) > 0 {
+			value = values[0]
+		}
+	case config.LabelSourceKey:
+		if key, ok := APIKeyFromContext(ctx); ok {
+			value = key.Name
+		}
+	}
+
+	return context.WithValue(ctx, sourceCtxKey{}, source{label: cfg.Name, value: value})
+}
+
+// labelSource sets the source label on an update of a metric that defines it. An
+// unknown source removes the label pushed by the client, so the missing label
+// policy of the metric applies.
+func (h *MetricHandler) labelSource(ctx context.Context, update *MetricUpdate) {
+	src, ok := ctx.Value(sourceCtxKey{}).(source)
+	if !ok {
+		return
+	}
+
+	metricCfg, ok := h.collector.Resolve(update.Name)
+	if !ok || !slices.Contains(metricCfg.Labels, src.label) {
+		return
+	}
+
+	labels := maps.Clone(update.Labels)
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+
+	if src.value == "" {
+		delete(labels, src.label)
+	} else {
+		labels[src.label] = src.value
+	}
+	update.Labels = labels
+}
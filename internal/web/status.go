@@ -0,0 +1,63 @@
+package web
+
+import (
+	_ "embed"
+	"html/template"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hay-kot/cronprom/internal/services/collector"
+	"github.com/rs/zerolog/log"
+)
+
+//go:embed status.html
+var statusHTML string
+
+var statusTemplate = template.Must(template.New("status").Funcs(template.FuncMap{
+	"deref": func(v *float64) string {
+		return strconv.FormatFloat(*v, 'g', -1, 64)
+	},
+	"labels": func(labels map[string]string) string {
+		pairs := make([]string, 0, len(labels))
+		for _, k := range slices.Sorted(maps.Keys(labels)) {
+			pairs = append(pairs, k+"="+strconv.Quote(labels[k]))
+		}
+		return "{" + strings.Join(pairs, ", ") + "}"
+	},
+}).Parse(statusHTML))
+
+// statusPage is the data rendered by the status page
+type statusPage struct {
+	Now      time.Time
+	Metrics  []collector.MetricInfo
+	Samples  []collector.MetricSamples
+	Failures []PushFailure
+}
+
+// StatusHandler renders an HTML overview of the configured metrics, their current
+// values, and the most recent rejected pushes
+func (h *MetricHandler) StatusHandler(w http.ResponseWriter, _ *http.Request) {
+	page := statusPage{
+		Now:      time.Now(),
+		Metrics:  h.collector.Metrics(),
+		Failures: h.failures.recent(),
+	}
+
+	for _, m := range page.Metrics {
+		samples, err := h.collector.Samples(m.Name)
+		// The metric may have been removed since it was listed
+		if err != nil || len(samples.Samples) == 0 {
+			continue
+		}
+		page.Samples = append(page.Samples, samples)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := statusTemplate.Execute(w, page); err != nil {
+		log.Error().Err(err).Msg("failed to render status page")
+	}
+}
@@ -0,0 +1,173 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	// streamBuffer is the number of events buffered per subscriber, events are dropped
+	// for subscribers that fall further behind
+	streamBuffer = 256
+	// streamHeartbeat is the interval of keep-alive comments on idle streams
+	streamHeartbeat = 15 * time.Second
+)
+
+// MetricEvent describes an update applied to the collector
+type MetricEvent struct {
+	Time   time.Time         `json:"time"`
+	Name   string            `json:"name"`
+	Type   string            `json:"type"`
+	Op     string            `json:"op,omitempty"` // Gauge operation, empty for sets
+	Labels map[string]string `json:"labels"`
+	Value  float64           `json:"value"`
+	Source string            `json:"source,omitempty"` // IP address of the pusher
+}
+
+// eventHub fans out metric events to the connected stream subscribers
+type eventHub struct {
+	mu   sync.RWMutex
+	subs map[chan MetricEvent]struct{}
+}
+
+// subscribe registers a new subscriber, the returned function unregisters it
+func (e *eventHub) subscribe() (<-chan MetricEvent, func()) {
+	ch := make(chan MetricEvent, streamBuffer)
+
+	e.mu.Lock()
+	if e.subs == nil {
+		e.subs = make(map[chan MetricEvent]struct{})
+	}
+	e.subs[ch] = struct{}{}
+	e.mu.Unlock()
+
+	return ch, func() {
+		e.mu.Lock()
+		delete(e.subs, ch)
+		e.mu.Unlock()
+	}
+}
+
+// publish sends the event to every subscriber without blocking the push
+func (e *eventHub) publish(event MetricEvent) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	for ch := range e.subs {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// close disconnects every subscriber
+func (e *eventHub) close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for ch := range e.subs {
+		close(ch)
+		delete(e.subs, ch)
+	}
+}
+
+// active reports whether anyone is subscribed, so pushes can skip building events
+func (e *eventHub) active() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return len(e.subs) > 0
+}
+
+// sourceIP returns the address of the client that sent a push over HTTP or gRPC
+func sourceIP(ctx context.Context) string {
+	if addr, ok := ctx.Value(clientIPCtxKey{}).(netip.Addr); ok {
+		return addr.String()
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		if ap, err := netip.ParseAddrPort(p.Addr.String()); err == nil {
+			return ap.Addr().Unmap().String()
+		}
+	}
+
+	return ""
+}
+
+// CloseStreams ends all open streams, long lived connections would otherwise hold
+// up a graceful shutdown
+func (h *MetricHandler) CloseStreams() {
+	h.events.close()
+}
+
+// StreamHandler streams every applied metric update as server-sent events. The
+// optional "metric" query parameter limits the stream to a single metric.
+func (h *MetricHandler) StreamHandler(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+
+	// Streams outlive the write timeout of the server
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	filter := r.URL.Query().Get("metric")
+	if filter != "" {
+		if _, ok := h.collector.Resolve(filter); !ok {
+			writeError(w, http.StatusNotFound, CodeMetricNotFound, fmt.Sprintf("metric '%s' not found", filter))
+			return
+		}
+	}
+
+	events, unsubscribe := h.events.subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if err := rc.Flush(); err != nil {
+		log.Error().Err(err).Msg("stream: response does not support flushing")
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if filter != "" && event.Name != filter {
+				continue
+			}
+
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+
+			if _, err := fmt.Fprintf(w, "event: metric\ndata: %s\n\n", data); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/hay-kot/cronprom/internal/data/config"
+)
+
+// TLSConfig builds the server TLS configuration, including client certificate
+// verification when a client CA is configured.
+func TLSConfig(cfg config.TLS) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	tlsCfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+
+	if cfg.ClientCAFile != "" {
+		pool, err := LoadCertPool(cfg.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.ClientCAs = pool
+	}
+
+	switch cfg.ClientAuth {
+	case config.ClientAuthTypeRequest:
+		tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+	case config.ClientAuthTypeRequire:
+		tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		tlsCfg.ClientAuth = tls.NoClientCert
+	}
+
+	return tlsCfg, nil
+}
+
+// LoadCertPool reads the PEM encoded CA certificates of a file into a pool, used to
+// verify clients of the server and the server by the commands
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("failed to parse CA file: no certificates found")
+	}
+
+	return pool, nil
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"slices"
+	"time"
 
 	"github.com/hay-kot/cronprom/internal/commands"
 	"github.com/rs/zerolog"
@@ -27,6 +29,151 @@ func build() string {
 	return fmt.Sprintf("%s (%s) %s", version, short, date)
 }
 
+// connFlags are shared by commands that connect to the cronprom server
+func connFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "ca-cert",
+			Usage:   "CA certificate used to verify the server certificate",
+			Sources: cli.EnvVars("CRONPROM_CA_CERT"),
+		},
+		&cli.StringFlag{
+			Name:    "cert",
+			Usage:   "client certificate for mutual TLS authentication",
+			Sources: cli.EnvVars("CRONPROM_CLIENT_CERT"),
+		},
+		&cli.StringFlag{
+			Name:    "key",
+			Usage:   "client certificate key for mutual TLS authentication",
+			Sources: cli.EnvVars("CRONPROM_CLIENT_KEY"),
+		},
+		&cli.StringFlag{
+			Name:    "unix-socket",
+			Usage:   "connect to the server over a unix domain socket, the host of the URL is ignored",
+			Sources: cli.EnvVars("CRONPROM_UNIX_SOCKET"),
+		},
+		&cli.BoolFlag{
+			Name:    "http2",
+			Usage:   "negotiate HTTP/2 with TLS servers, --http2=false forces HTTP/1.1",
+			Value:   true,
+			Sources: cli.EnvVars("CRONPROM_HTTP2"),
+		},
+	}
+}
+
+func connFlagValues(c *cli.Command) commands.FlagsConn {
+	return commands.FlagsConn{
+		CAFile:     c.String("ca-cert"),
+		CertFile:   c.String("cert"),
+		KeyFile:    c.String("key"),
+		UnixSocket: c.String("unix-socket"),
+		HTTP2:      c.Bool("http2"),
+	}
+}
+
+// configFlags are shared by the commands that load the configuration
+func configFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:     "config-path",
+			Usage:    "config file, directory whose config files are merged, or http(s) URL",
+			Sources:  cli.EnvVars("CRONPROM_CONFIG_PATH"),
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:    "config-format",
+			Usage:   "format of the config file, yaml, json, or toml, defaults to the format of its extension",
+			Sources: cli.EnvVars("CRONPROM_CONFIG_FORMAT"),
+		},
+		&cli.StringSliceFlag{
+			Name:    "config-header",
+			Usage:   "Header in the format 'Name: value' sent when fetching a config from a URL (can be specified multiple times)",
+			Sources: cli.EnvVars("CRONPROM_CONFIG_HEADERS"),
+		},
+		&cli.BoolFlag{
+			Name:    "config-template",
+			Usage:   "render the config files as Go templates with the environment as .Env and the values file as .Values",
+			Sources: cli.EnvVars("CRONPROM_CONFIG_TEMPLATE"),
+		},
+		&cli.StringFlag{
+			Name:    "config-values",
+			Usage:   "YAML file with the values of config templates",
+			Sources: cli.EnvVars("CRONPROM_CONFIG_VALUES"),
+		},
+		&cli.BoolFlag{
+			Name:    "strict-config",
+			Usage:   "reject unknown config fields instead of ignoring them",
+			Sources: cli.EnvVars("CRONPROM_STRICT_CONFIG"),
+		},
+		&cli.StringFlag{
+			Name:    "sops-age-key-file",
+			Usage:   "age key file decrypting sops encrypted config files",
+			Sources: cli.EnvVars("CRONPROM_SOPS_AGE_KEY_FILE", "SOPS_AGE_KEY_FILE"),
+		},
+	}
+}
+
+func configFlagValues(c *cli.Command) commands.FlagsConfig {
+	return commands.FlagsConfig{
+		Path:     c.String("config-path"),
+		Format:   c.String("config-format"),
+		Headers:  c.StringSlice("config-header"),
+		Template: c.Bool("config-template"),
+		Values:   c.String("config-values"),
+		Strict:   c.Bool("strict-config"),
+		AgeKey:   c.String("sops-age-key-file"),
+	}
+}
+
+// snapshotFlags are shared by the snapshot commands
+func snapshotFlags() []cli.Flag {
+	return append([]cli.Flag{
+		&cli.StringFlag{
+			Name:     "url",
+			Usage:    "Base URL of the cronprom server (e.g., http://localhost:8080)",
+			Required: true,
+			Sources:  cli.EnvVars("CRONPROM_ADMIN_URL"),
+		},
+		&cli.StringFlag{
+			Name:    "token",
+			Usage:   "Bearer token of an admin key",
+			Sources: cli.EnvVars("CRONPROM_TOKEN"),
+		},
+	}, connFlags()...)
+}
+
+// snapshotFlagValues reads the flags of the snapshot commands, the file defaults to
+// stdout or stdin
+func snapshotFlagValues(c *cli.Command) commands.FlagsSnapshot {
+	return commands.FlagsSnapshot{
+		URL:   c.String("url"),
+		Token: c.String("token"),
+		File:  c.Args().First(),
+		Conn:  connFlagValues(c),
+	}
+}
+
+// runArgs works around the flag parsing of urfave/cli, which parses the arguments
+// after the "--" of a command as its flags once they start with a positional
+// argument, e.g., the -c of "run -- sh -c". The "--" of the run command is
+// doubled, the first ends the flags and the second is kept in the arguments.
+func runArgs(args []string) []string {
+	sep := slices.Index(args, "--")
+	if sep < 0 || !slices.Contains(args[:sep], "run") {
+		return args
+	}
+	return slices.Concat(args[:sep], []string{"--"}, args[sep:])
+}
+
+// commandArgs returns the command of the run command without the "--" kept by
+// runArgs
+func commandArgs(args []string) []string {
+	if len(args) > 0 && args[0] == "--" {
+		return args[1:]
+	}
+	return args
+}
+
 func main() {
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
 
@@ -56,60 +203,325 @@ func main() {
 			{
 				Name:  "push",
 				Usage: "push metrics to cronmon",
-				Flags: []cli.Flag{
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:    "url",
+						Usage:   "URL of the cronprom API (e.g., http://localhost:8080/api/v1/push), required unless --udp is set",
+						Sources: cli.EnvVars("CRONPROM_URL"),
+					},
+					&cli.StringFlag{
+						Name:    "udp",
+						Usage:   "send the update as a single UDP packet to this address (e.g., localhost:9125) without waiting for a response",
+						Sources: cli.EnvVars("CRONPROM_UDP"),
+					},
+					&cli.StringFlag{
+						Name:    "token",
+						Usage:   "Bearer token used to authenticate with the cronprom API",
+						Sources: cli.EnvVars("CRONPROM_TOKEN"),
+					},
+					&cli.StringFlag{
+						Name:  "name",
+						Usage: "Name of the metric to update, required unless --keepalive is set",
+					},
+					&cli.StringFlag{
+						Name:  "type",
+						Usage: "Type of metric (gauge, counter, histogram, summary, info, enum), required unless --keepalive is set",
+					},
+					&cli.FloatFlag{
+						Name:  "value",
+						Usage: "Value to update the metric with, not used by the inc, dec, and set_to_current_time gauge operations",
+					},
+					&cli.StringFlag{
+						Name:  "op",
+						Usage: "Gauge operation (set, inc, dec, add, set_to_current_time)",
+					},
+					&cli.StringFlag{
+						Name:    "trace-id",
+						Usage:   "Trace ID attached as an exemplar to counter and histogram updates",
+						Sources: cli.EnvVars("CRONPROM_TRACE_ID"),
+					},
+					&cli.StringSliceFlag{
+						Name:  "label",
+						Usage: "Label in the format key=value (can be specified multiple times)",
+					},
+					&cli.StringFlag{
+						Name:  "output",
+						Usage: "Output format (text, json)",
+						Value: "text",
+					},
+					&cli.BoolFlag{
+						Name:  "keepalive",
+						Usage: "send updates read from stdin, one JSON object per line, over a persistent connection, --name, --type, and --label are defaults for the updates",
+					},
+				}, connFlags()...),
+				Action: func(ctx context.Context, c *cli.Command) error {
+					return commands.Push(ctx, commands.FlagsPush{
+						URL:       c.String("url"),
+						UDP:       c.String("udp"),
+						Name:      c.String("name"),
+						Type:      c.String("type"),
+						Labels:    c.StringSlice("label"),
+						Value:     c.Float("value"),
+						ValueSet:  c.IsSet("value"),
+						Op:        c.String("op"),
+						TraceID:   c.String("trace-id"),
+						Output:    c.String("output"),
+						Token:     c.String("token"),
+						KeepAlive: c.Bool("keepalive"),
+						Conn:      connFlagValues(c),
+					})
+				},
+			},
+			{
+				Name:  "get",
+				Usage: "read the current value of a metric from cronprom",
+				Flags: append([]cli.Flag{
 					&cli.StringFlag{
 						Name:     "url",
-						Usage:    "URL of the cronprom API (e.g., http://localhost:8080/api/v1/push)",
+						Usage:    "URL of the cronprom metrics endpoint (e.g., http://localhost:8080/metrics)",
 						Required: true,
-						Sources:  cli.EnvVars("CRONPROM_URL"),
+						Sources:  cli.EnvVars("CRONPROM_METRICS_URL"),
 					},
 					&cli.StringFlag{
 						Name:     "name",
-						Usage:    "Name of the metric to update",
+						Usage:    "Name of the metric to read",
 						Required: true,
 					},
+					&cli.StringSliceFlag{
+						Name:  "label",
+						Usage: "Only print series matching the label key=value (can be specified multiple times)",
+					},
 					&cli.StringFlag{
-						Name:     "type",
-						Usage:    "Type of metric (gauge, counter, histogram, summary)",
+						Name:    "username",
+						Usage:   "Basic auth username for the metrics endpoint",
+						Sources: cli.EnvVars("CRONPROM_METRICS_USERNAME"),
+					},
+					&cli.StringFlag{
+						Name:    "password",
+						Usage:   "Basic auth password for the metrics endpoint",
+						Sources: cli.EnvVars("CRONPROM_METRICS_PASSWORD"),
+					},
+					&cli.FloatSliceFlag{
+						Name:  "quantile",
+						Usage: "Quantile to print for histogram or summary metrics (can be specified multiple times)",
+					},
+					&cli.FloatSliceFlag{
+						Name:  "bucket",
+						Usage: "Upper bound of a histogram bucket to print the cumulative count of (can be specified multiple times)",
+					},
+					&cli.StringFlag{
+						Name:  "output",
+						Usage: "Output format (text, json)",
+						Value: "text",
+					},
+				}, connFlags()...),
+				Action: func(ctx context.Context, c *cli.Command) error {
+					return commands.Get(ctx, commands.FlagsGet{
+						URL:       c.String("url"),
+						Name:      c.String("name"),
+						Labels:    c.StringSlice("label"),
+						Quantiles: c.FloatSlice("quantile"),
+						Buckets:   c.FloatSlice("bucket"),
+						Output:    c.String("output"),
+						Username:  c.String("username"),
+						Password:  c.String("password"),
+						Conn:      connFlagValues(c),
+					})
+				},
+			},
+			{
+				Name:      "run",
+				Usage:     "run a command as a run of a job, reporting its start, finish, and exit code to cronprom",
+				ArgsUsage: "-- command [args...]",
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:     "url",
+						Usage:    "Base URL of the cronprom server (e.g., http://localhost:8080)",
 						Required: true,
+						Sources:  cli.EnvVars("CRONPROM_SERVER_URL"),
 					},
-					&cli.FloatFlag{
-						Name:     "value",
-						Usage:    "Value to update the metric with",
+					&cli.StringFlag{
+						Name:    "token",
+						Usage:   "Bearer token used to authenticate with the cronprom API",
+						Sources: cli.EnvVars("CRONPROM_TOKEN"),
+					},
+					&cli.StringFlag{
+						Name:     "job",
+						Usage:    "Name of the job the run is reported for",
 						Required: true,
+						Sources:  cli.EnvVars("CRONPROM_JOB"),
 					},
-					&cli.StringSliceFlag{
-						Name:  "label",
-						Usage: "Label in the format key=value (can be specified multiple times)",
+					&cli.DurationFlag{
+						Name:  "timeout",
+						Usage: "kill the process group of the command after this long and exit with 124, 0 lets it run until it exits",
 					},
-				},
+					&cli.BoolFlag{
+						Name:  "lock",
+						Usage: "skip the run and exit with 75 while another run of the job holds its lock, the lock is held on the server or in --lock-file if the server can't be reached",
+					},
+					&cli.DurationFlag{
+						Name:  "lock-wait",
+						Usage: "wait this long for the lock of the job before skipping the run",
+					},
+					&cli.StringFlag{
+						Name:  "lock-file",
+						Usage: "file locked when the server can't be reached, defaults to cronprom-<job>.lock in the temp directory",
+					},
+					&cli.IntFlag{
+						Name:  "output-tail",
+						Usage: "bytes of the end of the output of the command kept in the history of the job on the server, 0 disables the capture",
+						Value: 4096,
+					},
+				}, connFlags()...),
 				Action: func(ctx context.Context, c *cli.Command) error {
-					return commands.Push(ctx, commands.FlagsPush{
-						URL:    c.String("url"),
-						Name:   c.String("name"),
-						Type:   c.String("type"),
-						Labels: c.StringSlice("label"),
-						Value:  c.Float("value"),
+					code, err := commands.Run(ctx, commands.FlagsRun{
+						URL:      c.String("url"),
+						Token:    c.String("token"),
+						Job:      c.String("job"),
+						Timeout:  c.Duration("timeout"),
+						Lock:     c.Bool("lock"),
+						LockWait: c.Duration("lock-wait"),
+						LockFile: c.String("lock-file"),
+						Output:   int(c.Int("output-tail")),
+						Command:  commandArgs(c.Args().Slice()),
+						Conn:     connFlagValues(c),
 					})
+					if err != nil {
+						return err
+					}
+					if code != 0 {
+						// Exit with the code of the command, like cron expects
+						return cli.Exit("", code)
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "snapshot",
+				Usage: "save or restore the state of all series through the admin API",
+				Commands: []*cli.Command{
+					{
+						Name:      "save",
+						Usage:     "write a snapshot of the server to a file",
+						ArgsUsage: "[file]",
+						Flags:     snapshotFlags(),
+						Action: func(ctx context.Context, c *cli.Command) error {
+							return commands.SnapshotSave(ctx, snapshotFlagValues(c))
+						},
+					},
+					{
+						Name:      "restore",
+						Usage:     "load a snapshot file into the server",
+						ArgsUsage: "[file]",
+						Flags:     snapshotFlags(),
+						Action: func(ctx context.Context, c *cli.Command) error {
+							return commands.SnapshotRestore(ctx, snapshotFlagValues(c))
+						},
+					},
+				},
+			},
+			{
+				Name:  "config",
+				Usage: "manage configuration files",
+				Commands: []*cli.Command{
+					{
+						Name:  "init",
+						Usage: "write a starter config with example metrics, asks for the settings when run in a terminal",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "output",
+								Usage: "file to write, - for stdout",
+								Value: "cronprom.yml",
+							},
+							&cli.StringFlag{
+								Name:  "namespace",
+								Usage: "namespace of the metrics",
+								Value: "cron",
+							},
+							&cli.StringFlag{
+								Name:  "address",
+								Usage: "address to listen on",
+								Value: ":8080",
+							},
+							&cli.BoolFlag{
+								Name:  "force",
+								Usage: "overwrite an existing file",
+							},
+						},
+						Action: func(ctx context.Context, c *cli.Command) error {
+							return commands.ConfigInit(commands.FlagsConfigInit{
+								Output:    c.String("output"),
+								Namespace: c.String("namespace"),
+								Address:   c.String("address"),
+								Force:     c.Bool("force"),
+							})
+						},
+					},
+					{
+						Name:  "schema",
+						Usage: "print the JSON Schema of the config file for editors and CI",
+						Action: func(ctx context.Context, c *cli.Command) error {
+							return commands.ConfigSchema()
+						},
+					},
+				},
+			},
+			{
+				Name:  "validate",
+				Usage: "validate the configuration and print a summary, exits non-zero if it is invalid",
+				Flags: configFlags(),
+				Action: func(ctx context.Context, c *cli.Command) error {
+					return commands.Validate(ctx, configFlagValues(c))
 				},
 			},
 			{
 				Name:  "serve",
 				Usage: "serve the http backup for cronmon",
-				Flags: []cli.Flag{
+				Flags: append(configFlags(),
+					&cli.DurationFlag{
+						Name:    "config-refresh",
+						Usage:   "how often a config from a URL is fetched again to detect changes, 0 disables",
+						Value:   time.Minute,
+						Sources: cli.EnvVars("CRONPROM_CONFIG_REFRESH"),
+					},
 					&cli.StringFlag{
-						Name:     "config-path",
-						Usage:    "config path",
-						Sources:  cli.EnvVars("CRONPROM_CONFIG_PATH"),
-						Required: true,
+						Name:    "web.address",
+						Usage:   "address to listen on, overrides web.address of the config",
+						Sources: cli.EnvVars("CRONPROM_WEB_ADDRESS"),
 					},
-				},
+					&cli.StringFlag{
+						Name:    "web.internal-address",
+						Usage:   "address of the exposition and admin endpoints, overrides web.internal_address of the config",
+						Sources: cli.EnvVars("CRONPROM_WEB_INTERNAL_ADDRESS"),
+					},
+					&cli.StringFlag{
+						Name:    "namespace",
+						Usage:   "namespace of the metrics, overrides global.namespace of the config",
+						Sources: cli.EnvVars("CRONPROM_NAMESPACE"),
+					},
+					&cli.StringFlag{
+						Name:    "overlay-file",
+						Usage:   "file persisting metrics created through the admin API, overrides global.overlay_file of the config",
+						Sources: cli.EnvVars("CRONPROM_OVERLAY_FILE"),
+					},
+					&cli.StringFlag{
+						Name:    "state-file",
+						Usage:   "file the series values are checkpointed to, overrides global.state_file of the config",
+						Sources: cli.EnvVars("CRONPROM_STATE_FILE"),
+					},
+				),
 				Action: func(ctx context.Context, c *cli.Command) error {
 					return commands.Serve(ctx, commands.FlagsServe{
-						ConfigFile: c.String("config-path"),
-						Version:    version,
-						Commit:     commit,
-						Date:       date,
+						Config:          configFlagValues(c),
+						ConfigRefresh:   c.Duration("config-refresh"),
+						Address:         c.String("web.address"),
+						InternalAddress: c.String("web.internal-address"),
+						Namespace:       c.String("namespace"),
+						OverlayFile:     c.String("overlay-file"),
+						StateFile:       c.String("state-file"),
+						Version:         version,
+						Commit:          commit,
+						Date:            date,
 					})
 				},
 			},
@@ -118,7 +530,7 @@ func main() {
 
 	ctx := context.Background()
 
-	if err := app.Run(ctx, os.Args); err != nil {
+	if err := app.Run(ctx, runArgs(os.Args)); err != nil {
 		log.Fatal().Err(err).Msg("failed to run cronprom")
 	}
 }
@@ -0,0 +1,87 @@
+package validate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Exec is a Validator backed by an external command.
+//
+// The update is written to the command's stdin as JSON. A non-zero exit status vetoes
+// the push, using the command's stderr as the reason. On success the command may
+// write a JSON object to stdout to annotate the push:
+//
+//	{"labels": {"team": "platform"}}
+//
+// Annotated labels are merged into the update, overwriting existing values.
+type Exec struct {
+	Name    string
+	Command string
+	Args    []string
+	Timeout time.Duration
+}
+
+// Annotation is the optional output of an exec validator.
+type Annotation struct {
+	Labels map[string]string `json:"labels"`
+}
+
+// Validate runs the command for the given update.
+func (e *Exec) Validate(ctx context.Context, update *Update) error {
+	if e.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.Timeout)
+		defer cancel()
+	}
+
+	payload, err := json.Marshal(update)
+	if err != nil {
+		return fmt.Errorf("validator '%s': failed to marshal update: %w", e.Name, err)
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, e.Command, e.Args...)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && ctx.Err() == nil {
+			reason := strings.TrimSpace(stderr.String())
+			if reason == "" {
+				reason = fmt.Sprintf("exit status %d", exitErr.ExitCode())
+			}
+			return Reject("validator '%s': %s", e.Name, reason)
+		}
+
+		return fmt.Errorf("validator '%s': failed to run command: %w", e.Name, err)
+	}
+
+	out := bytes.TrimSpace(stdout.Bytes())
+	if len(out) == 0 {
+		return nil
+	}
+
+	var annotation Annotation
+	if err := json.Unmarshal(out, &annotation); err != nil {
+		return fmt.Errorf("validator '%s': invalid output: %w", e.Name, err)
+	}
+
+	if len(annotation.Labels) > 0 && update.Labels == nil {
+		update.Labels = make(map[string]string, len(annotation.Labels))
+	}
+
+	for k, v := range annotation.Labels {
+		update.Labels[k] = v
+	}
+
+	return nil
+}
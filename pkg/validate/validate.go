@@ -0,0 +1,79 @@
+// Package validate provides the extension point for custom push validators.
+//
+// Validators run after the built-in validation of a push and before the update is
+// applied to the collector. A validator can veto a push by returning an error, or
+// annotate it by modifying the update in place (e.g., adding or rewriting labels).
+package validate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrRejected is returned (wrapped) by validators that veto a push.
+var ErrRejected = errors.New("push rejected")
+
+// Update is a metric update as seen by validators. Changes to Value and Labels are
+// applied to the push, Name, Type, and Op are read-only.
+type Update struct {
+	Name   string            `json:"name"`
+	Type   string            `json:"type"`
+	Op     string            `json:"op,omitempty"` // Gauge operation, empty for sets
+	Value  float64           `json:"value"`
+	Labels map[string]string `json:"labels"`
+}
+
+// Validator inspects a push before it's applied to the collector.
+type Validator interface {
+	Validate(ctx context.Context, update *Update) error
+}
+
+// Func adapts an ordinary function to the Validator interface.
+type Func func(ctx context.Context, update *Update) error
+
+// Validate calls f(ctx, update).
+func (f Func) Validate(ctx context.Context, update *Update) error {
+	return f(ctx, update)
+}
+
+// Chain runs each validator in order, stopping at the first error.
+type Chain []Validator
+
+// Validate runs all validators in the chain.
+func (c Chain) Validate(ctx context.Context, update *Update) error {
+	for _, v := range c {
+		if err := v.Validate(ctx, update); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reject returns an error that vetoes a push with the given reason.
+func Reject(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrRejected, fmt.Sprintf(format, args...))
+}
+
+var (
+	mu         sync.RWMutex
+	registered []Validator
+)
+
+// Register adds a validator to the set run for every push. It's intended to be
+// called from the main package of a custom build before the server is started.
+func Register(v Validator) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	registered = append(registered, v)
+}
+
+// Registered returns the validators added with Register, in registration order.
+func Registered() []Validator {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return append([]Validator(nil), registered...)
+}